@@ -0,0 +1,64 @@
+package main
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Infrastructure"
+	"a2sv-backend/task_manager_v3/Usecases"
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// bootstrapAdminFromConfig creates the initial admin when the config names
+// one. It is safe to leave set: once an admin exists nothing happens.
+func bootstrapAdminFromConfig(userUsecase Usecases.UserUsecase, config Infrastructure.BootstrapConfig) {
+	if config.AdminUsername == "" {
+		return
+	}
+
+	user, err := userUsecase.BootstrapAdmin(config.AdminUsername, config.AdminPassword)
+	switch {
+	case errors.Is(err, Domain.ErrAlreadyBootstrapped):
+		log.Println("Admin already exists, skipping bootstrap admin")
+	case err != nil:
+		log.Fatalf("Could not create bootstrap admin: %v", err)
+	default:
+		log.Printf("Created bootstrap admin %q", user.Username)
+	}
+}
+
+// runBootstrapAdmin implements the bootstrap-admin command:
+//
+//	task_manager bootstrap-admin -username alice
+//
+// The password is the configured bootstrap admin password (such as
+// BOOTSTRAP_ADMIN_PASSWORD) or the first line of stdin, so it never shows
+// up in the process list.
+func runBootstrapAdmin(userUsecase Usecases.UserUsecase, password string, args []string) {
+	flags := flag.NewFlagSet("bootstrap-admin", flag.ExitOnError)
+	username := flags.String("username", "", "username of the admin to create")
+	flags.Parse(args)
+	if *username == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if password == "" {
+		fmt.Fprint(os.Stderr, "Password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			log.Fatalf("Could not read password: %v", err)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+
+	user, err := userUsecase.BootstrapAdmin(*username, password)
+	if err != nil {
+		log.Fatalf("Could not create bootstrap admin: %v", err)
+	}
+	fmt.Printf("Created admin %s (%s)\n", user.Username, user.ID.Hex())
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Usecases"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AccessTokenController struct {
+	accessTokenUsecase Usecases.AccessTokenUsecase
+}
+
+func NewAccessTokenController(accessTokenUsecase Usecases.AccessTokenUsecase) *AccessTokenController {
+	return &AccessTokenController{
+		accessTokenUsecase: accessTokenUsecase,
+	}
+}
+
+func accessTokenErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, Domain.ErrAccessTokenName), errors.Is(err, Domain.ErrInvalidScope),
+		errors.Is(err, Domain.ErrTokenLifetime):
+		return http.StatusBadRequest
+	case errors.Is(err, Domain.ErrTooManyTokens):
+		return http.StatusConflict
+	case err.Error() == "access token not found":
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// CreateAccessToken issues a personal access token. Body: name, scopes and
+// expires_in_days (90 when omitted). The token is only in this response.
+func (ac *AccessTokenController) CreateAccessToken(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req CreateAccessTokenRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	token, value, err := ac.accessTokenUsecase.Create(id, req.Name, req.Scopes, time.Duration(req.ExpiresInDays)*24*time.Hour)
+	if err != nil {
+		c.JSON(accessTokenErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"token": value, "access_token": token})
+}
+
+func (ac *AccessTokenController) ListAccessTokens(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	tokens, err := ac.accessTokenUsecase.List(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (ac *AccessTokenController) RevokeAccessToken(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID"})
+		return
+	}
+
+	if err := ac.accessTokenUsecase.Revoke(userID, id); err != nil {
+		c.JSON(accessTokenErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Access token revoked"})
+}
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Usecases"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AccountController struct {
+	accountUsecase Usecases.AccountUsecase
+}
+
+func NewAccountController(accountUsecase Usecases.AccountUsecase) *AccountController {
+	return &AccountController{
+		accountUsecase: accountUsecase,
+	}
+}
+
+func accountErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, Domain.ErrInvalidToken):
+		return http.StatusBadRequest
+	case errors.Is(err, Domain.ErrWeakPassword), errors.Is(err, Domain.ErrPasswordTooLong):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func (ac *AccountController) VerifyEmail(c *gin.Context) {
+	var req VerifyEmailRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if _, err := ac.accountUsecase.VerifyEmail(req.Token); err != nil {
+		c.JSON(accountErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+}
+
+func (ac *AccountController) ResendVerification(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := ac.accountUsecase.SendVerification(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Verification email sent"})
+}
+
+// forgotPasswordMessage is the only answer ForgotPassword gives, whether or
+// not the address belongs to anyone
+const forgotPasswordMessage = "If an account uses that email, a reset link is on its way"
+
+func (ac *AccountController) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	// Only real accounts get a token and a mail, and both take time, so the
+	// answer can't wait for them; neither can failures, which would also
+	// give away which addresses exist
+	go func(email string) {
+		if err := ac.accountUsecase.ForgotPassword(email); err != nil {
+			log.Printf("Password reset request failed: %v", err)
+		}
+	}(req.Email)
+
+	c.JSON(http.StatusAccepted, gin.H{"message": forgotPasswordMessage})
+}
+
+func (ac *AccountController) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if err := ac.accountUsecase.ResetPassword(req.Token, req.Password); err != nil {
+		c.JSON(accountErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Usecases"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AttachmentController struct {
+	attachmentUsecase Usecases.AttachmentUsecase
+}
+
+func NewAttachmentController(attachmentUsecase Usecases.AttachmentUsecase) *AttachmentController {
+	return &AttachmentController{
+		attachmentUsecase: attachmentUsecase,
+	}
+}
+
+// attachmentIDs parses the task and attachment IDs from the path
+func attachmentIDs(c *gin.Context) (taskID, id primitive.ObjectID, ok bool) {
+	taskID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return taskID, id, false
+	}
+	if raw := c.Param("attachmentId"); raw != "" {
+		if id, err = primitive.ObjectIDFromHex(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attachment ID"})
+			return taskID, id, false
+		}
+	}
+	return taskID, id, true
+}
+
+func attachmentErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, Domain.ErrAttachmentTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, Domain.ErrAttachmentNotFound), errors.Is(err, Domain.ErrTaskNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// UploadAttachment reads the multipart "file" part and streams it to the
+// blob store without buffering the whole upload
+func (ac *AttachmentController) UploadAttachment(c *gin.Context) {
+	taskID, _, ok := attachmentIDs(c)
+	if !ok {
+		return
+	}
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Expected a multipart/form-data upload"})
+		return
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		attachment, err := ac.attachmentUsecase.Upload(taskID, part.FileName(), part, viewerFromContext(c).Username)
+		part.Close()
+		if err != nil {
+			c.JSON(attachmentErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, attachment)
+		return
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Missing file field"})
+}
+
+func (ac *AttachmentController) ListAttachments(c *gin.Context) {
+	taskID, _, ok := attachmentIDs(c)
+	if !ok {
+		return
+	}
+
+	attachments, err := ac.attachmentUsecase.List(taskID)
+	if err != nil {
+		c.JSON(attachmentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, attachments)
+}
+
+func (ac *AttachmentController) DownloadAttachment(c *gin.Context) {
+	taskID, id, ok := attachmentIDs(c)
+	if !ok {
+		return
+	}
+
+	attachment, content, err := ac.attachmentUsecase.Open(taskID, id)
+	if err != nil {
+		c.JSON(attachmentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	defer content.Close()
+
+	etag := strconv.Quote(attachment.SHA256)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, content, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}),
+		"ETag":                   etag,
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+func (ac *AttachmentController) DeleteAttachment(c *gin.Context) {
+	taskID, id, ok := attachmentIDs(c)
+	if !ok {
+		return
+	}
+
+	if err := ac.attachmentUsecase.Delete(taskID, id); err != nil {
+		c.JSON(attachmentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Attachment deleted successfully"})
+}
@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Usecases"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type CatalogController struct {
+	catalogUsecase Usecases.CatalogUsecase
+}
+
+func NewCatalogController(catalogUsecase Usecases.CatalogUsecase) *CatalogController {
+	return &CatalogController{
+		catalogUsecase: catalogUsecase,
+	}
+}
+
+// catalogErrorStatus maps catalog usecase errors to HTTP status codes
+func catalogErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, Domain.ErrLabelExists), errors.Is(err, Domain.ErrCustomFieldExists):
+		return http.StatusConflict
+	case errors.Is(err, Domain.ErrLabelNotFound), errors.Is(err, Domain.ErrCustomFieldNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, Domain.ErrInvalidLabel), errors.Is(err, Domain.ErrInvalidCustomField):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func (cc *CatalogController) ListLabels(c *gin.Context) {
+	labels, err := cc.catalogUsecase.ListLabels(c.Param("project"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, labels)
+}
+
+func (cc *CatalogController) CreateLabel(c *gin.Context) {
+	var req LabelRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	label := req.label()
+	label.Project = c.Param("project")
+
+	created, err := cc.catalogUsecase.CreateLabel(label)
+	if err != nil {
+		c.JSON(catalogErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+func (cc *CatalogController) UpdateLabel(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid label ID"})
+		return
+	}
+
+	var req LabelRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	updated, err := cc.catalogUsecase.UpdateLabel(id, req.label())
+	if err != nil {
+		c.JSON(catalogErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+func (cc *CatalogController) DeleteLabel(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid label ID"})
+		return
+	}
+
+	if err := cc.catalogUsecase.DeleteLabel(id); err != nil {
+		c.JSON(catalogErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Label deleted successfully"})
+}
+
+func (cc *CatalogController) ListFields(c *gin.Context) {
+	fields, err := cc.catalogUsecase.ListFields()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, fields)
+}
+
+func (cc *CatalogController) CreateField(c *gin.Context) {
+	var req CustomFieldRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	created, err := cc.catalogUsecase.CreateField(req.field())
+	if err != nil {
+		c.JSON(catalogErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+func (cc *CatalogController) UpdateField(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid field ID"})
+		return
+	}
+
+	var req CustomFieldRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	updated, err := cc.catalogUsecase.UpdateField(id, req.field())
+	if err != nil {
+		c.JSON(catalogErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+func (cc *CatalogController) DeleteField(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid field ID"})
+		return
+	}
+
+	if err := cc.catalogUsecase.DeleteField(id); err != nil {
+		c.JSON(catalogErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Custom field deleted successfully"})
+}
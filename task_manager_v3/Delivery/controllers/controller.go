@@ -2,8 +2,14 @@ package controllers
 
 import (
 	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Infrastructure"
 	"a2sv-backend/task_manager_v3/Usecases"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -20,29 +26,246 @@ func NewTaskController(taskUsecase Usecases.TaskUsecase) *TaskController {
 }
 
 func (tc *TaskController) CreateTask(c *gin.Context) {
-	var task Domain.Task
-	if err := c.ShouldBindJSON(&task); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var req CreateTaskRequest
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	createdTask, err := tc.taskUsecase.Create(task)
+	createdTask, err := tc.taskUsecase.Create(req.task())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, createdTask)
+	c.JSON(http.StatusCreated, taskResponse(createdTask))
 }
 
+// GetAllTasks lists tasks. Optional filters: status, priority, project,
+// label (repeatable, must carry all) and cf.<key>=value. sort takes a comma
+// separated list of fields, each optionally prefixed with - for descending.
 func (tc *TaskController) GetAllTasks(c *gin.Context) {
-	tasks, err := tc.taskUsecase.GetAll()
+	filter, ok := taskFilter(c)
+	if !ok {
+		return
+	}
+
+	tasks, err := tc.taskUsecase.List(filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, taskResponses(tasks))
+}
+
+// GetTaskPage is API v2's task listing: GetAllTasks' filters and sort, one
+// page at a time. Query: page, page_size.
+func (tc *TaskController) GetTaskPage(c *gin.Context) {
+	filter, ok := taskFilter(c)
+	if !ok {
+		return
+	}
+	page, pageSize := 1, Domain.DefaultTaskPageSize
+	var err error
+	if raw := c.Query("page"); raw != "" {
+		if page, err = strconv.Atoi(raw); err != nil || page < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "page must be a positive integer"})
+			return
+		}
+	}
+	if raw := c.Query("page_size"); raw != "" {
+		if pageSize, err = strconv.Atoi(raw); err != nil || pageSize < 1 || pageSize > Domain.MaxTaskPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("page_size must be between 1 and %d", Domain.MaxTaskPageSize)})
+			return
+		}
+	}
+
+	tasks, total, err := tc.taskUsecase.ListPage(filter, page, pageSize)
+	if err != nil {
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, TaskPageResponse{Tasks: taskResponses(tasks), Total: total, Page: page, PageSize: pageSize})
+}
+
+// taskFilter reads the filters and sort of a task listing from the query,
+// responding 400 if they don't parse
+func taskFilter(c *gin.Context) (Domain.TaskFilter, bool) {
+	filter := Domain.TaskFilter{
+		Status:       c.Query("status"),
+		Priority:     c.Query("priority"),
+		Project:      c.Query("project"),
+		CustomFields: map[string]interface{}{},
+	}
+	for _, raw := range c.QueryArray("label") {
+		id, err := primitive.ObjectIDFromHex(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid label ID"})
+			return filter, false
+		}
+		filter.Labels = append(filter.Labels, id)
+	}
+	for key, values := range c.Request.URL.Query() {
+		if field, ok := strings.CutPrefix(key, "cf."); ok && len(values) > 0 {
+			filter.CustomFields[field] = values[0]
+		}
+	}
+	if raw := c.Query("sort"); raw != "" {
+		for _, field := range strings.Split(raw, ",") {
+			desc := strings.HasPrefix(field, "-")
+			filter.Sort = append(filter.Sort, Domain.SortField{Field: strings.TrimPrefix(field, "-"), Desc: desc})
+		}
+	}
+	return filter, true
+}
+
+// taskErrorStatus maps validation failures to 400 and everything else to 500
+func taskErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, Domain.ErrInvalidTask):
+		return http.StatusBadRequest
+	case errors.Is(err, Domain.ErrTaskNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+func (tc *TaskController) SearchTasks(c *gin.Context) {
+	q := c.Query("q")
+	if q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter q is required"})
+		return
+	}
+
+	limit := defaultSearchLimit
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxSearchLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+			return
+		}
+		limit = parsed
+	}
+
+	results, err := tc.taskUsecase.Search(viewerFromContext(c), q, limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, searchResponses(results))
+}
+
+func (tc *TaskController) ExportTasks(c *gin.Context) {
+	format := c.DefaultQuery("format", Domain.FormatJSON)
+	contentType, ok := Infrastructure.ContentTypes[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be one of csv, json, ndjson, ics"})
 		return
 	}
 
-	c.JSON(http.StatusOK, tasks)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="tasks.%s"`, format))
+	c.Status(http.StatusOK)
+
+	encoder, err := Infrastructure.NewTaskEncoder(format, c.Writer)
+	if err == nil {
+		err = tc.taskUsecase.Export(viewerFromContext(c), encoder)
+	}
+	if err != nil {
+		// Headers are already out, so all we can do is cut the stream short
+		c.Error(err)
+		c.Abort()
+	}
+}
+
+// maxImportSize caps the request body accepted by ImportTasks
+const maxImportSize = 10 << 20
+
+// ImportTasks reads tasks in any export format. ?dry_run=true only validates
+// and ?map=Summary:title,Due:due_date renames source columns.
+func (tc *TaskController) ImportTasks(c *gin.Context) {
+	format := c.Query("format")
+	if format == "" {
+		format = formatFromContentType(c.ContentType())
+	}
+	if _, ok := Infrastructure.ContentTypes[format]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be one of csv, json, ndjson, ics"})
+		return
+	}
+
+	mapping := map[string]string{}
+	if raw := c.Query("map"); raw != "" {
+		for _, pair := range strings.Split(raw, ",") {
+			from, to, ok := strings.Cut(pair, ":")
+			if !ok || from == "" || to == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "map must look like Source:field,Other:field"})
+				return
+			}
+			mapping[from] = to
+		}
+	}
+
+	// Set on the request so the multipart parser is held to it too
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	body := c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, err := c.FormFile("file")
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "multipart upload needs a file field"})
+			return
+		}
+		opened, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer opened.Close()
+		body = opened
+	}
+
+	decoder, err := Infrastructure.NewTaskDecoder(format, body, mapping)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := tc.taskUsecase.Import(decoder, c.Query("dry_run") == "true")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "report": report})
+		return
+	}
+
+	status := http.StatusOK
+	if report.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	c.JSON(status, report)
+}
+
+func formatFromContentType(contentType string) string {
+	switch contentType {
+	case "text/csv":
+		return Domain.FormatCSV
+	case "application/json":
+		return Domain.FormatJSON
+	case "application/x-ndjson":
+		return Domain.FormatNDJSON
+	case "text/calendar":
+		return Domain.FormatICS
+	}
+	return ""
 }
 
 func (tc *TaskController) GetTaskByID(c *gin.Context) {
@@ -59,7 +282,7 @@ func (tc *TaskController) GetTaskByID(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, task)
+	c.JSON(http.StatusOK, taskResponse(task))
 }
 
 func (tc *TaskController) UpdateTask(c *gin.Context) {
@@ -70,19 +293,62 @@ func (tc *TaskController) UpdateTask(c *gin.Context) {
 		return
 	}
 
-	var task Domain.Task
-	if err := c.ShouldBindJSON(&task); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var req UpdateTaskRequest
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	updatedTask, err := tc.taskUsecase.Update(id, task)
+	updatedTask, err := tc.taskUsecase.Update(id, req.task())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if errors.Is(err, Domain.ErrTaskNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+			return
+		}
+		c.JSON(taskErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, updatedTask)
+	c.JSON(http.StatusOK, taskResponse(updatedTask))
+}
+
+// BulkTasks applies a list of operations. Pass ?atomic=true for all-or-nothing.
+func (tc *TaskController) BulkTasks(c *gin.Context) {
+	var req BulkRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	atomic := req.Atomic || c.Query("atomic") == "true"
+
+	results, err := tc.taskUsecase.Bulk(req.operations(), atomic)
+	switch {
+	case errors.Is(err, Domain.ErrBulkTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error(), "max_operations": Usecases.MaxBulkOperations})
+		return
+	case errors.Is(err, Domain.ErrBulkInvalid):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "results": bulkResponses(results)})
+		return
+	case errors.Is(err, Domain.ErrBulkAborted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "results": bulkResponses(results)})
+		return
+	case errors.Is(err, Domain.ErrTransactionsUnsupported):
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		return
+	case err != nil && results == nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "results": bulkResponses(results)})
+		return
+	}
+
+	status := http.StatusOK
+	for _, result := range results {
+		if result.Status >= http.StatusBadRequest {
+			status = http.StatusMultiStatus
+			break
+		}
+	}
+	c.JSON(status, gin.H{"results": bulkResponses(results)})
 }
 
 func (tc *TaskController) DeleteTask(c *gin.Context) {
@@ -112,14 +378,13 @@ func NewUserController(userUsecase Usecases.UserUsecase) *UserController {
 }
 
 func (uc *UserController) Register(c *gin.Context) {
-	var user Domain.User
-	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var req RegisterRequest
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	if err := uc.userUsecase.Register(user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := uc.userUsecase.Register(req.registration()); err != nil {
+		c.JSON(userErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -127,23 +392,22 @@ func (uc *UserController) Register(c *gin.Context) {
 }
 
 func (uc *UserController) Login(c *gin.Context) {
-	var credentials struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
+	var credentials LoginRequest
+	if !bindJSON(c, &credentials) {
+		return
 	}
 
-	if err := c.ShouldBindJSON(&credentials); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	result, err := uc.userUsecase.Login(credentials.Username, credentials.Password, clientInfo(c))
+	if errors.Is(err, Domain.ErrUserDeactivated) || errors.Is(err, Domain.ErrEmailNotVerified) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		return
 	}
-
-	token, user, err := uc.userUsecase.Login(credentials.Username, credentials.Password)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token, "user_id": user.ID})
+	c.JSON(http.StatusOK, loginResponse(result))
 }
 
 func (uc *UserController) GetProfile(c *gin.Context) {
@@ -160,25 +424,274 @@ func (uc *UserController) GetProfile(c *gin.Context) {
 	})
 }
 
+// GetCurrentUser is API v2's GET /me: the caller's account as it is now,
+// rather than as their token describes it
+func (uc *UserController) GetCurrentUser(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := uc.userUsecase.GetUser(userID)
+	if err != nil {
+		c.JSON(userErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, userResponse(user))
+}
+
 func (uc *UserController) PromoteUser(c *gin.Context) {
-	var req struct {
-		UserID string `json:"user_id"`
+	var req PromoteRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	id, _ := primitive.ObjectIDFromHex(req.UserID)
+
+	if err := uc.userUsecase.Promote(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User promoted successfully"})
+}
+
+func userErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, Domain.ErrSelfManagement), errors.Is(err, Domain.ErrLastAdmin):
+		return http.StatusConflict
+	case errors.Is(err, Domain.ErrInvalidRole), errors.Is(err, Domain.ErrWeakPassword),
+		errors.Is(err, Domain.ErrPasswordTooLong):
+		return http.StatusBadRequest
+	case errors.Is(err, Domain.ErrInvalidInvitation), errors.Is(err, Domain.ErrRegistrationClosed),
+		errors.Is(err, Domain.ErrEmailDomainNotAllowed):
+		return http.StatusForbidden
+	case errors.Is(err, Domain.ErrWrongPassword):
+		return http.StatusForbidden
+	case errors.Is(err, Domain.ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, Domain.ErrUsernameTaken), errors.Is(err, Domain.ErrEmailTaken):
+		return http.StatusConflict
+	case errors.Is(err, Domain.ErrUsernameRequired), errors.Is(err, Domain.ErrInvalidEmail):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// clientInfo describes the client making a login request
+func clientInfo(c *gin.Context) Domain.ClientInfo {
+	return Domain.ClientInfo{UserAgent: c.Request.UserAgent(), IP: c.ClientIP()}
+}
+
+// currentUserID is the ID of the authenticated caller
+func currentUserID(c *gin.Context) (primitive.ObjectID, bool) {
+	id, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user in token"})
+		return id, false
+	}
+	return id, true
+}
+
+// ListUsers pages through users. Query: q, role, status (active or
+// deactivated), page, page_size.
+func (uc *UserController) ListUsers(c *gin.Context) {
+	query := Domain.UserQuery{
+		Search: c.Query("q"),
+		Role:   c.Query("role"),
+		Status: c.Query("status"),
+	}
+	if query.Status != "" && query.Status != Domain.UserStatusActive && query.Status != Domain.UserStatusDeactivated {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be active or deactivated"})
+		return
+	}
+	var err error
+	if raw := c.Query("page"); raw != "" {
+		if query.Page, err = strconv.Atoi(raw); err != nil || query.Page < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "page must be a positive integer"})
+			return
+		}
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+	if raw := c.Query("page_size"); raw != "" {
+		if query.PageSize, err = strconv.Atoi(raw); err != nil || query.PageSize < 1 || query.PageSize > Domain.MaxUserPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("page_size must be between 1 and %d", Domain.MaxUserPageSize)})
+			return
+		}
+	}
+
+	users, total, err := uc.userUsecase.ListUsers(query)
+	if err != nil {
+		c.JSON(userErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	id, err := primitive.ObjectIDFromHex(req.UserID)
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PageSize == 0 {
+		query.PageSize = Domain.DefaultUserPageSize
+	}
+	items := make([]UserResponse, 0, len(users))
+	for _, user := range users {
+		items = append(items, userResponse(user))
+	}
+	c.JSON(http.StatusOK, UserListResponse{Users: items, Total: total, Page: query.Page, PageSize: query.PageSize})
+}
+
+func (uc *UserController) GetUser(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	if err := uc.userUsecase.Promote(id); err != nil {
+	user, err := uc.userUsecase.GetUser(id)
+	if err != nil {
+		c.JSON(userErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, userResponse(user))
+}
+
+// manageUser runs an admin action against the user in the path and
+// responds with the user as it is afterwards
+func (uc *UserController) manageUser(c *gin.Context, action func(actorID, id primitive.ObjectID) (Domain.User, error)) {
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	user, err := action(actorID, id)
+	if err != nil {
+		c.JSON(userErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, userResponse(user))
+}
+
+func (uc *UserController) ChangeRole(c *gin.Context) {
+	var req ChangeRoleRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	uc.manageUser(c, func(actorID, id primitive.ObjectID) (Domain.User, error) {
+		return uc.userUsecase.ChangeRole(actorID, id, req.Role)
+	})
+}
+
+func (uc *UserController) DeactivateUser(c *gin.Context) {
+	uc.manageUser(c, uc.userUsecase.Deactivate)
+}
+
+func (uc *UserController) ReactivateUser(c *gin.Context) {
+	uc.manageUser(c, uc.userUsecase.Reactivate)
+}
+
+func (uc *UserController) DeleteUser(c *gin.Context) {
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := uc.userUsecase.Delete(actorID, id); err != nil {
+		c.JSON(userErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
+}
+
+func (uc *UserController) UpdateProfile(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req UpdateProfileRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	user, err := uc.userUsecase.UpdateProfile(id, req.update())
+	if err != nil {
+		c.JSON(userErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, userResponse(user))
+}
+
+func (uc *UserController) ChangePassword(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req ChangePasswordRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if err := uc.userUsecase.ChangePassword(id, c.GetString("session_id"), req.CurrentPassword, req.NewPassword); err != nil {
+		c.JSON(userErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully"})
+}
+
+// CreateInvitation issues an invitation. The token is only in this response.
+func (uc *UserController) CreateInvitation(c *gin.Context) {
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req CreateInvitationRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	invitation, token, err := uc.userUsecase.CreateInvitation(actorID, req.Role, req.Email, time.Duration(req.ExpiresInHours)*time.Hour)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"invitation": invitation, "token": token})
+}
+
+func (uc *UserController) ListInvitations(c *gin.Context) {
+	invitations, err := uc.userUsecase.ListInvitations()
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "User promoted successfully"})
+	c.JSON(http.StatusOK, invitations)
+}
+
+func (uc *UserController) RevokeInvitation(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	}
+
+	if err := uc.userUsecase.RevokeInvitation(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked successfully"})
 }
@@ -0,0 +1,602 @@
+package controllers
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Infrastructure"
+	"a2sv-backend/task_manager_v3/Usecases"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Codes reported under a GraphQL error's extensions
+const (
+	GraphQLParseFailed      = "GRAPHQL_PARSE_FAILED"
+	GraphQLValidationFailed = "GRAPHQL_VALIDATION_FAILED"
+	GraphQLQueryTooDeep     = "QUERY_TOO_DEEP"
+	GraphQLQueryTooComplex  = "QUERY_TOO_COMPLEX"
+	GraphQLBadUserInput     = "BAD_USER_INPUT"
+	GraphQLUnauthenticated  = "UNAUTHENTICATED"
+	GraphQLForbidden        = "FORBIDDEN"
+	GraphQLNotFound         = "NOT_FOUND"
+	GraphQLInternal         = "INTERNAL_SERVER_ERROR"
+)
+
+// graphQLError is a resolver error clients can tell apart by its code.
+// Input errors also list the offending fields.
+type graphQLError struct {
+	message string
+	code    string
+	fields  []FieldError
+}
+
+func (e graphQLError) Error() string {
+	return e.message
+}
+
+func (e graphQLError) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{"code": e.code}
+	if len(e.fields) > 0 {
+		extensions["fields"] = e.fields
+	}
+	return extensions
+}
+
+// GraphQLRequest is a GraphQL request, as a POST body or GET query.
+// Variables are a JSON object, sent as a string in a GET query.
+type GraphQLRequest struct {
+	Query         string                 `json:"query" form:"query" binding:"required"`
+	OperationName string                 `json:"operationName" form:"operationName"`
+	Variables     map[string]interface{} `json:"variables" form:"-"`
+}
+
+type GraphQLController struct {
+	taskUsecase    Usecases.TaskUsecase
+	userUsecase    Usecases.UserUsecase
+	catalogUsecase Usecases.CatalogUsecase
+	maxDepth       int
+	maxComplexity  int
+	schema         graphql.Schema
+}
+
+// NewGraphQLController serves /graphql from the same usecases as the REST
+// routes. A zero maxDepth or maxComplexity means the default.
+func NewGraphQLController(taskUsecase Usecases.TaskUsecase, userUsecase Usecases.UserUsecase, catalogUsecase Usecases.CatalogUsecase, maxDepth, maxComplexity int) *GraphQLController {
+	if maxDepth == 0 {
+		maxDepth = DefaultGraphQLMaxDepth
+	}
+	if maxComplexity == 0 {
+		maxComplexity = DefaultGraphQLMaxComplexity
+	}
+	gc := &GraphQLController{
+		taskUsecase:    taskUsecase,
+		userUsecase:    userUsecase,
+		catalogUsecase: catalogUsecase,
+		maxDepth:       maxDepth,
+		maxComplexity:  maxComplexity,
+	}
+	schema, err := newGraphQLSchema(gc)
+	if err != nil {
+		// The schema is fixed at compile time, so this is a programming error
+		panic(fmt.Sprintf("invalid GraphQL schema: %v", err))
+	}
+	gc.schema = schema
+	return gc
+}
+
+// graphQLRequestKey is the context key the request state is stored under
+type graphQLRequestKey struct{}
+
+// graphQLRequestState is what resolvers know about the request they serve,
+// and the loaders batching its lookups
+type graphQLRequestState struct {
+	viewer Domain.Viewer
+	// admin is true for admins whose credentials carry the admin scope, as
+	// the REST admin routes require
+	admin bool
+
+	users  *batchLoader
+	labels *batchLoader
+
+	fieldsOnce sync.Once
+	fields     map[string]Domain.CustomField
+	fieldsErr  error
+}
+
+func graphQLRequestFrom(p graphql.ResolveParams) *graphQLRequestState {
+	req, _ := p.Context.Value(graphQLRequestKey{}).(*graphQLRequestState)
+	return req
+}
+
+// fieldDefinitions loads the custom field definitions once per request
+func (gc *GraphQLController) fieldDefinitions(req *graphQLRequestState) (map[string]Domain.CustomField, error) {
+	req.fieldsOnce.Do(func() {
+		definitions, err := gc.catalogUsecase.ListFields()
+		req.fields, req.fieldsErr = map[string]Domain.CustomField{}, err
+		for _, field := range definitions {
+			req.fields[field.Key] = field
+		}
+	})
+	return req.fields, req.fieldsErr
+}
+
+func (gc *GraphQLController) newRequestState(c *gin.Context) *graphQLRequestState {
+	viewer := viewerFromContext(c)
+	return &graphQLRequestState{
+		viewer: viewer,
+		admin:  viewer.Role == Domain.RoleAdmin && Infrastructure.TokenHasScope(c, Domain.ScopeAdmin),
+		users: newBatchLoader(func(ids []primitive.ObjectID) (map[primitive.ObjectID]interface{}, error) {
+			users, err := gc.userUsecase.GetUsers(ids)
+			found := make(map[primitive.ObjectID]interface{}, len(users))
+			for _, user := range users {
+				found[user.ID] = userResponse(user)
+			}
+			return found, err
+		}),
+		labels: newBatchLoader(func(ids []primitive.ObjectID) (map[primitive.ObjectID]interface{}, error) {
+			labels, err := gc.catalogUsecase.GetLabels(ids)
+			found := make(map[primitive.ObjectID]interface{}, len(labels))
+			for _, label := range labels {
+				found[label.ID] = label
+			}
+			return found, err
+		}),
+	}
+}
+
+// GraphQL serves queries over GET and POST, and mutations over POST only so
+// they can't be triggered by a link. Requests that don't parse, validate or
+// fit the depth and complexity limits are answered 400 without running;
+// the rest 200, with any resolver errors listed beside the data.
+func (gc *GraphQLController) GraphQL(c *gin.Context) {
+	var req GraphQLRequest
+	if c.Request.Method == http.MethodGet {
+		if err := c.ShouldBindQuery(&req); err != nil {
+			graphQLErrors(c, http.StatusBadRequest, GraphQLBadUserInput, "query is required")
+			return
+		}
+		if raw := c.Query("variables"); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &req.Variables); err != nil {
+				graphQLErrors(c, http.StatusBadRequest, GraphQLBadUserInput, "variables must be a JSON object")
+				return
+			}
+		}
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		graphQLErrors(c, http.StatusBadRequest, GraphQLBadUserInput, "body must be a JSON object with a query")
+		return
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"})})
+	if err != nil {
+		formatted := gqlerrors.FormatError(err)
+		formatted.Extensions = map[string]interface{}{"code": GraphQLParseFailed}
+		c.JSON(http.StatusBadRequest, gin.H{"errors": []gqlerrors.FormattedError{formatted}})
+		return
+	}
+	if validation := graphql.ValidateDocument(&gc.schema, doc, graphql.SpecifiedRules); !validation.IsValid {
+		for i := range validation.Errors {
+			validation.Errors[i].Extensions = map[string]interface{}{"code": GraphQLValidationFailed}
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"errors": validation.Errors})
+		return
+	}
+
+	operation := selectOperation(doc, req.OperationName)
+	if operation == nil {
+		graphQLErrors(c, http.StatusBadRequest, GraphQLBadUserInput, "operationName must name one operation of the document")
+		return
+	}
+	if operation.Operation != ast.OperationTypeQuery && c.Request.Method == http.MethodGet {
+		c.Header("Allow", http.MethodPost)
+		graphQLErrors(c, http.StatusMethodNotAllowed, GraphQLBadUserInput, "mutations must be sent with POST")
+		return
+	}
+	limits := newQueryLimits(doc, req.Variables)
+	if depth := limits.depth(operation.SelectionSet); depth > gc.maxDepth {
+		graphQLErrors(c, http.StatusBadRequest, GraphQLQueryTooDeep, fmt.Sprintf("query is %d levels deep, more than the limit of %d", depth, gc.maxDepth))
+		return
+	}
+	if complexity := limits.complexity(operation.SelectionSet, true); complexity > gc.maxComplexity {
+		graphQLErrors(c, http.StatusBadRequest, GraphQLQueryTooComplex, fmt.Sprintf("query has a complexity of %d, more than the limit of %d", complexity, gc.maxComplexity))
+		return
+	}
+
+	ctx := context.WithValue(c.Request.Context(), graphQLRequestKey{}, gc.newRequestState(c))
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        gc.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       ctx,
+	})
+	c.JSON(http.StatusOK, result)
+}
+
+// graphQLErrors responds with a single request error
+func graphQLErrors(c *gin.Context, status int, code, message string) {
+	formatted := gqlerrors.NewFormattedError(message)
+	formatted.Extensions = map[string]interface{}{"code": code}
+	c.JSON(status, gin.H{"errors": []gqlerrors.FormattedError{formatted}})
+}
+
+// selectOperation finds the operation named name, or the only one when
+// name is empty
+func selectOperation(doc *ast.Document, name string) *ast.OperationDefinition {
+	var found *ast.OperationDefinition
+	for _, definition := range doc.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if name == "" {
+			if found != nil {
+				return nil
+			}
+			found = operation
+		} else if operation.Name != nil && operation.Name.Value == name {
+			return operation
+		}
+	}
+	return found
+}
+
+// requireAdmin fails unless the caller may use the REST admin routes
+func requireAdmin(p graphql.ResolveParams) error {
+	if !graphQLRequestFrom(p).admin {
+		return graphQLError{message: "Forbidden: Admins only", code: GraphQLForbidden}
+	}
+	return nil
+}
+
+// objectIDArg parses an ID argument
+func objectIDArg(value interface{}, what string) (primitive.ObjectID, error) {
+	s, _ := value.(string)
+	id, err := primitive.ObjectIDFromHex(s)
+	if err != nil {
+		return id, graphQLError{message: "Invalid " + what + " ID", code: GraphQLBadUserInput}
+	}
+	return id, nil
+}
+
+// objectIDList parses a list of IDs given for field
+func objectIDList(value interface{}, field, what string) ([]primitive.ObjectID, error) {
+	raw, _ := value.([]interface{})
+	ids := make([]primitive.ObjectID, 0, len(raw))
+	for i, item := range raw {
+		id, err := objectIDArg(item, what)
+		if err != nil {
+			path := fmt.Sprintf("%s[%d]", field, i)
+			return nil, graphQLError{
+				message: "Invalid " + what + " ID",
+				code:    GraphQLBadUserInput,
+				fields:  []FieldError{{Field: path, Code: "objectid", Message: path + " must be a valid ID"}},
+			}
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// customFieldArgs reads [CustomFieldInput!] into a custom field map
+func customFieldArgs(value interface{}) map[string]interface{} {
+	raw, _ := value.([]interface{})
+	fields := make(map[string]interface{}, len(raw))
+	for _, item := range raw {
+		field, _ := item.(map[string]interface{})
+		key, _ := field["key"].(string)
+		fields[key] = field["value"]
+	}
+	return fields
+}
+
+// readPage reads and checks the page and pageSize arguments
+func readPage(p graphql.ResolveParams, maxPageSize int) (int, int, error) {
+	page, _ := p.Args["page"].(int)
+	pageSize, _ := p.Args["pageSize"].(int)
+	if page < 1 {
+		return 0, 0, graphQLError{message: "page must be a positive integer", code: GraphQLBadUserInput}
+	}
+	if pageSize < 1 || pageSize > maxPageSize {
+		return 0, 0, graphQLError{message: fmt.Sprintf("pageSize must be between 1 and %d", maxPageSize), code: GraphQLBadUserInput}
+	}
+	return page, pageSize, nil
+}
+
+// taskError gives usecase errors the code their REST status stands for
+func taskError(err error) error {
+	switch {
+	case errors.Is(err, Domain.ErrInvalidTask):
+		return graphQLError{message: err.Error(), code: GraphQLBadUserInput}
+	case errors.Is(err, Domain.ErrTaskNotFound):
+		return graphQLError{message: "Task not found", code: GraphQLNotFound}
+	}
+	return graphQLError{message: err.Error(), code: GraphQLInternal}
+}
+
+func (gc *GraphQLController) resolveMe(p graphql.ResolveParams) (interface{}, error) {
+	id, err := primitive.ObjectIDFromHex(graphQLRequestFrom(p).viewer.UserID)
+	if err != nil {
+		return nil, graphQLError{message: "Invalid user in token", code: GraphQLUnauthenticated}
+	}
+	user, err := gc.userUsecase.GetUser(id)
+	if err != nil {
+		return nil, graphQLError{message: err.Error(), code: GraphQLNotFound}
+	}
+	return userResponse(user), nil
+}
+
+func (gc *GraphQLController) resolveUsers(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireAdmin(p); err != nil {
+		return nil, err
+	}
+	page, pageSize, err := readPage(p, Domain.MaxUserPageSize)
+	if err != nil {
+		return nil, err
+	}
+	query := Domain.UserQuery{Page: page, PageSize: pageSize}
+	query.Search, _ = p.Args["search"].(string)
+	query.Role, _ = p.Args["role"].(string)
+	query.Status, _ = p.Args["status"].(string)
+
+	users, total, err := gc.userUsecase.ListUsers(query)
+	if err != nil {
+		return nil, graphQLError{message: err.Error(), code: GraphQLInternal}
+	}
+	items := make([]UserResponse, 0, len(users))
+	for _, user := range users {
+		items = append(items, userResponse(user))
+	}
+	return UserListResponse{Users: items, Total: total, Page: page, PageSize: pageSize}, nil
+}
+
+func (gc *GraphQLController) resolveUser(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireAdmin(p); err != nil {
+		return nil, err
+	}
+	id, err := objectIDArg(p.Args["id"], "user")
+	if err != nil {
+		return nil, err
+	}
+	user, err := gc.userUsecase.GetUser(id)
+	if err != nil {
+		return nil, graphQLError{message: "User not found", code: GraphQLNotFound}
+	}
+	return userResponse(user), nil
+}
+
+func (gc *GraphQLController) resolveTasks(p graphql.ResolveParams) (interface{}, error) {
+	page, pageSize, err := readPage(p, Domain.MaxTaskPageSize)
+	if err != nil {
+		return nil, err
+	}
+	filter := Domain.TaskFilter{CustomFields: map[string]interface{}{}}
+	if raw, ok := p.Args["filter"].(map[string]interface{}); ok {
+		filter.Status, _ = raw["status"].(string)
+		filter.Priority, _ = raw["priority"].(string)
+		filter.Project, _ = raw["project"].(string)
+		if filter.Labels, err = objectIDList(raw["labels"], "filter.labels", "label"); err != nil {
+			return nil, err
+		}
+		filter.CustomFields = customFieldArgs(raw["customFields"])
+	}
+	sorts, _ := p.Args["sort"].([]interface{})
+	for _, item := range sorts {
+		raw, _ := item.(map[string]interface{})
+		field, _ := raw["field"].(string)
+		desc, _ := raw["desc"].(bool)
+		filter.Sort = append(filter.Sort, Domain.SortField{Field: field, Desc: desc})
+	}
+
+	tasks, total, err := gc.taskUsecase.ListPage(filter, page, pageSize)
+	if err != nil {
+		return nil, taskError(err)
+	}
+	return TaskPageResponse{Tasks: taskResponses(tasks), Total: total, Page: page, PageSize: pageSize}, nil
+}
+
+func (gc *GraphQLController) resolveTask(p graphql.ResolveParams) (interface{}, error) {
+	id, err := objectIDArg(p.Args["id"], "task")
+	if err != nil {
+		return nil, err
+	}
+	task, err := gc.taskUsecase.GetByID(id)
+	if err != nil {
+		return nil, graphQLError{message: "Task not found", code: GraphQLNotFound}
+	}
+	return taskResponse(task), nil
+}
+
+// resolveTaskLabels batches the labels of every task in the response into
+// one lookup
+func (gc *GraphQLController) resolveTaskLabels(p graphql.ResolveParams) (interface{}, error) {
+	task, _ := p.Source.(TaskResponse)
+	load := graphQLRequestFrom(p).labels.loadMany(task.Labels)
+	return func() (interface{}, error) {
+		return load()
+	}, nil
+}
+
+// customFieldValue is one entry of a task's custom fields
+type customFieldValue struct {
+	Key   string
+	Type  string
+	Value string
+}
+
+func (gc *GraphQLController) resolveTaskCustomFields(p graphql.ResolveParams) (interface{}, error) {
+	task, _ := p.Source.(TaskResponse)
+	definitions, err := gc.fieldDefinitions(graphQLRequestFrom(p))
+	if err != nil {
+		return nil, graphQLError{message: err.Error(), code: GraphQLInternal}
+	}
+
+	values := make([]customFieldValue, 0, len(task.CustomFields))
+	for key, value := range task.CustomFields {
+		text := fmt.Sprint(value)
+		if number, ok := value.(float64); ok {
+			text = strconv.FormatFloat(number, 'f', -1, 64)
+		}
+		values = append(values, customFieldValue{Key: key, Type: definitions[key].Type, Value: text})
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].Key < values[j].Key })
+	return values, nil
+}
+
+// resolveFieldUser batches the users of every user field in the response
+// into one lookup
+func (gc *GraphQLController) resolveFieldUser(p graphql.ResolveParams) (interface{}, error) {
+	field, _ := p.Source.(customFieldValue)
+	if field.Type != Domain.FieldUser {
+		return nil, nil
+	}
+	id, err := primitive.ObjectIDFromHex(field.Value)
+	if err != nil {
+		return nil, nil
+	}
+	load := graphQLRequestFrom(p).users.load(id)
+	return func() (interface{}, error) {
+		return load()
+	}, nil
+}
+
+// taskInput reads a TaskInput and checks it with the rules of the matching
+// REST body
+func taskInput(p graphql.ResolveParams) (CreateTaskRequest, error) {
+	raw, _ := p.Args["input"].(map[string]interface{})
+	req := CreateTaskRequest{CustomFields: customFieldArgs(raw["customFields"])}
+	req.Title, _ = raw["title"].(string)
+	req.Description, _ = raw["description"].(string)
+	req.DueDate, _ = raw["dueDate"].(time.Time)
+	req.Status, _ = raw["status"].(string)
+	req.Priority, _ = raw["priority"].(string)
+	req.Project, _ = raw["project"].(string)
+	req.ExternalID, _ = raw["externalId"].(string)
+	labels, err := objectIDList(raw["labels"], "labels", "label")
+	if err != nil {
+		return req, err
+	}
+	req.Labels = labels
+	return req, nil
+}
+
+// validateInput runs the binding rules of a REST body over input
+func validateInput(body interface{}) error {
+	err := binding.Validator.ValidateStruct(body)
+	if err == nil {
+		return nil
+	}
+	fields := fieldErrors(err)
+	for i, field := range fields {
+		name := graphQLFieldName(field.Field)
+		fields[i].Message = name + strings.TrimPrefix(field.Message, field.Field)
+		fields[i].Field = name
+	}
+	return graphQLError{message: "Invalid input", code: GraphQLBadUserInput, fields: fields}
+}
+
+// graphQLFieldName turns a JSON field name like due_date into the input
+// field name dueDate
+func graphQLFieldName(name string) string {
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+func (gc *GraphQLController) resolveCreateTask(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireAdmin(p); err != nil {
+		return nil, err
+	}
+	req, err := taskInput(p)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateInput(&req); err != nil {
+		return nil, err
+	}
+
+	created, err := gc.taskUsecase.Create(req.task())
+	if err != nil {
+		return nil, taskError(err)
+	}
+	return taskResponse(created), nil
+}
+
+func (gc *GraphQLController) resolveUpdateTask(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireAdmin(p); err != nil {
+		return nil, err
+	}
+	id, err := objectIDArg(p.Args["id"], "task")
+	if err != nil {
+		return nil, err
+	}
+	input, err := taskInput(p)
+	if err != nil {
+		return nil, err
+	}
+	req := UpdateTaskRequest(input)
+	if err := validateInput(&req); err != nil {
+		return nil, err
+	}
+
+	updated, err := gc.taskUsecase.Update(id, req.task())
+	if err != nil {
+		return nil, taskError(err)
+	}
+	return taskResponse(updated), nil
+}
+
+func (gc *GraphQLController) resolveDeleteTask(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireAdmin(p); err != nil {
+		return nil, err
+	}
+	id, err := objectIDArg(p.Args["id"], "task")
+	if err != nil {
+		return nil, err
+	}
+	if err := gc.taskUsecase.Delete(id); err != nil {
+		return nil, taskError(err)
+	}
+	return true, nil
+}
+
+// resolveSetTaskStatus moves a task to another status, keeping the rest
+func (gc *GraphQLController) resolveSetTaskStatus(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireAdmin(p); err != nil {
+		return nil, err
+	}
+	id, err := objectIDArg(p.Args["id"], "task")
+	if err != nil {
+		return nil, err
+	}
+	task, err := gc.taskUsecase.GetByID(id)
+	if err != nil {
+		return nil, graphQLError{message: "Task not found", code: GraphQLNotFound}
+	}
+
+	task.Status, _ = p.Args["status"].(string)
+	updated, err := gc.taskUsecase.Update(id, task)
+	if err != nil {
+		return nil, taskError(err)
+	}
+	return taskResponse(updated), nil
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Query limits applied before a GraphQL operation runs. The default depth
+// leaves room for the introspection query GraphiQL and other tools send,
+// which is 13 levels deep.
+const (
+	DefaultGraphQLMaxDepth      = 15
+	DefaultGraphQLMaxComplexity = 1000
+)
+
+// pagedFields are the root fields that multiply the cost of their selection
+// by the page size they ask for, since it is resolved once per item
+var pagedFields = map[string]bool{"tasks": true, "users": true}
+
+// queryLimits measures an operation, expanding fragment spreads as if
+// written inline. Fragment cycles are rejected by validation beforehand.
+type queryLimits struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+func newQueryLimits(doc *ast.Document, variables map[string]interface{}) queryLimits {
+	limits := queryLimits{fragments: map[string]*ast.FragmentDefinition{}, variables: variables}
+	for _, definition := range doc.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			limits.fragments[fragment.Name.Value] = fragment
+		}
+	}
+	return limits
+}
+
+// depth is how many fields deep the selection nests
+func (l queryLimits) depth(set *ast.SelectionSet) int {
+	if set == nil {
+		return 0
+	}
+	deepest := 0
+	for _, selection := range set.Selections {
+		var d int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			d = 1 + l.depth(selection.SelectionSet)
+		case *ast.InlineFragment:
+			d = l.depth(selection.SelectionSet)
+		case *ast.FragmentSpread:
+			if fragment, ok := l.fragments[selection.Name.Value]; ok {
+				d = l.depth(fragment.SelectionSet)
+			}
+		}
+		if d > deepest {
+			deepest = d
+		}
+	}
+	return deepest
+}
+
+// complexity costs every field 1 plus what its selection costs, times the
+// page size for paged root fields. root is true for the operation's own
+// selection.
+func (l queryLimits) complexity(set *ast.SelectionSet, root bool) int {
+	if set == nil {
+		return 0
+	}
+	total := 0
+	for _, selection := range set.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			children := l.complexity(selection.SelectionSet, false)
+			if root && pagedFields[selection.Name.Value] {
+				children *= l.pageSize(selection)
+			}
+			total += 1 + children
+		case *ast.InlineFragment:
+			total += l.complexity(selection.SelectionSet, root)
+		case *ast.FragmentSpread:
+			if fragment, ok := l.fragments[selection.Name.Value]; ok {
+				total += l.complexity(fragment.SelectionSet, root)
+			}
+		}
+	}
+	return total
+}
+
+// pageSize reads a field's pageSize argument, whether written literally or
+// passed as a variable
+func (l queryLimits) pageSize(field *ast.Field) int {
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "pageSize" {
+			continue
+		}
+		var value interface{}
+		switch v := argument.Value.(type) {
+		case *ast.IntValue:
+			value = v.Value
+		case *ast.Variable:
+			value = l.variables[v.Name.Value]
+		}
+		n := 0
+		switch v := value.(type) {
+		case string:
+			n, _ = strconv.Atoi(v)
+		case float64:
+			n = int(v)
+		case int:
+			n = v
+		}
+		// Out of range sizes fail in the resolver; count them as one item
+		if n < 1 {
+			n = 1
+		}
+		return n
+	}
+	// The schema defaults users and tasks alike to a page of this size
+	return graphQLDefaultPageSize
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// batchLoader fetches records by ID for one GraphQL request, the way a
+// dataloader does. Resolvers queue the IDs they need and return a thunk;
+// the executor calls thunks only once every sibling field has resolved, so
+// the first thunk fetches everything queued by then in a single call.
+// Records are cached for the rest of the request.
+type batchLoader struct {
+	// fetch loads records keyed by ID, leaving out IDs it can't find
+	fetch func(ids []primitive.ObjectID) (map[primitive.ObjectID]interface{}, error)
+
+	mu      sync.Mutex
+	queued  []primitive.ObjectID
+	fetched map[primitive.ObjectID]interface{}
+	// failed remembers why the fetch for an ID went wrong
+	failed map[primitive.ObjectID]error
+}
+
+func newBatchLoader(fetch func(ids []primitive.ObjectID) (map[primitive.ObjectID]interface{}, error)) *batchLoader {
+	return &batchLoader{
+		fetch:   fetch,
+		fetched: map[primitive.ObjectID]interface{}{},
+		failed:  map[primitive.ObjectID]error{},
+	}
+}
+
+// loadMany queues ids and returns a thunk giving the records found for
+// them, in order, skipping IDs with no record
+func (l *batchLoader) loadMany(ids []primitive.ObjectID) func() ([]interface{}, error) {
+	l.mu.Lock()
+	for _, id := range ids {
+		if _, done := l.fetched[id]; !done {
+			l.queued = append(l.queued, id)
+		}
+	}
+	l.mu.Unlock()
+
+	return func() ([]interface{}, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.dispatch()
+
+		records := make([]interface{}, 0, len(ids))
+		for _, id := range ids {
+			if err := l.failed[id]; err != nil {
+				return nil, err
+			}
+			if record := l.fetched[id]; record != nil {
+				records = append(records, record)
+			}
+		}
+		return records, nil
+	}
+}
+
+// load is loadMany for a single ID; the thunk gives nil when there is no
+// record
+func (l *batchLoader) load(id primitive.ObjectID) func() (interface{}, error) {
+	many := l.loadMany([]primitive.ObjectID{id})
+	return func() (interface{}, error) {
+		records, err := many()
+		if err != nil || len(records) == 0 {
+			return nil, err
+		}
+		return records[0], nil
+	}
+}
+
+// dispatch fetches every queued ID not fetched yet. l.mu must be held.
+func (l *batchLoader) dispatch() {
+	seen := map[primitive.ObjectID]bool{}
+	ids := make([]primitive.ObjectID, 0, len(l.queued))
+	for _, id := range l.queued {
+		if _, done := l.fetched[id]; !done && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	l.queued = nil
+	if len(ids) == 0 {
+		return
+	}
+
+	records, err := l.fetch(ids)
+	for _, id := range ids {
+		if err != nil {
+			l.failed[id] = err
+		}
+		// A nil entry marks the ID as looked up and not found
+		l.fetched[id] = records[id]
+	}
+}
@@ -0,0 +1,319 @@
+package controllers
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+
+	"github.com/graphql-go/graphql"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// graphQLDefaultPageSize is the page size tasks and users list by default
+const graphQLDefaultPageSize = Domain.DefaultTaskPageSize
+
+// GraphQL enums carry the domain's values, so resolvers see and return
+// "in_progress" while clients see IN_PROGRESS
+
+var taskStatusEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "TaskStatus",
+	Values: graphql.EnumValueConfigMap{
+		"PENDING":     &graphql.EnumValueConfig{Value: Domain.StatusPending},
+		"IN_PROGRESS": &graphql.EnumValueConfig{Value: Domain.StatusInProgress},
+		"COMPLETED":   &graphql.EnumValueConfig{Value: Domain.StatusCompleted},
+	},
+})
+
+var priorityEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name:        "Priority",
+	Description: "P0 is the most urgent",
+	Values: graphql.EnumValueConfigMap{
+		"P0": &graphql.EnumValueConfig{Value: Domain.PriorityP0},
+		"P1": &graphql.EnumValueConfig{Value: Domain.PriorityP1},
+		"P2": &graphql.EnumValueConfig{Value: Domain.PriorityP2},
+		"P3": &graphql.EnumValueConfig{Value: Domain.PriorityP3},
+	},
+})
+
+var roleEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "Role",
+	Values: graphql.EnumValueConfigMap{
+		"ADMIN": &graphql.EnumValueConfig{Value: Domain.RoleAdmin},
+		"USER":  &graphql.EnumValueConfig{Value: Domain.RoleUser},
+	},
+})
+
+var userStatusEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "UserStatus",
+	Values: graphql.EnumValueConfigMap{
+		"ACTIVE":      &graphql.EnumValueConfig{Value: Domain.UserStatusActive},
+		"DEACTIVATED": &graphql.EnumValueConfig{Value: Domain.UserStatusDeactivated},
+	},
+})
+
+// resolveID serves ObjectIDs as their hex form
+func resolveID(p graphql.ResolveParams) (interface{}, error) {
+	value, err := graphql.DefaultResolveFn(p)
+	if id, ok := value.(primitive.ObjectID); ok {
+		return id.Hex(), err
+	}
+	return value, err
+}
+
+// resolveOptional serves empty strings as null
+func resolveOptional(p graphql.ResolveParams) (interface{}, error) {
+	value, err := graphql.DefaultResolveFn(p)
+	if value == "" {
+		return nil, err
+	}
+	return value, err
+}
+
+// resolvePrivate serves a user field only to admins and the user themself
+func resolvePrivate(p graphql.ResolveParams) (interface{}, error) {
+	user, _ := p.Source.(UserResponse)
+	if req := graphQLRequestFrom(p); !req.admin && req.viewer.UserID != user.ID.Hex() {
+		return nil, nil
+	}
+	return resolveOptional(p)
+}
+
+// resolveStatusHistory serves a task without history as an empty list
+func resolveStatusHistory(p graphql.ResolveParams) (interface{}, error) {
+	task, _ := p.Source.(TaskResponse)
+	if task.StatusHistory == nil {
+		return []Domain.StatusChange{}, nil
+	}
+	return task.StatusHistory, nil
+}
+
+func nonNull(t graphql.Output) graphql.Output {
+	return graphql.NewNonNull(t)
+}
+
+func listOf(t graphql.Output) graphql.Output {
+	return graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(t)))
+}
+
+// newGraphQLSchema builds the schema, resolving through gc's usecases.
+// Objects resolve from the REST response types, so GraphQL shows no more of
+// a record than the REST API does.
+func newGraphQLSchema(gc *GraphQLController) (graphql.Schema, error) {
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Description: "Email, verification, deactivation and 2FA state are only shown to admins " +
+			"and the user themself, and are null otherwise",
+		Fields: graphql.Fields{
+			"id":            &graphql.Field{Type: nonNull(graphql.ID), Resolve: resolveID},
+			"username":      &graphql.Field{Type: nonNull(graphql.String)},
+			"displayName":   &graphql.Field{Type: nonNull(graphql.String)},
+			"role":          &graphql.Field{Type: nonNull(roleEnum)},
+			"email":         &graphql.Field{Type: graphql.String, Resolve: resolvePrivate},
+			"emailVerified": &graphql.Field{Type: graphql.Boolean, Resolve: resolvePrivate},
+			"deactivated":   &graphql.Field{Type: graphql.Boolean, Resolve: resolvePrivate},
+			"mfaEnabled":    &graphql.Field{Type: graphql.Boolean, Resolve: resolvePrivate},
+		},
+	})
+
+	labelType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Label",
+		Fields: graphql.Fields{
+			"id":      &graphql.Field{Type: nonNull(graphql.ID), Resolve: resolveID},
+			"project": &graphql.Field{Type: nonNull(graphql.String)},
+			"name":    &graphql.Field{Type: nonNull(graphql.String)},
+			"color":   &graphql.Field{Type: nonNull(graphql.String)},
+		},
+	})
+
+	customFieldType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "CustomFieldValue",
+		Description: "A custom field set on a task. Values are shown as strings: numbers in decimal, dates as YYYY-MM-DD and users as IDs.",
+		Fields: graphql.Fields{
+			"key":   &graphql.Field{Type: nonNull(graphql.String)},
+			"type":  &graphql.Field{Type: graphql.String, Resolve: resolveOptional},
+			"value": &graphql.Field{Type: graphql.String},
+			"user": &graphql.Field{
+				Type:        userType,
+				Description: "The user a user field refers to",
+				Resolve:     gc.resolveFieldUser,
+			},
+		},
+	})
+
+	statusChangeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StatusChange",
+		Fields: graphql.Fields{
+			"status": &graphql.Field{Type: nonNull(taskStatusEnum)},
+			"at":     &graphql.Field{Type: nonNull(graphql.DateTime)},
+		},
+	})
+
+	taskType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Task",
+		Fields: graphql.Fields{
+			"id":            &graphql.Field{Type: nonNull(graphql.ID), Resolve: resolveID},
+			"title":         &graphql.Field{Type: nonNull(graphql.String)},
+			"description":   &graphql.Field{Type: nonNull(graphql.String)},
+			"dueDate":       &graphql.Field{Type: nonNull(graphql.DateTime)},
+			"status":        &graphql.Field{Type: taskStatusEnum, Resolve: resolveOptional},
+			"priority":      &graphql.Field{Type: priorityEnum, Resolve: resolveOptional},
+			"project":       &graphql.Field{Type: nonNull(graphql.String)},
+			"externalId":    &graphql.Field{Type: graphql.String, Resolve: resolveOptional},
+			"labels":        &graphql.Field{Type: listOf(labelType), Resolve: gc.resolveTaskLabels},
+			"customFields":  &graphql.Field{Type: listOf(customFieldType), Resolve: gc.resolveTaskCustomFields},
+			"createdAt":     &graphql.Field{Type: nonNull(graphql.DateTime)},
+			"updatedAt":     &graphql.Field{Type: nonNull(graphql.DateTime)},
+			"statusHistory": &graphql.Field{Type: listOf(statusChangeType), Resolve: resolveStatusHistory},
+		},
+	})
+
+	taskPageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TaskPage",
+		Fields: graphql.Fields{
+			"tasks":    &graphql.Field{Type: listOf(taskType)},
+			"total":    &graphql.Field{Type: nonNull(graphql.Int), Description: "How many tasks match across all pages"},
+			"page":     &graphql.Field{Type: nonNull(graphql.Int)},
+			"pageSize": &graphql.Field{Type: nonNull(graphql.Int)},
+		},
+	})
+
+	userPageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "UserPage",
+		Fields: graphql.Fields{
+			"users":    &graphql.Field{Type: listOf(userType)},
+			"total":    &graphql.Field{Type: nonNull(graphql.Int), Description: "How many users match across all pages"},
+			"page":     &graphql.Field{Type: nonNull(graphql.Int)},
+			"pageSize": &graphql.Field{Type: nonNull(graphql.Int)},
+		},
+	})
+
+	customFieldInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "CustomFieldInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"key":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"value": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+
+	taskFilterInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "TaskFilter",
+		Description: "Every set criterion must match; a task must carry all of labels",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"status":       &graphql.InputObjectFieldConfig{Type: taskStatusEnum},
+			"priority":     &graphql.InputObjectFieldConfig{Type: priorityEnum},
+			"project":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"labels":       &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.ID))},
+			"customFields": &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(customFieldInput))},
+		},
+	})
+
+	taskSortInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "TaskSort",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"field": &graphql.InputObjectFieldConfig{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "A task field like due_date, or cf.<key> for a custom field",
+			},
+			"desc": &graphql.InputObjectFieldConfig{Type: graphql.Boolean, DefaultValue: false},
+		},
+	})
+
+	taskInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "TaskInput",
+		Description: "A whole task; updates replace every field",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"title":        &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"description":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"dueDate":      &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.DateTime)},
+			"status":       &graphql.InputObjectFieldConfig{Type: taskStatusEnum},
+			"priority":     &graphql.InputObjectFieldConfig{Type: priorityEnum},
+			"project":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"externalId":   &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"labels":       &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.ID))},
+			"customFields": &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(customFieldInput))},
+		},
+	})
+
+	pageArgs := func(args graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+		args["page"] = &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1}
+		args["pageSize"] = &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: graphQLDefaultPageSize}
+		return args
+	}
+	idArgs := func() graphql.FieldConfigArgument {
+		return graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}}
+	}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"me": &graphql.Field{
+				Type:        nonNull(userType),
+				Description: "The caller's account",
+				Resolve:     gc.resolveMe,
+			},
+			"users": &graphql.Field{
+				Type:        nonNull(userPageType),
+				Description: "Admins only",
+				Args: pageArgs(graphql.FieldConfigArgument{
+					"search": &graphql.ArgumentConfig{Type: graphql.String},
+					"role":   &graphql.ArgumentConfig{Type: roleEnum},
+					"status": &graphql.ArgumentConfig{Type: userStatusEnum},
+				}),
+				Resolve: gc.resolveUsers,
+			},
+			"user": &graphql.Field{
+				Type:        userType,
+				Description: "Admins only",
+				Args:        idArgs(),
+				Resolve:     gc.resolveUser,
+			},
+			"tasks": &graphql.Field{
+				Type: nonNull(taskPageType),
+				Args: pageArgs(graphql.FieldConfigArgument{
+					"filter": &graphql.ArgumentConfig{Type: taskFilterInput},
+					"sort":   &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(taskSortInput))},
+				}),
+				Resolve: gc.resolveTasks,
+			},
+			"task": &graphql.Field{
+				Type:    taskType,
+				Args:    idArgs(),
+				Resolve: gc.resolveTask,
+			},
+		},
+	})
+
+	withInput := func(args graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+		args["input"] = &graphql.ArgumentConfig{Type: graphql.NewNonNull(taskInput)}
+		return args
+	}
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Mutation",
+		Description: "Every mutation is for admins only",
+		Fields: graphql.Fields{
+			"createTask": &graphql.Field{
+				Type:    nonNull(taskType),
+				Args:    withInput(graphql.FieldConfigArgument{}),
+				Resolve: gc.resolveCreateTask,
+			},
+			"updateTask": &graphql.Field{
+				Type:    nonNull(taskType),
+				Args:    withInput(idArgs()),
+				Resolve: gc.resolveUpdateTask,
+			},
+			"deleteTask": &graphql.Field{
+				Type:    nonNull(graphql.Boolean),
+				Args:    idArgs(),
+				Resolve: gc.resolveDeleteTask,
+			},
+			"setTaskStatus": &graphql.Field{
+				Type: nonNull(taskType),
+				Args: graphql.FieldConfigArgument{
+					"id":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"status": &graphql.ArgumentConfig{Type: graphql.NewNonNull(taskStatusEnum)},
+				},
+				Resolve: gc.resolveSetTaskStatus,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
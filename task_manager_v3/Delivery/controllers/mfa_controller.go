@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Usecases"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type MFAController struct {
+	mfaUsecase Usecases.MFAUsecase
+}
+
+func NewMFAController(mfaUsecase Usecases.MFAUsecase) *MFAController {
+	return &MFAController{
+		mfaUsecase: mfaUsecase,
+	}
+}
+
+func mfaErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, Domain.ErrInvalidMFAToken), errors.Is(err, Domain.ErrInvalidMFACode),
+		errors.Is(err, Domain.ErrMFAAttemptsExceeded):
+		return http.StatusUnauthorized
+	case errors.Is(err, Domain.ErrUserDeactivated), errors.Is(err, Domain.ErrWrongPassword):
+		return http.StatusForbidden
+	case errors.Is(err, Domain.ErrMFAAlreadyEnabled), errors.Is(err, Domain.ErrMFANotEnrolled),
+		errors.Is(err, Domain.ErrMFAEnforced), errors.Is(err, Domain.ErrSelfManagement):
+		return http.StatusConflict
+	case errors.Is(err, Domain.ErrInvalidRole):
+		return http.StatusBadRequest
+	case errors.Is(err, Domain.ErrUserNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// CompleteLogin is the second login step: POST /login/mfa
+func (mc *MFAController) CompleteLogin(c *gin.Context) {
+	var req MFALoginRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	result, err := mc.mfaUsecase.CompleteLogin(req.MFAToken, req.Code, clientInfo(c))
+	if err != nil {
+		c.JSON(mfaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, loginResponse(result))
+}
+
+// BeginLoginEnrollment sets up 2FA for users who must have it before they
+// can finish logging in
+func (mc *MFAController) BeginLoginEnrollment(c *gin.Context) {
+	var req MFAEnrollmentRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	enrollment, err := mc.mfaUsecase.BeginLoginEnrollment(req.MFAToken)
+	if err != nil {
+		c.JSON(mfaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+func (mc *MFAController) BeginEnrollment(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	enrollment, err := mc.mfaUsecase.BeginEnrollment(id)
+	if err != nil {
+		c.JSON(mfaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+func (mc *MFAController) ConfirmEnrollment(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req MFACodeRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	codes, err := mc.mfaUsecase.ConfirmEnrollment(id, req.Code)
+	if err != nil {
+		c.JSON(mfaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+func (mc *MFAController) RegenerateRecoveryCodes(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req MFACodeRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	codes, err := mc.mfaUsecase.RegenerateRecoveryCodes(id, req.Code)
+	if err != nil {
+		c.JSON(mfaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+func (mc *MFAController) Disable(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req DisableMFARequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if err := mc.mfaUsecase.Disable(id, req.Password, req.Code); err != nil {
+		c.JSON(mfaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+func (mc *MFAController) ResetUser(c *gin.Context) {
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := mc.mfaUsecase.Reset(actorID, id); err != nil {
+		c.JSON(mfaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication reset"})
+}
+
+func (mc *MFAController) GetPolicy(c *gin.Context) {
+	policy, err := mc.mfaUsecase.Policy()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+func (mc *MFAController) SetPolicy(c *gin.Context) {
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req MFAPolicyRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	policy, err := mc.mfaUsecase.SetPolicy(actorID, req.MFARequiredRoles)
+	if err != nil {
+		c.JSON(mfaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
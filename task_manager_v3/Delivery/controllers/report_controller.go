@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Usecases"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReportController struct {
+	reportUsecase Usecases.ReportUsecase
+}
+
+func NewReportController(reportUsecase Usecases.ReportUsecase) *ReportController {
+	return &ReportController{
+		reportUsecase: reportUsecase,
+	}
+}
+
+// reportErrorStatus answers a bad interval or range with 400 and anything
+// else, such as a failed aggregation, with 500
+func reportErrorStatus(err error) int {
+	if errors.Is(err, Domain.ErrInvalidInterval) || errors.Is(err, Domain.ErrInvalidReportRange) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// parseRange reads the optional from/to query parameters, accepting either
+// RFC 3339 timestamps or plain YYYY-MM-DD dates
+func parseRange(c *gin.Context) (time.Time, time.Time, bool) {
+	var bounds [2]time.Time
+	for i, name := range []string{"from", "to"} {
+		raw := c.Query(name)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			t, err = time.Parse("2006-01-02", raw)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name + " date"})
+			return time.Time{}, time.Time{}, false
+		}
+		bounds[i] = t
+	}
+	return bounds[0], bounds[1], true
+}
+
+func (rc *ReportController) Summary(c *gin.Context) {
+	summary, err := rc.reportUsecase.Summary()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+func (rc *ReportController) Throughput(c *gin.Context) {
+	from, to, ok := parseRange(c)
+	if !ok {
+		return
+	}
+
+	buckets, err := rc.reportUsecase.Throughput(from, to, c.Query("interval"))
+	if err != nil {
+		c.JSON(reportErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, buckets)
+}
+
+func (rc *ReportController) CycleTime(c *gin.Context) {
+	from, to, ok := parseRange(c)
+	if !ok {
+		return
+	}
+
+	report, err := rc.reportUsecase.CycleTime(from, to)
+	if err != nil {
+		c.JSON(reportErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,227 @@
+package controllers
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Request bodies. Each endpoint binds its own type holding only what a
+// client may set, so IDs, roles, timestamps and other server-maintained
+// fields can't be smuggled in; unknown JSON fields are ignored. The binding
+// tags are checked by bindJSON, the usecases still enforce the rules that
+// need the database.
+
+// CreateTaskRequest is the body of POST /tasks
+type CreateTaskRequest struct {
+	Title        string                 `json:"title" binding:"required,max=200"`
+	Description  string                 `json:"description" binding:"max=10000"`
+	DueDate      time.Time              `json:"due_date" binding:"required,notpast"`
+	Status       string                 `json:"status" binding:"omitempty,taskstatus"`
+	Priority     string                 `json:"priority" binding:"omitempty,priority"`
+	Project      string                 `json:"project" binding:"max=100"`
+	ExternalID   string                 `json:"external_id" binding:"max=200"`
+	Labels       []primitive.ObjectID   `json:"labels"`
+	CustomFields map[string]interface{} `json:"custom_fields"`
+}
+
+func (r CreateTaskRequest) task() Domain.Task {
+	return Domain.Task{
+		Title:        r.Title,
+		Description:  r.Description,
+		DueDate:      r.DueDate,
+		Status:       r.Status,
+		Priority:     r.Priority,
+		Project:      r.Project,
+		ExternalID:   r.ExternalID,
+		Labels:       r.Labels,
+		CustomFields: r.CustomFields,
+	}
+}
+
+// UpdateTaskRequest is the body of PUT /tasks/:id, which replaces the task.
+// Unlike a new task, an existing one may be overdue.
+type UpdateTaskRequest struct {
+	Title        string                 `json:"title" binding:"required,max=200"`
+	Description  string                 `json:"description" binding:"max=10000"`
+	DueDate      time.Time              `json:"due_date" binding:"required"`
+	Status       string                 `json:"status" binding:"omitempty,taskstatus"`
+	Priority     string                 `json:"priority" binding:"omitempty,priority"`
+	Project      string                 `json:"project" binding:"max=100"`
+	ExternalID   string                 `json:"external_id" binding:"max=200"`
+	Labels       []primitive.ObjectID   `json:"labels"`
+	CustomFields map[string]interface{} `json:"custom_fields"`
+}
+
+func (r UpdateTaskRequest) task() Domain.Task {
+	return Domain.Task{
+		Title:        r.Title,
+		Description:  r.Description,
+		DueDate:      r.DueDate,
+		Status:       r.Status,
+		Priority:     r.Priority,
+		Project:      r.Project,
+		ExternalID:   r.ExternalID,
+		Labels:       r.Labels,
+		CustomFields: r.CustomFields,
+	}
+}
+
+// BulkRequest is the body of POST /tasks/bulk. The operations themselves
+// are checked one by one by the usecase, which reports each in its result.
+type BulkRequest struct {
+	Operations []BulkOperationRequest `json:"operations" binding:"required"`
+	Atomic     bool                   `json:"atomic"`
+}
+
+// BulkOperationRequest is one item of a BulkRequest
+type BulkOperationRequest struct {
+	Op     string             `json:"op"`
+	ID     string             `json:"id"`
+	Task   *UpdateTaskRequest `json:"task"`
+	Status string             `json:"status"`
+}
+
+func (r BulkRequest) operations() []Domain.BulkOperation {
+	ops := make([]Domain.BulkOperation, 0, len(r.Operations))
+	for _, op := range r.Operations {
+		bulkOp := Domain.BulkOperation{Op: op.Op, ID: op.ID, Status: op.Status}
+		if op.Task != nil {
+			task := op.Task.task()
+			bulkOp.Task = &task
+		}
+		ops = append(ops, bulkOp)
+	}
+	return ops
+}
+
+// RegisterRequest is the body of POST /register. There is no role: new
+// accounts are users unless an invitation says otherwise.
+type RegisterRequest struct {
+	Username   string `json:"username" binding:"required,max=64"`
+	Password   string `json:"password" binding:"required,min=8,maxbytes=72"`
+	Email      string `json:"email" binding:"omitempty,email"`
+	Invitation string `json:"invitation"`
+}
+
+func (r RegisterRequest) registration() Domain.Registration {
+	return Domain.Registration{Username: r.Username, Password: r.Password, Email: r.Email, Invitation: r.Invitation}
+}
+
+// LoginRequest is the body of POST /login
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required,maxbytes=72"`
+}
+
+// UpdateProfileRequest is the body of PUT /me; omitted fields are left
+// alone
+type UpdateProfileRequest struct {
+	Username    *string `json:"username" binding:"omitempty,max=64"`
+	DisplayName *string `json:"display_name" binding:"omitempty,max=100"`
+	Email       *string `json:"email" binding:"omitempty,email"`
+}
+
+func (r UpdateProfileRequest) update() Domain.ProfileUpdate {
+	return Domain.ProfileUpdate{Username: r.Username, DisplayName: r.DisplayName, Email: r.Email}
+}
+
+// ChangePasswordRequest is the body of PUT /me/password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required,maxbytes=72"`
+	NewPassword     string `json:"new_password" binding:"required,min=8,maxbytes=72"`
+}
+
+// PromoteRequest is the body of POST /promote
+type PromoteRequest struct {
+	UserID string `json:"user_id" binding:"required,objectid"`
+}
+
+// ChangeRoleRequest is the body of PUT /users/:id/role
+type ChangeRoleRequest struct {
+	Role string `json:"role" binding:"required,role"`
+}
+
+// CreateInvitationRequest is the body of POST /invitations. Role defaults
+// to user and the lifetime to the usecase's default.
+type CreateInvitationRequest struct {
+	Role           string `json:"role" binding:"omitempty,role"`
+	Email          string `json:"email" binding:"omitempty,email"`
+	ExpiresInHours int    `json:"expires_in_hours" binding:"min=0"`
+}
+
+// LabelRequest is the body for creating or updating a label. The project
+// comes from the path.
+type LabelRequest struct {
+	Name  string `json:"name" binding:"required,max=50"`
+	Color string `json:"color" binding:"required"`
+}
+
+func (r LabelRequest) label() Domain.Label {
+	return Domain.Label{Name: r.Name, Color: r.Color}
+}
+
+// CustomFieldRequest is the body for creating or updating a custom field
+type CustomFieldRequest struct {
+	Key      string   `json:"key" binding:"required"`
+	Name     string   `json:"name" binding:"required,max=100"`
+	Type     string   `json:"type" binding:"required,oneof=text number date enum user"`
+	Options  []string `json:"options"`
+	Required bool     `json:"required"`
+}
+
+func (r CustomFieldRequest) field() Domain.CustomField {
+	return Domain.CustomField{Key: r.Key, Name: r.Name, Type: r.Type, Options: r.Options, Required: r.Required}
+}
+
+// CreateAccessTokenRequest is the body of POST /me/tokens; expires_in_days
+// defaults to 90
+type CreateAccessTokenRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"min=0"`
+}
+
+// VerifyEmailRequest is the body of POST /email/verify
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ForgotPasswordRequest is the body of POST /password/forgot
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// ResetPasswordRequest is the body of POST /password/reset
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8,maxbytes=72"`
+}
+
+// MFALoginRequest is the body of POST /login/mfa
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// MFAEnrollmentRequest is the body of POST /login/mfa/enroll
+type MFAEnrollmentRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+}
+
+// MFACodeRequest is a body carrying just a second-factor code
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// DisableMFARequest is the body of DELETE /me/mfa
+type DisableMFARequest struct {
+	Password string `json:"password" binding:"required,maxbytes=72"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// MFAPolicyRequest is the body of PUT /security/policy
+type MFAPolicyRequest struct {
+	MFARequiredRoles []string `json:"mfa_required_roles" binding:"dive,role"`
+}
@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Response bodies. Handlers map domain values into these instead of
+// serializing them directly, so a field added to a domain type stays
+// private until it is added here. Credentials (password hashes, TOTP
+// secrets, recovery code hashes) have no place in any of them.
+
+// UserResponse is what the API shows of a user
+type UserResponse struct {
+	ID            primitive.ObjectID `json:"id"`
+	Username      string             `json:"username"`
+	DisplayName   string             `json:"display_name"`
+	Email         string             `json:"email,omitempty"`
+	EmailVerified bool               `json:"email_verified"`
+	Role          string             `json:"role"`
+	Deactivated   bool               `json:"deactivated"`
+	MFAEnabled    bool               `json:"mfa_enabled"`
+}
+
+func userResponse(user Domain.User) UserResponse {
+	return UserResponse{
+		ID:            user.ID,
+		Username:      user.Username,
+		DisplayName:   user.DisplayName,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		Role:          user.Role,
+		Deactivated:   user.Deactivated,
+		MFAEnabled:    user.MFAEnabled,
+	}
+}
+
+// UserListResponse is a page of users
+type UserListResponse struct {
+	Users    []UserResponse `json:"users"`
+	Total    int64          `json:"total"`
+	Page     int            `json:"page"`
+	PageSize int            `json:"page_size"`
+}
+
+// LoginResponse is the body of every successful login step: the access
+// token, or the pending token for the second factor
+type LoginResponse struct {
+	Token  string `json:"token,omitempty"`
+	UserID string `json:"user_id,omitempty"`
+
+	MFARequired        bool   `json:"mfa_required,omitempty"`
+	MFAToken           string `json:"mfa_token,omitempty"`
+	EnrollmentRequired bool   `json:"enrollment_required,omitempty"`
+
+	// RecoveryCodes is set when finishing the login also finished 2FA
+	// enrollment; they are never shown again
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+	// Provisioned is set when single sign-on created the account
+	Provisioned bool `json:"provisioned,omitempty"`
+}
+
+func loginResponse(result Domain.LoginResult) LoginResponse {
+	if result.MFAPending {
+		return LoginResponse{MFARequired: true, MFAToken: result.Token, EnrollmentRequired: result.EnrollmentRequired}
+	}
+	return LoginResponse{Token: result.Token, UserID: result.User.ID.Hex(), RecoveryCodes: result.RecoveryCodes}
+}
+
+// TaskResponse is what the API shows of a task
+type TaskResponse struct {
+	ID            primitive.ObjectID     `json:"id"`
+	Title         string                 `json:"title"`
+	Description   string                 `json:"description"`
+	DueDate       time.Time              `json:"due_date"`
+	Status        string                 `json:"status"`
+	ExternalID    string                 `json:"external_id,omitempty"`
+	Priority      string                 `json:"priority,omitempty"`
+	Project       string                 `json:"project,omitempty"`
+	Labels        []primitive.ObjectID   `json:"labels,omitempty"`
+	CustomFields  map[string]interface{} `json:"custom_fields,omitempty"`
+	CreatedAt     time.Time              `json:"created_at"`
+	UpdatedAt     time.Time              `json:"updated_at"`
+	StatusHistory []Domain.StatusChange  `json:"status_history,omitempty"`
+}
+
+func taskResponse(task Domain.Task) TaskResponse {
+	return TaskResponse{
+		ID:            task.ID,
+		Title:         task.Title,
+		Description:   task.Description,
+		DueDate:       task.DueDate,
+		Status:        task.Status,
+		ExternalID:    task.ExternalID,
+		Priority:      task.Priority,
+		Project:       task.Project,
+		Labels:        task.Labels,
+		CustomFields:  task.CustomFields,
+		CreatedAt:     task.CreatedAt,
+		UpdatedAt:     task.UpdatedAt,
+		StatusHistory: task.StatusHistory,
+	}
+}
+
+func taskResponses(tasks []Domain.Task) []TaskResponse {
+	out := make([]TaskResponse, 0, len(tasks))
+	for _, task := range tasks {
+		out = append(out, taskResponse(task))
+	}
+	return out
+}
+
+// taskResponsePtr maps an optional task
+func taskResponsePtr(task *Domain.Task) *TaskResponse {
+	if task == nil {
+		return nil
+	}
+	out := taskResponse(*task)
+	return &out
+}
+
+// TaskPageResponse is a page of tasks, as API v2 lists them
+type TaskPageResponse struct {
+	Tasks    []TaskResponse `json:"tasks"`
+	Total    int64          `json:"total"`
+	Page     int            `json:"page"`
+	PageSize int            `json:"page_size"`
+}
+
+// TaskSearchResultResponse is a search hit
+type TaskSearchResultResponse struct {
+	Task       TaskResponse      `json:"task"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+func searchResponses(results []Domain.TaskSearchResult) []TaskSearchResultResponse {
+	out := make([]TaskSearchResultResponse, 0, len(results))
+	for _, result := range results {
+		out = append(out, TaskSearchResultResponse{Task: taskResponse(result.Task), Score: result.Score, Highlights: result.Highlights})
+	}
+	return out
+}
+
+// BulkResultResponse reports one operation of a bulk request
+type BulkResultResponse struct {
+	Index  int           `json:"index"`
+	Op     string        `json:"op"`
+	ID     string        `json:"id,omitempty"`
+	Status int           `json:"status"`
+	Error  string        `json:"error,omitempty"`
+	Task   *TaskResponse `json:"task,omitempty"`
+}
+
+func bulkResponses(results []Domain.BulkResult) []BulkResultResponse {
+	if results == nil {
+		return nil
+	}
+	out := make([]BulkResultResponse, 0, len(results))
+	for _, result := range results {
+		out = append(out, BulkResultResponse{
+			Index:  result.Index,
+			Op:     result.Op,
+			ID:     result.ID,
+			Status: result.Status,
+			Error:  result.Error,
+			Task:   taskResponsePtr(result.Task),
+		})
+	}
+	return out
+}
+
+// TaskEventResponse is a task event as pushed to stream subscribers
+type TaskEventResponse struct {
+	ID         string             `json:"id"`
+	Type       string             `json:"type"`
+	TaskID     primitive.ObjectID `json:"task_id"`
+	Task       *TaskResponse      `json:"task,omitempty"`
+	OccurredAt time.Time          `json:"occurred_at"`
+}
+
+func taskEventResponse(event Domain.TaskEvent) TaskEventResponse {
+	return TaskEventResponse{
+		ID:         event.ID,
+		Type:       event.Type,
+		TaskID:     event.TaskID,
+		Task:       taskResponsePtr(event.Task),
+		OccurredAt: event.OccurredAt,
+	}
+}
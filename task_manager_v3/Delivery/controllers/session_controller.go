@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"a2sv-backend/task_manager_v3/Usecases"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SessionController struct {
+	sessionUsecase Usecases.SessionUsecase
+}
+
+func NewSessionController(sessionUsecase Usecases.SessionUsecase) *SessionController {
+	return &SessionController{
+		sessionUsecase: sessionUsecase,
+	}
+}
+
+func sessionErrorStatus(err error) int {
+	if err.Error() == "session not found" {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// ListSessions lists the caller's sessions, marking the one in use
+func (sc *SessionController) ListSessions(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := sc.sessionUsecase.List(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID.Hex() == c.GetString("session_id")
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession ends one of the caller's sessions. Ending the current one
+// logs out.
+func (sc *SessionController) RevokeSession(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	if err := sc.sessionUsecase.Revoke(userID, id); err != nil {
+		c.JSON(sessionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+func (sc *SessionController) ListUserSessions(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	sessions, err := sc.sessionUsecase.List(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeUserSessions logs a user out everywhere
+func (sc *SessionController) RevokeUserSessions(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	revoked, err := sc.sessionUsecase.RevokeAll(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User logged out everywhere", "revoked": revoked})
+}
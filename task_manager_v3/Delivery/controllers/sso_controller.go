@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Usecases"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ssoStateCookie binds a sign-in to the browser that started it, so a
+// callback URL can't be replayed in someone else's session
+const ssoStateCookie = "oidc_state"
+
+// ssoStateCookiePath is "/" since the callback is reachable under each API
+// version's prefix as well as the deprecated root
+const ssoStateCookiePath = "/"
+
+type SSOController struct {
+	ssoUsecase Usecases.SSOUsecase
+}
+
+// NewSSOController takes a nil usecase when SSO is not configured; its
+// routes then answer 404
+func NewSSOController(ssoUsecase Usecases.SSOUsecase) *SSOController {
+	return &SSOController{
+		ssoUsecase: ssoUsecase,
+	}
+}
+
+func ssoErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, Domain.ErrSSONotConfigured), err.Error() == "identity not found":
+		return http.StatusNotFound
+	case errors.Is(err, Domain.ErrInvalidSSOState):
+		return http.StatusBadRequest
+	case errors.Is(err, Domain.ErrSSOFailed):
+		return http.StatusUnauthorized
+	case errors.Is(err, Domain.ErrSSOSignupClosed), errors.Is(err, Domain.ErrUserDeactivated):
+		return http.StatusForbidden
+	case errors.Is(err, Domain.ErrIdentityLinked), errors.Is(err, Domain.ErrSSOLinkRequired),
+		errors.Is(err, Domain.ErrLastLoginMethod):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// configured answers 404 when SSO is off
+func (sc *SSOController) configured(c *gin.Context) bool {
+	if sc.ssoUsecase == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": Domain.ErrSSONotConfigured.Error()})
+		return false
+	}
+	return true
+}
+
+// begin starts a flow and sets the state cookie
+func (sc *SSOController) begin(c *gin.Context, linkUserID primitive.ObjectID) (string, bool) {
+	authURL, state, err := sc.ssoUsecase.Begin(linkUserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return "", false
+	}
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(ssoStateCookie, state, int(Usecases.SSOStateTTL.Seconds()), ssoStateCookiePath, "", secure, true)
+	return authURL, true
+}
+
+// Login redirects the browser to the identity provider
+func (sc *SSOController) Login(c *gin.Context) {
+	if !sc.configured(c) {
+		return
+	}
+	authURL, ok := sc.begin(c, primitive.NilObjectID)
+	if !ok {
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// StartLink returns the provider URL that links an identity to the caller
+func (sc *SSOController) StartLink(c *gin.Context) {
+	if !sc.configured(c) {
+		return
+	}
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	authURL, ok := sc.begin(c, id)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"authorization_url": authURL})
+}
+
+// Callback is where the provider sends the browser back
+func (sc *SSOController) Callback(c *gin.Context) {
+	if !sc.configured(c) {
+		return
+	}
+	if providerError := c.Query("error"); providerError != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": providerError, "error_description": c.Query("error_description")})
+		return
+	}
+
+	state := c.Query("state")
+	cookie, err := c.Cookie(ssoStateCookie)
+	if err != nil || state == "" || cookie != state {
+		c.JSON(http.StatusBadRequest, gin.H{"error": Domain.ErrInvalidSSOState.Error()})
+		return
+	}
+	c.SetCookie(ssoStateCookie, "", -1, ssoStateCookiePath, "", false, true)
+
+	result, err := sc.ssoUsecase.Complete(state, c.Query("code"), clientInfo(c))
+	if err != nil {
+		c.JSON(ssoErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if result.Linked != nil {
+		c.JSON(http.StatusOK, gin.H{"message": "Identity linked", "identity": result.Linked})
+		return
+	}
+	response := loginResponse(result.Login)
+	response.Provisioned = result.Provisioned
+	c.JSON(http.StatusOK, response)
+}
+
+func (sc *SSOController) ListIdentities(c *gin.Context) {
+	if !sc.configured(c) {
+		return
+	}
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	identities, err := sc.ssoUsecase.ListIdentities(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, identities)
+}
+
+func (sc *SSOController) Unlink(c *gin.Context) {
+	if !sc.configured(c) {
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid identity ID"})
+		return
+	}
+
+	if err := sc.ssoUsecase.Unlink(userID, id); err != nil {
+		c.JSON(ssoErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Identity unlinked"})
+}
@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FieldError is one reason a request body was rejected. Field is the JSON
+// path of the offending value ("" when the body as a whole is unreadable)
+// and Code is stable for clients to switch on.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Codes for bodies that fail before any rule is checked
+const (
+	CodeMalformed = "malformed"
+	CodeType      = "type"
+)
+
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		RegisterValidators(v)
+	}
+}
+
+// RegisterValidators adds the custom rules used by the request DTOs and
+// makes errors name fields by their JSON names
+func RegisterValidators(v *validator.Validate) {
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	v.RegisterValidation("notpast", func(fl validator.FieldLevel) bool {
+		due, ok := fl.Field().Interface().(time.Time)
+		return ok && notPast(due, time.Now())
+	})
+	v.RegisterValidation("taskstatus", func(fl validator.FieldLevel) bool {
+		return Domain.ValidStatus(fl.Field().String())
+	})
+	v.RegisterValidation("priority", func(fl validator.FieldLevel) bool {
+		return Domain.ValidPriority(fl.Field().String())
+	})
+	v.RegisterValidation("role", func(fl validator.FieldLevel) bool {
+		role := fl.Field().String()
+		return role == Domain.RoleUser || role == Domain.RoleAdmin
+	})
+	v.RegisterValidation("objectid", func(fl validator.FieldLevel) bool {
+		return primitive.IsValidObjectID(fl.Field().String())
+	})
+	// maxbytes bounds a string's UTF-8 length, which is what bcrypt limits
+	v.RegisterValidation("maxbytes", func(fl validator.FieldLevel) bool {
+		n, err := strconv.Atoi(fl.Param())
+		return err == nil && len(fl.Field().String()) <= n
+	})
+}
+
+// notPast reports whether due is today or later. Today is the calendar day
+// in due's own time zone, so a date-only due date for today is accepted all
+// day long.
+func notPast(due, now time.Time) bool {
+	y, m, d := now.In(due.Location()).Date()
+	return !due.Before(time.Date(y, m, d, 0, 0, 0, 0, due.Location()))
+}
+
+// bindJSON decodes and validates the body into req. On failure it responds
+// 400 with every problem found and returns false.
+func bindJSON(c *gin.Context, req interface{}) bool {
+	err := c.ShouldBindJSON(req)
+	if err == nil {
+		return true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "errors": fieldErrors(err)})
+	return false
+}
+
+// ValidateRequest checks req, a pointer to a request DTO, against its
+// binding rules for transports that don't decode it through gin. It returns
+// nil when req is valid.
+func ValidateRequest(req interface{}) []FieldError {
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return fieldErrors(err)
+	}
+	return nil
+}
+
+// fieldErrors describes a binding error
+func fieldErrors(err error) []FieldError {
+	var invalid validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &invalid):
+		out := make([]FieldError, 0, len(invalid))
+		for _, fe := range invalid {
+			out = append(out, fieldError(fe))
+		}
+		return out
+	case errors.As(err, &typeErr):
+		return []FieldError{{Field: typeErr.Field, Code: CodeType, Message: fmt.Sprintf("%s must be a %s", typeErr.Field, jsonType(typeErr.Type))}}
+	}
+	return []FieldError{{Code: CodeMalformed, Message: "Request body is not valid JSON: " + err.Error()}}
+}
+
+// fieldError turns a failed rule into a FieldError. The code is the rule's
+// tag, with min and max told apart by the kind of value.
+func fieldError(fe validator.FieldError) FieldError {
+	field := fe.Namespace()
+	if _, rest, ok := strings.Cut(field, "."); ok {
+		field = rest
+	}
+	out := FieldError{Field: field, Code: fe.Tag()}
+
+	switch fe.Tag() {
+	case "required":
+		out.Message = "is required"
+	case "min", "max":
+		bound := "at least"
+		if fe.Tag() == "max" {
+			bound = "at most"
+		}
+		switch fe.Kind() {
+		case reflect.String:
+			out.Message = fmt.Sprintf("must be %s %s characters long", bound, fe.Param())
+		case reflect.Slice, reflect.Map:
+			out.Message = fmt.Sprintf("must have %s %s items", bound, fe.Param())
+		default:
+			out.Message = fmt.Sprintf("must be %s %s", bound, fe.Param())
+		}
+	case "email":
+		out.Message = "must be a valid email address"
+	case "oneof":
+		out.Message = "must be one of " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "notpast":
+		out.Message = "must not be in the past"
+	case "taskstatus":
+		out.Message = fmt.Sprintf("must be one of %s, %s, %s", Domain.StatusPending, Domain.StatusInProgress, Domain.StatusCompleted)
+	case "priority":
+		out.Message = fmt.Sprintf("must be one of %s, %s, %s, %s", Domain.PriorityP0, Domain.PriorityP1, Domain.PriorityP2, Domain.PriorityP3)
+	case "role":
+		out.Message = fmt.Sprintf("must be %s or %s", Domain.RoleUser, Domain.RoleAdmin)
+	case "objectid":
+		out.Message = "must be a valid ID"
+	case "maxbytes":
+		out.Message = fmt.Sprintf("must be at most %s bytes long", fe.Param())
+	default:
+		out.Message = "is invalid"
+	}
+	out.Message = field + " " + out.Message
+	return out
+}
+
+// jsonType names a Go type the way a JSON client thinks of it
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	}
+	return "object"
+}
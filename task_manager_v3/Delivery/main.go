@@ -2,37 +2,49 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
+	"net"
 	"os"
 	"time"
 
 	"a2sv-backend/task_manager_v3/Delivery/controllers"
 	"a2sv-backend/task_manager_v3/Delivery/routers"
+	"a2sv-backend/task_manager_v3/Delivery/rpc"
 	"a2sv-backend/task_manager_v3/Infrastructure"
 	"a2sv-backend/task_manager_v3/Repositories"
 	"a2sv-backend/task_manager_v3/Usecases"
 
-	"github.com/joho/godotenv"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 func main() {
-	// Load .env file
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found or error loading it — continuing using environment variables")
-	}
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML settings file, overridden by .env and the environment (default $CONFIG_FILE)")
+	printConfig := flag.Bool("print-config", false, "print the configuration with secrets redacted, then exit")
+	flag.Parse()
 
-	// MongoDB Connection
-	mongoURI := os.Getenv("MONGODB_URI")
-	if mongoURI == "" {
-		log.Fatal("MONGODB_URI environment variable is not set")
+	config, err := Infrastructure.LoadConfig(*configPath, ".env")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *printConfig {
+		if err := config.Print(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := config.Validate(); err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	if *printConfig {
+		return
 	}
 
+	// MongoDB Connection
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	clientOptions := options.Client().ApplyURI(mongoURI)
+	clientOptions := options.Client().ApplyURI(config.Database.URI)
 	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
 		log.Fatal(err)
@@ -44,37 +56,108 @@ func main() {
 	}
 
 	log.Println("Connected to MongoDB Atlas!")
-	db := client.Database("task_manager")
+	db := client.Database(config.Database.Name)
 
 	// Initialize Repositories
 	userRepo := Repositories.NewMongoUserRepository(db)
 	taskRepo := Repositories.NewMongoTaskRepository(db)
+	reportRepo := Repositories.NewMongoReportRepository(db)
+	labelRepo := Repositories.NewMongoLabelRepository(db)
+	fieldRepo := Repositories.NewMongoCustomFieldRepository(db)
+	attachmentRepo := Repositories.NewMongoAttachmentRepository(db)
+	invitationRepo := Repositories.NewMongoInvitationRepository(db)
+	userTokenRepo := Repositories.NewMongoUserTokenRepository(db)
+	securityPolicyRepo := Repositories.NewMongoSecurityPolicyRepository(db)
+	identityRepo := Repositories.NewMongoIdentityRepository(db)
+	ssoStateRepo := Repositories.NewMongoSSOStateRepository(db)
+	accessTokenRepo := Repositories.NewMongoAccessTokenRepository(db)
+	sessionRepo := Repositories.NewMongoSessionRepository(db)
+	mfaChallengeRepo := Repositories.NewMongoMFAChallengeRepository(db)
 
 	// Initialize Infrastructure Services
-	passwordService := Infrastructure.NewBcryptPasswordService()
-	jwtService := Infrastructure.NewJWTService()
+	passwordService := Infrastructure.NewBcryptPasswordService(config.Auth.BcryptCost)
+	jwtService := Infrastructure.NewJWTService(config.Auth.JWTSecret)
+
+	var eventBus Infrastructure.EventBus
+	switch config.Events.Bus {
+	case "memory":
+		eventBus = Infrastructure.NewInMemoryEventBus(1024)
+	case "mongo":
+		eventBus = Infrastructure.NewMongoChangeStreamEventBus(db)
+	}
+
+	var blobStore Infrastructure.BlobStore
+	switch config.Attachments.Store {
+	case "local":
+		blobStore, err = Infrastructure.NewLocalBlobStore(config.Attachments.Dir)
+	case "gridfs":
+		blobStore, err = Infrastructure.NewGridFSBlobStore(db)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var mailer Infrastructure.MailSender
+	if config.Mail.SMTPAddr != "" {
+		mailer = Infrastructure.NewSMTPMailSender(config.Mail.SMTPAddr, config.Mail.SMTPUsername, config.Mail.SMTPPassword, config.Mail.From)
+	} else {
+		log.Println("No SMTP server is configured; verification and reset emails will not be delivered")
+		mailer = Infrastructure.NewInMemoryOutbox()
+	}
+	appURL := config.Server.BaseURL
 
 	// Initialize Usecases
-	userUsecase := Usecases.NewUserUsecase(userRepo, passwordService, jwtService)
-	taskUsecase := Usecases.NewTaskUsecase(taskRepo)
+	sessionUsecase := Usecases.NewSessionUsecase(sessionRepo, jwtService)
+	accountUsecase := Usecases.NewAccountUsecase(userRepo, userTokenRepo, passwordService, mailer, sessionUsecase, appURL)
+	mfaUsecase := Usecases.NewMFAUsecase(userRepo, securityPolicyRepo, mfaChallengeRepo, passwordService, jwtService, sessionUsecase, config.Auth.MFAIssuer)
+	userUsecase := Usecases.NewUserUsecase(userRepo, invitationRepo, passwordService, jwtService, config.Registration.Policy(), accountUsecase, mfaUsecase, sessionUsecase)
+	ssoUsecase := newSSOUsecase(config.SSO, appURL, ssoStateRepo, identityRepo, userRepo, jwtService, mfaUsecase, sessionUsecase)
+	accessTokenUsecase := Usecases.NewAccessTokenUsecase(accessTokenRepo)
+	attachmentUsecase := Usecases.NewAttachmentUsecase(attachmentRepo, taskRepo, blobStore, config.Attachments.MaxBytes)
+	taskUsecase := Usecases.NewTaskUsecase(taskRepo, labelRepo, fieldRepo, userRepo, eventBus, attachmentUsecase)
+	reportUsecase := Usecases.NewReportUsecase(reportRepo)
+	catalogUsecase := Usecases.NewCatalogUsecase(labelRepo, fieldRepo, taskRepo)
+
+	if args := flag.Args(); len(args) > 0 && args[0] == "bootstrap-admin" {
+		runBootstrapAdmin(userUsecase, config.Bootstrap.AdminPassword, args[1:])
+		return
+	}
+	bootstrapAdminFromConfig(userUsecase, config.Bootstrap)
 
 	// Initialize Controllers
 	userController := controllers.NewUserController(userUsecase)
 	taskController := controllers.NewTaskController(taskUsecase)
+	reportController := controllers.NewReportController(reportUsecase)
+	catalogController := controllers.NewCatalogController(catalogUsecase)
+	attachmentController := controllers.NewAttachmentController(attachmentUsecase)
+	accountController := controllers.NewAccountController(accountUsecase)
+	mfaController := controllers.NewMFAController(mfaUsecase)
+	ssoController := controllers.NewSSOController(ssoUsecase)
+	accessTokenController := controllers.NewAccessTokenController(accessTokenUsecase)
+	sessionController := controllers.NewSessionController(sessionUsecase)
+	graphQLController := controllers.NewGraphQLController(taskUsecase, userUsecase, catalogUsecase, config.GraphQL.MaxDepth, config.GraphQL.MaxComplexity)
 
 	// Setup Router
-	r := routers.SetupRouter(taskController, userController, jwtService)
+	limiter := newRateLimiter(config.RateLimit, db)
+	metrics := Infrastructure.NewMetrics()
+	r := routers.SetupRouter(taskController, userController, reportController, catalogController, attachmentController, accountController, mfaController, ssoController, accessTokenController, sessionController, graphQLController, jwtService, userUsecase, accessTokenUsecase, sessionUsecase, limiter, metrics, config.API, config.Server.TrustedProxies)
 
-	// Run Server
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = ":8080"
-	}
-	if port[0] != ':' {
-		port = ":" + port
+	// gRPC for other backend services, beside the HTTP API and sharing its
+	// usecases, authentication and metrics
+	grpcListener, err := net.Listen("tcp", config.Server.GRPCPort)
+	if err != nil {
+		log.Fatalf("gRPC listener failed: %v", err)
 	}
+	authenticator := Infrastructure.NewAuthenticator(jwtService, userUsecase, accessTokenUsecase, sessionUsecase)
+	grpcServer := rpc.NewServer(taskUsecase, userUsecase, authenticator, metrics)
+	go func() {
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			log.Fatalf("gRPC server failed: %v", err)
+		}
+	}()
 
-	if err := r.Run(port); err != nil {
+	// Run Server
+	if err := r.Run(config.Server.Port); err != nil {
 		log.Fatalf("Server failed: %v", err)
 	}
 }
@@ -0,0 +1,92 @@
+package openapi
+
+import (
+	"a2sv-backend/task_manager_v3/Delivery/controllers"
+	"a2sv-backend/task_manager_v3/Domain"
+)
+
+// Shapes of the small bodies handlers build with gin.H. They exist only to
+// be described here; the contract test keeps them honest.
+
+// Error is the body of every error response. Some carry more context, such
+// as the per-item results of a failed bulk request.
+type Error struct {
+	Error            string                   `json:"error"`
+	ErrorDescription string                   `json:"error_description,omitempty"`
+	Errors           []controllers.FieldError `json:"errors,omitempty"`
+}
+
+// Message confirms an action that has nothing else to return
+type Message struct {
+	Message string `json:"message"`
+}
+
+// Profile is the caller as the access token describes them
+type Profile struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// CreatedAccessToken carries the token value, shown only this once
+type CreatedAccessToken struct {
+	Token       string             `json:"token"`
+	AccessToken Domain.AccessToken `json:"access_token"`
+}
+
+// CreatedInvitation carries the invitation token, shown only this once
+type CreatedInvitation struct {
+	Invitation Domain.Invitation `json:"invitation"`
+	Token      string            `json:"token"`
+}
+
+// RecoveryCodes are shown only when they are issued
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// AuthorizationURL is where to send the browser to continue with the
+// identity provider
+type AuthorizationURL struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+// LinkedIdentity answers a callback that linked an identity rather than
+// signing in
+type LinkedIdentity struct {
+	Message  string                  `json:"message"`
+	Identity Domain.ExternalIdentity `json:"identity"`
+}
+
+// RevokedSessions reports how many sessions were ended
+type RevokedSessions struct {
+	Message string `json:"message"`
+	Revoked int64  `json:"revoked"`
+}
+
+// BulkResults reports each operation of a bulk request
+type BulkResults struct {
+	Results []controllers.BulkResultResponse `json:"results"`
+}
+
+// GraphQLResponse is the body of every /graphql answer: the data of an
+// operation that ran, and the errors of anything that went wrong
+type GraphQLResponse struct {
+	Data   interface{}    `json:"data,omitempty"`
+	Errors []GraphQLError `json:"errors,omitempty"`
+}
+
+// GraphQLError is one GraphQL error. Extensions carry a code and, for bad
+// input, the offending fields.
+type GraphQLError struct {
+	Message    string                 `json:"message"`
+	Locations  []GraphQLLocation      `json:"locations"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// GraphQLLocation points into the query
+type GraphQLLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
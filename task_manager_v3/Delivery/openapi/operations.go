@@ -0,0 +1,243 @@
+package openapi
+
+import (
+	"a2sv-backend/task_manager_v3/Delivery/controllers"
+	"a2sv-backend/task_manager_v3/Domain"
+	"net/http"
+)
+
+const (
+	ok        = http.StatusOK
+	created   = http.StatusCreated
+	accepted  = http.StatusAccepted
+	conflict  = http.StatusConflict
+	forbidden = http.StatusForbidden
+	tooLarge  = http.StatusRequestEntityTooLarge
+	notFound  = http.StatusNotFound
+
+	badRequest  = http.StatusBadRequest
+	notAllowed  = http.StatusMethodNotAllowed
+	rateLimited = http.StatusTooManyRequests
+)
+
+var taskFormats = media{types: []string{"application/json", "application/x-ndjson", "text/csv", "text/calendar"}}
+
+var taskListQuery = []param{
+	{name: "status", typ: "string"},
+	{name: "priority", typ: "string"},
+	{name: "project", typ: "string"},
+	{name: "label", typ: "string", array: true, description: "Label IDs the task must all carry"},
+	{name: "sort", typ: "string", description: "Comma separated fields, - prefix for descending, cf.<key> for custom fields"},
+}
+
+// v2Changes are the operations API v2 serves differently from v1, by
+// method and path
+func v2Changes() map[string]operation {
+	return map[string]operation{
+		"GET /tasks": {method: "GET", path: "/tasks", tag: "tasks", summary: "List tasks a page at a time", access: authenticated,
+			query: append(append([]param{}, taskListQuery...),
+				param{name: "page", typ: "integer", description: "From 1"},
+				param{name: "page_size", typ: "integer", description: "1 to 100, default 20"},
+			),
+			responses: map[int]interface{}{ok: controllers.TaskPageResponse{}}},
+		"GET /me": {method: "GET", path: "/me", tag: "me", summary: "The caller's account", access: authenticated,
+			responses: map[int]interface{}{ok: controllers.UserResponse{}}, errors: []int{notFound}},
+	}
+}
+
+// operations lists every route of API v1, in the order SetupRouter
+// registers them, and the routes outside the API
+func operations() []operation {
+	return []operation{
+		// Public
+		{method: "POST", path: "/register", tag: "auth", summary: "Create an account",
+			body: controllers.RegisterRequest{}, responses: map[int]interface{}{created: Message{}}, errors: []int{forbidden, conflict}},
+		{method: "POST", path: "/login", tag: "auth", summary: "Log in with a username and password",
+			body: controllers.LoginRequest{}, responses: map[int]interface{}{ok: controllers.LoginResponse{}}, errors: []int{http.StatusUnauthorized, forbidden}},
+		{method: "POST", path: "/login/mfa", tag: "auth", summary: "Finish logging in with a second-factor code",
+			body: controllers.MFALoginRequest{}, responses: map[int]interface{}{ok: controllers.LoginResponse{}}, errors: []int{http.StatusUnauthorized, forbidden, notFound, conflict}},
+		{method: "POST", path: "/login/mfa/enroll", tag: "auth", summary: "Set up 2FA required by policy before the login can finish",
+			body: controllers.MFAEnrollmentRequest{}, responses: map[int]interface{}{ok: Domain.MFAEnrollment{}}, errors: []int{http.StatusUnauthorized, forbidden, notFound, conflict}},
+		{method: "GET", path: "/auth/oidc/login", tag: "auth", summary: "Sign in with the identity provider",
+			responses: map[int]interface{}{http.StatusFound: noBody{"Redirect to the identity provider"}}, errors: []int{notFound}},
+		{method: "GET", path: "/auth/oidc/callback", tag: "auth", summary: "Where the identity provider returns the browser",
+			query:     []param{{name: "state", typ: "string"}, {name: "code", typ: "string"}, {name: "error", typ: "string"}, {name: "error_description", typ: "string"}},
+			responses: map[int]interface{}{ok: oneOf{controllers.LoginResponse{}, LinkedIdentity{}}}, errors: []int{http.StatusUnauthorized, forbidden, notFound, conflict}},
+		{method: "POST", path: "/email/verify", tag: "auth", summary: "Confirm an email address",
+			body: controllers.VerifyEmailRequest{}, responses: map[int]interface{}{ok: Message{}}},
+		{method: "POST", path: "/password/forgot", tag: "auth", summary: "Email a password reset link, if the address has an account",
+			body: controllers.ForgotPasswordRequest{}, responses: map[int]interface{}{accepted: Message{}}},
+		{method: "POST", path: "/password/reset", tag: "auth", summary: "Set a new password with a reset token",
+			body: controllers.ResetPasswordRequest{}, responses: map[int]interface{}{ok: Message{}}},
+
+		// Any authenticated caller
+		{method: "GET", path: "/me", tag: "me", summary: "Who the caller is", access: authenticated,
+			responses: map[int]interface{}{ok: Profile{}}},
+		{method: "PUT", path: "/me", tag: "me", summary: "Update the caller's profile", access: authenticated,
+			body: controllers.UpdateProfileRequest{}, responses: map[int]interface{}{ok: controllers.UserResponse{}}, errors: []int{notFound, conflict}},
+		{method: "POST", path: "/me/email/verification", tag: "me", summary: "Resend the verification email", access: authenticated,
+			responses: map[int]interface{}{accepted: Message{}}, errors: []int{http.StatusBadRequest}},
+		{method: "GET", path: "/tasks", tag: "tasks", summary: "List tasks", access: authenticated,
+			query: taskListQuery, responses: map[int]interface{}{ok: []controllers.TaskResponse{}}},
+		{method: "GET", path: "/tasks/export", tag: "tasks", summary: "Download every visible task", access: authenticated,
+			query:     []param{{name: "format", typ: "string", description: "json (default), ndjson, csv or ics"}},
+			responses: map[int]interface{}{ok: taskFormats}},
+		{method: "GET", path: "/tasks/search", tag: "tasks", summary: "Full-text search", access: authenticated,
+			query:     []param{{name: "q", typ: "string", required: true}, {name: "limit", typ: "integer", description: "1 to 100, default 20"}},
+			responses: map[int]interface{}{ok: []controllers.TaskSearchResultResponse{}}},
+		{method: "GET", path: "/tasks/stream", tag: "tasks", summary: "Task events as server-sent events", access: authenticated,
+			query:     []param{{name: "last_event_id", typ: "string", description: "Resume after this event; the Last-Event-ID header wins"}},
+			responses: map[int]interface{}{ok: media{types: []string{"text/event-stream"}, description: "Each event's data is a TaskEventResponse"}}},
+		{method: "GET", path: "/tasks/ws", tag: "tasks", summary: "Task events over a WebSocket", access: authenticated,
+			query:     []param{{name: "last_event_id", typ: "string"}},
+			responses: map[int]interface{}{http.StatusSwitchingProtocols: noBody{"Each message is a TaskEventResponse. Browsers pass the token as the subprotocols [\"bearer\", token]"}}},
+		{method: "GET", path: "/tasks/:id", tag: "tasks", summary: "Get a task", access: authenticated,
+			responses: map[int]interface{}{ok: controllers.TaskResponse{}}},
+		{method: "GET", path: "/tasks/:id/attachments", tag: "attachments", summary: "List a task's attachments", access: authenticated,
+			responses: map[int]interface{}{ok: []Domain.Attachment{}}},
+		{method: "GET", path: "/tasks/:id/attachments/:attachmentId", tag: "attachments", summary: "Download an attachment", access: authenticated,
+			responses: map[int]interface{}{
+				ok:                     media{types: []string{"application/octet-stream"}, description: "The file, with its own content type"},
+				http.StatusNotModified: noBody{"If-None-Match matched the ETag"},
+			}},
+		{method: "GET", path: "/projects/:project/labels", tag: "catalog", summary: "List a project's labels", access: authenticated,
+			responses: map[int]interface{}{ok: []Domain.Label{}}},
+		{method: "GET", path: "/fields", tag: "catalog", summary: "List custom fields", access: authenticated,
+			responses: map[int]interface{}{ok: []Domain.CustomField{}}},
+		{method: "GET", path: "/reports/summary", tag: "reports", summary: "Task counts", access: authenticated,
+			responses: map[int]interface{}{ok: Domain.TaskSummary{}}},
+		{method: "GET", path: "/reports/throughput", tag: "reports", summary: "Tasks completed per day or week", access: authenticated,
+			query:     []param{{name: "from", typ: "string"}, {name: "to", typ: "string"}, {name: "interval", typ: "string", description: "day or week"}},
+			responses: map[int]interface{}{ok: []Domain.ThroughputBucket{}}},
+		{method: "GET", path: "/reports/cycle-time", tag: "reports", summary: "Time from in progress to completed", access: authenticated,
+			query:     []param{{name: "from", typ: "string"}, {name: "to", typ: "string"}},
+			responses: map[int]interface{}{ok: Domain.CycleTimeReport{}}},
+
+		// Interactive logins only
+		{method: "PUT", path: "/me/password", tag: "me", summary: "Change the caller's password", access: interactive,
+			body: controllers.ChangePasswordRequest{}, responses: map[int]interface{}{ok: Message{}}, errors: []int{notFound}},
+		{method: "POST", path: "/me/mfa/enroll", tag: "me", summary: "Start setting up 2FA", access: interactive,
+			responses: map[int]interface{}{ok: Domain.MFAEnrollment{}}, errors: []int{notFound, conflict}},
+		{method: "POST", path: "/me/mfa/confirm", tag: "me", summary: "Finish setting up 2FA", access: interactive,
+			body: controllers.MFACodeRequest{}, responses: map[int]interface{}{ok: RecoveryCodes{}}, errors: []int{notFound, conflict}},
+		{method: "POST", path: "/me/mfa/recovery-codes", tag: "me", summary: "Replace the recovery codes", access: interactive,
+			body: controllers.MFACodeRequest{}, responses: map[int]interface{}{ok: RecoveryCodes{}}, errors: []int{notFound, conflict}},
+		{method: "DELETE", path: "/me/mfa", tag: "me", summary: "Turn 2FA off", access: interactive,
+			body: controllers.DisableMFARequest{}, responses: map[int]interface{}{ok: Message{}}, errors: []int{notFound, conflict}},
+		{method: "GET", path: "/me/identities", tag: "me", summary: "List linked sign-in identities", access: interactive,
+			responses: map[int]interface{}{ok: []Domain.ExternalIdentity{}}, errors: []int{notFound}},
+		{method: "POST", path: "/me/identities/oidc", tag: "me", summary: "Start linking an identity", access: interactive,
+			responses: map[int]interface{}{ok: AuthorizationURL{}}, errors: []int{notFound}},
+		{method: "DELETE", path: "/me/identities/:id", tag: "me", summary: "Unlink an identity", access: interactive,
+			responses: map[int]interface{}{ok: Message{}}, errors: []int{conflict}},
+		{method: "GET", path: "/me/tokens", tag: "me", summary: "List personal access tokens", access: interactive,
+			responses: map[int]interface{}{ok: []Domain.AccessToken{}}},
+		{method: "POST", path: "/me/tokens", tag: "me", summary: "Create a personal access token", access: interactive,
+			body: controllers.CreateAccessTokenRequest{}, responses: map[int]interface{}{created: CreatedAccessToken{}}, errors: []int{conflict}},
+		{method: "DELETE", path: "/me/tokens/:id", tag: "me", summary: "Revoke a personal access token", access: interactive,
+			responses: map[int]interface{}{ok: Message{}}},
+		{method: "GET", path: "/me/sessions", tag: "me", summary: "List login sessions", access: interactive,
+			responses: map[int]interface{}{ok: []Domain.Session{}}},
+		{method: "DELETE", path: "/me/sessions/:id", tag: "me", summary: "Log a session out", access: interactive,
+			responses: map[int]interface{}{ok: Message{}}},
+
+		// Admins
+		{method: "POST", path: "/tasks", tag: "tasks", summary: "Create a task", access: admin,
+			body: controllers.CreateTaskRequest{}, responses: map[int]interface{}{created: controllers.TaskResponse{}}},
+		{method: "POST", path: "/tasks/bulk", tag: "tasks", summary: "Apply many operations at once", access: admin,
+			query: []param{{name: "atomic", typ: "boolean", description: "All or nothing"}},
+			body:  controllers.BulkRequest{}, responses: map[int]interface{}{ok: BulkResults{}, http.StatusMultiStatus: BulkResults{}},
+			errors: []int{conflict, tooLarge, http.StatusUnprocessableEntity, http.StatusNotImplemented}},
+		{method: "POST", path: "/tasks/import", tag: "tasks", summary: "Create or update tasks from a file", access: admin,
+			query: []param{
+				{name: "format", typ: "string", description: "Defaults from the content type"},
+				{name: "dry_run", typ: "boolean"},
+				{name: "map", typ: "string", description: "Source:field pairs renaming columns"},
+			},
+			body:      media{types: append(taskFormats.types, "multipart/form-data")},
+			responses: map[int]interface{}{ok: Domain.ImportReport{}, http.StatusMultiStatus: Domain.ImportReport{}}, errors: []int{tooLarge}},
+		{method: "PUT", path: "/tasks/:id", tag: "tasks", summary: "Replace a task", access: admin,
+			body: controllers.UpdateTaskRequest{}, responses: map[int]interface{}{ok: controllers.TaskResponse{}}},
+		{method: "DELETE", path: "/tasks/:id", tag: "tasks", summary: "Delete a task", access: admin,
+			responses: map[int]interface{}{ok: Message{}}},
+		{method: "POST", path: "/tasks/:id/attachments", tag: "attachments", summary: "Upload an attachment", access: admin,
+			body: media{types: []string{"multipart/form-data"}}, responses: map[int]interface{}{created: Domain.Attachment{}}, errors: []int{tooLarge}},
+		{method: "DELETE", path: "/tasks/:id/attachments/:attachmentId", tag: "attachments", summary: "Delete an attachment", access: admin,
+			responses: map[int]interface{}{ok: Message{}}},
+		{method: "POST", path: "/promote", tag: "users", summary: "Make a user an admin", access: admin,
+			body: controllers.PromoteRequest{}, responses: map[int]interface{}{ok: Message{}}},
+		{method: "GET", path: "/users", tag: "users", summary: "List users", access: admin,
+			query: []param{
+				{name: "q", typ: "string"},
+				{name: "role", typ: "string"},
+				{name: "status", typ: "string", description: "active or deactivated"},
+				{name: "page", typ: "integer"},
+				{name: "page_size", typ: "integer"},
+			},
+			responses: map[int]interface{}{ok: controllers.UserListResponse{}}},
+		{method: "GET", path: "/users/:id", tag: "users", summary: "Get a user", access: admin,
+			responses: map[int]interface{}{ok: controllers.UserResponse{}}},
+		{method: "PUT", path: "/users/:id/role", tag: "users", summary: "Change a user's role", access: admin,
+			body: controllers.ChangeRoleRequest{}, responses: map[int]interface{}{ok: controllers.UserResponse{}}, errors: []int{conflict}},
+		{method: "POST", path: "/users/:id/deactivate", tag: "users", summary: "Lock a user out", access: admin,
+			responses: map[int]interface{}{ok: controllers.UserResponse{}}, errors: []int{conflict}},
+		{method: "POST", path: "/users/:id/reactivate", tag: "users", summary: "Let a user back in", access: admin,
+			responses: map[int]interface{}{ok: controllers.UserResponse{}}, errors: []int{conflict}},
+		{method: "DELETE", path: "/users/:id", tag: "users", summary: "Delete a user", access: admin,
+			responses: map[int]interface{}{ok: Message{}}, errors: []int{conflict}},
+		{method: "DELETE", path: "/users/:id/mfa", tag: "users", summary: "Reset a user's 2FA", access: admin,
+			responses: map[int]interface{}{ok: Message{}}, errors: []int{conflict}},
+		{method: "GET", path: "/users/:id/sessions", tag: "users", summary: "List a user's sessions", access: admin,
+			responses: map[int]interface{}{ok: []Domain.Session{}}},
+		{method: "DELETE", path: "/users/:id/sessions", tag: "users", summary: "Log a user out everywhere", access: admin,
+			responses: map[int]interface{}{ok: RevokedSessions{}}},
+		{method: "GET", path: "/security/policy", tag: "security", summary: "Get the security policy", access: admin,
+			responses: map[int]interface{}{ok: Domain.SecurityPolicy{}}},
+		{method: "PUT", path: "/security/policy", tag: "security", summary: "Set the roles that require 2FA", access: admin,
+			body: controllers.MFAPolicyRequest{}, responses: map[int]interface{}{ok: Domain.SecurityPolicy{}}, errors: []int{notFound, conflict}},
+		{method: "POST", path: "/invitations", tag: "security", summary: "Invite someone", access: admin,
+			body: controllers.CreateInvitationRequest{}, responses: map[int]interface{}{created: CreatedInvitation{}}},
+		{method: "GET", path: "/invitations", tag: "security", summary: "List invitations", access: admin,
+			responses: map[int]interface{}{ok: []Domain.Invitation{}}},
+		{method: "DELETE", path: "/invitations/:id", tag: "security", summary: "Revoke an invitation", access: admin,
+			responses: map[int]interface{}{ok: Message{}}},
+		{method: "POST", path: "/projects/:project/labels", tag: "catalog", summary: "Create a label", access: admin,
+			body: controllers.LabelRequest{}, responses: map[int]interface{}{created: Domain.Label{}}, errors: []int{conflict}},
+		{method: "PUT", path: "/labels/:id", tag: "catalog", summary: "Rename or recolor a label", access: admin,
+			body: controllers.LabelRequest{}, responses: map[int]interface{}{ok: Domain.Label{}}, errors: []int{conflict}},
+		{method: "DELETE", path: "/labels/:id", tag: "catalog", summary: "Delete a label and remove it from tasks", access: admin,
+			responses: map[int]interface{}{ok: Message{}}},
+		{method: "POST", path: "/fields", tag: "catalog", summary: "Define a custom field", access: admin,
+			body: controllers.CustomFieldRequest{}, responses: map[int]interface{}{created: Domain.CustomField{}}, errors: []int{conflict}},
+		{method: "PUT", path: "/fields/:id", tag: "catalog", summary: "Change a custom field", access: admin,
+			body: controllers.CustomFieldRequest{}, responses: map[int]interface{}{ok: Domain.CustomField{}}, errors: []int{conflict}},
+		{method: "DELETE", path: "/fields/:id", tag: "catalog", summary: "Delete a custom field", access: admin,
+			responses: map[int]interface{}{ok: Message{}}},
+
+		// GraphQL, rate limited like the API but outside its versions
+		{method: "GET", path: "/graphql", tag: "graphql", summary: "Run a GraphQL query", access: authenticated, root: true,
+			query: []param{
+				{name: "query", typ: "string", required: true},
+				{name: "operationName", typ: "string"},
+				{name: "variables", typ: "string", description: "A JSON object"},
+			},
+			responses: map[int]interface{}{ok: GraphQLResponse{}, badRequest: GraphQLResponse{}, notAllowed: GraphQLResponse{}},
+			errors:    []int{rateLimited}},
+		{method: "POST", path: "/graphql", tag: "graphql", summary: "Run a GraphQL query or mutation", access: authenticated, root: true,
+			body:      controllers.GraphQLRequest{},
+			responses: map[int]interface{}{ok: GraphQLResponse{}, badRequest: GraphQLResponse{}},
+			errors:    []int{rateLimited}},
+
+		// Outside the API
+		{method: "GET", path: "/openapi.json", tag: "docs", summary: "This document", root: true,
+			responses: map[int]interface{}{ok: media{types: []string{"application/json"}}}},
+		{method: "GET", path: "/docs", tag: "docs", summary: "Swagger UI for this document", root: true,
+			responses: map[int]interface{}{ok: media{types: []string{"text/html"}}}},
+		{method: "GET", path: "/docs/swagger-ui.css", tag: "docs", summary: "Swagger UI stylesheet", root: true,
+			responses: map[int]interface{}{ok: media{types: []string{"text/css"}}}},
+		{method: "GET", path: "/docs/swagger-ui-bundle.js", tag: "docs", summary: "Swagger UI script", root: true,
+			responses: map[int]interface{}{ok: media{types: []string{"text/javascript"}}}},
+		{method: "GET", path: "/metrics", tag: "monitoring", summary: "Request counters in the Prometheus text format", root: true,
+			responses: map[int]interface{}{ok: media{types: []string{"text/plain"}}}},
+	}
+}
@@ -0,0 +1,220 @@
+package openapi
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"fmt"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// object is a JSON object of the document
+type object = map[string]interface{}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+)
+
+// schemas derives JSON Schemas from the Go types the handlers bind and
+// serialize, so the document follows the code. Named structs become
+// components. Request types take their constraints from the binding tags;
+// response types are closed (additionalProperties: false) so a field the
+// document doesn't know about is a contract failure.
+type schemas struct {
+	components object
+	names      map[reflect.Type]string
+}
+
+func newSchemas() *schemas {
+	return &schemas{components: object{}, names: map[reflect.Type]string{}}
+}
+
+// of returns the schema of v's type; request selects binding-tag rules
+// over response rules
+func (s *schemas) of(v interface{}, request bool) object {
+	return s.schema(reflect.TypeOf(v), request)
+}
+
+func (s *schemas) schema(t reflect.Type, request bool) object {
+	switch t {
+	case timeType:
+		return object{"type": "string", "format": "date-time"}
+	case objectIDType:
+		return object{"type": "string", "pattern": "^[0-9a-f]{24}$"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return s.schema(t.Elem(), request)
+	case reflect.String:
+		return object{"type": "string"}
+	case reflect.Bool:
+		return object{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return object{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return object{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return object{"type": "array", "items": s.schema(t.Elem(), request)}
+	case reflect.Map:
+		return object{"type": "object", "additionalProperties": s.schema(t.Elem(), request)}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t, request)
+		}
+		return object{"$ref": "#/components/schemas/" + s.component(t, request)}
+	}
+	// interface{} and anything else: any JSON value
+	return object{}
+}
+
+// component registers a named struct and returns its component name
+func (s *schemas) component(t reflect.Type, request bool) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if _, taken := s.components[name]; taken {
+		name = path.Base(t.PkgPath()) + name
+	}
+	s.names[t] = name
+	// Reserve the name first: the struct may refer to itself
+	s.components[name] = object{}
+	s.components[name] = s.structSchema(t, request)
+	return name
+}
+
+func (s *schemas) structSchema(t reflect.Type, request bool) object {
+	properties := object{}
+	var required []string
+	s.fields(t, request, properties, &required)
+
+	out := object{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		out["required"] = required
+	}
+	if !request {
+		out["additionalProperties"] = false
+	}
+	return out
+}
+
+// fields adds t's JSON fields, following encoding/json: embedded structs
+// without a name are flattened, "-" is skipped
+func (s *schemas) fields(t reflect.Type, request bool, properties object, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			s.fields(field.Type, request, properties, required)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		omitempty := strings.Contains(","+options+",", ",omitempty,")
+
+		schema := s.schema(field.Type, request)
+		if request {
+			rules := field.Tag.Get("binding")
+			applyRules(schema, field.Type, rules)
+			if hasRule(rules, "required") {
+				*required = append(*required, name)
+			}
+		} else {
+			kind := field.Type.Kind()
+			// encoding/json never omits structs or arrays, and writes nil
+			// pointers, slices and maps as null
+			if !omitempty || kind == reflect.Struct || kind == reflect.Array {
+				*required = append(*required, name)
+			}
+			if !omitempty && (kind == reflect.Ptr || kind == reflect.Slice || kind == reflect.Map) {
+				schema = nullable(schema)
+			}
+		}
+		properties[name] = schema
+	}
+}
+
+func nullable(schema object) object {
+	if t, ok := schema["type"].(string); ok {
+		schema["type"] = []string{t, "null"}
+		return schema
+	}
+	return object{"anyOf": []object{schema, {"type": "null"}}}
+}
+
+func hasRule(rules, rule string) bool {
+	for _, r := range strings.Split(rules, ",") {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// applyRules turns validator tags into schema keywords. Rules after dive
+// apply to the items.
+func applyRules(schema object, t reflect.Type, rules string) {
+	if rules == "" {
+		return
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	list := strings.Split(rules, ",")
+	for i, rule := range list {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "dive":
+			if items, ok := schema["items"].(object); ok {
+				applyRules(items, t.Elem(), strings.Join(list[i+1:], ","))
+			}
+			return
+		case "min", "max":
+			n, _ := strconv.Atoi(param)
+			keyword := map[reflect.Kind]string{reflect.String: "Length", reflect.Slice: "Items", reflect.Map: "Properties"}[t.Kind()]
+			switch {
+			case keyword != "":
+				schema[name+keyword] = n
+			case name == "min":
+				schema["minimum"] = n
+			default:
+				schema["maximum"] = n
+			}
+		case "oneof":
+			schema["enum"] = strings.Fields(param)
+		case "email":
+			schema["format"] = "email"
+		case "taskstatus":
+			schema["enum"] = []string{Domain.StatusPending, Domain.StatusInProgress, Domain.StatusCompleted}
+		case "priority":
+			schema["enum"] = []string{Domain.PriorityP0, Domain.PriorityP1, Domain.PriorityP2, Domain.PriorityP3}
+		case "role":
+			schema["enum"] = []string{Domain.RoleUser, Domain.RoleAdmin}
+		case "objectid":
+			schema["pattern"] = "^[0-9a-f]{24}$"
+		case "maxbytes":
+			// JSON Schema only counts characters, which is the same bound
+			// for ASCII
+			n, _ := strconv.Atoi(param)
+			schema["maxLength"] = n
+			schema["description"] = fmt.Sprintf("At most %d bytes in UTF-8", n)
+		case "notpast":
+			schema["description"] = "Today or later"
+		}
+	}
+}
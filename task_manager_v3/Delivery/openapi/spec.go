@@ -0,0 +1,289 @@
+package openapi
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Who may call an operation
+type access int
+
+const (
+	public access = iota
+	// authenticated takes a JWT or a personal access token
+	authenticated
+	// interactive takes a JWT only
+	interactive
+	admin
+)
+
+// param is a query parameter
+type param struct {
+	name        string
+	typ         string
+	required    bool
+	array       bool
+	description string
+}
+
+// media is a body that isn't JSON, or is JSON of no fixed shape
+type media struct {
+	types       []string
+	description string
+}
+
+// noBody marks a response without content
+type noBody struct{ description string }
+
+// oneOf is a JSON body that takes one of several shapes
+type oneOf []interface{}
+
+// operation describes one route. Body and the response values are zero
+// values of the Go types the handler binds and writes, or media, noBody or
+// oneOf. Every operation can also fail with the errors its access level and
+// parameters imply; errors lists any others.
+type operation struct {
+	method, path string
+	tag          string
+	summary      string
+	access       access
+	query        []param
+	body         interface{}
+	responses    map[int]interface{}
+	errors       []int
+	// root operations are served once, outside the API versions
+	root bool
+}
+
+const objectIDPattern = "^[0-9a-f]{24}$"
+
+var pathParam = regexp.MustCompile(`:(\w+)`)
+
+// Document builds the OpenAPI 3.1 document for the routes registered by
+// routers.SetupRouter
+func Document() map[string]interface{} {
+	s := newSchemas()
+	paths := object{}
+	add := func(prefix string, op operation, deprecated bool) {
+		path := pathParam.ReplaceAllString(prefix+op.path, "{$1}")
+		item, _ := paths[path].(object)
+		if item == nil {
+			item = object{}
+			paths[path] = item
+		}
+		item[strings.ToLower(op.method)] = op.build(s, path, deprecated)
+	}
+
+	changed := v2Changes()
+	for _, op := range operations() {
+		if op.root {
+			add("", op, false)
+			continue
+		}
+		add("/api/v1", op, false)
+		if v2, ok := changed[op.method+" "+op.path]; ok {
+			op = v2
+		}
+		add("/api/v2", op, false)
+	}
+	// The unversioned paths are v1's, kept until their sunset
+	for _, op := range operations() {
+		if !op.root {
+			add("", op, true)
+		}
+	}
+
+	s.components["Error"].(object)["additionalProperties"] = true
+	return object{
+		"openapi": "3.1.0",
+		"info": object{
+			"title":   "Task Manager API",
+			"version": "1.0.0",
+			"description": "Tasks, users and the accounts around them. Errors always carry an error message; validation failures also list each problem under errors.\n\n" +
+				"The API is served under /api/v1 and /api/v2, which differ only in GET /tasks (paged) and GET /me (the stored account). " +
+				"The unversioned paths are deprecated aliases of v1: their responses carry Deprecation, Sunset and Link headers. " +
+				"GraphQL is served beside them at /graphql.",
+		},
+		"servers": []object{{"url": "/"}},
+		"tags": []object{
+			{"name": "auth", "description": "Registration, login and account recovery"},
+			{"name": "me", "description": "The caller's own account"},
+			{"name": "tasks"},
+			{"name": "attachments"},
+			{"name": "catalog", "description": "Labels and custom fields"},
+			{"name": "reports"},
+			{"name": "users", "description": "User administration"},
+			{"name": "security", "description": "Security policy and invitations"},
+			{"name": "graphql", "description": "The same data as a GraphQL schema, which introspection describes"},
+			{"name": "docs"},
+			{"name": "monitoring"},
+		},
+		"paths": paths,
+		"components": object{
+			"schemas": s.components,
+			"securitySchemes": object{
+				"bearerAuth": object{
+					"type":         "http",
+					"scheme":       "bearer",
+					"bearerFormat": "JWT",
+					"description":  "Access token from POST /login",
+				},
+				"accessToken": object{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "Personal access token (tm_pat_...) from POST /me/tokens. GET needs the read scope, other methods write, admin routes admin.",
+				},
+			},
+		},
+	}
+}
+
+func (op operation) build(s *schemas, path string, deprecated bool) object {
+	out := object{
+		"operationId": operationID(op.method, path),
+		"summary":     op.summary,
+		"tags":        []string{op.tag},
+	}
+	if deprecated {
+		out["deprecated"] = true
+	}
+
+	var parameters []object
+	for _, name := range pathParam.FindAllStringSubmatch(op.path, -1) {
+		schema := object{"type": "string"}
+		if name[1] != "project" {
+			schema["pattern"] = objectIDPattern
+		}
+		parameters = append(parameters, object{"name": name[1], "in": "path", "required": true, "schema": schema})
+	}
+	for _, p := range op.query {
+		schema := object{"type": p.typ}
+		if p.array {
+			schema = object{"type": "array", "items": schema}
+		}
+		parameter := object{"name": p.name, "in": "query", "required": p.required, "schema": schema}
+		if p.description != "" {
+			parameter["description"] = p.description
+		}
+		parameters = append(parameters, parameter)
+	}
+	if parameters != nil {
+		out["parameters"] = parameters
+	}
+
+	if op.body != nil {
+		out["requestBody"] = object{"required": true, "content": content(s, op.body, true)}
+	}
+
+	switch op.access {
+	case public:
+		out["security"] = []object{}
+	case interactive:
+		out["security"] = []object{{"bearerAuth": []string{}}}
+	default:
+		out["security"] = []object{{"bearerAuth": []string{}}, {"accessToken": []string{}}}
+	}
+
+	responses := object{}
+	for status, body := range op.responses {
+		responses[strconv.Itoa(status)] = response(s, status, body)
+	}
+	for _, status := range op.errorStatuses() {
+		if _, ok := responses[strconv.Itoa(status)]; !ok {
+			responses[strconv.Itoa(status)] = response(s, status, Error{})
+		}
+	}
+	if deprecated {
+		for _, r := range responses {
+			r.(object)["headers"] = deprecationHeaders
+		}
+	}
+	out["responses"] = responses
+	return out
+}
+
+// deprecationHeaders come with every response of a deprecated path
+var deprecationHeaders = object{
+	"Deprecation": object{"description": "When the path was deprecated, as @<unix time>", "schema": object{"type": "string"}},
+	"Sunset":      object{"description": "When the path stops answering", "schema": object{"type": "string"}},
+	"Link":        object{"description": "The same route under /api/v1, as the successor-version", "schema": object{"type": "string"}},
+}
+
+// errorStatuses lists the errors the operation can answer with
+func (op operation) errorStatuses() []int {
+	statuses := append([]int{http.StatusInternalServerError}, op.errors...)
+	if !op.root {
+		statuses = append(statuses, http.StatusTooManyRequests)
+	}
+	if op.access != public {
+		statuses = append(statuses, http.StatusUnauthorized, http.StatusForbidden)
+	}
+	if op.body != nil || len(op.query) > 0 || strings.Contains(op.path, ":") {
+		statuses = append(statuses, http.StatusBadRequest)
+	}
+	if strings.Contains(op.path, ":") {
+		statuses = append(statuses, http.StatusNotFound)
+	}
+	sort.Ints(statuses)
+	return statuses
+}
+
+func response(s *schemas, status int, body interface{}) object {
+	description := http.StatusText(status)
+	switch body := body.(type) {
+	case noBody:
+		if body.description != "" {
+			description = body.description
+		}
+		return object{"description": description}
+	case media:
+		if body.description != "" {
+			description = body.description
+		}
+	}
+	return object{"description": description, "content": content(s, body, false)}
+}
+
+func content(s *schemas, body interface{}, request bool) object {
+	if m, ok := body.(media); ok {
+		out := object{}
+		for _, t := range m.types {
+			schema := object{"type": "string"}
+			switch t {
+			case "application/json":
+				schema = object{}
+			case "multipart/form-data":
+				schema = object{
+					"type":       "object",
+					"properties": object{"file": object{"type": "string", "contentMediaType": "application/octet-stream"}},
+					"required":   []string{"file"},
+				}
+			}
+			out[t] = object{"schema": schema}
+		}
+		return out
+	}
+	if shapes, ok := body.(oneOf); ok {
+		var options []object
+		for _, shape := range shapes {
+			options = append(options, s.of(shape, request))
+		}
+		return object{"application/json": object{"schema": object{"oneOf": options}}}
+	}
+	return object{"application/json": object{"schema": s.of(body, request)}}
+}
+
+// operationID is e.g. "putApiV1TasksById" for PUT /api/v1/tasks/{id}
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '-' || r == '.' }) {
+		if strings.HasPrefix(part, "{") {
+			part = "by" + strings.ToUpper(part[1:2]) + strings.TrimSuffix(part[2:], "}")
+		}
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+	return id
+}
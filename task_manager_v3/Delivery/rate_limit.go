@@ -0,0 +1,29 @@
+package main
+
+import (
+	"a2sv-backend/task_manager_v3/Infrastructure"
+	"log"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// newRateLimiter applies the validated rate limit settings, returning nil
+// when they are "off"
+func newRateLimiter(config Infrastructure.RateLimitConfig, db *mongo.Database) *Infrastructure.RateLimiter {
+	if config.Limits == "off" {
+		log.Println("Rate limits are off; requests are not rate limited")
+		return nil
+	}
+	policies, err := Infrastructure.ParseRateLimitPolicies(config.Limits)
+	if err != nil {
+		log.Fatalf("Invalid rate limits: %v", err)
+	}
+
+	var store Infrastructure.RateLimitStore
+	if config.Store == "mongo" {
+		store = Infrastructure.NewMongoRateLimitStore(db)
+	} else {
+		store = Infrastructure.NewInMemoryRateLimitStore()
+	}
+	return Infrastructure.NewRateLimiter(store, policies)
+}
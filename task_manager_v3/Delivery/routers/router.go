@@ -2,36 +2,191 @@ package routers
 
 import (
 	"a2sv-backend/task_manager_v3/Delivery/controllers"
+	"a2sv-backend/task_manager_v3/Delivery/openapi"
 	"a2sv-backend/task_manager_v3/Infrastructure"
+	"log"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRouter(taskController *controllers.TaskController, userController *controllers.UserController, jwtService Infrastructure.JWTService) *gin.Engine {
+// api holds what every version's routes are served by
+type api struct {
+	task        *controllers.TaskController
+	user        *controllers.UserController
+	report      *controllers.ReportController
+	catalog     *controllers.CatalogController
+	attachment  *controllers.AttachmentController
+	account     *controllers.AccountController
+	mfa         *controllers.MFAController
+	sso         *controllers.SSOController
+	accessToken *controllers.AccessTokenController
+	session     *controllers.SessionController
+	graphQL     *controllers.GraphQLController
+
+	auth gin.HandlerFunc
+	// preAuthRateLimit runs before auth and rateLimit after it
+	preAuthRateLimit gin.HandlerFunc
+	rateLimit        gin.HandlerFunc
+}
+
+// SetupRouter mounts the API at /api/v1 and /api/v2, and as v1 at the root
+// for clients that predate versioning, with GraphQL beside it at /graphql.
+// The root paths serve v1 until the sunset in apiConfig, announcing it on
+// every response. A nil metrics turns /metrics off.
+//
+// Client IPs, which rate limits and sessions record, are only taken from
+// X-Forwarded-For when the request comes through one of trustedProxies (IPs
+// or CIDRs). With none, they are the connection's remote address.
+func SetupRouter(taskController *controllers.TaskController, userController *controllers.UserController, reportController *controllers.ReportController, catalogController *controllers.CatalogController, attachmentController *controllers.AttachmentController, accountController *controllers.AccountController, mfaController *controllers.MFAController, ssoController *controllers.SSOController, accessTokenController *controllers.AccessTokenController, sessionController *controllers.SessionController, graphQLController *controllers.GraphQLController, jwtService Infrastructure.JWTService, accounts Infrastructure.AccountLookup, tokens Infrastructure.AccessTokenAuthenticator, sessions Infrastructure.SessionChecker, limiter *Infrastructure.RateLimiter, metrics *Infrastructure.Metrics, apiConfig Infrastructure.APIConfig, trustedProxies []string) *gin.Engine {
 	r := gin.Default()
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		// Config validation rejects these; never fall back to trusting everyone
+		log.Printf("Invalid trusted proxies, trusting none: %v", err)
+		r.SetTrustedProxies(nil)
+	}
+	r.Use(Infrastructure.MetricsMiddleware(metrics))
+
+	// API documentation and monitoring
+	r.GET("/openapi.json", openapi.Spec)
+	r.GET("/docs", openapi.Docs)
+	r.GET("/docs/swagger-ui.css", openapi.DocsAsset("swagger-ui.css", "text/css; charset=utf-8"))
+	r.GET("/docs/swagger-ui-bundle.js", openapi.DocsAsset("swagger-ui-bundle.js", "text/javascript; charset=utf-8"))
+	if metrics != nil {
+		r.GET("/metrics", Infrastructure.MetricsHandler(metrics))
+	}
 
-	// Public routes
-	r.POST("/register", userController.Register)
-	r.POST("/login", userController.Login)
+	a := api{
+		task:             taskController,
+		user:             userController,
+		report:           reportController,
+		catalog:          catalogController,
+		attachment:       attachmentController,
+		account:          accountController,
+		mfa:              mfaController,
+		sso:              ssoController,
+		accessToken:      accessTokenController,
+		session:          sessionController,
+		graphQL:          graphQLController,
+		auth:             Infrastructure.AuthMiddleware(jwtService, accounts, tokens, sessions),
+		preAuthRateLimit: Infrastructure.PreAuthRateLimitMiddleware(limiter),
+		rateLimit:        Infrastructure.RateLimitMiddleware(limiter),
+	}
+	a.register(r.Group("/api/v1", Infrastructure.APIVersionMiddleware(Infrastructure.APIVersion1, "/api/v1")), Infrastructure.APIVersion1)
+	a.register(r.Group("/api/v2", Infrastructure.APIVersionMiddleware(Infrastructure.APIVersion2, "/api/v2")), Infrastructure.APIVersion2)
+	a.register(r.Group("/",
+		Infrastructure.APIVersionMiddleware(Infrastructure.APIUnversioned, ""),
+		Infrastructure.DeprecatedMiddleware(apiConfig.UnversionedDeprecated, apiConfig.UnversionedSunset, "/api/v1"),
+	), Infrastructure.APIUnversioned)
+
+	// GraphQL is versioned by its schema rather than its path. Queries need
+	// the read scope from access tokens like any GET, so read-only tokens
+	// must send them over GET.
+	r.GET("/graphql", a.preAuthRateLimit, a.auth, a.rateLimit, a.graphQL.GraphQL)
+	r.POST("/graphql", a.preAuthRateLimit, a.auth, a.rateLimit, a.graphQL.GraphQL)
+
+	return r
+}
 
-	// Protected routes
-	protected := r.Group("/")
-	protected.Use(Infrastructure.AuthMiddleware(jwtService))
+// register adds every route of the API to g. Versions share the routes and
+// the usecases behind them; v2 differs from v1 in:
+//
+//   - GET /tasks answers a page of tasks, with page and page_size
+//   - GET /me answers the account as it is now, not as the token says
+func (a api) register(g *gin.RouterGroup, version string) {
+	listTasks, getProfile := a.task.GetAllTasks, a.user.GetProfile
+	if version == Infrastructure.APIVersion2 {
+		listTasks, getProfile = a.task.GetTaskPage, a.user.GetCurrentUser
+	}
+
+	// Public routes, rate limited per client IP
+	public := g.Group("/")
+	public.Use(a.rateLimit)
+	{
+		public.POST("/register", a.user.Register)
+		public.POST("/login", a.user.Login)
+		public.POST("/login/mfa", a.mfa.CompleteLogin)
+		public.POST("/login/mfa/enroll", a.mfa.BeginLoginEnrollment)
+		public.GET("/auth/oidc/login", a.sso.Login)
+		public.GET("/auth/oidc/callback", a.sso.Callback)
+		public.POST("/email/verify", a.account.VerifyEmail)
+		public.POST("/password/forgot", a.account.ForgotPassword)
+		public.POST("/password/reset", a.account.ResetPassword)
+	}
+
+	// Protected routes, rate limited per client IP before authentication so
+	// bad credentials are throttled too, and again after it so policies can
+	// count per user or access token
+	protected := g.Group("/")
+	protected.Use(a.preAuthRateLimit, a.auth, a.rateLimit)
 	{
-		protected.GET("/me", userController.GetProfile)
-		protected.GET("/tasks", taskController.GetAllTasks)
-		protected.GET("/tasks/:id", taskController.GetTaskByID)
+		protected.GET("/me", getProfile)
+		protected.PUT("/me", a.user.UpdateProfile)
+		protected.POST("/me/email/verification", a.account.ResendVerification)
+		protected.GET("/tasks", listTasks)
+		protected.GET("/tasks/export", a.task.ExportTasks)
+		protected.GET("/tasks/search", a.task.SearchTasks)
+		protected.GET("/tasks/stream", a.task.StreamTasks)
+		protected.GET("/tasks/ws", a.task.TaskEventsSocket)
+		protected.GET("/tasks/:id", a.task.GetTaskByID)
+		protected.GET("/tasks/:id/attachments", a.attachment.ListAttachments)
+		protected.GET("/tasks/:id/attachments/:attachmentId", a.attachment.DownloadAttachment)
+		protected.GET("/projects/:project/labels", a.catalog.ListLabels)
+		protected.GET("/fields", a.catalog.ListFields)
+		protected.GET("/reports/summary", a.report.Summary)
+		protected.GET("/reports/throughput", a.report.Throughput)
+		protected.GET("/reports/cycle-time", a.report.CycleTime)
+
+		// Credential management needs a login; access tokens are refused
+		interactive := protected.Group("/")
+		interactive.Use(Infrastructure.InteractiveOnly())
+		{
+			interactive.PUT("/me/password", a.user.ChangePassword)
+			interactive.POST("/me/mfa/enroll", a.mfa.BeginEnrollment)
+			interactive.POST("/me/mfa/confirm", a.mfa.ConfirmEnrollment)
+			interactive.POST("/me/mfa/recovery-codes", a.mfa.RegenerateRecoveryCodes)
+			interactive.DELETE("/me/mfa", a.mfa.Disable)
+			interactive.GET("/me/identities", a.sso.ListIdentities)
+			interactive.POST("/me/identities/oidc", a.sso.StartLink)
+			interactive.DELETE("/me/identities/:id", a.sso.Unlink)
+			interactive.GET("/me/tokens", a.accessToken.ListAccessTokens)
+			interactive.POST("/me/tokens", a.accessToken.CreateAccessToken)
+			interactive.DELETE("/me/tokens/:id", a.accessToken.RevokeAccessToken)
+			interactive.GET("/me/sessions", a.session.ListSessions)
+			interactive.DELETE("/me/sessions/:id", a.session.RevokeSession)
+		}
 
 		// Admin routes
 		admin := protected.Group("/")
 		admin.Use(Infrastructure.AdminMiddleware())
 		{
-			admin.POST("/tasks", taskController.CreateTask)
-			admin.PUT("/tasks/:id", taskController.UpdateTask)
-			admin.DELETE("/tasks/:id", taskController.DeleteTask)
-			admin.POST("/promote", userController.PromoteUser)
+			admin.POST("/tasks", a.task.CreateTask)
+			admin.POST("/tasks/bulk", a.task.BulkTasks)
+			admin.POST("/tasks/import", a.task.ImportTasks)
+			admin.PUT("/tasks/:id", a.task.UpdateTask)
+			admin.DELETE("/tasks/:id", a.task.DeleteTask)
+			admin.POST("/tasks/:id/attachments", a.attachment.UploadAttachment)
+			admin.DELETE("/tasks/:id/attachments/:attachmentId", a.attachment.DeleteAttachment)
+			admin.POST("/promote", a.user.PromoteUser)
+			admin.GET("/users", a.user.ListUsers)
+			admin.GET("/users/:id", a.user.GetUser)
+			admin.PUT("/users/:id/role", a.user.ChangeRole)
+			admin.POST("/users/:id/deactivate", a.user.DeactivateUser)
+			admin.POST("/users/:id/reactivate", a.user.ReactivateUser)
+			admin.DELETE("/users/:id", a.user.DeleteUser)
+			admin.DELETE("/users/:id/mfa", a.mfa.ResetUser)
+			admin.GET("/users/:id/sessions", a.session.ListUserSessions)
+			admin.DELETE("/users/:id/sessions", a.session.RevokeUserSessions)
+			admin.GET("/security/policy", a.mfa.GetPolicy)
+			admin.PUT("/security/policy", a.mfa.SetPolicy)
+			admin.POST("/invitations", a.user.CreateInvitation)
+			admin.GET("/invitations", a.user.ListInvitations)
+			admin.DELETE("/invitations/:id", a.user.RevokeInvitation)
+			admin.POST("/projects/:project/labels", a.catalog.CreateLabel)
+			admin.PUT("/labels/:id", a.catalog.UpdateLabel)
+			admin.DELETE("/labels/:id", a.catalog.DeleteLabel)
+			admin.POST("/fields", a.catalog.CreateField)
+			admin.PUT("/fields/:id", a.catalog.UpdateField)
+			admin.DELETE("/fields/:id", a.catalog.DeleteField)
 		}
 	}
-
-	return r
 }
@@ -0,0 +1,165 @@
+package rpc
+
+import (
+	"a2sv-backend/task_manager_v3/Delivery/controllers"
+	"a2sv-backend/task_manager_v3/Delivery/rpc/pb"
+	"a2sv-backend/task_manager_v3/Domain"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// timestamp leaves unset times out of the message
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// customFields converts custom field values, sending values protobuf has
+// no type for as text
+func customFields(fields map[string]interface{}) *structpb.Struct {
+	if len(fields) == 0 {
+		return nil
+	}
+	out := &structpb.Struct{Fields: map[string]*structpb.Value{}}
+	for key, value := range fields {
+		converted, err := structpb.NewValue(value)
+		if err != nil {
+			converted = structpb.NewStringValue(fmt.Sprint(value))
+		}
+		out.Fields[key] = converted
+	}
+	return out
+}
+
+func taskMessage(task Domain.Task) *pb.Task {
+	out := &pb.Task{
+		Id:           task.ID.Hex(),
+		Title:        task.Title,
+		Description:  task.Description,
+		DueDate:      timestamp(task.DueDate),
+		Status:       task.Status,
+		Priority:     task.Priority,
+		Project:      task.Project,
+		ExternalId:   task.ExternalID,
+		CustomFields: customFields(task.CustomFields),
+		CreatedAt:    timestamp(task.CreatedAt),
+		UpdatedAt:    timestamp(task.UpdatedAt),
+	}
+	for _, label := range task.Labels {
+		out.Labels = append(out.Labels, label.Hex())
+	}
+	for _, change := range task.StatusHistory {
+		out.StatusHistory = append(out.StatusHistory, &pb.StatusChange{Status: change.Status, At: timestamp(change.At)})
+	}
+	return out
+}
+
+func taskEventMessage(event Domain.TaskEvent) *pb.TaskEvent {
+	out := &pb.TaskEvent{
+		Id:         event.ID,
+		Type:       event.Type,
+		TaskId:     event.TaskID.Hex(),
+		OccurredAt: timestamp(event.OccurredAt),
+	}
+	if event.Task != nil {
+		out.Task = taskMessage(*event.Task)
+	}
+	return out
+}
+
+func userMessage(user Domain.User) *pb.User {
+	return &pb.User{
+		Id:            user.ID.Hex(),
+		Username:      user.Username,
+		DisplayName:   user.DisplayName,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		Role:          user.Role,
+		Deactivated:   user.Deactivated,
+		MfaEnabled:    user.MFAEnabled,
+	}
+}
+
+// objectID parses the ID of a what, failing with InvalidArgument
+func objectID(hex, what string) (primitive.ObjectID, error) {
+	id, err := primitive.ObjectIDFromHex(hex)
+	if err != nil {
+		return id, status.Errorf(codes.InvalidArgument, "Invalid %s ID", what)
+	}
+	return id, nil
+}
+
+func labelIDs(hexes []string) ([]primitive.ObjectID, error) {
+	var ids []primitive.ObjectID
+	for _, hex := range hexes {
+		id, err := objectID(hex, "label")
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// taskRequest reads a TaskInput into the REST body it stands for, so it can
+// be checked with the same rules
+func taskRequest(input *pb.TaskInput) (controllers.CreateTaskRequest, error) {
+	if input == nil {
+		input = &pb.TaskInput{}
+	}
+	req := controllers.CreateTaskRequest{
+		Title:       input.GetTitle(),
+		Description: input.GetDescription(),
+		Status:      input.GetStatus(),
+		Priority:    input.GetPriority(),
+		Project:     input.GetProject(),
+		ExternalID:  input.GetExternalId(),
+	}
+	if input.DueDate != nil {
+		req.DueDate = input.DueDate.AsTime()
+	}
+	if input.CustomFields != nil {
+		req.CustomFields = input.CustomFields.AsMap()
+	}
+	labels, err := labelIDs(input.GetLabels())
+	if err != nil {
+		return req, err
+	}
+	req.Labels = labels
+	return req, nil
+}
+
+// invalidInput fails with InvalidArgument, listing what is wrong as
+// BadRequest details
+func invalidInput(fields []controllers.FieldError) error {
+	violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(fields))
+	for _, field := range fields {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: field.Field, Description: field.Message})
+	}
+	st, err := status.New(codes.InvalidArgument, "Invalid input").WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "Invalid input")
+	}
+	return st.Err()
+}
+
+// taskStatus gives usecase errors the code their REST status stands for
+func taskStatus(err error) error {
+	switch {
+	case errors.Is(err, Domain.ErrInvalidTask):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, Domain.ErrTaskNotFound):
+		return status.Error(codes.NotFound, "Task not found")
+	}
+	return status.Error(codes.Internal, err.Error())
+}
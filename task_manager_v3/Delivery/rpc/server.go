@@ -0,0 +1,89 @@
+// Package rpc serves the task and user APIs over gRPC for other backend
+// services. The handlers call the same usecases as the HTTP controllers.
+//
+// The protobuf definitions live in proto/. After changing them, regenerate
+// pb/ from the module root with protoc-gen-go and protoc-gen-go-grpc:
+//
+//	protoc -I Delivery/rpc/proto \
+//		--go_out=. --go_opt=module=a2sv-backend/task_manager_v3 \
+//		--go-grpc_out=. --go-grpc_opt=module=a2sv-backend/task_manager_v3 \
+//		taskmanager/v1/task.proto taskmanager/v1/user.proto
+package rpc
+
+import (
+	"a2sv-backend/task_manager_v3/Delivery/rpc/pb"
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Infrastructure"
+	"a2sv-backend/task_manager_v3/Usecases"
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ReadMethods need only the read scope from access tokens, like GET routes
+var ReadMethods = map[string]bool{
+	pb.TaskService_ListTasks_FullMethodName:      true,
+	pb.TaskService_GetTask_FullMethodName:        true,
+	pb.TaskService_WatchTasks_FullMethodName:     true,
+	pb.UserService_GetCurrentUser_FullMethodName: true,
+	pb.UserService_GetUser_FullMethodName:        true,
+	pb.UserService_ListUsers_FullMethodName:      true,
+}
+
+// NewServer builds a gRPC server with the task and user services. Calls
+// are logged, counted in metrics (when not nil) and authenticated, in that
+// order, so rejected calls are logged and counted too.
+func NewServer(taskUsecase Usecases.TaskUsecase, userUsecase Usecases.UserUsecase, authenticator *Infrastructure.Authenticator, metrics *Infrastructure.Metrics) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			Infrastructure.UnaryLoggingInterceptor(),
+			Infrastructure.UnaryMetricsInterceptor(metrics),
+			Infrastructure.UnaryAuthInterceptor(authenticator, ReadMethods),
+		),
+		grpc.ChainStreamInterceptor(
+			Infrastructure.StreamLoggingInterceptor(),
+			Infrastructure.StreamMetricsInterceptor(metrics),
+			Infrastructure.StreamAuthInterceptor(authenticator, ReadMethods),
+		),
+	)
+	pb.RegisterTaskServiceServer(server, NewTaskServer(taskUsecase))
+	pb.RegisterUserServiceServer(server, NewUserServer(userUsecase))
+	return server
+}
+
+// viewer is the caller the auth interceptors let through
+func viewer(ctx context.Context) Domain.Viewer {
+	principal, _ := Infrastructure.PrincipalFromContext(ctx)
+	return Domain.Viewer{UserID: principal.UserID, Username: principal.Username, Role: principal.Role}
+}
+
+// requireAdmin is AdminMiddleware for gRPC handlers
+func requireAdmin(ctx context.Context) error {
+	principal, _ := Infrastructure.PrincipalFromContext(ctx)
+	if principal.Role != Domain.RoleAdmin {
+		return status.Error(codes.PermissionDenied, "Forbidden: Admins only")
+	}
+	if !principal.HasScope(Domain.ScopeAdmin) {
+		return status.Error(codes.PermissionDenied, "Access token lacks the admin scope")
+	}
+	return nil
+}
+
+// pageArgs applies the defaults to a requested page and checks it
+func pageArgs(page, pageSize int32, defaultSize, maxSize int) (int, int, error) {
+	if page == 0 {
+		page = 1
+	}
+	if pageSize == 0 {
+		pageSize = int32(defaultSize)
+	}
+	if page < 1 {
+		return 0, 0, status.Error(codes.InvalidArgument, "page must be a positive integer")
+	}
+	if pageSize < 1 || int(pageSize) > maxSize {
+		return 0, 0, status.Errorf(codes.InvalidArgument, "page_size must be between 1 and %d", maxSize)
+	}
+	return int(page), int(pageSize), nil
+}
@@ -0,0 +1,186 @@
+package rpc
+
+import (
+	"a2sv-backend/task_manager_v3/Delivery/controllers"
+	"a2sv-backend/task_manager_v3/Delivery/rpc/pb"
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Usecases"
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TaskServer implements pb.TaskServiceServer
+type TaskServer struct {
+	pb.UnimplementedTaskServiceServer
+	taskUsecase Usecases.TaskUsecase
+}
+
+func NewTaskServer(taskUsecase Usecases.TaskUsecase) *TaskServer {
+	return &TaskServer{taskUsecase: taskUsecase}
+}
+
+func (s *TaskServer) ListTasks(ctx context.Context, req *pb.ListTasksRequest) (*pb.ListTasksResponse, error) {
+	page, pageSize, err := pageArgs(req.GetPage(), req.GetPageSize(), Domain.DefaultTaskPageSize, Domain.MaxTaskPageSize)
+	if err != nil {
+		return nil, err
+	}
+	filter := Domain.TaskFilter{
+		Status:       req.GetStatus(),
+		Priority:     req.GetPriority(),
+		Project:      req.GetProject(),
+		CustomFields: map[string]interface{}{},
+	}
+	if filter.Labels, err = labelIDs(req.GetLabels()); err != nil {
+		return nil, err
+	}
+	if req.CustomFields != nil {
+		filter.CustomFields = req.CustomFields.AsMap()
+	}
+	for _, field := range req.GetSort() {
+		filter.Sort = append(filter.Sort, Domain.SortField{Field: field.GetField(), Desc: field.GetDesc()})
+	}
+
+	tasks, total, err := s.taskUsecase.ListPage(filter, page, pageSize)
+	if err != nil {
+		return nil, taskStatus(err)
+	}
+	out := &pb.ListTasksResponse{Total: total, Page: int32(page), PageSize: int32(pageSize)}
+	for _, task := range tasks {
+		out.Tasks = append(out.Tasks, taskMessage(task))
+	}
+	return out, nil
+}
+
+func (s *TaskServer) GetTask(ctx context.Context, req *pb.GetTaskRequest) (*pb.Task, error) {
+	id, err := objectID(req.GetId(), "task")
+	if err != nil {
+		return nil, err
+	}
+	task, err := s.taskUsecase.GetByID(id)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "Task not found")
+	}
+	return taskMessage(task), nil
+}
+
+func (s *TaskServer) CreateTask(ctx context.Context, req *pb.CreateTaskRequest) (*pb.Task, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	input, err := taskRequest(req.GetTask())
+	if err != nil {
+		return nil, err
+	}
+	if fields := controllers.ValidateRequest(&input); fields != nil {
+		return nil, invalidInput(fields)
+	}
+
+	created, err := s.taskUsecase.Create(domainTask(input))
+	if err != nil {
+		return nil, taskStatus(err)
+	}
+	return taskMessage(created), nil
+}
+
+func (s *TaskServer) UpdateTask(ctx context.Context, req *pb.UpdateTaskRequest) (*pb.Task, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	id, err := objectID(req.GetId(), "task")
+	if err != nil {
+		return nil, err
+	}
+	input, err := taskRequest(req.GetTask())
+	if err != nil {
+		return nil, err
+	}
+	// Existing tasks may be overdue, so the update rules apply
+	update := controllers.UpdateTaskRequest(input)
+	if fields := controllers.ValidateRequest(&update); fields != nil {
+		return nil, invalidInput(fields)
+	}
+
+	updated, err := s.taskUsecase.Update(id, domainTask(input))
+	if err != nil {
+		return nil, taskStatus(err)
+	}
+	return taskMessage(updated), nil
+}
+
+func (s *TaskServer) DeleteTask(ctx context.Context, req *pb.DeleteTaskRequest) (*pb.DeleteTaskResponse, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	id, err := objectID(req.GetId(), "task")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.taskUsecase.Delete(id); err != nil {
+		return nil, taskStatus(err)
+	}
+	return &pb.DeleteTaskResponse{}, nil
+}
+
+func (s *TaskServer) SetTaskStatus(ctx context.Context, req *pb.SetTaskStatusRequest) (*pb.Task, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	id, err := objectID(req.GetId(), "task")
+	if err != nil {
+		return nil, err
+	}
+	if !Domain.ValidStatus(req.GetStatus()) {
+		return nil, status.Errorf(codes.InvalidArgument, "status must be one of %s, %s, %s", Domain.StatusPending, Domain.StatusInProgress, Domain.StatusCompleted)
+	}
+	task, err := s.taskUsecase.GetByID(id)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "Task not found")
+	}
+
+	task.Status = req.GetStatus()
+	updated, err := s.taskUsecase.Update(id, task)
+	if err != nil {
+		return nil, taskStatus(err)
+	}
+	return taskMessage(updated), nil
+}
+
+// WatchTasks sends task events until the client goes away. Unlike the SSE
+// stream it needs no heartbeat; HTTP/2 keeps the connection alive.
+func (s *TaskServer) WatchTasks(req *pb.WatchTasksRequest, stream pb.TaskService_WatchTasksServer) error {
+	ctx := stream.Context()
+	events, err := s.taskUsecase.Subscribe(ctx, viewer(ctx), req.GetLastEventId())
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(taskEventMessage(event)); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// domainTask is the task a validated input describes
+func domainTask(input controllers.CreateTaskRequest) Domain.Task {
+	return Domain.Task{
+		Title:        input.Title,
+		Description:  input.Description,
+		DueDate:      input.DueDate,
+		Status:       input.Status,
+		Priority:     input.Priority,
+		Project:      input.Project,
+		ExternalID:   input.ExternalID,
+		Labels:       input.Labels,
+		CustomFields: input.CustomFields,
+	}
+}
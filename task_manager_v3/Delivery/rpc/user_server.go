@@ -0,0 +1,91 @@
+package rpc
+
+import (
+	"a2sv-backend/task_manager_v3/Delivery/rpc/pb"
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Usecases"
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UserServer implements pb.UserServiceServer
+type UserServer struct {
+	pb.UnimplementedUserServiceServer
+	userUsecase Usecases.UserUsecase
+}
+
+func NewUserServer(userUsecase Usecases.UserUsecase) *UserServer {
+	return &UserServer{userUsecase: userUsecase}
+}
+
+func (s *UserServer) GetCurrentUser(ctx context.Context, req *pb.GetCurrentUserRequest) (*pb.User, error) {
+	id, err := objectID(viewer(ctx).UserID, "user")
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid user in token")
+	}
+	user, err := s.userUsecase.GetUser(id)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "User not found")
+	}
+	return userMessage(user), nil
+}
+
+func (s *UserServer) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.User, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	id, err := objectID(req.GetId(), "user")
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userUsecase.GetUser(id)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "User not found")
+	}
+	return userMessage(user), nil
+}
+
+func (s *UserServer) ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	page, pageSize, err := pageArgs(req.GetPage(), req.GetPageSize(), Domain.DefaultUserPageSize, Domain.MaxUserPageSize)
+	if err != nil {
+		return nil, err
+	}
+	query := Domain.UserQuery{Search: req.GetSearch(), Role: req.GetRole(), Status: req.GetStatus(), Page: page, PageSize: pageSize}
+
+	users, total, err := s.userUsecase.ListUsers(query)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	out := &pb.ListUsersResponse{Total: total, Page: int32(page), PageSize: int32(pageSize)}
+	for _, user := range users {
+		out.Users = append(out.Users, userMessage(user))
+	}
+	return out, nil
+}
+
+func (s *UserServer) PromoteUser(ctx context.Context, req *pb.PromoteUserRequest) (*pb.User, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	id, err := objectID(req.GetId(), "user")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userUsecase.Promote(id); err != nil {
+		if errors.Is(err, Domain.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "User not found")
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	user, err := s.userUsecase.GetUser(id)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "User not found")
+	}
+	return userMessage(user), nil
+}
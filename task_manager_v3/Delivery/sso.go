@@ -0,0 +1,43 @@
+package main
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Infrastructure"
+	"a2sv-backend/task_manager_v3/Repositories"
+	"a2sv-backend/task_manager_v3/Usecases"
+	"log"
+	"strings"
+)
+
+// newSSOUsecase sets up OIDC sign-in when the config names an issuer and
+// returns nil otherwise. The callback defaults to appURL +
+// /api/v1/auth/oidc/callback.
+func newSSOUsecase(config Infrastructure.SSOConfig, appURL string, stateRepo Repositories.SSOStateRepository, identityRepo Repositories.IdentityRepository, userRepo Repositories.UserRepository, jwtService Infrastructure.JWTService, mfa Usecases.MFAUsecase, sessions Usecases.SessionUsecase) Usecases.SSOUsecase {
+	if config.IssuerURL == "" {
+		return nil
+	}
+
+	redirectURL := config.RedirectURL
+	if redirectURL == "" {
+		redirectURL = strings.TrimRight(appURL, "/") + "/api/v1/auth/oidc/callback"
+	}
+	provider, err := Infrastructure.NewOIDCProvider(Infrastructure.OIDCConfig{
+		IssuerURL:    config.IssuerURL,
+		ClientID:     config.ClientID,
+		ClientSecret: config.ClientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       config.Scopes,
+		GroupsClaim:  config.GroupsClaim,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	policy := Domain.SSOPolicy{GroupRoles: map[string]string{}, Provision: config.Provision}
+	for group, role := range config.GroupRoles {
+		policy.GroupRoles[group] = role
+	}
+
+	log.Printf("OIDC sign-in enabled with %s", config.IssuerURL)
+	return Usecases.NewSSOUsecase(provider, stateRepo, identityRepo, userRepo, jwtService, mfa, sessions, policy)
+}
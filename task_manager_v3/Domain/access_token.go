@@ -0,0 +1,65 @@
+package Domain
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AccessTokenPrefix starts every personal access token, which is how they
+// are told apart from JWTs and spotted by secret scanners
+const AccessTokenPrefix = "tm_pat_"
+
+// Access token scopes. They are checked independently: a token that should
+// create tasks as an admin needs write and admin.
+const (
+	// ScopeRead allows GET requests
+	ScopeRead = "read"
+	// ScopeWrite allows requests that change data
+	ScopeWrite = "write"
+	// ScopeAdmin allows admin-only routes, if the owner is an admin
+	ScopeAdmin = "admin"
+)
+
+// AccessTokenScopes lists the valid scopes
+var AccessTokenScopes = []string{ScopeRead, ScopeWrite, ScopeAdmin}
+
+const (
+	DefaultAccessTokenLifetime = 90 * 24 * time.Hour
+	MaxAccessTokenLifetime     = 365 * 24 * time.Hour
+	MaxAccessTokensPerUser     = 50
+)
+
+var (
+	ErrInvalidAccessToken = errors.New("access token is invalid, expired or revoked")
+	ErrAccessTokenName    = errors.New("name is required and must be at most 100 characters")
+	ErrInvalidScope       = errors.New("scopes must be one or more of read, write and admin")
+	ErrTokenLifetime      = errors.New("access tokens expire after 1 to 365 days")
+	ErrTooManyTokens      = errors.New("too many access tokens; revoke some first")
+)
+
+// AccessToken is a long-lived credential for scripts and other automation.
+// Only its hash is stored; Prefix is enough of it to recognize it in a list.
+type AccessToken struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Name       string             `bson:"name" json:"name"`
+	Prefix     string             `bson:"prefix" json:"prefix"`
+	TokenHash  string             `bson:"token_hash" json:"-"`
+	Scopes     []string           `bson:"scopes" json:"scopes"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	ExpiresAt  time.Time          `bson:"expires_at" json:"expires_at"`
+	LastUsedAt time.Time          `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	LastUsedIP string             `bson:"last_used_ip,omitempty" json:"last_used_ip,omitempty"`
+}
+
+// HasScope reports whether the token was granted scope
+func (t AccessToken) HasScope(scope string) bool {
+	for _, granted := range t.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
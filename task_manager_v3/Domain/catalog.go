@@ -0,0 +1,101 @@
+package Domain
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrLabelExists         = errors.New("a label with that name already exists in the project")
+	ErrCustomFieldExists   = errors.New("a custom field with that key already exists")
+	ErrLabelNotFound       = errors.New("label not found")
+	ErrCustomFieldNotFound = errors.New("custom field not found")
+	// ErrInvalidLabel and ErrInvalidCustomField wrap every label and field
+	// definition validation failure
+	ErrInvalidLabel       = errors.New("invalid label")
+	ErrInvalidCustomField = errors.New("invalid custom field")
+)
+
+// Task priorities, P0 being the most urgent. They sort correctly as strings.
+const (
+	PriorityP0 = "P0"
+	PriorityP1 = "P1"
+	PriorityP2 = "P2"
+	PriorityP3 = "P3"
+)
+
+// ValidPriority reports whether priority is empty or one of P0-P3
+func ValidPriority(priority string) bool {
+	switch priority {
+	case "", PriorityP0, PriorityP1, PriorityP2, PriorityP3:
+		return true
+	}
+	return false
+}
+
+// DefaultProject is the project of tasks and labels that don't name one
+const DefaultProject = "default"
+
+// Label is a colored tag defined per project
+type Label struct {
+	ID      primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Project string             `bson:"project" json:"project"`
+	Name    string             `bson:"name" json:"name"`
+	Color   string             `bson:"color" json:"color"`
+}
+
+// Custom field types
+const (
+	FieldText   = "text"
+	FieldNumber = "number"
+	FieldDate   = "date"
+	FieldEnum   = "enum"
+	FieldUser   = "user"
+)
+
+// CustomField is an admin-defined extra field tasks can carry under
+// Task.CustomFields[Key]. Numbers are stored as float64, dates as YYYY-MM-DD
+// strings (so they sort), users as hex user IDs and the rest as strings.
+type CustomField struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Key      string             `bson:"key" json:"key"`
+	Name     string             `bson:"name" json:"name"`
+	Type     string             `bson:"type" json:"type"`
+	Options  []string           `bson:"options,omitempty" json:"options,omitempty"`
+	Required bool               `bson:"required" json:"required"`
+}
+
+// SortableTaskFields lists the task fields a listing can be sorted by, on top
+// of "cf.<key>" for custom fields
+var SortableTaskFields = []string{"title", "due_date", "status", "priority", "created_at", "updated_at"}
+
+// SortField orders a task listing. Field is a task JSON field name or
+// "cf.<key>" for a custom field.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// TaskFilter narrows a task listing. Every set criterion must match; a task
+// must carry all of Labels. CustomFields values may be raw query strings;
+// TaskUsecase.List converts them to each field's stored type.
+type TaskFilter struct {
+	Status       string
+	Priority     string
+	Project      string
+	Labels       []primitive.ObjectID
+	CustomFields map[string]interface{}
+	Sort         []SortField
+}
+
+const (
+	DefaultTaskPageSize = 20
+	MaxTaskPageSize     = 100
+)
+
+var (
+	// ErrInvalidTask wraps every task validation failure
+	ErrInvalidTask  = errors.New("invalid task")
+	ErrTaskNotFound = errors.New("task not found")
+)
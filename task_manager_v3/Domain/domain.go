@@ -1,6 +1,7 @@
 package Domain
 
 import (
+	"errors"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -13,11 +14,245 @@ type Task struct {
 	Description string             `bson:"description" json:"description"`
 	DueDate     time.Time          `bson:"duedate" json:"due_date"`
 	Status      string             `bson:"status" json:"status"`
+	ExternalID  string             `bson:"external_id,omitempty" json:"external_id,omitempty"`
+	Priority    string             `bson:"priority,omitempty" json:"priority,omitempty"`
+	Project     string             `bson:"project,omitempty" json:"project,omitempty"`
+	// Labels holds label IDs; every label must belong to the task's project
+	Labels       []primitive.ObjectID   `bson:"labels,omitempty" json:"labels,omitempty"`
+	CustomFields map[string]interface{} `bson:"custom_fields,omitempty" json:"custom_fields,omitempty"`
+	CreatedAt    time.Time              `bson:"created_at,omitempty" json:"created_at"`
+	UpdatedAt    time.Time              `bson:"updated_at,omitempty" json:"updated_at"`
+	// StatusHistory records every status the task has been in, oldest first.
+	// It is maintained by the server and drives the cycle-time report.
+	StatusHistory []StatusChange `bson:"status_history,omitempty" json:"status_history,omitempty"`
 }
 
+// StatusChange is one entry of a task's status history
+type StatusChange struct {
+	Status string    `bson:"status" json:"status"`
+	At     time.Time `bson:"at" json:"at"`
+}
+
+// Task statuses
+const (
+	StatusPending    = "pending"
+	StatusInProgress = "in_progress"
+	StatusCompleted  = "completed"
+)
+
+// ValidStatus reports whether status is one a task can be in
+func ValidStatus(status string) bool {
+	switch status {
+	case StatusPending, StatusInProgress, StatusCompleted:
+		return true
+	}
+	return false
+}
+
+// Track carries the server-maintained fields over from the stored version of
+// the task (nil when it is new) and appends to the status history when the
+// status changed
+func (t *Task) Track(previous *Task, now time.Time) {
+	if previous != nil {
+		t.CreatedAt = previous.CreatedAt
+		t.StatusHistory = append([]StatusChange{}, previous.StatusHistory...)
+	}
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = now
+	}
+	t.UpdatedAt = now
+
+	last := ""
+	if n := len(t.StatusHistory); n > 0 {
+		last = t.StatusHistory[n-1].Status
+	}
+	if t.Status != "" && t.Status != last {
+		t.StatusHistory = append(t.StatusHistory, StatusChange{Status: t.Status, At: now})
+	}
+}
+
+// User is an account. UsernameKey is Username after NormalizeUsername;
+// repositories keep it in sync and enforce its uniqueness. EmailVerified is
+// reset whenever Email changes, and accounts with VerificationRequired can't
+// log in while it is unset. TOTPSecret is set from the start of 2FA
+// enrollment but only checked once MFAEnabled; RecoveryCodes holds hashes.
 type User struct {
-	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Username string             `bson:"username" json:"username"`
-	Password string             `bson:"password" json:"password"`
-	Role     string             `bson:"role" json:"role"`
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Username      string             `bson:"username" json:"username"`
+	UsernameKey   string             `bson:"username_key" json:"-"`
+	DisplayName   string             `bson:"display_name,omitempty" json:"display_name"`
+	Email         string             `bson:"email,omitempty" json:"email"`
+	EmailVerified bool               `bson:"email_verified" json:"email_verified"`
+	// VerificationRequired is set on accounts whose registration was only
+	// allowed because of their email's domain
+	VerificationRequired bool     `bson:"verification_required,omitempty" json:"-"`
+	Password             string   `bson:"password" json:"-"`
+	Role                 string   `bson:"role" json:"role"`
+	Deactivated          bool     `bson:"deactivated" json:"deactivated"`
+	MFAEnabled           bool     `bson:"mfa_enabled" json:"mfa_enabled"`
+	TOTPSecret           string   `bson:"totp_secret" json:"-"`
+	TOTPLastStep         int64    `bson:"totp_last_step" json:"-"`
+	RecoveryCodes        []string `bson:"recovery_codes" json:"-"`
+}
+
+// Task event types published whenever a task changes
+const (
+	TaskCreated = "task.created"
+	TaskUpdated = "task.updated"
+	TaskDeleted = "task.deleted"
+	// TaskEventsReset is sent instead of a replay when the events after a
+	// client's Last-Event-ID are no longer known, for example after a
+	// restart. The client has to reload the tasks.
+	TaskEventsReset = "reset"
+)
+
+// TaskEvent describes a single change to a task. ID is assigned by the event
+// bus and is what clients send back as Last-Event-ID to resume a stream.
+type TaskEvent struct {
+	ID         string             `bson:"-" json:"id"`
+	Type       string             `bson:"type" json:"type"`
+	TaskID     primitive.ObjectID `bson:"task_id" json:"task_id"`
+	Task       *Task              `bson:"task,omitempty" json:"task,omitempty"`
+	OccurredAt time.Time          `bson:"occurred_at" json:"occurred_at"`
+}
+
+// Viewer is the authenticated caller a request is made on behalf of
+type Viewer struct {
+	UserID   string
+	Username string
+	Role     string
+}
+
+// SearchQuery is a parsed full-text query. Every term, prefix and phrase
+// must match somewhere in the task for it to be a hit.
+type SearchQuery struct {
+	Terms    []string
+	Prefixes []string
+	Phrases  [][]string
+}
+
+// IsEmpty reports whether the query has nothing to match on
+func (q SearchQuery) IsEmpty() bool {
+	return len(q.Terms) == 0 && len(q.Prefixes) == 0 && len(q.Phrases) == 0
+}
+
+// TaskSearchResult is a task matched by a search with its relevance score
+// and the matching fields as HTML, escaped, with hits wrapped in <mark> tags
+type TaskSearchResult struct {
+	Task       Task              `json:"task"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+// Bulk operation kinds accepted by POST /tasks/bulk
+const (
+	BulkCreate     = "create"
+	BulkUpdate     = "update"
+	BulkDelete     = "delete"
+	BulkTransition = "transition"
+)
+
+var (
+	ErrBulkTooLarge            = errors.New("too many operations in one bulk request")
+	ErrBulkInvalid             = errors.New("bulk request has invalid operations")
+	ErrBulkAborted             = errors.New("bulk request aborted, nothing was written")
+	ErrTransactionsUnsupported = errors.New("transactions are not supported by this database deployment")
+)
+
+// BulkOperation is one item of a bulk request. TaskID is the parsed form of
+// ID, filled in during validation.
+type BulkOperation struct {
+	Op     string             `json:"op"`
+	ID     string             `json:"id,omitempty"`
+	Task   *Task              `json:"task,omitempty"`
+	Status string             `json:"status,omitempty"`
+	TaskID primitive.ObjectID `json:"-"`
+}
+
+// BulkResult reports what happened to the operation at Index. Status uses
+// HTTP status codes so clients can treat each item like its own request.
+type BulkResult struct {
+	Index  int    `json:"index"`
+	Op     string `json:"op"`
+	ID     string `json:"id,omitempty"`
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+	Task   *Task  `json:"task,omitempty"`
+}
+
+// Import/export formats
+const (
+	FormatCSV    = "csv"
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+	FormatICS    = "ics"
+)
+
+// ImportRecord is one row of an import file with its values keyed by task
+// field name (after any field mapping). Err is set when the row could not
+// even be parsed.
+type ImportRecord struct {
+	Row    int
+	Fields map[string]string
+	Err    error
+}
+
+// Import row outcomes
+const (
+	ImportCreated = "created"
+	ImportUpdated = "updated"
+	ImportFailed  = "failed"
+)
+
+type ImportRowResult struct {
+	Row        int      `json:"row"`
+	ExternalID string   `json:"external_id,omitempty"`
+	ID         string   `json:"id,omitempty"`
+	Action     string   `json:"action"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+type ImportReport struct {
+	DryRun  bool              `json:"dry_run"`
+	Total   int               `json:"total"`
+	Created int               `json:"created"`
+	Updated int               `json:"updated"`
+	Failed  int               `json:"failed"`
+	Rows    []ImportRowResult `json:"rows"`
+}
+
+// TaskSummary is the payload of GET /reports/summary
+type TaskSummary struct {
+	Total       int64            `json:"total"`
+	ByStatus    map[string]int64 `json:"by_status"`
+	Overdue     int64            `json:"overdue"`
+	DueThisWeek int64            `json:"due_this_week"`
+	WeekStart   time.Time        `json:"week_start"`
+	GeneratedAt time.Time        `json:"generated_at"`
+}
+
+// Throughput bucket sizes
+const (
+	IntervalDay  = "day"
+	IntervalWeek = "week"
+)
+
+var (
+	ErrInvalidInterval    = errors.New("interval must be day or week")
+	ErrInvalidReportRange = errors.New("from must be before to")
+)
+
+// ThroughputBucket counts tasks completed in one day ("2026-01-31") or ISO
+// week ("2026-W05")
+type ThroughputBucket struct {
+	Period    string `json:"period"`
+	Completed int64  `json:"completed"`
+}
+
+// CycleTimeReport measures the time from a task first going in_progress to
+// it being completed, for tasks completed in the reporting window
+type CycleTimeReport struct {
+	Tasks        int64   `json:"tasks"`
+	MedianHours  float64 `json:"median_hours"`
+	AverageHours float64 `json:"average_hours"`
 }
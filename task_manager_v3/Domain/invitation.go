@@ -0,0 +1,70 @@
+package Domain
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrInvalidInvitation     = errors.New("invitation is invalid, expired or already used")
+	ErrRegistrationClosed    = errors.New("registration requires an invitation")
+	ErrEmailDomainNotAllowed = errors.New("email domain is not allowed to register")
+	ErrAlreadyBootstrapped   = errors.New("an admin already exists")
+)
+
+// Invitation lets someone register with a preset role. Only a hash of the
+// token is stored; the token itself is shown once when the invitation is
+// created. When Email is set the invitation only works for that address.
+type Invitation struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	TokenHash string              `bson:"token_hash" json:"-"`
+	Role      string              `bson:"role" json:"role"`
+	Email     string              `bson:"email,omitempty" json:"email,omitempty"`
+	CreatedBy primitive.ObjectID  `bson:"created_by" json:"created_by"`
+	CreatedAt time.Time           `bson:"created_at" json:"created_at"`
+	ExpiresAt time.Time           `bson:"expires_at" json:"expires_at"`
+	UsedAt    *time.Time          `bson:"used_at,omitempty" json:"used_at,omitempty"`
+	UsedBy    *primitive.ObjectID `bson:"used_by,omitempty" json:"used_by,omitempty"`
+}
+
+// Registration modes
+const (
+	RegistrationInviteOnly = "invite"
+	RegistrationOpen       = "open"
+	RegistrationDomain     = "domain"
+)
+
+// RegistrationPolicy decides who may register without an invitation. In
+// domain mode only emails under AllowedDomains may, and those accounts can't
+// log in until the address is verified. A valid invitation is accepted in
+// every mode.
+type RegistrationPolicy struct {
+	Mode           string
+	AllowedDomains []string
+}
+
+// AllowsEmail reports whether email falls under one of the allowed domains
+func (p RegistrationPolicy) AllowsEmail(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+	for _, allowed := range p.AllowedDomains {
+		if domain == strings.ToLower(strings.TrimSpace(allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Registration is what a new user submits
+type Registration struct {
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+	Email      string `json:"email"`
+	Invitation string `json:"invitation"`
+}
@@ -0,0 +1,77 @@
+package Domain
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrInvalidMFACode    = errors.New("verification code is invalid")
+	ErrInvalidMFAToken   = errors.New("two-factor login is invalid or has expired")
+	ErrMFANotEnrolled    = errors.New("two-factor authentication is not set up")
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrMFAAttemptsExceeded is returned once a pending login has seen
+	// MaxMFAAttempts wrong codes; the user has to sign in again
+	ErrMFAAttemptsExceeded = errors.New("too many wrong codes, sign in again")
+	// ErrMFAEnforced is returned when the security policy requires 2FA for
+	// the account's role and the account doesn't have it
+	ErrMFAEnforced = errors.New("two-factor authentication is required for this role")
+)
+
+// RecoveryCodeCount is how many recovery codes a user gets at a time
+const RecoveryCodeCount = 10
+
+// MaxMFAAttempts is how many wrong codes one pending login token allows
+const MaxMFAAttempts = 5
+
+// MFAChallenge is the server side of a pending login token, keyed by its
+// "jti" claim. It counts wrong codes and is deleted when the token is
+// exchanged, so each token completes at most one login.
+type MFAChallenge struct {
+	ID        string             `bson:"_id"`
+	UserID    primitive.ObjectID `bson:"user_id"`
+	Failures  int                `bson:"failures"`
+	ExpiresAt time.Time          `bson:"expires_at"`
+}
+
+// MFAEnrollment is what an authenticator app needs to start producing codes.
+// URI is the otpauth:// form, usually shown as a QR code.
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// SecurityPolicy holds the account security settings admins control
+type SecurityPolicy struct {
+	// MFARequiredRoles lists the roles that may only sign in with 2FA
+	MFARequiredRoles []string           `bson:"mfa_required_roles" json:"mfa_required_roles"`
+	UpdatedBy        primitive.ObjectID `bson:"updated_by,omitempty" json:"updated_by,omitempty"`
+	UpdatedAt        time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+}
+
+// RequiresMFA reports whether accounts with role must use 2FA
+func (p SecurityPolicy) RequiresMFA(role string) bool {
+	for _, required := range p.MFARequiredRoles {
+		if required == role {
+			return true
+		}
+	}
+	return false
+}
+
+// LoginResult is the outcome of a password login. When MFAPending is set,
+// Token is not an access token: it can only be exchanged, together with a
+// code, at the second login step.
+type LoginResult struct {
+	Token      string
+	User       User
+	MFAPending bool
+	// EnrollmentRequired means the policy requires 2FA for the user's role
+	// but they have none yet; the pending token lets them enroll
+	EnrollmentRequired bool
+	// RecoveryCodes is set when completing the login also finished
+	// enrollment. They are not stored in readable form and aren't shown again.
+	RecoveryCodes []string
+}
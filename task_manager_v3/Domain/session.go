@@ -0,0 +1,30 @@
+package Domain
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrSessionEnded = errors.New("session has ended; log in again")
+
+// ClientInfo describes where a login came from
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
+
+// Session is one login. Access tokens carry its ID, and stop working once
+// the session is revoked.
+type Session struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
+	UserAgent  string             `bson:"user_agent" json:"user_agent"`
+	IP         string             `bson:"ip" json:"ip"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	LastSeenAt time.Time          `bson:"last_seen_at" json:"last_seen_at"`
+	ExpiresAt  time.Time          `bson:"expires_at" json:"expires_at"`
+	// Current marks the session of the request that listed it
+	Current bool `bson:"-" json:"current"`
+}
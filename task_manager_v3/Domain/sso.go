@@ -0,0 +1,78 @@
+package Domain
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrSSONotConfigured = errors.New("single sign-on is not configured")
+	ErrInvalidSSOState  = errors.New("sign-in request is invalid or has expired")
+	ErrSSOFailed        = errors.New("identity provider sign-in failed")
+	// ErrIdentityLinked is returned when an external identity already
+	// belongs to another account
+	ErrIdentityLinked = errors.New("identity is already linked to another account")
+	// ErrSSOLinkRequired is returned instead of provisioning a second
+	// account for an email a local account already uses
+	ErrSSOLinkRequired = errors.New("an account with this email already exists; sign in and link the identity from your profile")
+	ErrSSOSignupClosed = errors.New("no account is linked to this identity")
+	// ErrLastLoginMethod stops users without a password from unlinking the
+	// only identity they can sign in with
+	ErrLastLoginMethod = errors.New("cannot remove the only way to sign in to this account")
+)
+
+// ExternalIdentity links an account to a user at an identity provider,
+// identified by the provider's issuer and the user's subject there
+type ExternalIdentity struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Issuer      string             `bson:"issuer" json:"issuer"`
+	Subject     string             `bson:"subject" json:"subject"`
+	Email       string             `bson:"email,omitempty" json:"email,omitempty"`
+	LinkedAt    time.Time          `bson:"linked_at" json:"linked_at"`
+	LastLoginAt time.Time          `bson:"last_login_at,omitempty" json:"last_login_at,omitempty"`
+}
+
+// SSOState is a sign-in in progress, kept server-side between the redirect
+// to the provider and its callback. LinkUserID is set when a signed-in user
+// is linking an identity rather than signing in.
+type SSOState struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	StateHash    string             `bson:"state_hash" json:"-"`
+	Nonce        string             `bson:"nonce" json:"-"`
+	CodeVerifier string             `bson:"code_verifier" json:"-"`
+	LinkUserID   primitive.ObjectID `bson:"link_user_id,omitempty" json:"link_user_id,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	ExpiresAt    time.Time          `bson:"expires_at" json:"expires_at"`
+}
+
+// SSOPolicy controls how provider users become accounts. GroupRoles maps
+// provider groups to roles; when it is set, the role is recomputed from the
+// groups at every SSO sign-in. Provision allows creating accounts on first
+// sign-in.
+type SSOPolicy struct {
+	GroupRoles map[string]string
+	Provision  bool
+}
+
+// RoleFor picks the role groups map to: admin if any group maps to admin,
+// user otherwise
+func (p SSOPolicy) RoleFor(groups []string) string {
+	for _, group := range groups {
+		if p.GroupRoles[group] == RoleAdmin {
+			return RoleAdmin
+		}
+	}
+	return RoleUser
+}
+
+// SSOResult is the outcome of a provider callback: a login, or, when the
+// flow was started by a signed-in user, the identity linked to them
+type SSOResult struct {
+	Login  LoginResult
+	Linked *ExternalIdentity
+	// Provisioned is set when the sign-in created the account
+	Provisioned bool
+}
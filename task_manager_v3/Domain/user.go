@@ -0,0 +1,86 @@
+package Domain
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// ValidRole reports whether role is one a user can hold
+func ValidRole(role string) bool {
+	return role == RoleAdmin || role == RoleUser
+}
+
+var (
+	ErrUserNotFound     = errors.New("user not found")
+	ErrUsernameRequired = errors.New("username is required")
+	ErrInvalidEmail     = errors.New("email is not a valid address")
+	ErrUserDeactivated  = errors.New("account is deactivated")
+	ErrLastAdmin        = errors.New("cannot remove the last active admin")
+	ErrSelfManagement   = errors.New("admins cannot change their own role or status")
+	ErrInvalidRole      = errors.New("role must be admin or user")
+	ErrWrongPassword    = errors.New("current password is incorrect")
+	ErrWeakPassword     = errors.New("password must be at least 8 characters")
+	// ErrPasswordTooLong is returned for passwords bcrypt can't hash
+	ErrPasswordTooLong = errors.New("password must be at most 72 bytes")
+	// ErrEmailNotVerified is returned when logging in to an account that
+	// must verify its email first
+	ErrEmailNotVerified = errors.New("verify your email address before logging in")
+)
+
+// User statuses accepted by UserQuery.Status
+const (
+	UserStatusActive      = "active"
+	UserStatusDeactivated = "deactivated"
+)
+
+const (
+	DefaultUserPageSize = 20
+	MaxUserPageSize     = 100
+)
+
+// UserQuery selects a page of users. Search matches username or display
+// name case-insensitively; Page starts at 1.
+type UserQuery struct {
+	Search   string
+	Role     string
+	Status   string
+	Page     int
+	PageSize int
+}
+
+// ProfileUpdate holds the fields users may change about themselves. Nil
+// fields are left as they are.
+type ProfileUpdate struct {
+	Username    *string `json:"username"`
+	DisplayName *string `json:"display_name"`
+	// Changing Email marks it unverified until the new address is confirmed
+	Email *string `json:"email"`
+}
+
+// ErrUsernameTaken is returned when a username collides with an existing one
+// after normalization
+var ErrUsernameTaken = errors.New("username already exists")
+
+var usernameFolder = cases.Fold()
+
+// NormalizeUsername returns the form usernames are compared in: NFKC
+// normalized and case folded, so "Alice", "ALICE" and "Ａｌｉｃｅ" collide.
+func NormalizeUsername(username string) string {
+	return usernameFolder.String(norm.NFKC.String(strings.TrimSpace(username)))
+}
+
+// ErrEmailTaken is returned when another account already uses an email
+var ErrEmailTaken = errors.New("email is already in use")
+
+// NormalizeEmail is the form emails are stored and compared in
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,48 @@
+package Infrastructure
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// API versions, as the "api_version" context key and the version label of
+// the request metrics
+const (
+	APIVersion1 = "v1"
+	APIVersion2 = "v2"
+	// APIUnversioned marks the original paths at the root, kept as aliases
+	// of v1 until their sunset
+	APIUnversioned = "unversioned"
+)
+
+// APIVersionMiddleware tags requests to a group mounted at prefix with the
+// API version it serves
+func APIVersionMiddleware(version, prefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("api_version", version)
+		c.Set("api_prefix", prefix)
+		c.Next()
+	}
+}
+
+// DeprecatedMiddleware announces that a route is going away: Deprecation
+// (RFC 9745) carries when it was deprecated, Sunset (RFC 8594) when it will
+// stop answering, and Link points at the same route under successorPrefix.
+func DeprecatedMiddleware(deprecated, sunset time.Time, successorPrefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", fmt.Sprintf("@%d", deprecated.Unix()))
+		c.Header("Sunset", sunset.UTC().Format(http.TimeFormat))
+		c.Header("Link", fmt.Sprintf(`<%s%s>; rel="successor-version"`, successorPrefix, c.Request.URL.Path))
+		c.Next()
+	}
+}
+
+// RoutePath is the matched route without the version prefix, so every
+// version of a route is, for example, "/tasks/:id"
+func RoutePath(c *gin.Context) string {
+	return strings.TrimPrefix(c.FullPath(), c.GetString("api_prefix"))
+}
@@ -1,16 +1,70 @@
 package Infrastructure
 
 import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"errors"
 	"net/http"
 	"strings"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 )
 
-func AuthMiddleware(jwtService JWTService) gin.HandlerFunc {
+// AccountLookup loads the current state of the account a token was issued
+// for, failing if it no longer exists or has been deactivated
+type AccountLookup interface {
+	ActiveAccount(userID string) (Domain.User, error)
+}
+
+// AccessTokenAuthenticator checks personal access tokens
+type AccessTokenAuthenticator interface {
+	Authenticate(token, ip string) (Domain.AccessToken, error)
+}
+
+// SessionChecker tells whether the session a JWT belongs to is still live
+type SessionChecker interface {
+	Check(sessionID, userID string) error
+}
+
+// Values of the "auth_method" context key
+const (
+	AuthMethodJWT         = "jwt"
+	AuthMethodAccessToken = "access_token"
+)
+
+// WebSocketTokenProtocol is the subprotocol a WebSocket client offers ahead
+// of its token, as in new WebSocket(url, ["bearer", token]), since browsers
+// can't set an Authorization header on the handshake. The server answers
+// with this protocol, never with the token.
+const WebSocketTokenProtocol = "bearer"
+
+// webSocketToken returns the token offered through Sec-WebSocket-Protocol on
+// a WebSocket handshake
+func webSocketToken(c *gin.Context) (string, bool) {
+	if !websocket.IsWebSocketUpgrade(c.Request) {
+		return "", false
+	}
+	protocols := websocket.Subprotocols(c.Request)
+	if len(protocols) != 2 || protocols[0] != WebSocketTokenProtocol {
+		return "", false
+	}
+	return protocols[1], true
+}
+
+// AuthMiddleware validates the bearer token, either a JWT or, when tokens is
+// set, a personal access token. WebSocket handshakes may carry the token in
+// Sec-WebSocket-Protocol instead, see WebSocketTokenProtocol. When accounts
+// is set, the account is re-checked on every request and its current role
+// replaces the one in the token, so deactivations and role changes apply
+// immediately. When sessions is set, JWTs must belong to a session that
+// hasn't been revoked.
+func AuthMiddleware(jwtService JWTService, accounts AccountLookup, tokens AccessTokenAuthenticator, sessions SessionChecker) gin.HandlerFunc {
+	authenticator := NewAuthenticator(jwtService, accounts, tokens, sessions)
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
+		if token, ok := webSocketToken(c); ok && authHeader == "" {
+			authHeader = "Bearer " + token
+		}
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
 			c.Abort()
@@ -24,26 +78,54 @@ func AuthMiddleware(jwtService JWTService) gin.HandlerFunc {
 			return
 		}
 
-		tokenString := authParts[1]
-		token, err := jwtService.ValidateToken(tokenString)
-
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid JWT token"})
+		scope := Domain.ScopeWrite
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			scope = Domain.ScopeRead
+		}
+		principal, err := authenticator.Authenticate(authParts[1], c.ClientIP(), scope)
+		if err != nil {
+			var authErr *AuthError
+			errors.As(err, &authErr)
+			c.JSON(authErr.Status, gin.H{"error": authErr.Message})
 			c.Abort()
 			return
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid JWT claims"})
-			c.Abort()
-			return
+		c.Set("user_id", principal.UserID)
+		c.Set("username", principal.Username)
+		c.Set("role", principal.Role)
+		c.Set("auth_method", principal.Method)
+		if principal.Method == AuthMethodAccessToken {
+			c.Set("access_token_id", principal.AccessTokenID)
+			c.Set("token_scopes", principal.Scopes)
 		}
+		if principal.Method == AuthMethodJWT && sessions != nil {
+			c.Set("session_id", principal.SessionID)
+		}
+
+		c.Next()
+	}
+}
 
-		c.Set("user_id", claims["user_id"])
-		c.Set("username", claims["username"])
-		c.Set("role", claims["role"])
+// TokenHasScope is true for JWT requests and for access tokens granted scope
+func TokenHasScope(c *gin.Context, scope string) bool {
+	if c.GetString("auth_method") != AuthMethodAccessToken {
+		return true
+	}
+	scopes, _ := c.Get("token_scopes")
+	return Domain.AccessToken{Scopes: scopes.([]string)}.HasScope(scope)
+}
 
+// InteractiveOnly keeps access tokens away from routes that manage the
+// account's credentials, so a leaked token can't be used to mint more or
+// to take the account over
+func InteractiveOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("auth_method") == AuthMethodAccessToken {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This route requires logging in; access tokens are not accepted"})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
@@ -56,6 +138,11 @@ func AdminMiddleware() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		if !TokenHasScope(c, Domain.ScopeAdmin) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access token lacks the admin scope"})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
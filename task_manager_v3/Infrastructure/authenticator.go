@@ -0,0 +1,120 @@
+package Infrastructure
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Principal is who a bearer token was issued to
+type Principal struct {
+	UserID   string
+	Username string
+	Role     string
+	// Method is AuthMethodJWT or AuthMethodAccessToken
+	Method string
+	// AccessTokenID and Scopes are set for access tokens only
+	AccessTokenID string
+	Scopes        []string
+	// SessionID is set for JWTs when sessions are checked
+	SessionID string
+}
+
+// HasScope is true for JWTs and for access tokens granted scope
+func (p Principal) HasScope(scope string) bool {
+	if p.Method != AuthMethodAccessToken {
+		return true
+	}
+	return Domain.AccessToken{Scopes: p.Scopes}.HasScope(scope)
+}
+
+// AuthError is a rejected bearer token. Status is the HTTP status the
+// rejection maps to: 401 for bad credentials, 403 for a missing scope.
+type AuthError struct {
+	Status  int
+	Message string
+}
+
+func (e *AuthError) Error() string {
+	return e.Message
+}
+
+func unauthenticated(message string) error {
+	return &AuthError{Status: http.StatusUnauthorized, Message: message}
+}
+
+// Authenticator checks bearer tokens the same way for every transport. The
+// optional dependencies behave as described on AuthMiddleware.
+type Authenticator struct {
+	jwtService JWTService
+	accounts   AccountLookup
+	tokens     AccessTokenAuthenticator
+	sessions   SessionChecker
+}
+
+func NewAuthenticator(jwtService JWTService, accounts AccountLookup, tokens AccessTokenAuthenticator, sessions SessionChecker) *Authenticator {
+	return &Authenticator{jwtService: jwtService, accounts: accounts, tokens: tokens, sessions: sessions}
+}
+
+// Authenticate resolves a bearer token sent from ip. Access tokens must
+// have scope, which is the read scope for calls that change nothing and
+// the write scope otherwise. Failures are *AuthError.
+func (a *Authenticator) Authenticate(tokenString, ip, scope string) (Principal, error) {
+	var principal Principal
+	if a.tokens != nil && strings.HasPrefix(tokenString, Domain.AccessTokenPrefix) {
+		accessToken, err := a.tokens.Authenticate(tokenString, ip)
+		if err != nil {
+			return Principal{}, unauthenticated(Domain.ErrInvalidAccessToken.Error())
+		}
+		if !accessToken.HasScope(scope) {
+			return Principal{}, &AuthError{Status: http.StatusForbidden, Message: "Access token lacks the " + scope + " scope"}
+		}
+		principal = Principal{
+			UserID:        accessToken.UserID.Hex(),
+			Method:        AuthMethodAccessToken,
+			AccessTokenID: accessToken.ID.Hex(),
+			Scopes:        accessToken.Scopes,
+		}
+	} else {
+		token, err := a.jwtService.ValidateToken(tokenString)
+		if err != nil || !token.Valid {
+			return Principal{}, unauthenticated("Invalid JWT token")
+		}
+
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok {
+			return Principal{}, unauthenticated("Invalid JWT claims")
+		}
+
+		if pending, _ := claims["mfa_pending"].(bool); pending {
+			return Principal{}, unauthenticated("Two-factor authentication is not complete")
+		}
+
+		principal.UserID, _ = claims["user_id"].(string)
+		principal.Username, _ = claims["username"].(string)
+		principal.Role, _ = claims["role"].(string)
+		principal.Method = AuthMethodJWT
+		if a.sessions != nil {
+			principal.SessionID, _ = claims["sid"].(string)
+			if err := a.sessions.Check(principal.SessionID, principal.UserID); err != nil {
+				return Principal{}, unauthenticated("Session has ended; log in again")
+			}
+		}
+	}
+
+	if a.accounts != nil {
+		user, err := a.accounts.ActiveAccount(principal.UserID)
+		if errors.Is(err, Domain.ErrMFAEnforced) {
+			return Principal{}, unauthenticated("Two-factor authentication is now required; log in again to set it up")
+		}
+		if err != nil {
+			return Principal{}, unauthenticated("Account is no longer active")
+		}
+		principal.Username = user.Username
+		principal.Role = user.Role
+	}
+	return principal, nil
+}
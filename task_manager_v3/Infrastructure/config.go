@@ -0,0 +1,491 @@
+package Infrastructure
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is every setting the server reads at startup. LoadConfig layers
+// it from, lowest precedence first: DefaultConfig, a YAML or TOML file, a
+// .env file and the environment. Each field's env tag names the variable
+// that sets it; lists are comma separated and maps are key=value pairs.
+// Fields tagged secret are hidden by Redacted.
+type Config struct {
+	Server       ServerConfig       `yaml:"server" toml:"server"`
+	Database     DatabaseConfig     `yaml:"database" toml:"database"`
+	Auth         AuthConfig         `yaml:"auth" toml:"auth"`
+	Bootstrap    BootstrapConfig    `yaml:"bootstrap" toml:"bootstrap"`
+	Registration RegistrationConfig `yaml:"registration" toml:"registration"`
+	Mail         MailConfig         `yaml:"mail" toml:"mail"`
+	Attachments  AttachmentConfig   `yaml:"attachments" toml:"attachments"`
+	Events       EventConfig        `yaml:"events" toml:"events"`
+	RateLimit    RateLimitConfig    `yaml:"rate_limit" toml:"rate_limit"`
+	GraphQL      GraphQLConfig      `yaml:"graphql" toml:"graphql"`
+	SSO          SSOConfig          `yaml:"sso" toml:"sso"`
+	API          APIConfig          `yaml:"api" toml:"api"`
+}
+
+type ServerConfig struct {
+	// Port and GRPCPort are listen addresses; a bare port number listens on
+	// every interface
+	Port     string `yaml:"port" toml:"port" env:"PORT"`
+	GRPCPort string `yaml:"grpc_port" toml:"grpc_port" env:"GRPC_PORT"`
+	// BaseURL is where users reach the app, for links in emails and the
+	// single sign-on callback
+	BaseURL string `yaml:"base_url" toml:"base_url" env:"APP_BASE_URL"`
+	// TrustedProxies are the IPs or CIDRs of the load balancers in front of
+	// the server. Only requests from them may name the client IP in
+	// X-Forwarded-For; by default none may, since anyone can send it.
+	TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+}
+
+type DatabaseConfig struct {
+	URI  string `yaml:"uri" toml:"uri" env:"MONGODB_URI" secret:"userinfo"`
+	Name string `yaml:"name" toml:"name" env:"MONGODB_DATABASE"`
+}
+
+type AuthConfig struct {
+	// JWTSecret signs access tokens. It has no default: anyone who knows it
+	// can mint tokens for any account.
+	JWTSecret  string `yaml:"jwt_secret" toml:"jwt_secret" env:"JWT_SECRET" secret:"true"`
+	BcryptCost int    `yaml:"bcrypt_cost" toml:"bcrypt_cost" env:"BCRYPT_COST"`
+	// MFAIssuer names the app in authenticator apps
+	MFAIssuer string `yaml:"mfa_issuer" toml:"mfa_issuer" env:"MFA_ISSUER"`
+}
+
+// BootstrapConfig creates the initial admin at startup, when set. It is
+// safe to leave set: once an admin exists nothing happens.
+type BootstrapConfig struct {
+	AdminUsername string `yaml:"admin_username" toml:"admin_username" env:"BOOTSTRAP_ADMIN_USERNAME"`
+	AdminPassword string `yaml:"admin_password" toml:"admin_password" env:"BOOTSTRAP_ADMIN_PASSWORD" secret:"true"`
+}
+
+// RegistrationConfig decides who may register without an invitation:
+// nobody in "invite" mode, anyone in "open" mode, and emails under Domains
+// in "domain" mode
+type RegistrationConfig struct {
+	Mode    string   `yaml:"mode" toml:"mode" env:"REGISTRATION_MODE"`
+	Domains []string `yaml:"domains" toml:"domains" env:"REGISTRATION_DOMAINS"`
+}
+
+// Policy is the registration policy the usecases apply
+func (c RegistrationConfig) Policy() Domain.RegistrationPolicy {
+	policy := Domain.RegistrationPolicy{Mode: c.Mode}
+	if c.Mode == Domain.RegistrationDomain {
+		policy.AllowedDomains = c.Domains
+	}
+	return policy
+}
+
+// MailConfig sends mail through SMTPAddr (host:port). Without it messages
+// are only kept in memory, which is fine for development only.
+type MailConfig struct {
+	SMTPAddr     string `yaml:"smtp_addr" toml:"smtp_addr" env:"SMTP_ADDR"`
+	SMTPUsername string `yaml:"smtp_username" toml:"smtp_username" env:"SMTP_USERNAME"`
+	SMTPPassword string `yaml:"smtp_password" toml:"smtp_password" env:"SMTP_PASSWORD" secret:"true"`
+	From         string `yaml:"from" toml:"from" env:"MAIL_FROM"`
+}
+
+// AttachmentConfig picks where attachment content lives: "local" keeps
+// files under Dir, "gridfs" stores them in MongoDB next to the metadata
+type AttachmentConfig struct {
+	Store string `yaml:"store" toml:"store" env:"BLOB_STORE"`
+	Dir   string `yaml:"dir" toml:"dir" env:"ATTACHMENT_DIR"`
+	// MaxBytes of 0 keeps the usecase's default
+	MaxBytes int64 `yaml:"max_bytes" toml:"max_bytes" env:"ATTACHMENT_MAX_BYTES"`
+}
+
+// EventConfig picks the task event bus: "memory" for a single node,
+// "mongo" to share events across instances through a change stream (which
+// needs a replica set)
+type EventConfig struct {
+	Bus string `yaml:"bus" toml:"bus" env:"EVENT_BUS"`
+}
+
+// RateLimitConfig holds the rate limits in the format of
+// ParseRateLimitPolicies, or "off", and where buckets live: "memory" (per
+// instance) or "mongo" (shared by all instances)
+type RateLimitConfig struct {
+	Limits string `yaml:"limits" toml:"limits" env:"RATE_LIMITS"`
+	Store  string `yaml:"store" toml:"store" env:"RATE_LIMIT_STORE"`
+}
+
+// GraphQLConfig limits queries; 0 keeps the controller's defaults
+type GraphQLConfig struct {
+	MaxDepth      int `yaml:"max_depth" toml:"max_depth" env:"GRAPHQL_MAX_DEPTH"`
+	MaxComplexity int `yaml:"max_complexity" toml:"max_complexity" env:"GRAPHQL_MAX_COMPLEXITY"`
+}
+
+// SSOConfig turns on OIDC sign-in when IssuerURL is set
+type SSOConfig struct {
+	IssuerURL    string `yaml:"issuer_url" toml:"issuer_url" env:"OIDC_ISSUER_URL"`
+	ClientID     string `yaml:"client_id" toml:"client_id" env:"OIDC_CLIENT_ID"`
+	ClientSecret string `yaml:"client_secret" toml:"client_secret" env:"OIDC_CLIENT_SECRET" secret:"true"`
+	// RedirectURL defaults to the server's BaseURL + /api/v1/auth/oidc/callback
+	RedirectURL string `yaml:"redirect_url" toml:"redirect_url" env:"OIDC_REDIRECT_URL"`
+	// Scopes are requested besides openid (default profile and email)
+	Scopes []string `yaml:"scopes" toml:"scopes" env:"OIDC_SCOPES"`
+	// GroupsClaim is the ID token claim holding groups (default groups)
+	GroupsClaim string `yaml:"groups_claim" toml:"groups_claim" env:"OIDC_GROUPS_CLAIM"`
+	// GroupRoles maps groups to roles, e.g. "task-admins=admin,staff=user"
+	GroupRoles map[string]string `yaml:"group_roles" toml:"group_roles" env:"OIDC_GROUP_ROLES"`
+	// Provision creates accounts on first sign-in; without it only
+	// existing, linked accounts get in
+	Provision bool `yaml:"provision" toml:"provision" env:"OIDC_PROVISION"`
+}
+
+// APIConfig dates the retirement of the paths at the root, which serve v1
+// for clients that predate versioning
+type APIConfig struct {
+	UnversionedDeprecated time.Time `yaml:"unversioned_deprecated" toml:"unversioned_deprecated" env:"API_UNVERSIONED_DEPRECATED"`
+	UnversionedSunset     time.Time `yaml:"unversioned_sunset" toml:"unversioned_sunset" env:"API_UNVERSIONED_SUNSET"`
+}
+
+// DefaultRateLimits guards the login and signup routes against guessing,
+// caps everything else per client, and caps each IP's authenticated
+// requests before their credentials are checked
+const DefaultRateLimits = "POST /login=10/1m,by=ip;" +
+	"POST /login/mfa=10/1m,by=ip;" +
+	"POST /register=5/1m,by=ip;" +
+	"POST /password/forgot=5/1m,by=ip;" +
+	"POST /password/reset=10/1m,by=ip;" +
+	"POST /tasks=60/1m,burst=20,by=api_key;" +
+	"*=600/1m,burst=100,by=api_key;" +
+	"auth=1200/1m,burst=200,by=ip"
+
+// DefaultConfig is every setting before any file or variable is read
+func DefaultConfig() Config {
+	return Config{
+		Server: ServerConfig{
+			Port:     ":8080",
+			GRPCPort: ":9090",
+			BaseURL:  "http://localhost:8080",
+		},
+		Database: DatabaseConfig{
+			URI:  "mongodb://localhost:27017",
+			Name: "task_manager",
+		},
+		Auth:         AuthConfig{BcryptCost: 14},
+		Registration: RegistrationConfig{Mode: Domain.RegistrationInviteOnly},
+		Attachments:  AttachmentConfig{Store: "local", Dir: "data/attachments"},
+		Events:       EventConfig{Bus: "memory"},
+		RateLimit:    RateLimitConfig{Limits: DefaultRateLimits, Store: "memory"},
+		SSO:          SSOConfig{Provision: true},
+		API: APIConfig{
+			UnversionedDeprecated: time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC),
+			UnversionedSunset:     time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC),
+		},
+	}
+}
+
+// LoadConfig layers DefaultConfig, the file at path (YAML or TOML by its
+// extension), the .env file at dotEnvPath and the environment. Either path
+// may be empty to skip it, and a missing .env file is not an error. Empty
+// variables count as unset. The result still needs Validate.
+func LoadConfig(path, dotEnvPath string) (Config, error) {
+	config := DefaultConfig()
+	if path != "" {
+		if err := config.decodeFile(path); err != nil {
+			return config, err
+		}
+	}
+
+	dotEnv := map[string]string{}
+	if dotEnvPath != "" {
+		var err error
+		if dotEnv, err = godotenv.Read(dotEnvPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return config, fmt.Errorf("reading %s: %w", dotEnvPath, err)
+		}
+	}
+	lookup := func(name string) string {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+		return dotEnv[name]
+	}
+	if err := applyEnv(reflect.ValueOf(&config).Elem(), lookup); err != nil {
+		return config, err
+	}
+
+	config.Server.Port = listenAddr(config.Server.Port)
+	config.Server.GRPCPort = listenAddr(config.Server.GRPCPort)
+	return config, nil
+}
+
+// decodeFile overlays the settings in a YAML or TOML file. Unknown keys are
+// errors so that typos don't go unnoticed.
+func (c *Config) decodeFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(c)
+		if errors.Is(err, io.EOF) {
+			// An empty file changes nothing
+			err = nil
+		}
+	case ".toml":
+		decoder := toml.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(c)
+		var strict *toml.StrictMissingError
+		if errors.As(err, &strict) {
+			keys := make([]string, 0, len(strict.Errors))
+			for _, unknown := range strict.Errors {
+				keys = append(keys, strings.Join(unknown.Key(), "."))
+			}
+			err = fmt.Errorf("unknown settings %s", strings.Join(keys, ", "))
+		}
+	default:
+		return fmt.Errorf("config file %s: expected a .yaml, .yml or .toml extension", path)
+	}
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv sets every field with an env tag whose variable is set
+func applyEnv(v reflect.Value, lookup func(string) string) error {
+	for i := 0; i < v.NumField(); i++ {
+		field, value := v.Type().Field(i), v.Field(i)
+		name := field.Tag.Get("env")
+		if name == "" {
+			if field.Type.Kind() == reflect.Struct {
+				if err := applyEnv(value, lookup); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		raw := lookup(name)
+		if raw == "" {
+			continue
+		}
+		if err := setFromEnv(value, raw); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func setFromEnv(value reflect.Value, raw string) error {
+	switch value.Interface().(type) {
+	case string:
+		value.SetString(raw)
+	case int, int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", raw)
+		}
+		value.SetInt(n)
+	case bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", raw)
+		}
+		value.SetBool(b)
+	case []string:
+		value.Set(reflect.ValueOf(splitList(raw)))
+	case map[string]string:
+		pairs := map[string]string{}
+		for _, pair := range splitList(raw) {
+			key, item, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("%q is not a key=value pair", pair)
+			}
+			pairs[strings.TrimSpace(key)] = strings.TrimSpace(item)
+		}
+		value.Set(reflect.ValueOf(pairs))
+	case time.Time:
+		t, err := parseConfigTime(strings.TrimSpace(raw))
+		if err != nil {
+			return err
+		}
+		value.Set(reflect.ValueOf(t))
+	default:
+		return fmt.Errorf("unsupported setting type %s", value.Type())
+	}
+	return nil
+}
+
+// parseConfigTime accepts RFC 3339 timestamps and plain dates, taken as
+// midnight UTC
+func parseConfigTime(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, raw); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date or an RFC 3339 timestamp", raw)
+}
+
+// splitList splits a comma-separated setting, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// listenAddr turns a bare port number into an address on every interface
+func listenAddr(port string) string {
+	if port != "" && !strings.Contains(port, ":") {
+		return ":" + port
+	}
+	return port
+}
+
+// insecureJWTSecret is the default JWT_SECRET used to have, which is public
+const insecureJWTSecret = "your_jwt_secret_key"
+
+// minJWTSecretLength is 256 bits, the size of an HS256 key
+const minJWTSecretLength = 32
+
+// Validate reports every setting that is missing, malformed or
+// contradicts another, all at once
+func (c Config) Validate() error {
+	var problems []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Errorf(format, args...))
+		}
+	}
+	oneOf := func(name, value string, allowed ...string) {
+		for _, option := range allowed {
+			if value == option {
+				return
+			}
+		}
+		problems = append(problems, fmt.Errorf("%s is %q, expected one of %s", name, value, strings.Join(allowed, ", ")))
+	}
+
+	for name, addr := range map[string]string{"server.port": c.Server.Port, "server.grpc_port": c.Server.GRPCPort} {
+		_, _, err := net.SplitHostPort(addr)
+		check(err == nil, "%s %q is not a listen address like :8080", name, addr)
+	}
+	check(c.Server.Port != c.Server.GRPCPort, "server.port and server.grpc_port are both %s", c.Server.Port)
+	check(isAbsoluteURL(c.Server.BaseURL), "server.base_url %q is not an absolute URL", c.Server.BaseURL)
+	for _, proxy := range c.Server.TrustedProxies {
+		_, _, err := net.ParseCIDR(proxy)
+		check(err == nil || net.ParseIP(proxy) != nil, "server.trusted_proxies entry %q is not an IP or CIDR", proxy)
+	}
+
+	check(c.Database.URI != "", "database.uri is required")
+	check(c.Database.Name != "", "database.name is required")
+
+	switch {
+	case c.Auth.JWTSecret == "":
+		problems = append(problems, errors.New("auth.jwt_secret is required"))
+	case c.Auth.JWTSecret == insecureJWTSecret:
+		problems = append(problems, errors.New("auth.jwt_secret is the published example value; generate a random one"))
+	case len(c.Auth.JWTSecret) < minJWTSecretLength:
+		problems = append(problems, fmt.Errorf("auth.jwt_secret must be at least %d bytes", minJWTSecretLength))
+	}
+	check(c.Auth.BcryptCost >= bcrypt.MinCost && c.Auth.BcryptCost <= bcrypt.MaxCost,
+		"auth.bcrypt_cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+
+	oneOf("registration.mode", c.Registration.Mode, Domain.RegistrationInviteOnly, Domain.RegistrationOpen, Domain.RegistrationDomain)
+	check(c.Registration.Mode != Domain.RegistrationDomain || len(c.Registration.Domains) > 0,
+		"registration.mode domain needs registration.domains")
+
+	oneOf("attachments.store", c.Attachments.Store, "local", "gridfs")
+	check(c.Attachments.Store != "local" || c.Attachments.Dir != "", "attachments.dir is required for the local store")
+	check(c.Attachments.MaxBytes >= 0, "attachments.max_bytes must not be negative")
+
+	oneOf("events.bus", c.Events.Bus, "memory", "mongo")
+
+	if c.RateLimit.Limits != "off" {
+		_, err := ParseRateLimitPolicies(c.RateLimit.Limits)
+		check(err == nil, "rate_limit.limits: %v", err)
+	}
+	oneOf("rate_limit.store", c.RateLimit.Store, "memory", "mongo")
+
+	check(c.GraphQL.MaxDepth >= 0, "graphql.max_depth must not be negative")
+	check(c.GraphQL.MaxComplexity >= 0, "graphql.max_complexity must not be negative")
+
+	if c.SSO.IssuerURL != "" {
+		check(isAbsoluteURL(c.SSO.IssuerURL), "sso.issuer_url %q is not an absolute URL", c.SSO.IssuerURL)
+		check(c.SSO.ClientID != "", "sso.client_id is required with sso.issuer_url")
+		check(c.SSO.RedirectURL == "" || isAbsoluteURL(c.SSO.RedirectURL), "sso.redirect_url %q is not an absolute URL", c.SSO.RedirectURL)
+		for group, role := range c.SSO.GroupRoles {
+			check(Domain.ValidRole(role), "sso.group_roles maps %q to %q, expected admin or user", group, role)
+		}
+	}
+
+	check(c.API.UnversionedSunset.After(c.API.UnversionedDeprecated), "api.unversioned_sunset must be after api.unversioned_deprecated")
+
+	return errors.Join(problems...)
+}
+
+func isAbsoluteURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+// redactedValue replaces secrets in Redacted
+const redactedValue = "[REDACTED]"
+
+// Redacted is a copy safe to print or log: secrets that are set become
+// [REDACTED], and passwords in URIs are masked
+func (c Config) Redacted() Config {
+	redact(reflect.ValueOf(&c).Elem())
+	return c
+}
+
+func redact(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		field, value := v.Type().Field(i), v.Field(i)
+		switch field.Tag.Get("secret") {
+		case "true":
+			if value.String() != "" {
+				value.SetString(redactedValue)
+			}
+		case "userinfo":
+			if u, err := url.Parse(value.String()); err == nil {
+				value.SetString(u.Redacted())
+			} else if value.String() != "" {
+				value.SetString(redactedValue)
+			}
+		default:
+			if field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Time{}) {
+				redact(value)
+			}
+		}
+	}
+}
+
+// Print writes the configuration as YAML with its secrets redacted, in the
+// shape a config file takes
+func (c Config) Print(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c.Redacted()); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
@@ -0,0 +1,129 @@
+package Infrastructure
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventBus carries task change events from the usecases to streaming clients
+type EventBus interface {
+	Publish(event Domain.TaskEvent) (Domain.TaskEvent, error)
+	// Subscribe returns a channel of events published after lastEventID.
+	// An empty lastEventID means "only new events". When the events after
+	// lastEventID can't be replayed, the channel starts with a
+	// Domain.TaskEventsReset event instead. The channel is closed when ctx
+	// is done or when the subscriber falls too far behind.
+	Subscribe(ctx context.Context, lastEventID string) (<-chan Domain.TaskEvent, error)
+}
+
+const subscriberBuffer = 64
+
+type inMemoryEventBus struct {
+	mu sync.Mutex
+	// epoch tells this process's event IDs from those of an earlier run,
+	// whose sequence numbers started over
+	epoch       string
+	seq         uint64
+	history     []Domain.TaskEvent
+	historySize int
+	subscribers map[chan Domain.TaskEvent]struct{}
+}
+
+// NewInMemoryEventBus returns a single-node event bus that keeps the last
+// historySize events around so reconnecting clients can resume.
+func NewInMemoryEventBus(historySize int) EventBus {
+	if historySize <= 0 {
+		historySize = 1024
+	}
+	return &inMemoryEventBus{
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		historySize: historySize,
+		subscribers: make(map[chan Domain.TaskEvent]struct{}),
+	}
+}
+
+func (b *inMemoryEventBus) Publish(event Domain.TaskEvent) (Domain.TaskEvent, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	event.ID = b.eventID(b.seq)
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	b.history = append(b.history, event)
+	if len(b.history) > b.historySize {
+		b.history = b.history[len(b.history)-b.historySize:]
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// Slow consumer: drop it and let the client resume with Last-Event-ID
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+	return event, nil
+}
+
+func (b *inMemoryEventBus) Subscribe(ctx context.Context, lastEventID string) (<-chan Domain.TaskEvent, error) {
+	b.mu.Lock()
+
+	var backlog []Domain.TaskEvent
+	if lastEventID != "" {
+		var ok bool
+		if backlog, ok = b.after(lastEventID); !ok {
+			backlog = []Domain.TaskEvent{{ID: b.eventID(b.seq), Type: Domain.TaskEventsReset, OccurredAt: time.Now()}}
+		}
+	}
+
+	ch := make(chan Domain.TaskEvent, subscriberBuffer+len(backlog))
+	for _, event := range backlog {
+		ch <- event
+	}
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}()
+
+	return ch, nil
+}
+
+// eventID formats the ID of the seq'th event of this run
+func (b *inMemoryEventBus) eventID(seq uint64) string {
+	return b.epoch + "-" + strconv.FormatUint(seq, 10)
+}
+
+// after returns the history following lastEventID. It reports false when
+// that isn't known: the ID is from another run, or events after it have
+// already left the history. Callers hold the lock.
+func (b *inMemoryEventBus) after(lastEventID string) ([]Domain.TaskEvent, bool) {
+	epoch, seqPart, found := strings.Cut(lastEventID, "-")
+	if !found || epoch != b.epoch {
+		return nil, false
+	}
+	last, err := strconv.ParseUint(seqPart, 10, 64)
+	if err != nil || last > b.seq {
+		return nil, false
+	}
+	missed := b.seq - last
+	if missed > uint64(len(b.history)) {
+		return nil, false
+	}
+	return append([]Domain.TaskEvent{}, b.history[len(b.history)-int(missed):]...), true
+}
@@ -0,0 +1,170 @@
+package Infrastructure
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type principalKey struct{}
+
+// ContextWithPrincipal attaches the authenticated caller to ctx
+func ContextWithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the caller the gRPC auth interceptors
+// authenticated
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+// authenticateCall checks the bearer token in the "authorization" metadata
+// of a call. Methods in readMethods only need the read scope from access
+// tokens; every other method needs the write scope.
+func authenticateCall(ctx context.Context, authenticator *Authenticator, readMethods map[string]bool, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 || values[0] == "" {
+		return nil, status.Error(codes.Unauthenticated, "Authorization header is required")
+	}
+	authParts := strings.Split(values[0], " ")
+	if len(authParts) != 2 || strings.ToLower(authParts[0]) != "bearer" {
+		return nil, status.Error(codes.Unauthenticated, "Invalid authorization header format")
+	}
+
+	scope := Domain.ScopeWrite
+	if readMethods[method] {
+		scope = Domain.ScopeRead
+	}
+	principal, err := authenticator.Authenticate(authParts[1], peerIP(ctx), scope)
+	if err != nil {
+		var authErr *AuthError
+		errors.As(err, &authErr)
+		code := codes.Unauthenticated
+		if authErr.Status == http.StatusForbidden {
+			code = codes.PermissionDenied
+		}
+		return nil, status.Error(code, authErr.Message)
+	}
+	return ContextWithPrincipal(ctx, principal), nil
+}
+
+// peerIP is the address the call came from, without the port
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// principalStream hands handlers a context carrying the caller
+type principalStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s principalStream) Context() context.Context {
+	return s.ctx
+}
+
+// UnaryAuthInterceptor is AuthMiddleware for unary gRPC calls. The caller
+// is available to handlers through PrincipalFromContext.
+func UnaryAuthInterceptor(authenticator *Authenticator, readMethods map[string]bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticateCall(ctx, authenticator, readMethods, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuthInterceptor is UnaryAuthInterceptor for streaming calls. The
+// token is checked once, when the stream opens.
+func StreamAuthInterceptor(authenticator *Authenticator, readMethods map[string]bool) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticateCall(stream.Context(), authenticator, readMethods, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, principalStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// UnaryLoggingInterceptor logs every call with its outcome and duration
+func UnaryLoggingInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(ctx, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamLoggingInterceptor logs every stream once it ends
+func StreamLoggingInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, stream)
+		logCall(stream.Context(), info.FullMethod, start, err)
+		return err
+	}
+}
+
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	log.Printf("[gRPC] %s | %s | %v | %s", status.Code(err), method, time.Since(start), peerIP(ctx))
+}
+
+// splitMethod splits "/package.Service/Method" into service and method
+func splitMethod(fullMethod string) (string, string) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return "unknown", fullMethod
+	}
+	return service, method
+}
+
+// UnaryMetricsInterceptor counts every call in metrics.GRPCRequests. A nil
+// Metrics counts nothing.
+func UnaryMetricsInterceptor(metrics *Metrics) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		countCall(metrics, info.FullMethod, err)
+		return resp, err
+	}
+}
+
+// StreamMetricsInterceptor counts every stream once it ends
+func StreamMetricsInterceptor(metrics *Metrics) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		err := handler(srv, stream)
+		countCall(metrics, info.FullMethod, err)
+		return err
+	}
+}
+
+func countCall(metrics *Metrics, fullMethod string, err error) {
+	if metrics == nil {
+		return
+	}
+	service, method := splitMethod(fullMethod)
+	metrics.GRPCRequests.Inc(service, method, status.Code(err).String())
+}
@@ -3,14 +3,19 @@ package Infrastructure
 import (
 	"a2sv-backend/task_manager_v3/Domain"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
 )
 
 type JWTService interface {
-	GenerateToken(user Domain.User) (string, error)
+	// GenerateToken issues an access token. A non-empty sessionID binds it
+	// to that session as the "sid" claim.
+	GenerateToken(user Domain.User, sessionID string) (string, error)
+	// GenerateMFAToken issues the short-lived token that stands between a
+	// correct password and the second factor, carrying challengeID as its
+	// "jti" claim. AuthMiddleware refuses it.
+	GenerateMFAToken(user Domain.User, challengeID string) (string, error)
 	ValidateToken(tokenString string) (*jwt.Token, error)
 }
 
@@ -18,22 +23,40 @@ type jwtService struct {
 	secretKey []byte
 }
 
-func NewJWTService() JWTService {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "your_jwt_secret_key"
-	}
+// NewJWTService signs and checks tokens with secret, Config.Auth.JWTSecret
+func NewJWTService(secret string) JWTService {
 	return &jwtService{
 		secretKey: []byte(secret),
 	}
 }
 
-func (s *jwtService) GenerateToken(user Domain.User) (string, error) {
+// TokenTTL is how long access tokens are valid
+const TokenTTL = 72 * time.Hour
+
+func (s *jwtService) GenerateToken(user Domain.User, sessionID string) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id":  user.ID.Hex(),
 		"username": user.Username,
 		"role":     user.Role,
-		"exp":      time.Now().Add(time.Hour * 72).Unix(),
+		"exp":      time.Now().Add(TokenTTL).Unix(),
+	}
+	if sessionID != "" {
+		claims["sid"] = sessionID
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(s.secretKey)
+}
+
+// MFATokenTTL is how long a user has to enter their second factor
+const MFATokenTTL = 5 * time.Minute
+
+func (s *jwtService) GenerateMFAToken(user Domain.User, challengeID string) (string, error) {
+	claims := jwt.MapClaims{
+		"jti":         challengeID,
+		"user_id":     user.ID.Hex(),
+		"mfa_pending": true,
+		"exp":         time.Now().Add(MFATokenTTL).Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
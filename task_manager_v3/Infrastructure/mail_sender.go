@@ -0,0 +1,85 @@
+package Infrastructure
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MailMessage is a plain-text email
+type MailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type MailSender interface {
+	Send(message MailMessage) error
+}
+
+type smtpMailSender struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPMailSender sends through the SMTP server at addr (host:port),
+// authenticating with PLAIN auth when username is set
+func NewSMTPMailSender(addr, username, password, from string) MailSender {
+	var auth smtp.Auth
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &smtpMailSender{addr: addr, auth: auth, from: from}
+}
+
+func (s *smtpMailSender) Send(message MailMessage) error {
+	// Header values come partly from users; never let them add headers
+	if strings.ContainsAny(message.To+message.Subject, "\r\n") {
+		return errors.New("mail headers cannot contain line breaks")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.from)
+	fmt.Fprintf(&b, "To: %s\r\n", message.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+
+	return smtp.SendMail(s.addr, s.auth, s.from, []string{message.To}, []byte(b.String()))
+}
+
+// InMemoryOutbox keeps sent messages instead of delivering them, for tests
+// and local development
+type InMemoryOutbox struct {
+	mu       sync.Mutex
+	messages []MailMessage
+}
+
+func NewInMemoryOutbox() *InMemoryOutbox {
+	return &InMemoryOutbox{}
+}
+
+func (o *InMemoryOutbox) Send(message MailMessage) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.messages = append(o.messages, message)
+	return nil
+}
+
+// Messages returns a copy of everything sent so far
+func (o *InMemoryOutbox) Messages() []MailMessage {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return append([]MailMessage(nil), o.messages...)
+}
@@ -0,0 +1,122 @@
+package Infrastructure
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CounterVec is a family of counters told apart by label values, like a
+// Prometheus counter vector
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]uint64
+}
+
+// labelSeparator can't appear in label values that come from routes and
+// status codes
+const labelSeparator = "\xff"
+
+// Inc adds one to the counter with the given label values, in the order the
+// labels were declared
+func (v *CounterVec) Inc(values ...string) {
+	v.mu.Lock()
+	v.values[strings.Join(values, labelSeparator)]++
+	v.mu.Unlock()
+}
+
+// Value reads the counter with the given label values
+func (v *CounterVec) Value(values ...string) uint64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.values[strings.Join(values, labelSeparator)]
+}
+
+// Metrics holds the counters served at /metrics
+type Metrics struct {
+	// HTTPRequests counts requests by API version, method, route and status
+	HTTPRequests *CounterVec
+	// GRPCRequests counts gRPC calls by service, method and status code
+	GRPCRequests *CounterVec
+
+	counters []*CounterVec
+}
+
+func NewMetrics() *Metrics {
+	m := &Metrics{}
+	m.HTTPRequests = m.Counter("http_requests_total", "HTTP requests served, by API version, method, route and status code", "version", "method", "route", "code")
+	m.GRPCRequests = m.Counter("grpc_requests_total", "gRPC calls served, by service, method and status code", "service", "method", "code")
+	return m
+}
+
+// Counter registers a counter family
+func (m *Metrics) Counter(name, help string, labels ...string) *CounterVec {
+	v := &CounterVec{name: name, help: help, labels: labels, values: map[string]uint64{}}
+	m.counters = append(m.counters, v)
+	return v
+}
+
+// WriteTo writes every counter in the Prometheus text exposition format
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	for _, v := range m.counters {
+		fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s counter\n", v.name, v.help, v.name)
+
+		v.mu.Lock()
+		keys := make([]string, 0, len(v.values))
+		for key := range v.values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			pairs := make([]string, len(v.labels))
+			for i, value := range strings.Split(key, labelSeparator) {
+				pairs[i] = v.labels[i] + "=" + strconv.Quote(value)
+			}
+			fmt.Fprintf(&buf, "%s{%s} %d\n", v.name, strings.Join(pairs, ","), v.values[key])
+		}
+		v.mu.Unlock()
+	}
+	return buf.WriteTo(w)
+}
+
+// MetricsMiddleware counts every request once it has been handled. Requests
+// outside the API, like /metrics itself, have version "none"; requests that
+// matched no route have route "unmatched". A nil Metrics counts nothing.
+func MetricsMiddleware(metrics *Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if metrics == nil {
+			return
+		}
+		version := c.GetString("api_version")
+		if version == "" {
+			version = "none"
+		}
+		route := RoutePath(c)
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.HTTPRequests.Inc(version, c.Request.Method, route, strconv.Itoa(c.Writer.Status()))
+	}
+}
+
+// MetricsHandler serves the counters for Prometheus to scrape
+func MetricsHandler(metrics *Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		c.Status(http.StatusOK)
+		metrics.WriteTo(c.Writer)
+	}
+}
@@ -0,0 +1,165 @@
+package Infrastructure
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoEvent struct {
+	ID               primitive.ObjectID `bson:"_id"`
+	Domain.TaskEvent `bson:",inline"`
+}
+
+type mongoChangeStreamEventBus struct {
+	db *mongo.Database
+}
+
+// EventRetention is how long task_events keeps events, and so how far back
+// a reconnecting client can resume
+const EventRetention = 24 * time.Hour
+
+// NewMongoChangeStreamEventBus returns an event bus that stores events in the
+// task_events collection and tails it with a change stream, so every instance
+// behind a load balancer sees every event. Change streams need a replica set.
+// A TTL index drops events after EventRetention.
+func NewMongoChangeStreamEventBus(db *mongo.Database) EventBus {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := db.Collection("task_events").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "occurred_at", Value: 1}},
+		Options: options.Index().SetName("task_event_expiry").SetExpireAfterSeconds(int32(EventRetention.Seconds())),
+	})
+	if err != nil {
+		log.Printf("Could not create task event indexes: %v", err)
+	}
+	return &mongoChangeStreamEventBus{db: db}
+}
+
+func (b *mongoChangeStreamEventBus) Publish(event Domain.TaskEvent) (Domain.TaskEvent, error) {
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+	doc := mongoEvent{ID: primitive.NewObjectID(), TaskEvent: event}
+	if _, err := b.db.Collection("task_events").InsertOne(context.Background(), doc); err != nil {
+		return Domain.TaskEvent{}, err
+	}
+	event.ID = doc.ID.Hex()
+	return event, nil
+}
+
+func (b *mongoChangeStreamEventBus) Subscribe(ctx context.Context, lastEventID string) (<-chan Domain.TaskEvent, error) {
+	coll := b.db.Collection("task_events")
+
+	// Open the stream before replaying so nothing published in between is lost
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.M{"operationType": "insert"}}}}
+	stream, err := coll.Watch(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	backlog, reset, err := replayAfter(ctx, coll, lastEventID)
+	if err != nil {
+		stream.Close(context.Background())
+		return nil, err
+	}
+
+	ch := make(chan Domain.TaskEvent, subscriberBuffer)
+	go func() {
+		defer close(ch)
+		defer stream.Close(context.Background())
+
+		send := func(doc mongoEvent) bool {
+			event := doc.TaskEvent
+			event.ID = doc.ID.Hex()
+			select {
+			case ch <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		if reset {
+			reset := Domain.TaskEvent{Type: Domain.TaskEventsReset, OccurredAt: time.Now()}
+			select {
+			case ch <- reset:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		// Events published after the stream opened can be both in the
+		// backlog and on the stream. ObjectIDs from different instances
+		// aren't ordered, so duplicates are recognised by ID.
+		replayed := make(map[primitive.ObjectID]bool, len(backlog))
+		for _, doc := range backlog {
+			if !send(doc) {
+				return
+			}
+			replayed[doc.ID] = true
+		}
+
+		for stream.Next(ctx) {
+			var change struct {
+				FullDocument mongoEvent `bson:"fullDocument"`
+			}
+			if err := stream.Decode(&change); err != nil {
+				return
+			}
+			// Skip anything the replay already delivered. The stream is in
+			// insertion order, so the first event the backlog didn't have
+			// means the overlap is over.
+			if replayed != nil {
+				if replayed[change.FullDocument.ID] {
+					continue
+				}
+				replayed = nil
+			}
+			if !send(change.FullDocument) {
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// replayAfter loads the events stored after lastEventID. It reports reset
+// when that event isn't known, because the ID is malformed or the event
+// has expired, so what came after it can't be told.
+func replayAfter(ctx context.Context, coll *mongo.Collection, lastEventID string) ([]mongoEvent, bool, error) {
+	if lastEventID == "" {
+		return nil, false, nil
+	}
+	lastID, err := primitive.ObjectIDFromHex(lastEventID)
+	if err != nil {
+		return nil, true, nil
+	}
+	err = coll.FindOne(ctx, bson.M{"_id": lastID}).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var backlog []mongoEvent
+	opts := options.Find().SetSort(bson.M{"_id": 1})
+	cursor, err := coll.Find(ctx, bson.M{"_id": bson.M{"$gt": lastID}}, opts)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := cursor.All(ctx, &backlog); err != nil {
+		return nil, false, err
+	}
+	return backlog, false, nil
+}
@@ -0,0 +1,70 @@
+package Infrastructure
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoRateLimitStore struct {
+	db *mongo.Database
+}
+
+// NewMongoRateLimitStore keeps buckets in the rate_limits collection so all
+// instances share them. Each take is one atomic update; buckets that have
+// had time to refill completely are removed by a TTL index.
+func NewMongoRateLimitStore(db *mongo.Database) RateLimitStore {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := db.Collection("rate_limits").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetName("rate_limit_expiry").SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		log.Printf("Could not create rate limit indexes: %v", err)
+	}
+	return &mongoRateLimitStore{db: db}
+}
+
+func (s *mongoRateLimitStore) Take(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error) {
+	burst := float64(limit.Burst)
+	// Tokens after refilling for the time since the last take; a new
+	// bucket starts full
+	elapsedMillis := bson.M{"$max": bson.A{0, bson.M{"$subtract": bson.A{now, bson.M{"$ifNull": bson.A{"$updated_at", now}}}}}}
+	refilled := bson.M{"$min": bson.A{burst, bson.M{"$add": bson.A{
+		bson.M{"$ifNull": bson.A{"$tokens", burst}},
+		bson.M{"$multiply": bson.A{bson.M{"$divide": bson.A{elapsedMillis, 1000}}, limit.refill()}},
+	}}}}
+	emptyToFull := time.Duration(burst / limit.refill() * float64(time.Second))
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"tokens": refilled}}},
+		{{Key: "$set", Value: bson.M{"allowed": bson.M{"$gte": bson.A{"$tokens", 1}}}}},
+		{{Key: "$set", Value: bson.M{
+			"tokens":     bson.M{"$cond": bson.A{"$allowed", bson.M{"$subtract": bson.A{"$tokens", 1}}, "$tokens"}},
+			"updated_at": now,
+			"expires_at": now.Add(emptyToFull),
+		}}},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var bucket struct {
+		Tokens  float64 `bson:"tokens"`
+		Allowed bool    `bson:"allowed"`
+	}
+	err := s.db.Collection("rate_limits").FindOneAndUpdate(ctx, bson.M{"_id": key}, pipeline, opts).Decode(&bucket)
+	if mongo.IsDuplicateKeyError(err) {
+		// Two first requests raced to create the bucket; the loser now
+		// finds it
+		err = s.db.Collection("rate_limits").FindOneAndUpdate(ctx, bson.M{"_id": key}, pipeline, opts).Decode(&bucket)
+	}
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	return bucketResult(bucket.Allowed, bucket.Tokens, limit), nil
+}
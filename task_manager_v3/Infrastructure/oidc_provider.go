@@ -0,0 +1,163 @@
+package Infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// OIDCConfig describes the client registered with an OpenID Connect
+// provider. GroupsClaim names the ID token claim listing the user's groups,
+// "groups" by default.
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	GroupsClaim  string
+}
+
+// OIDCClaims is what the application uses from a verified ID token
+type OIDCClaims struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+	Groups            []string
+}
+
+// OIDCProvider runs the authorization-code flow with PKCE against one
+// provider
+type OIDCProvider interface {
+	Issuer() string
+	// AuthCodeURL is where to send the browser. nonce ends up in the ID
+	// token; verifier is the PKCE code verifier, kept server-side.
+	AuthCodeURL(state, nonce, verifier string) string
+	// Exchange redeems code and returns the claims of the ID token once its
+	// signature (against the provider's JWKS), issuer, audience, expiry and
+	// nonce are verified
+	Exchange(ctx context.Context, code, verifier, nonce string) (OIDCClaims, error)
+}
+
+type oidcProvider struct {
+	issuer      string
+	oauth       oauth2.Config
+	verifier    *oidc.IDTokenVerifier
+	groupsClaim string
+}
+
+// oidcTimeout bounds discovery and every call to the provider
+const oidcTimeout = 10 * time.Second
+
+// NewOIDCProvider fetches the provider's discovery document, which must
+// name the issuer exactly as configured
+func NewOIDCProvider(config OIDCConfig) (OIDCProvider, error) {
+	if config.IssuerURL == "" || config.ClientID == "" || config.RedirectURL == "" {
+		return nil, errors.New("OIDC needs an issuer URL, client ID and redirect URL")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), oidcTimeout)
+	defer cancel()
+
+	provider, err := oidc.NewProvider(ctx, config.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+
+	scopes := config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"profile", "email"}
+	}
+	groupsClaim := config.GroupsClaim
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+	return &oidcProvider{
+		issuer: config.IssuerURL,
+		oauth: oauth2.Config{
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			RedirectURL:  config.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       append([]string{oidc.ScopeOpenID}, scopes...),
+		},
+		verifier:    provider.Verifier(&oidc.Config{ClientID: config.ClientID}),
+		groupsClaim: groupsClaim,
+	}, nil
+}
+
+func (p *oidcProvider) Issuer() string {
+	return p.issuer
+}
+
+func (p *oidcProvider) AuthCodeURL(state, nonce, verifier string) string {
+	return p.oauth.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+}
+
+func (p *oidcProvider) Exchange(ctx context.Context, code, verifier, nonce string) (OIDCClaims, error) {
+	ctx, cancel := context.WithTimeout(ctx, oidcTimeout)
+	defer cancel()
+
+	token, err := p.oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return OIDCClaims{}, fmt.Errorf("code exchange failed: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return OIDCClaims{}, errors.New("token response has no id_token")
+	}
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return OIDCClaims{}, fmt.Errorf("invalid ID token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return OIDCClaims{}, errors.New("invalid ID token: nonce mismatch")
+	}
+
+	var standard struct {
+		Email             string `json:"email"`
+		Name              string `json:"name"`
+		PreferredUsername string `json:"preferred_username"`
+	}
+	var all map[string]interface{}
+	if err := idToken.Claims(&standard); err != nil {
+		return OIDCClaims{}, err
+	}
+	if err := idToken.Claims(&all); err != nil {
+		return OIDCClaims{}, err
+	}
+
+	return OIDCClaims{
+		Issuer:            idToken.Issuer,
+		Subject:           idToken.Subject,
+		Email:             standard.Email,
+		EmailVerified:     all["email_verified"] == true || all["email_verified"] == "true",
+		Name:              standard.Name,
+		PreferredUsername: standard.PreferredUsername,
+		Groups:            stringList(all[p.groupsClaim]),
+	}, nil
+}
+
+// stringList reads a claim that providers send either as a list of strings
+// or, with a single value, as a plain string
+func stringList(claim interface{}) []string {
+	switch value := claim.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		var list []string
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
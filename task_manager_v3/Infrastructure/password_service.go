@@ -7,14 +7,19 @@ type PasswordService interface {
 	ComparePassword(hashedPassword, password string) error
 }
 
-type bcryptPasswordService struct{}
+type bcryptPasswordService struct {
+	cost int
+}
 
-func NewBcryptPasswordService() PasswordService {
-	return &bcryptPasswordService{}
+// NewBcryptPasswordService hashes with the given bcrypt cost,
+// Config.Auth.BcryptCost. Raising it doesn't invalidate existing hashes,
+// which keep the cost they were made with.
+func NewBcryptPasswordService(cost int) PasswordService {
+	return &bcryptPasswordService{cost: cost}
 }
 
 func (s *bcryptPasswordService) HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), s.cost)
 	return string(bytes), err
 }
 
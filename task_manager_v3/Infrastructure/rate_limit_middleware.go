@@ -0,0 +1,105 @@
+package Infrastructure
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitStoreTimeout bounds each take, so a slow store can't stall
+// every request
+const rateLimitStoreTimeout = 500 * time.Millisecond
+
+// RateLimitMiddleware applies the limiter's policy for the matched route.
+// It reports the bucket in RateLimit-* headers and answers 429 when it is
+// empty. Policies counting by user or access token only see them when the
+// middleware runs after AuthMiddleware. Every API version of a route shares
+// its policy and buckets. A nil limiter limits nothing.
+//
+// If the store fails the request is let through: an outage of the limiter
+// shouldn't take the API down with it.
+func RateLimitMiddleware(limiter *RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limiter == nil {
+			c.Next()
+			return
+		}
+		policy, ok := limiter.policy(c.Request.Method, RoutePath(c))
+		if !ok {
+			c.Next()
+			return
+		}
+		limit(c, limiter, policy)
+	}
+}
+
+// PreAuthRateLimitMiddleware applies the limiter's RateLimitPreAuth policy
+// per client IP. It goes in front of AuthMiddleware so that requests with
+// invalid credentials, which never reach RateLimitMiddleware, are limited
+// before they cost a token or session lookup.
+func PreAuthRateLimitMiddleware(limiter *RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limiter == nil {
+			c.Next()
+			return
+		}
+		policy, ok := limiter.policies[RateLimitPreAuth]
+		if !ok {
+			c.Next()
+			return
+		}
+		limit(c, limiter, policy)
+	}
+}
+
+// limit takes a token from the request's bucket under policy and answers
+// 429 when there is none
+func limit(c *gin.Context, limiter *RateLimiter, policy RateLimitPolicy) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), rateLimitStoreTimeout)
+	result, err := limiter.store.Take(ctx, policy.Route+"|"+rateLimitKey(c, policy.KeyBy), policy.Limit, time.Now())
+	cancel()
+	if err != nil {
+		log.Printf("Rate limit store failed, not limiting: %v", err)
+		c.Next()
+		return
+	}
+
+	c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d;burst=%d", policy.Limit.Rate, int(policy.Limit.Period.Seconds()), policy.Limit.Burst))
+	c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+	if !result.Allowed {
+		retryAfter := ceilSeconds(result.RetryAfter)
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": fmt.Sprintf("Too many requests; retry in %d seconds", retryAfter)})
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
+
+// rateLimitKey identifies who a request is counted against
+func rateLimitKey(c *gin.Context, keyBy string) string {
+	if keyBy == RateLimitByAPIKey {
+		if tokenID := c.GetString("access_token_id"); tokenID != "" {
+			return "token:" + tokenID
+		}
+	}
+	if keyBy == RateLimitByUser || keyBy == RateLimitByAPIKey {
+		if userID := c.GetString("user_id"); userID != "" {
+			return "user:" + userID
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
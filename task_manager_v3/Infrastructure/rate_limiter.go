@@ -0,0 +1,258 @@
+package Infrastructure
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit is a token bucket holding up to Burst requests, refilled at
+// Rate requests per Period
+type RateLimit struct {
+	Rate   int
+	Period time.Duration
+	Burst  int
+}
+
+// refill is how many tokens the bucket gains per second
+func (l RateLimit) refill() float64 {
+	return float64(l.Rate) / l.Period.Seconds()
+}
+
+// RateLimitResult is the outcome of taking a token from a bucket
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until the next request would be allowed
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps the buckets
+type RateLimitStore interface {
+	// Take removes a token from the bucket named key, if it has one
+	Take(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error)
+}
+
+// bucketResult describes a bucket left with tokens after a take
+func bucketResult(allowed bool, tokens float64, limit RateLimit) RateLimitResult {
+	result := RateLimitResult{
+		Allowed:   allowed,
+		Limit:     limit.Burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     secondsDuration((float64(limit.Burst) - tokens) / limit.refill()),
+	}
+	if !allowed {
+		result.RetryAfter = secondsDuration((1 - tokens) / limit.refill())
+	}
+	return result
+}
+
+func secondsDuration(seconds float64) time.Duration {
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
+
+// rateLimitShards spreads buckets over independently locked maps so
+// concurrent requests for different keys rarely wait on each other
+const rateLimitShards = 64
+
+// rateLimitSweepInterval is how often a shard drops buckets that have
+// refilled completely, which are the same as no bucket at all
+const rateLimitSweepInterval = time.Minute
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time
+}
+
+type rateLimitShard struct {
+	mu        sync.Mutex
+	buckets   map[string]tokenBucket
+	lastSweep time.Time
+}
+
+type inMemoryRateLimitStore struct {
+	shards [rateLimitShards]*rateLimitShard
+}
+
+// NewInMemoryRateLimitStore keeps buckets in process memory. Each instance
+// counts on its own, so behind a load balancer the effective limit is
+// multiplied by the number of instances.
+func NewInMemoryRateLimitStore() RateLimitStore {
+	s := &inMemoryRateLimitStore{}
+	for i := range s.shards {
+		s.shards[i] = &rateLimitShard{buckets: make(map[string]tokenBucket)}
+	}
+	return s
+}
+
+func (s *inMemoryRateLimitStore) shard(key string) *rateLimitShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return s.shards[h.Sum32()%rateLimitShards]
+}
+
+func (s *inMemoryRateLimitStore) Take(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error) {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if now.Sub(shard.lastSweep) >= rateLimitSweepInterval {
+		for k, bucket := range shard.buckets {
+			if !now.Before(bucket.full) {
+				delete(shard.buckets, k)
+			}
+		}
+		shard.lastSweep = now
+	}
+
+	tokens := float64(limit.Burst)
+	if bucket, ok := shard.buckets[key]; ok {
+		elapsed := now.Sub(bucket.updated).Seconds()
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		tokens = math.Min(float64(limit.Burst), bucket.tokens+elapsed*limit.refill())
+	}
+	allowed := tokens >= 1
+	if allowed {
+		tokens--
+	}
+
+	result := bucketResult(allowed, tokens, limit)
+	shard.buckets[key] = tokenBucket{tokens: tokens, updated: now, full: now.Add(result.Reset)}
+	return result, nil
+}
+
+// What a rate limit policy counts requests by. Requests without a user or
+// access token fall back to the next broader key.
+const (
+	RateLimitByIP = "ip"
+	// RateLimitByUser counts per authenticated user, or per IP
+	RateLimitByUser = "user"
+	// RateLimitByAPIKey counts per access token, or per user, or per IP
+	RateLimitByAPIKey = "api_key"
+)
+
+// RateLimitPreAuth is the route of the policy that authenticated routes take
+// from per client IP before the credentials are checked, so requests with
+// bad tokens are throttled too
+const RateLimitPreAuth = "auth"
+
+// RateLimitPolicy limits one route. Route is "METHOD /path" or "/path" for
+// every method, with the path as registered (e.g. /tasks/:id); "*" is the
+// policy for all routes without one of their own, which share its buckets.
+// RateLimitPreAuth is the policy checked before authentication.
+type RateLimitPolicy struct {
+	Route string
+	Limit RateLimit
+	KeyBy string
+}
+
+// RateLimiter picks the policy for each request and takes from its bucket
+type RateLimiter struct {
+	store    RateLimitStore
+	policies map[string]RateLimitPolicy
+}
+
+func NewRateLimiter(store RateLimitStore, policies []RateLimitPolicy) *RateLimiter {
+	limiter := &RateLimiter{store: store, policies: make(map[string]RateLimitPolicy)}
+	for _, policy := range policies {
+		limiter.policies[policy.Route] = policy
+	}
+	return limiter
+}
+
+// policy finds the policy for a route, if any
+func (l *RateLimiter) policy(method, path string) (RateLimitPolicy, bool) {
+	for _, route := range []string{method + " " + path, path, "*"} {
+		if policy, ok := l.policies[route]; ok {
+			return policy, true
+		}
+	}
+	return RateLimitPolicy{}, false
+}
+
+// ParseRateLimitPolicies reads policies written as
+//
+//	ROUTE=RATE/PERIOD[,burst=N][,by=ip|user|api_key]
+//
+// separated by semicolons, e.g. "POST /login=5/1m,by=ip;*=600/1m". Burst
+// defaults to the rate, and by to user. The RateLimitPreAuth policy only
+// counts by ip.
+func ParseRateLimitPolicies(spec string) ([]RateLimitPolicy, error) {
+	var policies []RateLimitPolicy
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		route, rule, ok := strings.Cut(entry, "=")
+		route = strings.Join(strings.Fields(route), " ")
+		if !ok || route == "" {
+			return nil, fmt.Errorf("rate limit %q: expected ROUTE=RATE/PERIOD", entry)
+		}
+
+		options := strings.Split(rule, ",")
+		rate, period, ok := strings.Cut(strings.TrimSpace(options[0]), "/")
+		policy := RateLimitPolicy{Route: route, KeyBy: RateLimitByUser}
+		if route == RateLimitPreAuth {
+			policy.KeyBy = RateLimitByIP
+		}
+		var err error
+		if policy.Limit.Rate, err = strconv.Atoi(rate); !ok || err != nil || policy.Limit.Rate <= 0 {
+			return nil, fmt.Errorf("rate limit %q: rate must be a positive number of requests", entry)
+		}
+		if policy.Limit.Period, err = parsePeriod(period); err != nil {
+			return nil, fmt.Errorf("rate limit %q: %v", entry, err)
+		}
+		policy.Limit.Burst = policy.Limit.Rate
+
+		for _, option := range options[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(option), "=")
+			switch name {
+			case "burst":
+				if policy.Limit.Burst, err = strconv.Atoi(value); err != nil || policy.Limit.Burst <= 0 {
+					return nil, fmt.Errorf("rate limit %q: burst must be a positive number", entry)
+				}
+			case "by":
+				if value != RateLimitByIP && value != RateLimitByUser && value != RateLimitByAPIKey {
+					return nil, fmt.Errorf("rate limit %q: by must be ip, user or api_key", entry)
+				}
+				policy.KeyBy = value
+			default:
+				return nil, fmt.Errorf("rate limit %q: unknown option %q", entry, name)
+			}
+		}
+		if route == RateLimitPreAuth && policy.KeyBy != RateLimitByIP {
+			return nil, fmt.Errorf("rate limit %q: %s runs before authentication, so it can only be by ip", entry, RateLimitPreAuth)
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+// parsePeriod accepts Go durations ("30s", "1m") and the bare units s, m,
+// h and d
+func parsePeriod(period string) (time.Duration, error) {
+	units := map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour, "d": 24 * time.Hour}
+	if unit, ok := units[period]; ok {
+		return unit, nil
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid period %q", period)
+	}
+	return d, nil
+}
@@ -0,0 +1,523 @@
+package Infrastructure
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TaskFields are the columns used for CSV export and the keys every decoder
+// produces, in export order. labels is a comma-separated list of label IDs
+// and custom_fields a JSON object.
+var TaskFields = []string{
+	"id", "external_id", "title", "description", "due_date", "status",
+	"priority", "project", "labels", "custom_fields",
+}
+
+// ContentTypes maps each export format to its MIME type
+var ContentTypes = map[string]string{
+	Domain.FormatCSV:    "text/csv; charset=utf-8",
+	Domain.FormatJSON:   "application/json",
+	Domain.FormatNDJSON: "application/x-ndjson",
+	Domain.FormatICS:    "text/calendar; charset=utf-8",
+}
+
+// TaskEncoder writes tasks one at a time so exports never hold the whole
+// collection in memory. Close writes any trailer and flushes.
+type TaskEncoder interface {
+	Encode(task Domain.Task) error
+	Close() error
+}
+
+// TaskDecoder reads an import file one record at a time and returns io.EOF
+// once it is exhausted
+type TaskDecoder interface {
+	Next() (Domain.ImportRecord, error)
+}
+
+func NewTaskEncoder(format string, w io.Writer) (TaskEncoder, error) {
+	switch format {
+	case Domain.FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(TaskFields); err != nil {
+			return nil, err
+		}
+		return &csvEncoder{w: cw}, nil
+	case Domain.FormatJSON:
+		return &jsonEncoder{w: w}, nil
+	case Domain.FormatNDJSON:
+		return &ndjsonEncoder{enc: json.NewEncoder(w)}, nil
+	case Domain.FormatICS:
+		bw := bufio.NewWriter(w)
+		writeICSLine(bw, "BEGIN:VCALENDAR")
+		writeICSLine(bw, "VERSION:2.0")
+		writeICSLine(bw, "PRODID:-//a2sv-backend//task_manager_v3//EN")
+		return &icsEncoder{w: bw}, nil
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+// NewTaskDecoder reads format from r. mapping renames source columns or keys
+// to task field names, e.g. {"Summary": "title"}.
+func NewTaskDecoder(format string, r io.Reader, mapping map[string]string) (TaskDecoder, error) {
+	switch format {
+	case Domain.FormatCSV:
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		header, err := cr.Read()
+		if err != nil {
+			return nil, fmt.Errorf("reading CSV header: %w", err)
+		}
+		for i := range header {
+			header[i] = mapField(strings.TrimSpace(header[i]), mapping)
+		}
+		return &csvDecoder{r: cr, header: header}, nil
+	case Domain.FormatJSON:
+		dec := json.NewDecoder(r)
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("reading JSON: %w", err)
+		}
+		if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+			return nil, errors.New("JSON import must be an array of tasks")
+		}
+		return &jsonDecoder{dec: dec, mapping: mapping}, nil
+	case Domain.FormatNDJSON:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		return &ndjsonDecoder{scanner: scanner, mapping: mapping}, nil
+	case Domain.FormatICS:
+		return &icsDecoder{lines: newUnfolder(r)}, nil
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+func mapField(name string, mapping map[string]string) string {
+	if target, ok := mapping[name]; ok {
+		return target
+	}
+	return strings.ToLower(name)
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatLabels(labels []primitive.ObjectID) string {
+	ids := make([]string, len(labels))
+	for i, id := range labels {
+		ids[i] = id.Hex()
+	}
+	return strings.Join(ids, ",")
+}
+
+func formatCustomFields(values map[string]interface{}) (string, error) {
+	if len(values) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(values)
+	return string(data), err
+}
+
+// CSV
+
+// csvFormulaStarts are the first characters that make a spreadsheet read a
+// cell as a formula. The quote is included so that escaping round-trips.
+const csvFormulaStarts = "=+-@\t\r'"
+
+// csvEscape keeps a cell from running as a formula when the export is
+// opened in a spreadsheet, by prefixing it with a quote
+func csvEscape(cell string) string {
+	if cell != "" && strings.ContainsRune(csvFormulaStarts, rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// csvUnescape undoes csvEscape
+func csvUnescape(cell string) string {
+	if len(cell) > 1 && cell[0] == '\'' && strings.ContainsRune(csvFormulaStarts, rune(cell[1])) {
+		return cell[1:]
+	}
+	return cell
+}
+
+type csvEncoder struct {
+	w *csv.Writer
+}
+
+func (e *csvEncoder) Encode(task Domain.Task) error {
+	customFields, err := formatCustomFields(task.CustomFields)
+	if err != nil {
+		return err
+	}
+	return e.w.Write([]string{
+		task.ID.Hex(), csvEscape(task.ExternalID), csvEscape(task.Title), csvEscape(task.Description),
+		formatDate(task.DueDate), task.Status,
+		task.Priority, csvEscape(task.Project), formatLabels(task.Labels), csvEscape(customFields),
+	})
+}
+
+func (e *csvEncoder) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type csvDecoder struct {
+	r      *csv.Reader
+	header []string
+	row    int
+}
+
+func (d *csvDecoder) Next() (Domain.ImportRecord, error) {
+	values, err := d.r.Read()
+	if err == io.EOF {
+		return Domain.ImportRecord{}, io.EOF
+	}
+	d.row++
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return Domain.ImportRecord{Row: d.row, Err: parseErr.Err}, nil
+		}
+		return Domain.ImportRecord{}, err
+	}
+
+	fields := make(map[string]string, len(values))
+	for i, value := range values {
+		if i < len(d.header) {
+			fields[d.header[i]] = csvUnescape(value)
+		}
+	}
+	return Domain.ImportRecord{Row: d.row, Fields: fields}, nil
+}
+
+// JSON and NDJSON
+
+type jsonEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonEncoder) Encode(task Domain.Task) error {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	sep := ","
+	if e.count == 0 {
+		sep = "["
+	}
+	e.count++
+	if _, err := io.WriteString(e.w, sep); err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonEncoder) Close() error {
+	closing := "]"
+	if e.count == 0 {
+		closing = "[]"
+	}
+	_, err := io.WriteString(e.w, closing)
+	return err
+}
+
+type ndjsonEncoder struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonEncoder) Encode(task Domain.Task) error {
+	return e.enc.Encode(task)
+}
+
+func (e *ndjsonEncoder) Close() error {
+	return nil
+}
+
+// objectFields flattens a decoded JSON object into string values the way
+// CSV holds them: arrays become comma-separated lists and objects stay JSON
+func objectFields(obj map[string]interface{}, mapping map[string]string) map[string]string {
+	fields := make(map[string]string, len(obj))
+	for key, value := range obj {
+		switch v := value.(type) {
+		case nil:
+		case string:
+			fields[mapField(key, mapping)] = v
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			fields[mapField(key, mapping)] = strings.Join(items, ",")
+		case map[string]interface{}:
+			data, _ := json.Marshal(v)
+			fields[mapField(key, mapping)] = string(data)
+		default:
+			fields[mapField(key, mapping)] = fmt.Sprint(v)
+		}
+	}
+	return fields
+}
+
+type jsonDecoder struct {
+	dec     *json.Decoder
+	mapping map[string]string
+	row     int
+}
+
+func (d *jsonDecoder) Next() (Domain.ImportRecord, error) {
+	if !d.dec.More() {
+		return Domain.ImportRecord{}, io.EOF
+	}
+	d.row++
+	var obj map[string]interface{}
+	if err := d.dec.Decode(&obj); err != nil {
+		// A malformed element leaves the stream in an unknown state
+		return Domain.ImportRecord{}, fmt.Errorf("row %d: %w", d.row, err)
+	}
+	return Domain.ImportRecord{Row: d.row, Fields: objectFields(obj, d.mapping)}, nil
+}
+
+type ndjsonDecoder struct {
+	scanner *bufio.Scanner
+	mapping map[string]string
+	row     int
+}
+
+func (d *ndjsonDecoder) Next() (Domain.ImportRecord, error) {
+	for d.scanner.Scan() {
+		d.row++
+		line := strings.TrimSpace(d.scanner.Text())
+		if line == "" {
+			continue
+		}
+		var obj map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &obj); err != nil {
+			return Domain.ImportRecord{Row: d.row, Err: err}, nil
+		}
+		return Domain.ImportRecord{Row: d.row, Fields: objectFields(obj, d.mapping)}, nil
+	}
+	if err := d.scanner.Err(); err != nil {
+		return Domain.ImportRecord{}, err
+	}
+	return Domain.ImportRecord{}, io.EOF
+}
+
+// iCalendar (RFC 5545), one VTODO per task
+
+var icsStatus = map[string]string{
+	Domain.StatusPending:    "NEEDS-ACTION",
+	Domain.StatusInProgress: "IN-PROCESS",
+	Domain.StatusCompleted:  "COMPLETED",
+}
+
+// icsPriority maps priorities onto the 1-9 PRIORITY scale, 1 being highest.
+// Imports read 1-2 as P0, 3-4 as P1, 5-6 as P2 and 7-9 as P3.
+var icsPriority = map[string]int{
+	Domain.PriorityP0: 1,
+	Domain.PriorityP1: 3,
+	Domain.PriorityP2: 5,
+	Domain.PriorityP3: 7,
+}
+
+func priorityFromICS(value string) string {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	switch {
+	case err != nil:
+		return value
+	case n <= 0:
+		return ""
+	case n <= 2:
+		return Domain.PriorityP0
+	case n <= 4:
+		return Domain.PriorityP1
+	case n <= 6:
+		return Domain.PriorityP2
+	}
+	return Domain.PriorityP3
+}
+
+const icsDate = "20060102T150405Z"
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+var icsUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+
+// writeICSLine folds content lines at 75 octets as RFC 5545 requires
+func writeICSLine(w *bufio.Writer, line string) {
+	for len(line) > 75 {
+		cut := 75
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		w.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+	}
+	w.WriteString(line + "\r\n")
+}
+
+type icsEncoder struct {
+	w *bufio.Writer
+}
+
+func (e *icsEncoder) Encode(task Domain.Task) error {
+	uid := task.ExternalID
+	if uid == "" {
+		uid = task.ID.Hex() + "@task_manager_v3"
+	}
+	writeICSLine(e.w, "BEGIN:VTODO")
+	writeICSLine(e.w, "UID:"+icsEscaper.Replace(uid))
+	writeICSLine(e.w, "DTSTAMP:"+time.Now().UTC().Format(icsDate))
+	writeICSLine(e.w, "SUMMARY:"+icsEscaper.Replace(task.Title))
+	if task.Description != "" {
+		writeICSLine(e.w, "DESCRIPTION:"+icsEscaper.Replace(task.Description))
+	}
+	if !task.DueDate.IsZero() {
+		writeICSLine(e.w, "DUE:"+task.DueDate.UTC().Format(icsDate))
+	}
+	if status, ok := icsStatus[task.Status]; ok {
+		writeICSLine(e.w, "STATUS:"+status)
+	}
+	if priority, ok := icsPriority[task.Priority]; ok {
+		writeICSLine(e.w, "PRIORITY:"+strconv.Itoa(priority))
+	}
+	if task.Project != "" {
+		writeICSLine(e.w, "X-TASK-PROJECT:"+icsEscaper.Replace(task.Project))
+	}
+	if len(task.Labels) > 0 {
+		writeICSLine(e.w, "CATEGORIES:"+formatLabels(task.Labels))
+	}
+	customFields, err := formatCustomFields(task.CustomFields)
+	if err != nil {
+		return err
+	}
+	if customFields != "" {
+		writeICSLine(e.w, "X-TASK-CUSTOM-FIELDS:"+icsEscaper.Replace(customFields))
+	}
+	writeICSLine(e.w, "END:VTODO")
+	return nil
+}
+
+func (e *icsEncoder) Close() error {
+	writeICSLine(e.w, "END:VCALENDAR")
+	return e.w.Flush()
+}
+
+// unfolder joins folded iCalendar lines back together
+type unfolder struct {
+	scanner *bufio.Scanner
+	pending string
+	has     bool
+}
+
+func newUnfolder(r io.Reader) *unfolder {
+	return &unfolder{scanner: bufio.NewScanner(r)}
+}
+
+func (u *unfolder) next() (string, bool) {
+	for u.scanner.Scan() {
+		raw := strings.TrimRight(u.scanner.Text(), "\r")
+		if strings.HasPrefix(raw, " ") || strings.HasPrefix(raw, "\t") {
+			u.pending += raw[1:]
+			continue
+		}
+		if u.has {
+			line := u.pending
+			u.pending = raw
+			return line, true
+		}
+		u.pending, u.has = raw, true
+	}
+	if u.has {
+		u.has = false
+		return u.pending, true
+	}
+	return "", false
+}
+
+type icsDecoder struct {
+	lines *unfolder
+	row   int
+}
+
+func (d *icsDecoder) Next() (Domain.ImportRecord, error) {
+	var fields map[string]string
+	for {
+		line, ok := d.lines.next()
+		if !ok {
+			if err := d.lines.scanner.Err(); err != nil {
+				return Domain.ImportRecord{}, err
+			}
+			return Domain.ImportRecord{}, io.EOF
+		}
+
+		name, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		// Drop parameters such as DUE;VALUE=DATE
+		name, _, _ = strings.Cut(strings.ToUpper(name), ";")
+
+		switch {
+		case name == "BEGIN" && (value == "VTODO" || value == "VEVENT"):
+			fields = map[string]string{}
+			d.row++
+		case name == "END" && (value == "VTODO" || value == "VEVENT") && fields != nil:
+			return Domain.ImportRecord{Row: d.row, Fields: fields}, nil
+		case fields == nil:
+		case name == "UID":
+			fields["external_id"] = icsUnescaper.Replace(value)
+		case name == "SUMMARY":
+			fields["title"] = icsUnescaper.Replace(value)
+		case name == "DESCRIPTION":
+			fields["description"] = icsUnescaper.Replace(value)
+		case name == "DUE" || (name == "DTSTART" && fields["due_date"] == ""):
+			if due, err := parseICSDate(value); err == nil {
+				fields["due_date"] = formatDate(due)
+			} else {
+				fields["due_date"] = value
+			}
+		case name == "STATUS":
+			for status, ics := range icsStatus {
+				if ics == strings.ToUpper(value) {
+					fields["status"] = status
+				}
+			}
+		case name == "PRIORITY":
+			fields["priority"] = priorityFromICS(value)
+		case name == "X-TASK-PROJECT":
+			fields["project"] = icsUnescaper.Replace(value)
+		case name == "CATEGORIES":
+			// Label IDs need no escaping, and the list may span several lines
+			if fields["labels"] != "" {
+				value = fields["labels"] + "," + value
+			}
+			fields["labels"] = value
+		case name == "X-TASK-CUSTOM-FIELDS":
+			fields["custom_fields"] = icsUnescaper.Replace(value)
+		}
+	}
+}
+
+func parseICSDate(value string) (time.Time, error) {
+	for _, layout := range []string{icsDate, "20060102T150405", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
@@ -0,0 +1,116 @@
+package Repositories
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AccessTokenRepository interface {
+	Create(token Domain.AccessToken) (Domain.AccessToken, error)
+	FindByHash(hash string) (Domain.AccessToken, error)
+	ListForUser(userID primitive.ObjectID) ([]Domain.AccessToken, error)
+	CountForUser(userID primitive.ObjectID) (int64, error)
+	TouchUsed(id primitive.ObjectID, at time.Time, ip string) error
+	// Delete removes one of userID's tokens
+	Delete(userID, id primitive.ObjectID) error
+}
+
+var errAccessTokenNotFound = errors.New("access token not found")
+
+type mongoAccessTokenRepository struct {
+	db *mongo.Database
+}
+
+// NewMongoAccessTokenRepository also creates a TTL index so expired tokens
+// are cleared out on their own
+func NewMongoAccessTokenRepository(db *mongo.Database) AccessTokenRepository {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := db.Collection("access_tokens").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetName("access_token_hash").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}},
+			Options: options.Index().SetName("access_token_user"),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetName("access_token_expiry").SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		log.Printf("Could not create access token indexes: %v", err)
+	}
+	return &mongoAccessTokenRepository{db: db}
+}
+
+func (r *mongoAccessTokenRepository) Create(token Domain.AccessToken) (Domain.AccessToken, error) {
+	result, err := r.db.Collection("access_tokens").InsertOne(context.Background(), token)
+	if err != nil {
+		return Domain.AccessToken{}, err
+	}
+	token.ID = result.InsertedID.(primitive.ObjectID)
+	return token, nil
+}
+
+func (r *mongoAccessTokenRepository) FindByHash(hash string) (Domain.AccessToken, error) {
+	var token Domain.AccessToken
+	err := r.db.Collection("access_tokens").FindOne(context.Background(), bson.M{"token_hash": hash}).Decode(&token)
+	if err == mongo.ErrNoDocuments {
+		return Domain.AccessToken{}, errAccessTokenNotFound
+	}
+	return token, err
+}
+
+func (r *mongoAccessTokenRepository) ListForUser(userID primitive.ObjectID) ([]Domain.AccessToken, error) {
+	cursor, err := r.db.Collection("access_tokens").Find(
+		context.Background(),
+		bson.M{"user_id": userID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	tokens := []Domain.AccessToken{}
+	if err := cursor.All(context.Background(), &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (r *mongoAccessTokenRepository) CountForUser(userID primitive.ObjectID) (int64, error) {
+	return r.db.Collection("access_tokens").CountDocuments(context.Background(), bson.M{"user_id": userID})
+}
+
+func (r *mongoAccessTokenRepository) TouchUsed(id primitive.ObjectID, at time.Time, ip string) error {
+	_, err := r.db.Collection("access_tokens").UpdateOne(
+		context.Background(),
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"last_used_at": at, "last_used_ip": ip}},
+	)
+	return err
+}
+
+func (r *mongoAccessTokenRepository) Delete(userID, id primitive.ObjectID) error {
+	result, err := r.db.Collection("access_tokens").DeleteOne(context.Background(), bson.M{"_id": id, "user_id": userID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errAccessTokenNotFound
+	}
+	return nil
+}
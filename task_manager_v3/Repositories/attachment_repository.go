@@ -0,0 +1,80 @@
+package Repositories
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AttachmentRepository interface {
+	Create(attachment Domain.Attachment) (Domain.Attachment, error)
+	FindByID(id primitive.ObjectID) (Domain.Attachment, error)
+	// FindByTask returns a task's attachments, oldest first
+	FindByTask(taskID primitive.ObjectID) ([]Domain.Attachment, error)
+	Delete(id primitive.ObjectID) error
+}
+
+type mongoAttachmentRepository struct {
+	db *mongo.Database
+}
+
+func NewMongoAttachmentRepository(db *mongo.Database) AttachmentRepository {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := db.Collection("attachments").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "task_id", Value: 1}, {Key: "created_at", Value: 1}},
+		Options: options.Index().SetName("attachment_task"),
+	})
+	if err != nil {
+		log.Printf("Could not create attachment indexes: %v", err)
+	}
+	return &mongoAttachmentRepository{db: db}
+}
+
+func (r *mongoAttachmentRepository) Create(attachment Domain.Attachment) (Domain.Attachment, error) {
+	result, err := r.db.Collection("attachments").InsertOne(context.Background(), attachment)
+	if err != nil {
+		return Domain.Attachment{}, err
+	}
+	attachment.ID = result.InsertedID.(primitive.ObjectID)
+	return attachment, nil
+}
+
+func (r *mongoAttachmentRepository) FindByID(id primitive.ObjectID) (Domain.Attachment, error) {
+	var attachment Domain.Attachment
+	err := r.db.Collection("attachments").FindOne(context.Background(), bson.M{"_id": id}).Decode(&attachment)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return Domain.Attachment{}, Domain.ErrAttachmentNotFound
+		}
+		return Domain.Attachment{}, err
+	}
+	return attachment, nil
+}
+
+func (r *mongoAttachmentRepository) FindByTask(taskID primitive.ObjectID) ([]Domain.Attachment, error) {
+	cursor, err := r.db.Collection("attachments").Find(context.Background(), bson.M{"task_id": taskID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	attachments := []Domain.Attachment{}
+	if err = cursor.All(context.Background(), &attachments); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+func (r *mongoAttachmentRepository) Delete(id primitive.ObjectID) error {
+	_, err := r.db.Collection("attachments").DeleteOne(context.Background(), bson.M{"_id": id})
+	return err
+}
@@ -0,0 +1,76 @@
+package Repositories
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// planBulk plays ops in order against the tasks that exist right now. Ops that
+// can't apply get a final 404 result; the others get a provisional success
+// carrying the task as it will look once written. ok is false if any op failed.
+func planBulk(ops []Domain.BulkOperation, existing map[primitive.ObjectID]Domain.Task) (results []Domain.BulkResult, ok bool) {
+	ok = true
+	now := time.Now()
+	results = make([]Domain.BulkResult, len(ops))
+	for i, op := range ops {
+		result := Domain.BulkResult{Index: i, Op: op.Op}
+
+		switch op.Op {
+		case Domain.BulkCreate:
+			task := *op.Task
+			task.ID = primitive.NewObjectID()
+			task.Track(nil, now)
+			existing[task.ID] = task
+			result.Status = http.StatusCreated
+			result.Task = &task
+		case Domain.BulkUpdate, Domain.BulkTransition, Domain.BulkDelete:
+			current, found := existing[op.TaskID]
+			if !found {
+				result.Status = http.StatusNotFound
+				result.Error = Domain.ErrTaskNotFound.Error()
+				ok = false
+				break
+			}
+			result.Status = http.StatusOK
+			switch op.Op {
+			case Domain.BulkUpdate:
+				task := *op.Task
+				task.ID = op.TaskID
+				task.Track(&current, now)
+				existing[task.ID] = task
+				result.Task = &task
+			case Domain.BulkTransition:
+				task := current
+				task.Status = op.Status
+				task.Track(&current, now)
+				existing[task.ID] = task
+				result.Task = &task
+			case Domain.BulkDelete:
+				delete(existing, op.TaskID)
+			}
+		}
+
+		if result.Task != nil {
+			result.ID = result.Task.ID.Hex()
+		} else {
+			result.ID = op.TaskID.Hex()
+		}
+		results[i] = result
+	}
+	return results, ok
+}
+
+// abortBulk marks every provisional success as not applied after an
+// all-or-nothing request failed
+func abortBulk(results []Domain.BulkResult) {
+	for i := range results {
+		if results[i].Status < http.StatusBadRequest {
+			results[i].Status = http.StatusFailedDependency
+			results[i].Error = "not applied: another operation in the request failed"
+			results[i].Task = nil
+		}
+	}
+}
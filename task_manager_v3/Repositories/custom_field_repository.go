@@ -0,0 +1,95 @@
+package Repositories
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CustomFieldRepository interface {
+	Create(field Domain.CustomField) (Domain.CustomField, error)
+	FindAll() ([]Domain.CustomField, error)
+	FindByID(id primitive.ObjectID) (Domain.CustomField, error)
+	Update(field Domain.CustomField) (Domain.CustomField, error)
+	Delete(id primitive.ObjectID) error
+}
+
+type mongoCustomFieldRepository struct {
+	db *mongo.Database
+}
+
+func NewMongoCustomFieldRepository(db *mongo.Database) CustomFieldRepository {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := db.Collection("custom_fields").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "key", Value: 1}},
+		Options: options.Index().SetName("custom_field_key").SetUnique(true),
+	})
+	if err != nil {
+		log.Printf("Could not create custom field indexes: %v", err)
+	}
+	return &mongoCustomFieldRepository{db: db}
+}
+
+func (r *mongoCustomFieldRepository) Create(field Domain.CustomField) (Domain.CustomField, error) {
+	result, err := r.db.Collection("custom_fields").InsertOne(context.Background(), field)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return Domain.CustomField{}, Domain.ErrCustomFieldExists
+		}
+		return Domain.CustomField{}, err
+	}
+	field.ID = result.InsertedID.(primitive.ObjectID)
+	return field, nil
+}
+
+func (r *mongoCustomFieldRepository) FindAll() ([]Domain.CustomField, error) {
+	cursor, err := r.db.Collection("custom_fields").Find(context.Background(), bson.M{},
+		options.Find().SetSort(bson.M{"key": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	fields := []Domain.CustomField{}
+	if err = cursor.All(context.Background(), &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func (r *mongoCustomFieldRepository) FindByID(id primitive.ObjectID) (Domain.CustomField, error) {
+	var field Domain.CustomField
+	err := r.db.Collection("custom_fields").FindOne(context.Background(), bson.M{"_id": id}).Decode(&field)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return Domain.CustomField{}, Domain.ErrCustomFieldNotFound
+		}
+		return Domain.CustomField{}, err
+	}
+	return field, nil
+}
+
+func (r *mongoCustomFieldRepository) Update(field Domain.CustomField) (Domain.CustomField, error) {
+	_, err := r.db.Collection("custom_fields").UpdateOne(
+		context.Background(),
+		bson.M{"_id": field.ID},
+		bson.M{"$set": field},
+	)
+	if err != nil {
+		return Domain.CustomField{}, err
+	}
+	return field, nil
+}
+
+func (r *mongoCustomFieldRepository) Delete(id primitive.ObjectID) error {
+	_, err := r.db.Collection("custom_fields").DeleteOne(context.Background(), bson.M{"_id": id})
+	return err
+}
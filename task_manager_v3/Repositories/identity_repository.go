@@ -0,0 +1,99 @@
+package Repositories
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type IdentityRepository interface {
+	// Create fails with Domain.ErrIdentityLinked if the issuer and subject
+	// are already linked to an account
+	Create(identity Domain.ExternalIdentity) (Domain.ExternalIdentity, error)
+	FindBySubject(issuer, subject string) (Domain.ExternalIdentity, error)
+	ListForUser(userID primitive.ObjectID) ([]Domain.ExternalIdentity, error)
+	TouchLogin(id primitive.ObjectID, at time.Time) error
+	Delete(id primitive.ObjectID) error
+}
+
+var errIdentityNotFound = errors.New("identity not found")
+
+type mongoIdentityRepository struct {
+	db *mongo.Database
+}
+
+func NewMongoIdentityRepository(db *mongo.Database) IdentityRepository {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := db.Collection("identities").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "issuer", Value: 1}, {Key: "subject", Value: 1}},
+			Options: options.Index().SetName("identity_subject").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}},
+			Options: options.Index().SetName("identity_user"),
+		},
+	})
+	if err != nil {
+		log.Printf("Could not create identity indexes: %v", err)
+	}
+	return &mongoIdentityRepository{db: db}
+}
+
+func (r *mongoIdentityRepository) Create(identity Domain.ExternalIdentity) (Domain.ExternalIdentity, error) {
+	result, err := r.db.Collection("identities").InsertOne(context.Background(), identity)
+	if mongo.IsDuplicateKeyError(err) {
+		return Domain.ExternalIdentity{}, Domain.ErrIdentityLinked
+	}
+	if err != nil {
+		return Domain.ExternalIdentity{}, err
+	}
+	identity.ID = result.InsertedID.(primitive.ObjectID)
+	return identity, nil
+}
+
+func (r *mongoIdentityRepository) FindBySubject(issuer, subject string) (Domain.ExternalIdentity, error) {
+	var identity Domain.ExternalIdentity
+	err := r.db.Collection("identities").FindOne(context.Background(), bson.M{"issuer": issuer, "subject": subject}).Decode(&identity)
+	if err == mongo.ErrNoDocuments {
+		return Domain.ExternalIdentity{}, errIdentityNotFound
+	}
+	return identity, err
+}
+
+func (r *mongoIdentityRepository) ListForUser(userID primitive.ObjectID) ([]Domain.ExternalIdentity, error) {
+	cursor, err := r.db.Collection("identities").Find(
+		context.Background(),
+		bson.M{"user_id": userID},
+		options.Find().SetSort(bson.D{{Key: "linked_at", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	identities := []Domain.ExternalIdentity{}
+	if err := cursor.All(context.Background(), &identities); err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+func (r *mongoIdentityRepository) TouchLogin(id primitive.ObjectID, at time.Time) error {
+	_, err := r.db.Collection("identities").UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": bson.M{"last_login_at": at}})
+	return err
+}
+
+func (r *mongoIdentityRepository) Delete(id primitive.ObjectID) error {
+	_, err := r.db.Collection("identities").DeleteOne(context.Background(), bson.M{"_id": id})
+	return err
+}
@@ -0,0 +1,92 @@
+package Repositories
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// invertedIndex maps each word in a task's title and description to the
+// tasks containing it. It only narrows down candidates; scoreTask does the
+// exact matching and ranking.
+type invertedIndex struct {
+	postings map[string]map[primitive.ObjectID]struct{}
+	docs     map[primitive.ObjectID][]string
+}
+
+func newInvertedIndex() *invertedIndex {
+	return &invertedIndex{
+		postings: make(map[string]map[primitive.ObjectID]struct{}),
+		docs:     make(map[primitive.ObjectID][]string),
+	}
+}
+
+func (idx *invertedIndex) add(task Domain.Task) {
+	idx.remove(task.ID)
+
+	seen := map[string]struct{}{}
+	for _, w := range append(words(task.Title), words(task.Description)...) {
+		if _, ok := seen[w]; ok {
+			continue
+		}
+		seen[w] = struct{}{}
+		if idx.postings[w] == nil {
+			idx.postings[w] = make(map[primitive.ObjectID]struct{})
+		}
+		idx.postings[w][task.ID] = struct{}{}
+		idx.docs[task.ID] = append(idx.docs[task.ID], w)
+	}
+}
+
+func (idx *invertedIndex) remove(id primitive.ObjectID) {
+	for _, w := range idx.docs[id] {
+		delete(idx.postings[w], id)
+		if len(idx.postings[w]) == 0 {
+			delete(idx.postings, w)
+		}
+	}
+	delete(idx.docs, id)
+}
+
+// lookup returns the ids of tasks containing every word of the query
+func (idx *invertedIndex) lookup(query Domain.SearchQuery) []primitive.ObjectID {
+	var sets []map[primitive.ObjectID]struct{}
+
+	required := append([]string{}, query.Terms...)
+	for _, phrase := range query.Phrases {
+		required = append(required, phrase...)
+	}
+	for _, w := range required {
+		sets = append(sets, idx.postings[w])
+	}
+	for _, prefix := range query.Prefixes {
+		union := map[primitive.ObjectID]struct{}{}
+		for w, ids := range idx.postings {
+			if strings.HasPrefix(w, prefix) {
+				for id := range ids {
+					union[id] = struct{}{}
+				}
+			}
+		}
+		sets = append(sets, union)
+	}
+
+	if len(sets) == 0 {
+		return nil
+	}
+	var ids []primitive.ObjectID
+	for id := range sets[0] {
+		inAll := true
+		for _, set := range sets[1:] {
+			if _, ok := set[id]; !ok {
+				inAll = false
+				break
+			}
+		}
+		if inAll {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
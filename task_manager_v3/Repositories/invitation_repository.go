@@ -0,0 +1,106 @@
+package Repositories
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type InvitationRepository interface {
+	Create(invitation Domain.Invitation) (Domain.Invitation, error)
+	FindByTokenHash(hash string) (Domain.Invitation, error)
+	// List returns invitations newest first
+	List() ([]Domain.Invitation, error)
+	// Redeem marks an unused, unexpired invitation as used by userID. It
+	// fails with Domain.ErrInvalidInvitation if another request got there
+	// first.
+	Redeem(id, userID primitive.ObjectID, at time.Time) error
+	// Release undoes Redeem when the registration it was for failed
+	Release(id primitive.ObjectID) error
+	Delete(id primitive.ObjectID) error
+}
+
+type mongoInvitationRepository struct {
+	db *mongo.Database
+}
+
+func NewMongoInvitationRepository(db *mongo.Database) InvitationRepository {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := db.Collection("invitations").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "token_hash", Value: 1}},
+		Options: options.Index().SetName("invitation_token_hash").SetUnique(true),
+	})
+	if err != nil {
+		log.Printf("Could not create invitation indexes: %v", err)
+	}
+	return &mongoInvitationRepository{db: db}
+}
+
+func (r *mongoInvitationRepository) Create(invitation Domain.Invitation) (Domain.Invitation, error) {
+	result, err := r.db.Collection("invitations").InsertOne(context.Background(), invitation)
+	if err != nil {
+		return Domain.Invitation{}, err
+	}
+	invitation.ID = result.InsertedID.(primitive.ObjectID)
+	return invitation, nil
+}
+
+func (r *mongoInvitationRepository) FindByTokenHash(hash string) (Domain.Invitation, error) {
+	var invitation Domain.Invitation
+	err := r.db.Collection("invitations").FindOne(context.Background(), bson.M{"token_hash": hash}).Decode(&invitation)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return Domain.Invitation{}, Domain.ErrInvalidInvitation
+		}
+		return Domain.Invitation{}, err
+	}
+	return invitation, nil
+}
+
+func (r *mongoInvitationRepository) List() ([]Domain.Invitation, error) {
+	cursor, err := r.db.Collection("invitations").Find(context.Background(), bson.M{},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	invitations := []Domain.Invitation{}
+	if err = cursor.All(context.Background(), &invitations); err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+func (r *mongoInvitationRepository) Redeem(id, userID primitive.ObjectID, at time.Time) error {
+	result, err := r.db.Collection("invitations").UpdateOne(context.Background(),
+		bson.M{"_id": id, "used_at": bson.M{"$exists": false}, "expires_at": bson.M{"$gt": at}},
+		bson.M{"$set": bson.M{"used_at": at, "used_by": userID}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return Domain.ErrInvalidInvitation
+	}
+	return nil
+}
+
+func (r *mongoInvitationRepository) Release(id primitive.ObjectID) error {
+	_, err := r.db.Collection("invitations").UpdateOne(context.Background(),
+		bson.M{"_id": id}, bson.M{"$unset": bson.M{"used_at": "", "used_by": ""}})
+	return err
+}
+
+func (r *mongoInvitationRepository) Delete(id primitive.ObjectID) error {
+	_, err := r.db.Collection("invitations").DeleteOne(context.Background(), bson.M{"_id": id})
+	return err
+}
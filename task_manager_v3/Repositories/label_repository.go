@@ -0,0 +1,115 @@
+package Repositories
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type LabelRepository interface {
+	Create(label Domain.Label) (Domain.Label, error)
+	FindByID(id primitive.ObjectID) (Domain.Label, error)
+	// FindByIDs loads the labels with the given IDs, in no particular order.
+	// IDs with no label are left out.
+	FindByIDs(ids []primitive.ObjectID) ([]Domain.Label, error)
+	FindByProject(project string) ([]Domain.Label, error)
+	Update(label Domain.Label) (Domain.Label, error)
+	Delete(id primitive.ObjectID) error
+}
+
+type mongoLabelRepository struct {
+	db *mongo.Database
+}
+
+func NewMongoLabelRepository(db *mongo.Database) LabelRepository {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := db.Collection("labels").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "project", Value: 1}, {Key: "name", Value: 1}},
+		Options: options.Index().SetName("label_project_name").SetUnique(true),
+	})
+	if err != nil {
+		log.Printf("Could not create label indexes: %v", err)
+	}
+	return &mongoLabelRepository{db: db}
+}
+
+func (r *mongoLabelRepository) Create(label Domain.Label) (Domain.Label, error) {
+	result, err := r.db.Collection("labels").InsertOne(context.Background(), label)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return Domain.Label{}, Domain.ErrLabelExists
+		}
+		return Domain.Label{}, err
+	}
+	label.ID = result.InsertedID.(primitive.ObjectID)
+	return label, nil
+}
+
+func (r *mongoLabelRepository) FindByID(id primitive.ObjectID) (Domain.Label, error) {
+	var label Domain.Label
+	err := r.db.Collection("labels").FindOne(context.Background(), bson.M{"_id": id}).Decode(&label)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return Domain.Label{}, Domain.ErrLabelNotFound
+		}
+		return Domain.Label{}, err
+	}
+	return label, nil
+}
+
+func (r *mongoLabelRepository) FindByIDs(ids []primitive.ObjectID) ([]Domain.Label, error) {
+	cursor, err := r.db.Collection("labels").Find(context.Background(), bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	labels := []Domain.Label{}
+	if err = cursor.All(context.Background(), &labels); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+func (r *mongoLabelRepository) FindByProject(project string) ([]Domain.Label, error) {
+	cursor, err := r.db.Collection("labels").Find(context.Background(), bson.M{"project": project},
+		options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	labels := []Domain.Label{}
+	if err = cursor.All(context.Background(), &labels); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+func (r *mongoLabelRepository) Update(label Domain.Label) (Domain.Label, error) {
+	_, err := r.db.Collection("labels").UpdateOne(
+		context.Background(),
+		bson.M{"_id": label.ID},
+		bson.M{"$set": label},
+	)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return Domain.Label{}, Domain.ErrLabelExists
+		}
+		return Domain.Label{}, err
+	}
+	return label, nil
+}
+
+func (r *mongoLabelRepository) Delete(id primitive.ObjectID) error {
+	_, err := r.db.Collection("labels").DeleteOne(context.Background(), bson.M{"_id": id})
+	return err
+}
@@ -0,0 +1,85 @@
+package Repositories
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type inMemoryAccessTokenRepository struct {
+	mu     sync.RWMutex
+	tokens map[primitive.ObjectID]Domain.AccessToken
+}
+
+// NewInMemoryAccessTokenRepository returns an AccessTokenRepository kept in
+// process memory, for tests and for running without MongoDB
+func NewInMemoryAccessTokenRepository() AccessTokenRepository {
+	return &inMemoryAccessTokenRepository{tokens: make(map[primitive.ObjectID]Domain.AccessToken)}
+}
+
+func (r *inMemoryAccessTokenRepository) Create(token Domain.AccessToken) (Domain.AccessToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token.ID = primitive.NewObjectID()
+	r.tokens[token.ID] = token
+	return token, nil
+}
+
+func (r *inMemoryAccessTokenRepository) FindByHash(hash string) (Domain.AccessToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, token := range r.tokens {
+		if token.TokenHash == hash {
+			return token, nil
+		}
+	}
+	return Domain.AccessToken{}, errAccessTokenNotFound
+}
+
+func (r *inMemoryAccessTokenRepository) ListForUser(userID primitive.ObjectID) ([]Domain.AccessToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tokens := []Domain.AccessToken{}
+	for _, token := range r.tokens {
+		if token.UserID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].CreatedAt.After(tokens[j].CreatedAt) })
+	return tokens, nil
+}
+
+func (r *inMemoryAccessTokenRepository) CountForUser(userID primitive.ObjectID) (int64, error) {
+	tokens, _ := r.ListForUser(userID)
+	return int64(len(tokens)), nil
+}
+
+func (r *inMemoryAccessTokenRepository) TouchUsed(id primitive.ObjectID, at time.Time, ip string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if token, ok := r.tokens[id]; ok {
+		token.LastUsedAt = at
+		token.LastUsedIP = ip
+		r.tokens[id] = token
+	}
+	return nil
+}
+
+func (r *inMemoryAccessTokenRepository) Delete(userID, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[id]
+	if !ok || token.UserID != userID {
+		return errAccessTokenNotFound
+	}
+	delete(r.tokens, id)
+	return nil
+}
@@ -0,0 +1,66 @@
+package Repositories
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type inMemoryAttachmentRepository struct {
+	mu          sync.RWMutex
+	attachments []Domain.Attachment
+}
+
+func NewInMemoryAttachmentRepository() AttachmentRepository {
+	return &inMemoryAttachmentRepository{}
+}
+
+func (r *inMemoryAttachmentRepository) Create(attachment Domain.Attachment) (Domain.Attachment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if attachment.ID.IsZero() {
+		attachment.ID = primitive.NewObjectID()
+	}
+	r.attachments = append(r.attachments, attachment)
+	return attachment, nil
+}
+
+func (r *inMemoryAttachmentRepository) FindByID(id primitive.ObjectID) (Domain.Attachment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, attachment := range r.attachments {
+		if attachment.ID == id {
+			return attachment, nil
+		}
+	}
+	return Domain.Attachment{}, Domain.ErrAttachmentNotFound
+}
+
+func (r *inMemoryAttachmentRepository) FindByTask(taskID primitive.ObjectID) ([]Domain.Attachment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	attachments := []Domain.Attachment{}
+	for _, attachment := range r.attachments {
+		if attachment.TaskID == taskID {
+			attachments = append(attachments, attachment)
+		}
+	}
+	return attachments, nil
+}
+
+func (r *inMemoryAttachmentRepository) Delete(id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, attachment := range r.attachments {
+		if attachment.ID == id {
+			r.attachments = append(r.attachments[:i], r.attachments[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package Repositories
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type inMemoryLabelRepository struct {
+	mu     sync.RWMutex
+	labels map[primitive.ObjectID]Domain.Label
+}
+
+func NewInMemoryLabelRepository() LabelRepository {
+	return &inMemoryLabelRepository{labels: make(map[primitive.ObjectID]Domain.Label)}
+}
+
+func (r *inMemoryLabelRepository) nameTaken(label Domain.Label) bool {
+	for id, existing := range r.labels {
+		if id != label.ID && existing.Project == label.Project && existing.Name == label.Name {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *inMemoryLabelRepository) Create(label Domain.Label) (Domain.Label, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.nameTaken(label) {
+		return Domain.Label{}, Domain.ErrLabelExists
+	}
+	label.ID = primitive.NewObjectID()
+	r.labels[label.ID] = label
+	return label, nil
+}
+
+func (r *inMemoryLabelRepository) FindByID(id primitive.ObjectID) (Domain.Label, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	label, ok := r.labels[id]
+	if !ok {
+		return Domain.Label{}, Domain.ErrLabelNotFound
+	}
+	return label, nil
+}
+
+func (r *inMemoryLabelRepository) FindByIDs(ids []primitive.ObjectID) ([]Domain.Label, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	labels := []Domain.Label{}
+	for _, id := range ids {
+		if label, ok := r.labels[id]; ok {
+			labels = append(labels, label)
+		}
+	}
+	return labels, nil
+}
+
+func (r *inMemoryLabelRepository) FindByProject(project string) ([]Domain.Label, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	labels := []Domain.Label{}
+	for _, label := range r.labels {
+		if label.Project == project {
+			labels = append(labels, label)
+		}
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+	return labels, nil
+}
+
+func (r *inMemoryLabelRepository) Update(label Domain.Label) (Domain.Label, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.nameTaken(label) {
+		return Domain.Label{}, Domain.ErrLabelExists
+	}
+	r.labels[label.ID] = label
+	return label, nil
+}
+
+func (r *inMemoryLabelRepository) Delete(id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.labels, id)
+	return nil
+}
+
+type inMemoryCustomFieldRepository struct {
+	mu     sync.RWMutex
+	fields map[primitive.ObjectID]Domain.CustomField
+}
+
+func NewInMemoryCustomFieldRepository() CustomFieldRepository {
+	return &inMemoryCustomFieldRepository{fields: make(map[primitive.ObjectID]Domain.CustomField)}
+}
+
+func (r *inMemoryCustomFieldRepository) Create(field Domain.CustomField) (Domain.CustomField, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.fields {
+		if existing.Key == field.Key {
+			return Domain.CustomField{}, Domain.ErrCustomFieldExists
+		}
+	}
+	field.ID = primitive.NewObjectID()
+	r.fields[field.ID] = field
+	return field, nil
+}
+
+func (r *inMemoryCustomFieldRepository) FindAll() ([]Domain.CustomField, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	fields := []Domain.CustomField{}
+	for _, field := range r.fields {
+		fields = append(fields, field)
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Key < fields[j].Key })
+	return fields, nil
+}
+
+func (r *inMemoryCustomFieldRepository) FindByID(id primitive.ObjectID) (Domain.CustomField, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	field, ok := r.fields[id]
+	if !ok {
+		return Domain.CustomField{}, Domain.ErrCustomFieldNotFound
+	}
+	return field, nil
+}
+
+func (r *inMemoryCustomFieldRepository) Update(field Domain.CustomField) (Domain.CustomField, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.fields[field.ID] = field
+	return field, nil
+}
+
+func (r *inMemoryCustomFieldRepository) Delete(id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.fields, id)
+	return nil
+}
@@ -0,0 +1,80 @@
+package Repositories
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type inMemoryIdentityRepository struct {
+	mu         sync.RWMutex
+	identities map[primitive.ObjectID]Domain.ExternalIdentity
+}
+
+// NewInMemoryIdentityRepository returns an IdentityRepository kept in
+// process memory, for tests and for running without MongoDB
+func NewInMemoryIdentityRepository() IdentityRepository {
+	return &inMemoryIdentityRepository{identities: make(map[primitive.ObjectID]Domain.ExternalIdentity)}
+}
+
+func (r *inMemoryIdentityRepository) Create(identity Domain.ExternalIdentity) (Domain.ExternalIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.identities {
+		if existing.Issuer == identity.Issuer && existing.Subject == identity.Subject {
+			return Domain.ExternalIdentity{}, Domain.ErrIdentityLinked
+		}
+	}
+	identity.ID = primitive.NewObjectID()
+	r.identities[identity.ID] = identity
+	return identity, nil
+}
+
+func (r *inMemoryIdentityRepository) FindBySubject(issuer, subject string) (Domain.ExternalIdentity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, identity := range r.identities {
+		if identity.Issuer == issuer && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return Domain.ExternalIdentity{}, errIdentityNotFound
+}
+
+func (r *inMemoryIdentityRepository) ListForUser(userID primitive.ObjectID) ([]Domain.ExternalIdentity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	identities := []Domain.ExternalIdentity{}
+	for _, identity := range r.identities {
+		if identity.UserID == userID {
+			identities = append(identities, identity)
+		}
+	}
+	sort.Slice(identities, func(i, j int) bool { return identities[i].LinkedAt.Before(identities[j].LinkedAt) })
+	return identities, nil
+}
+
+func (r *inMemoryIdentityRepository) TouchLogin(id primitive.ObjectID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if identity, ok := r.identities[id]; ok {
+		identity.LastLoginAt = at
+		r.identities[id] = identity
+	}
+	return nil
+}
+
+func (r *inMemoryIdentityRepository) Delete(id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.identities, id)
+	return nil
+}
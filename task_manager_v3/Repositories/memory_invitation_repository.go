@@ -0,0 +1,86 @@
+package Repositories
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type inMemoryInvitationRepository struct {
+	mu          sync.Mutex
+	invitations map[primitive.ObjectID]Domain.Invitation
+}
+
+func NewInMemoryInvitationRepository() InvitationRepository {
+	return &inMemoryInvitationRepository{invitations: make(map[primitive.ObjectID]Domain.Invitation)}
+}
+
+func (r *inMemoryInvitationRepository) Create(invitation Domain.Invitation) (Domain.Invitation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	invitation.ID = primitive.NewObjectID()
+	r.invitations[invitation.ID] = invitation
+	return invitation, nil
+}
+
+func (r *inMemoryInvitationRepository) FindByTokenHash(hash string) (Domain.Invitation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, invitation := range r.invitations {
+		if invitation.TokenHash == hash {
+			return invitation, nil
+		}
+	}
+	return Domain.Invitation{}, Domain.ErrInvalidInvitation
+}
+
+func (r *inMemoryInvitationRepository) List() ([]Domain.Invitation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	invitations := []Domain.Invitation{}
+	for _, invitation := range r.invitations {
+		invitations = append(invitations, invitation)
+	}
+	sort.Slice(invitations, func(i, j int) bool { return invitations[i].CreatedAt.After(invitations[j].CreatedAt) })
+	return invitations, nil
+}
+
+func (r *inMemoryInvitationRepository) Redeem(id, userID primitive.ObjectID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	invitation, ok := r.invitations[id]
+	if !ok || invitation.UsedAt != nil || !invitation.ExpiresAt.After(at) {
+		return Domain.ErrInvalidInvitation
+	}
+	invitation.UsedAt = &at
+	invitation.UsedBy = &userID
+	r.invitations[id] = invitation
+	return nil
+}
+
+func (r *inMemoryInvitationRepository) Release(id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if invitation, ok := r.invitations[id]; ok {
+		invitation.UsedAt = nil
+		invitation.UsedBy = nil
+		r.invitations[id] = invitation
+	}
+	return nil
+}
+
+func (r *inMemoryInvitationRepository) Delete(id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.invitations, id)
+	return nil
+}
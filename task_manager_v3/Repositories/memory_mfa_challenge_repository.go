@@ -0,0 +1,65 @@
+package Repositories
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"sync"
+	"time"
+)
+
+type inMemoryMFAChallengeRepository struct {
+	mu         sync.Mutex
+	challenges map[string]Domain.MFAChallenge
+}
+
+// NewInMemoryMFAChallengeRepository returns an MFAChallengeRepository kept in
+// process memory, for tests and for running without MongoDB
+func NewInMemoryMFAChallengeRepository() MFAChallengeRepository {
+	return &inMemoryMFAChallengeRepository{challenges: make(map[string]Domain.MFAChallenge)}
+}
+
+func (r *inMemoryMFAChallengeRepository) Create(challenge Domain.MFAChallenge) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.challenges[challenge.ID] = challenge
+	return nil
+}
+
+func (r *inMemoryMFAChallengeRepository) Find(id string, now time.Time) (Domain.MFAChallenge, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	challenge, ok := r.challenges[id]
+	if !ok || !challenge.ExpiresAt.After(now) {
+		return Domain.MFAChallenge{}, Domain.ErrInvalidMFAToken
+	}
+	if challenge.Failures >= Domain.MaxMFAAttempts {
+		return Domain.MFAChallenge{}, Domain.ErrMFAAttemptsExceeded
+	}
+	return challenge, nil
+}
+
+func (r *inMemoryMFAChallengeRepository) RecordFailure(id string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	challenge, ok := r.challenges[id]
+	if !ok {
+		return 0, Domain.ErrInvalidMFAToken
+	}
+	challenge.Failures++
+	r.challenges[id] = challenge
+	return challenge.Failures, nil
+}
+
+func (r *inMemoryMFAChallengeRepository) Consume(id string, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	challenge, ok := r.challenges[id]
+	if !ok || !challenge.ExpiresAt.After(now) || challenge.Failures >= Domain.MaxMFAAttempts {
+		return Domain.ErrInvalidMFAToken
+	}
+	delete(r.challenges, id)
+	return nil
+}
@@ -0,0 +1,102 @@
+package Repositories
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"sort"
+	"time"
+)
+
+type inMemoryReportRepository struct {
+	tasks TaskRepository
+}
+
+// NewInMemoryReportRepository computes the same reports as the Mongo
+// pipelines by walking tasks, for tests and in-memory deployments
+func NewInMemoryReportRepository(tasks TaskRepository) ReportRepository {
+	return &inMemoryReportRepository{tasks: tasks}
+}
+
+func (r *inMemoryReportRepository) Summary(now time.Time) (Domain.TaskSummary, error) {
+	start := weekStart(now)
+	end := start.AddDate(0, 0, 7)
+	summary := Domain.TaskSummary{ByStatus: map[string]int64{}, WeekStart: start, GeneratedAt: now}
+
+	err := r.tasks.ForEach(func(task Domain.Task) error {
+		summary.Total++
+		summary.ByStatus[task.Status]++
+		if task.Status == Domain.StatusCompleted || task.DueDate.IsZero() {
+			return nil
+		}
+		if task.DueDate.Before(now) {
+			summary.Overdue++
+		}
+		if !task.DueDate.Before(start) && task.DueDate.Before(end) {
+			summary.DueThisWeek++
+		}
+		return nil
+	})
+	return summary, err
+}
+
+func (r *inMemoryReportRepository) Throughput(from, to time.Time, interval string) ([]Domain.ThroughputBucket, error) {
+	counts := map[string]int64{}
+	err := r.tasks.ForEach(func(task Domain.Task) error {
+		for _, change := range task.StatusHistory {
+			if change.Status == Domain.StatusCompleted && !change.At.Before(from) && change.At.Before(to) {
+				counts[periodKey(change.At, interval)]++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := []Domain.ThroughputBucket{}
+	for period, completed := range counts {
+		buckets = append(buckets, Domain.ThroughputBucket{Period: period, Completed: completed})
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].Period < buckets[j].Period })
+	return buckets, nil
+}
+
+func (r *inMemoryReportRepository) CycleTime(from, to time.Time) (Domain.CycleTimeReport, error) {
+	var hours []float64
+	err := r.tasks.ForEach(func(task Domain.Task) error {
+		var started, completed time.Time
+		for _, change := range task.StatusHistory {
+			switch change.Status {
+			case Domain.StatusInProgress:
+				if started.IsZero() || change.At.Before(started) {
+					started = change.At
+				}
+			case Domain.StatusCompleted:
+				if change.At.After(completed) {
+					completed = change.At
+				}
+			}
+		}
+		if started.IsZero() || completed.Before(from) || !completed.Before(to) {
+			return nil
+		}
+		if h := completed.Sub(started).Hours(); h > 0 {
+			hours = append(hours, h)
+		}
+		return nil
+	})
+	if err != nil || len(hours) == 0 {
+		return Domain.CycleTimeReport{}, err
+	}
+
+	sort.Float64s(hours)
+	total := 0.0
+	for _, h := range hours {
+		total += h
+	}
+	n := len(hours)
+	return Domain.CycleTimeReport{
+		Tasks:        int64(n),
+		MedianHours:  (hours[(n-1)/2] + hours[n/2]) / 2,
+		AverageHours: total / float64(n),
+	}, nil
+}
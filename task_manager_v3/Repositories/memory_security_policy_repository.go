@@ -0,0 +1,35 @@
+package Repositories
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"sync"
+)
+
+type inMemorySecurityPolicyRepository struct {
+	mu     sync.RWMutex
+	policy Domain.SecurityPolicy
+}
+
+// NewInMemorySecurityPolicyRepository returns a SecurityPolicyRepository kept
+// in process memory, for tests and for running without MongoDB
+func NewInMemorySecurityPolicyRepository() SecurityPolicyRepository {
+	return &inMemorySecurityPolicyRepository{}
+}
+
+func (r *inMemorySecurityPolicyRepository) Get() (Domain.SecurityPolicy, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	policy := r.policy
+	policy.MFARequiredRoles = append([]string(nil), r.policy.MFARequiredRoles...)
+	return policy, nil
+}
+
+func (r *inMemorySecurityPolicyRepository) Save(policy Domain.SecurityPolicy) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	policy.MFARequiredRoles = append([]string(nil), policy.MFARequiredRoles...)
+	r.policy = policy
+	return nil
+}
@@ -0,0 +1,92 @@
+package Repositories
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type inMemorySessionRepository struct {
+	mu       sync.RWMutex
+	sessions map[primitive.ObjectID]Domain.Session
+}
+
+// NewInMemorySessionRepository returns a SessionRepository kept in process
+// memory, for tests and for running without MongoDB
+func NewInMemorySessionRepository() SessionRepository {
+	return &inMemorySessionRepository{sessions: make(map[primitive.ObjectID]Domain.Session)}
+}
+
+func (r *inMemorySessionRepository) Create(session Domain.Session) (Domain.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session.ID = primitive.NewObjectID()
+	r.sessions[session.ID] = session
+	return session, nil
+}
+
+func (r *inMemorySessionRepository) FindByID(id primitive.ObjectID) (Domain.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	session, ok := r.sessions[id]
+	if !ok {
+		return Domain.Session{}, errSessionNotFound
+	}
+	return session, nil
+}
+
+func (r *inMemorySessionRepository) ListForUser(userID primitive.ObjectID) ([]Domain.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sessions := []Domain.Session{}
+	for _, session := range r.sessions {
+		if session.UserID == userID {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt) })
+	return sessions, nil
+}
+
+func (r *inMemorySessionRepository) Touch(id primitive.ObjectID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if session, ok := r.sessions[id]; ok {
+		session.LastSeenAt = at
+		r.sessions[id] = session
+	}
+	return nil
+}
+
+func (r *inMemorySessionRepository) Delete(userID, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[id]
+	if !ok || session.UserID != userID {
+		return errSessionNotFound
+	}
+	delete(r.sessions, id)
+	return nil
+}
+
+func (r *inMemorySessionRepository) DeleteForUser(userID, keep primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, session := range r.sessions {
+		if session.UserID == userID && id != keep {
+			delete(r.sessions, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
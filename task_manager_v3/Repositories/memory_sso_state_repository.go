@@ -0,0 +1,41 @@
+package Repositories
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"sync"
+	"time"
+)
+
+type inMemorySSOStateRepository struct {
+	mu     sync.Mutex
+	states map[string]Domain.SSOState
+}
+
+// NewInMemorySSOStateRepository returns an SSOStateRepository kept in
+// process memory, for tests and for running without MongoDB
+func NewInMemorySSOStateRepository() SSOStateRepository {
+	return &inMemorySSOStateRepository{states: make(map[string]Domain.SSOState)}
+}
+
+func (r *inMemorySSOStateRepository) Create(state Domain.SSOState) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.states[state.StateHash] = state
+	return nil
+}
+
+func (r *inMemorySSOStateRepository) Consume(hash string, now time.Time) (Domain.SSOState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, ok := r.states[hash]
+	if !ok {
+		return Domain.SSOState{}, Domain.ErrInvalidSSOState
+	}
+	delete(r.states, hash)
+	if !state.ExpiresAt.After(now) {
+		return Domain.SSOState{}, Domain.ErrInvalidSSOState
+	}
+	return state, nil
+}
@@ -0,0 +1,241 @@
+package Repositories
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"errors"
+	"net/http"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type inMemoryTaskRepository struct {
+	mu    sync.RWMutex
+	order []primitive.ObjectID
+	tasks map[primitive.ObjectID]Domain.Task
+	index *invertedIndex
+}
+
+// NewInMemoryTaskRepository returns a TaskRepository kept in process memory,
+// for tests and for running without MongoDB
+func NewInMemoryTaskRepository() TaskRepository {
+	return &inMemoryTaskRepository{
+		tasks: make(map[primitive.ObjectID]Domain.Task),
+		index: newInvertedIndex(),
+	}
+}
+
+func (r *inMemoryTaskRepository) Create(task Domain.Task) (Domain.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if task.ID.IsZero() {
+		task.ID = primitive.NewObjectID()
+	}
+	if _, exists := r.tasks[task.ID]; exists {
+		return Domain.Task{}, errors.New("task already exists")
+	}
+	r.tasks[task.ID] = task
+	r.order = append(r.order, task.ID)
+	r.index.add(task)
+	return task, nil
+}
+
+func (r *inMemoryTaskRepository) FindAll() ([]Domain.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var tasks []Domain.Task
+	for _, id := range r.order {
+		tasks = append(tasks, r.tasks[id])
+	}
+	return tasks, nil
+}
+
+func (r *inMemoryTaskRepository) Find(filter Domain.TaskFilter) ([]Domain.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var tasks []Domain.Task
+	for _, id := range r.order {
+		if task := r.tasks[id]; matchesFilter(task, filter) {
+			tasks = append(tasks, task)
+		}
+	}
+	sortTasks(tasks, filter.Sort)
+	return tasks, nil
+}
+
+func (r *inMemoryTaskRepository) FindPage(filter Domain.TaskFilter, page, pageSize int) ([]Domain.Task, int64, error) {
+	tasks, _ := r.Find(filter)
+	total := len(tasks)
+	start := min((page-1)*pageSize, total)
+	end := min(start+pageSize, total)
+	return append([]Domain.Task{}, tasks[start:end]...), int64(total), nil
+}
+
+func (r *inMemoryTaskRepository) FindByID(id primitive.ObjectID) (Domain.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	task, ok := r.tasks[id]
+	if !ok {
+		return Domain.Task{}, Domain.ErrTaskNotFound
+	}
+	return task, nil
+}
+
+func (r *inMemoryTaskRepository) Update(task Domain.Task) (Domain.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tasks[task.ID]; !ok {
+		return Domain.Task{}, Domain.ErrTaskNotFound
+	}
+	r.tasks[task.ID] = task
+	r.index.add(task)
+	return task, nil
+}
+
+func (r *inMemoryTaskRepository) Delete(id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.deleteLocked(id)
+	return nil
+}
+
+func (r *inMemoryTaskRepository) deleteLocked(id primitive.ObjectID) {
+	if _, ok := r.tasks[id]; !ok {
+		return
+	}
+	delete(r.tasks, id)
+	r.index.remove(id)
+	for i, existing := range r.order {
+		if existing == id {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+}
+
+func (r *inMemoryTaskRepository) Search(query Domain.SearchQuery, limit int) ([]Domain.TaskSearchResult, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var candidates []Domain.Task
+	for _, id := range r.index.lookup(query) {
+		candidates = append(candidates, r.tasks[id])
+	}
+	return rankTasks(query, candidates, limit), nil
+}
+
+func (r *inMemoryTaskRepository) BulkWrite(ops []Domain.BulkOperation, atomic bool) ([]Domain.BulkResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing := make(map[primitive.ObjectID]Domain.Task, len(r.tasks))
+	for id, task := range r.tasks {
+		existing[id] = task
+	}
+
+	results, ok := planBulk(ops, existing)
+	if atomic && !ok {
+		abortBulk(results)
+		return results, Domain.ErrBulkAborted
+	}
+
+	for i, result := range results {
+		if result.Status >= http.StatusBadRequest {
+			continue
+		}
+		switch ops[i].Op {
+		case Domain.BulkCreate:
+			r.tasks[result.Task.ID] = *result.Task
+			r.order = append(r.order, result.Task.ID)
+			r.index.add(*result.Task)
+		case Domain.BulkUpdate, Domain.BulkTransition:
+			r.tasks[result.Task.ID] = *result.Task
+			r.index.add(*result.Task)
+		case Domain.BulkDelete:
+			r.deleteLocked(ops[i].TaskID)
+		}
+	}
+	return results, nil
+}
+
+func (r *inMemoryTaskRepository) RemoveLabel(labelID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, task := range r.tasks {
+		task.Labels = removeLabel(task.Labels, labelID)
+		r.tasks[id] = task
+	}
+	return nil
+}
+
+func (r *inMemoryTaskRepository) RemoveCustomField(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, task := range r.tasks {
+		if _, ok := task.CustomFields[key]; ok {
+			fields := make(map[string]interface{}, len(task.CustomFields))
+			for k, v := range task.CustomFields {
+				if k != key {
+					fields[k] = v
+				}
+			}
+			task.CustomFields = fields
+			r.tasks[id] = task
+		}
+	}
+	return nil
+}
+
+func (r *inMemoryTaskRepository) ForEach(fn func(task Domain.Task) error) error {
+	tasks, _ := r.FindAll()
+	for _, task := range tasks {
+		if err := fn(task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *inMemoryTaskRepository) FindByExternalID(externalID string) (Domain.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, task := range r.tasks {
+		if externalID != "" && task.ExternalID == externalID {
+			return task, nil
+		}
+	}
+	return Domain.Task{}, Domain.ErrTaskNotFound
+}
+
+func (r *inMemoryTaskRepository) UpsertByExternalID(task Domain.Task) (Domain.Task, bool, error) {
+	if task.ExternalID == "" {
+		return Domain.Task{}, false, errors.New("external ID is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, existing := range r.tasks {
+		if existing.ExternalID == task.ExternalID {
+			task.ID = id
+			r.tasks[id] = task
+			r.index.add(task)
+			return task, false, nil
+		}
+	}
+
+	task.ID = primitive.NewObjectID()
+	r.tasks[task.ID] = task
+	r.order = append(r.order, task.ID)
+	r.index.add(task)
+	return task, true, nil
+}
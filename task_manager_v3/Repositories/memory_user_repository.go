@@ -0,0 +1,288 @@
+package Repositories
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type inMemoryUserRepository struct {
+	mu    sync.RWMutex
+	users map[primitive.ObjectID]Domain.User
+	// claim is who holds the ClaimFirstAdmin lock, and until when
+	claim        primitive.ObjectID
+	claimExpires time.Time
+}
+
+func NewInMemoryUserRepository() UserRepository {
+	return &inMemoryUserRepository{users: make(map[primitive.ObjectID]Domain.User)}
+}
+
+// conflict reports which unique field of user another user already holds
+func (r *inMemoryUserRepository) conflict(user Domain.User) error {
+	for id, existing := range r.users {
+		if id == user.ID {
+			continue
+		}
+		if existing.UsernameKey == user.UsernameKey {
+			return Domain.ErrUsernameTaken
+		}
+		if user.Email != "" && existing.Email == user.Email {
+			return Domain.ErrEmailTaken
+		}
+	}
+	return nil
+}
+
+func (r *inMemoryUserRepository) Create(user Domain.User) (Domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user.UsernameKey = Domain.NormalizeUsername(user.Username)
+	user.Email = Domain.NormalizeEmail(user.Email)
+	if err := r.conflict(user); err != nil {
+		return Domain.User{}, err
+	}
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+	r.users[user.ID] = user
+	return user, nil
+}
+
+func (r *inMemoryUserRepository) FindByUsername(username string) (Domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key := Domain.NormalizeUsername(username)
+	for _, user := range r.users {
+		if user.UsernameKey == key {
+			return user, nil
+		}
+	}
+	return Domain.User{}, Domain.ErrUserNotFound
+}
+
+func (r *inMemoryUserRepository) FindByEmail(email string) (Domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	email = Domain.NormalizeEmail(email)
+	for _, user := range r.users {
+		if email != "" && user.Email == email {
+			return user, nil
+		}
+	}
+	return Domain.User{}, Domain.ErrUserNotFound
+}
+
+func (r *inMemoryUserRepository) FindByID(id primitive.ObjectID) (Domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return Domain.User{}, Domain.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (r *inMemoryUserRepository) FindByIDs(ids []primitive.ObjectID) ([]Domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := []Domain.User{}
+	for _, id := range ids {
+		if user, ok := r.users[id]; ok {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (r *inMemoryUserRepository) Update(user Domain.User) (Domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[user.ID]; !ok {
+		return Domain.User{}, Domain.ErrUserNotFound
+	}
+	user.UsernameKey = Domain.NormalizeUsername(user.Username)
+	user.Email = Domain.NormalizeEmail(user.Email)
+	if err := r.conflict(user); err != nil {
+		return Domain.User{}, err
+	}
+	r.users[user.ID] = user
+	return user, nil
+}
+
+func (r *inMemoryUserRepository) Count() (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return int64(len(r.users)), nil
+}
+
+func matchesUserQuery(user Domain.User, query Domain.UserQuery) bool {
+	if query.Search != "" {
+		search := strings.ToLower(query.Search)
+		if !strings.Contains(strings.ToLower(user.Username), search) &&
+			!strings.Contains(strings.ToLower(user.DisplayName), search) {
+			return false
+		}
+	}
+	if query.Role != "" && user.Role != query.Role {
+		return false
+	}
+	switch query.Status {
+	case Domain.UserStatusActive:
+		return !user.Deactivated
+	case Domain.UserStatusDeactivated:
+		return user.Deactivated
+	}
+	return true
+}
+
+func (r *inMemoryUserRepository) List(query Domain.UserQuery) ([]Domain.User, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []Domain.User
+	for _, user := range r.users {
+		if matchesUserQuery(user, query) {
+			matched = append(matched, user)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].Username < matched[j].Username })
+
+	users := []Domain.User{}
+	start := (query.Page - 1) * query.PageSize
+	if start < len(matched) {
+		end := min(start+query.PageSize, len(matched))
+		users = append(users, matched[start:end]...)
+	}
+	return users, int64(len(matched)), nil
+}
+
+func (r *inMemoryUserRepository) CountActiveAdmins() (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var n int64
+	for _, user := range r.users {
+		if user.Role == Domain.RoleAdmin && !user.Deactivated {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *inMemoryUserRepository) Delete(id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.users, id)
+	return nil
+}
+
+func (r *inMemoryUserRepository) UpdateKeepingAnAdmin(user Domain.User) (Domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.users[user.ID]
+	if !ok {
+		return Domain.User{}, Domain.ErrUserNotFound
+	}
+	if r.lastActiveAdmin(existing) && (user.Role != Domain.RoleAdmin || user.Deactivated) {
+		return Domain.User{}, Domain.ErrLastAdmin
+	}
+	user.UsernameKey = Domain.NormalizeUsername(user.Username)
+	user.Email = Domain.NormalizeEmail(user.Email)
+	if err := r.conflict(user); err != nil {
+		return Domain.User{}, err
+	}
+	r.users[user.ID] = user
+	return user, nil
+}
+
+func (r *inMemoryUserRepository) DeleteKeepingAnAdmin(id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.users[id]; ok && r.lastActiveAdmin(existing) {
+		return Domain.ErrLastAdmin
+	}
+	delete(r.users, id)
+	return nil
+}
+
+// lastActiveAdmin reports whether user is the only active admin. Callers
+// hold the lock.
+func (r *inMemoryUserRepository) lastActiveAdmin(user Domain.User) bool {
+	if user.Role != Domain.RoleAdmin || user.Deactivated {
+		return false
+	}
+	for id, other := range r.users {
+		if id != user.ID && other.Role == Domain.RoleAdmin && !other.Deactivated {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *inMemoryUserRepository) ClaimFirstAdmin(id primitive.ObjectID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if !r.claim.IsZero() && r.claimExpires.After(now) {
+		return false, nil
+	}
+	r.claim, r.claimExpires = id, now.Add(firstAdminClaimTTL)
+	return true, nil
+}
+
+func (r *inMemoryUserRepository) ReleaseFirstAdmin(id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.claim == id {
+		r.claim = primitive.NilObjectID
+	}
+	return nil
+}
+
+func (r *inMemoryUserRepository) UseTOTPStep(id primitive.ObjectID, step int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.TOTPLastStep >= step {
+		return false, nil
+	}
+	user.TOTPLastStep = step
+	r.users[id] = user
+	return true, nil
+}
+
+func (r *inMemoryUserRepository) UseRecoveryCode(id primitive.ObjectID, hash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return false, nil
+	}
+	for i, code := range user.RecoveryCodes {
+		if code == hash {
+			user.RecoveryCodes = append(append([]string{}, user.RecoveryCodes[:i]...), user.RecoveryCodes[i+1:]...)
+			r.users[id] = user
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,56 @@
+package Repositories
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type inMemoryUserTokenRepository struct {
+	mu     sync.Mutex
+	tokens map[string]Domain.UserToken
+}
+
+// NewInMemoryUserTokenRepository returns a UserTokenRepository kept in
+// process memory, for tests and for running without MongoDB
+func NewInMemoryUserTokenRepository() UserTokenRepository {
+	return &inMemoryUserTokenRepository{tokens: make(map[string]Domain.UserToken)}
+}
+
+func (r *inMemoryUserTokenRepository) Create(token Domain.UserToken) (Domain.UserToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token.ID = primitive.NewObjectID()
+	r.tokens[token.TokenHash] = token
+	return token, nil
+}
+
+func (r *inMemoryUserTokenRepository) Consume(hash, purpose string, now time.Time) (Domain.UserToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[hash]
+	if !ok || token.Purpose != purpose {
+		return Domain.UserToken{}, Domain.ErrInvalidToken
+	}
+	delete(r.tokens, hash)
+	if !token.ExpiresAt.After(now) {
+		return Domain.UserToken{}, Domain.ErrInvalidToken
+	}
+	return token, nil
+}
+
+func (r *inMemoryUserTokenRepository) DeleteForUser(userID primitive.ObjectID, purpose string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for hash, token := range r.tokens {
+		if token.UserID == userID && token.Purpose == purpose {
+			delete(r.tokens, hash)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package Repositories
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MFAChallengeRepository interface {
+	Create(challenge Domain.MFAChallenge) error
+	// Find returns the unexpired challenge with id. It fails with
+	// Domain.ErrMFAAttemptsExceeded once the challenge is used up.
+	Find(id string, now time.Time) (Domain.MFAChallenge, error)
+	// RecordFailure counts a wrong code and returns the new total
+	RecordFailure(id string) (int, error)
+	// Consume atomically removes the unexpired challenge with id, so only
+	// one exchange of its token succeeds
+	Consume(id string, now time.Time) error
+}
+
+type mongoMFAChallengeRepository struct {
+	db *mongo.Database
+}
+
+// NewMongoMFAChallengeRepository also creates a TTL index so abandoned
+// logins are cleared out
+func NewMongoMFAChallengeRepository(db *mongo.Database) MFAChallengeRepository {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := db.Collection("mfa_challenges").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetName("mfa_challenge_expiry").SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		log.Printf("Could not create MFA challenge indexes: %v", err)
+	}
+	return &mongoMFAChallengeRepository{db: db}
+}
+
+func (r *mongoMFAChallengeRepository) Create(challenge Domain.MFAChallenge) error {
+	_, err := r.db.Collection("mfa_challenges").InsertOne(context.Background(), challenge)
+	return err
+}
+
+func (r *mongoMFAChallengeRepository) Find(id string, now time.Time) (Domain.MFAChallenge, error) {
+	var challenge Domain.MFAChallenge
+	err := r.db.Collection("mfa_challenges").FindOne(context.Background(), bson.M{
+		"_id":        id,
+		"expires_at": bson.M{"$gt": now},
+	}).Decode(&challenge)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return Domain.MFAChallenge{}, Domain.ErrInvalidMFAToken
+		}
+		return Domain.MFAChallenge{}, err
+	}
+	if challenge.Failures >= Domain.MaxMFAAttempts {
+		return Domain.MFAChallenge{}, Domain.ErrMFAAttemptsExceeded
+	}
+	return challenge, nil
+}
+
+func (r *mongoMFAChallengeRepository) RecordFailure(id string) (int, error) {
+	var challenge Domain.MFAChallenge
+	err := r.db.Collection("mfa_challenges").FindOneAndUpdate(context.Background(),
+		bson.M{"_id": id},
+		bson.M{"$inc": bson.M{"failures": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&challenge)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return 0, Domain.ErrInvalidMFAToken
+		}
+		return 0, err
+	}
+	return challenge.Failures, nil
+}
+
+func (r *mongoMFAChallengeRepository) Consume(id string, now time.Time) error {
+	err := r.db.Collection("mfa_challenges").FindOneAndDelete(context.Background(), bson.M{
+		"_id":        id,
+		"expires_at": bson.M{"$gt": now},
+		"failures":   bson.M{"$lt": Domain.MaxMFAAttempts},
+	}).Err()
+	if err == mongo.ErrNoDocuments {
+		return Domain.ErrInvalidMFAToken
+	}
+	return err
+}
@@ -0,0 +1,198 @@
+package Repositories
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ReportRepository interface {
+	Summary(now time.Time) (Domain.TaskSummary, error)
+	Throughput(from, to time.Time, interval string) ([]Domain.ThroughputBucket, error)
+	CycleTime(from, to time.Time) (Domain.CycleTimeReport, error)
+}
+
+// weekStart returns midnight UTC on the Monday of the ISO week containing t
+func weekStart(t time.Time) time.Time {
+	t = t.UTC()
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, time.UTC)
+}
+
+// periodKey labels t the same way the Mongo pipeline's $dateToString does
+func periodKey(t time.Time, interval string) string {
+	t = t.UTC()
+	if interval == Domain.IntervalWeek {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%04d-W%02d", year, week)
+	}
+	return t.Format("2006-01-02")
+}
+
+type mongoReportRepository struct {
+	db *mongo.Database
+}
+
+func NewMongoReportRepository(db *mongo.Database) ReportRepository {
+	return &mongoReportRepository{db: db}
+}
+
+func (r *mongoReportRepository) aggregate(pipeline mongo.Pipeline, out interface{}) error {
+	cursor, err := r.db.Collection("tasks").Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(context.Background())
+	return cursor.All(context.Background(), out)
+}
+
+func (r *mongoReportRepository) Summary(now time.Time) (Domain.TaskSummary, error) {
+	start := weekStart(now)
+	open := bson.M{"$ne": Domain.StatusCompleted}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$facet", Value: bson.M{
+			"by_status": bson.A{
+				bson.M{"$group": bson.M{"_id": "$status", "count": bson.M{"$sum": 1}}},
+			},
+			"overdue": bson.A{
+				bson.M{"$match": bson.M{"status": open, "duedate": bson.M{"$gt": time.Time{}, "$lt": now}}},
+				bson.M{"$count": "count"},
+			},
+			"due_this_week": bson.A{
+				bson.M{"$match": bson.M{"status": open, "duedate": bson.M{"$gte": start, "$lt": start.AddDate(0, 0, 7)}}},
+				bson.M{"$count": "count"},
+			},
+		}}},
+	}
+
+	type counter struct {
+		ID    string `bson:"_id"`
+		Count int64  `bson:"count"`
+	}
+	var facets []struct {
+		ByStatus    []counter `bson:"by_status"`
+		Overdue     []counter `bson:"overdue"`
+		DueThisWeek []counter `bson:"due_this_week"`
+	}
+	if err := r.aggregate(pipeline, &facets); err != nil {
+		return Domain.TaskSummary{}, err
+	}
+
+	summary := Domain.TaskSummary{ByStatus: map[string]int64{}, WeekStart: start, GeneratedAt: now}
+	if len(facets) == 0 {
+		return summary, nil
+	}
+	for _, c := range facets[0].ByStatus {
+		summary.ByStatus[c.ID] += c.Count
+		summary.Total += c.Count
+	}
+	if len(facets[0].Overdue) > 0 {
+		summary.Overdue = facets[0].Overdue[0].Count
+	}
+	if len(facets[0].DueThisWeek) > 0 {
+		summary.DueThisWeek = facets[0].DueThisWeek[0].Count
+	}
+	return summary, nil
+}
+
+func (r *mongoReportRepository) Throughput(from, to time.Time, interval string) ([]Domain.ThroughputBucket, error) {
+	format := "%Y-%m-%d"
+	if interval == Domain.IntervalWeek {
+		format = "%G-W%V"
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status_history.status": Domain.StatusCompleted}}},
+		{{Key: "$unwind", Value: "$status_history"}},
+		{{Key: "$match", Value: bson.M{
+			"status_history.status": Domain.StatusCompleted,
+			"status_history.at":     bson.M{"$gte": from, "$lt": to},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"$dateToString": bson.M{
+				"format": format, "date": "$status_history.at", "timezone": "UTC",
+			}},
+			"completed": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+
+	var rows []struct {
+		Period    string `bson:"_id"`
+		Completed int64  `bson:"completed"`
+	}
+	if err := r.aggregate(pipeline, &rows); err != nil {
+		return nil, err
+	}
+
+	buckets := []Domain.ThroughputBucket{}
+	for _, row := range rows {
+		buckets = append(buckets, Domain.ThroughputBucket{Period: row.Period, Completed: row.Completed})
+	}
+	return buckets, nil
+}
+
+// statusTimes builds the expression for the times a task entered status
+func statusTimes(status string) bson.M {
+	return bson.M{"$map": bson.M{
+		"input": bson.M{"$filter": bson.M{
+			"input": "$status_history",
+			"cond":  bson.M{"$eq": bson.A{"$$this.status", status}},
+		}},
+		"in": "$$this.at",
+	}}
+}
+
+func (r *mongoReportRepository) CycleTime(from, to time.Time) (Domain.CycleTimeReport, error) {
+	middle := func(round string) bson.M {
+		return bson.M{"$arrayElemAt": bson.A{"$hours", bson.M{"$toInt": bson.M{round: bson.M{
+			"$divide": bson.A{bson.M{"$subtract": bson.A{"$tasks", 1}}, 2},
+		}}}}}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status_history.status": Domain.StatusCompleted}}},
+		{{Key: "$project", Value: bson.M{
+			"started":   bson.M{"$min": statusTimes(Domain.StatusInProgress)},
+			"completed": bson.M{"$max": statusTimes(Domain.StatusCompleted)},
+		}}},
+		{{Key: "$match", Value: bson.M{
+			"started":   bson.M{"$ne": nil},
+			"completed": bson.M{"$gte": from, "$lt": to},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"hours": bson.M{"$divide": bson.A{bson.M{"$subtract": bson.A{"$completed", "$started"}}, 3600000}},
+		}}},
+		{{Key: "$match", Value: bson.M{"hours": bson.M{"$gt": 0}}}},
+		{{Key: "$sort", Value: bson.M{"hours": 1}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     nil,
+			"hours":   bson.M{"$push": "$hours"},
+			"average": bson.M{"$avg": "$hours"},
+			"tasks":   bson.M{"$sum": 1},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"tasks":   1,
+			"average": 1,
+			"median":  bson.M{"$avg": bson.A{middle("$floor"), middle("$ceil")}},
+		}}},
+	}
+
+	var rows []struct {
+		Tasks   int64   `bson:"tasks"`
+		Average float64 `bson:"average"`
+		Median  float64 `bson:"median"`
+	}
+	if err := r.aggregate(pipeline, &rows); err != nil {
+		return Domain.CycleTimeReport{}, err
+	}
+	if len(rows) == 0 {
+		return Domain.CycleTimeReport{}, nil
+	}
+	return Domain.CycleTimeReport{Tasks: rows[0].Tasks, MedianHours: rows[0].Median, AverageHours: rows[0].Average}, nil
+}
@@ -0,0 +1,237 @@
+package Repositories
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Field weights shared by the Mongo text index and the in-memory index so
+// both backends rank the same way
+const (
+	titleWeight       = 10
+	descriptionWeight = 1
+	phraseBoost       = 2
+	snippetRadius     = 60
+)
+
+type token struct {
+	text       string
+	start, end int
+}
+
+// tokenize splits text into lower-cased runs of letters and digits along with
+// their byte offsets in the original string
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = append(tokens, token{text: strings.ToLower(text[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{text: strings.ToLower(text[start:]), start: start, end: len(text)})
+	}
+	return tokens
+}
+
+func words(text string) []string {
+	tokens := tokenize(text)
+	out := make([]string, len(tokens))
+	for i, t := range tokens {
+		out[i] = t.text
+	}
+	return out
+}
+
+// ParseSearchQuery turns user input into a SearchQuery. "Quoted text" is a
+// phrase, a trailing * makes a prefix term and everything else is a term.
+func ParseSearchQuery(q string) Domain.SearchQuery {
+	var query Domain.SearchQuery
+	parts := strings.Split(q, `"`)
+	for i, part := range parts {
+		// Odd segments sit between quotes
+		if i%2 == 1 {
+			phrase := words(part)
+			switch len(phrase) {
+			case 0:
+			case 1:
+				query.Terms = append(query.Terms, phrase[0])
+			default:
+				query.Phrases = append(query.Phrases, phrase)
+			}
+			continue
+		}
+		for _, field := range strings.Fields(part) {
+			prefix := strings.HasSuffix(field, "*")
+			for _, w := range words(field) {
+				if prefix {
+					query.Prefixes = append(query.Prefixes, w)
+				} else {
+					query.Terms = append(query.Terms, w)
+				}
+			}
+		}
+	}
+	return query
+}
+
+// matchField returns how often each clause of query hits the field, in
+// Terms, Prefixes, Phrases order, and the token spans that were hit
+func matchField(query Domain.SearchQuery, tokens []token) ([]int, []token) {
+	counts := make([]int, len(query.Terms)+len(query.Prefixes)+len(query.Phrases))
+	hit := make([]bool, len(tokens))
+
+	for i, term := range query.Terms {
+		for j, t := range tokens {
+			if t.text == term {
+				counts[i]++
+				hit[j] = true
+			}
+		}
+	}
+	offset := len(query.Terms)
+	for i, prefix := range query.Prefixes {
+		for j, t := range tokens {
+			if strings.HasPrefix(t.text, prefix) {
+				counts[offset+i]++
+				hit[j] = true
+			}
+		}
+	}
+	offset += len(query.Prefixes)
+	for i, phrase := range query.Phrases {
+		for j := 0; j+len(phrase) <= len(tokens); j++ {
+			matched := true
+			for k, w := range phrase {
+				if tokens[j+k].text != w {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				counts[offset+i]++
+				for k := range phrase {
+					hit[j+k] = true
+				}
+			}
+		}
+	}
+
+	var spans []token
+	for j, t := range tokens {
+		if hit[j] {
+			spans = append(spans, t)
+		}
+	}
+	return counts, spans
+}
+
+// scoreTask checks task against query. A task matches only when every clause
+// hits the title or the description.
+func scoreTask(query Domain.SearchQuery, task Domain.Task) (Domain.TaskSearchResult, bool) {
+	titleCounts, titleSpans := matchField(query, tokenize(task.Title))
+	descCounts, descSpans := matchField(query, tokenize(task.Description))
+
+	phraseStart := len(query.Terms) + len(query.Prefixes)
+	score := 0.0
+	for i := range titleCounts {
+		if titleCounts[i] == 0 && descCounts[i] == 0 {
+			return Domain.TaskSearchResult{}, false
+		}
+		clause := float64(titleCounts[i]*titleWeight + descCounts[i]*descriptionWeight)
+		if i >= phraseStart {
+			clause *= phraseBoost
+		}
+		score += clause
+	}
+
+	highlights := map[string]string{}
+	if len(titleSpans) > 0 {
+		highlights["title"] = mark(task.Title, titleSpans, false)
+	}
+	if len(descSpans) > 0 {
+		highlights["description"] = mark(task.Description, descSpans, true)
+	}
+	return Domain.TaskSearchResult{Task: task, Score: score, Highlights: highlights}, true
+}
+
+// mark wraps every span in <mark> tags and HTML-escapes the text around
+// them, so the result is safe to render. With snippet set the text is cut
+// down to a window around the first hit.
+func mark(text string, spans []token, snippet bool) string {
+	from, to := 0, len(text)
+	if snippet {
+		from = spans[0].start - snippetRadius
+		to = spans[0].end + snippetRadius
+		if from < 0 {
+			from = 0
+		}
+		if to > len(text) {
+			to = len(text)
+		}
+		// Don't cut through a multi-byte character
+		for from > 0 && !utf8Start(text[from]) {
+			from--
+		}
+		for to < len(text) && !utf8Start(text[to]) {
+			to++
+		}
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := from
+	for _, s := range spans {
+		if s.start < from || s.end > to {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:s.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[s.start:s.end]))
+		b.WriteString("</mark>")
+		pos = s.end
+	}
+	b.WriteString(html.EscapeString(text[pos:to]))
+	if to < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+func utf8Start(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// rankTasks scores candidates, drops the ones that don't match and returns
+// the best limit results
+func rankTasks(query Domain.SearchQuery, candidates []Domain.Task, limit int) []Domain.TaskSearchResult {
+	results := []Domain.TaskSearchResult{}
+	for _, task := range candidates {
+		if result, ok := scoreTask(query, task); ok {
+			results = append(results, result)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Task.ID.Hex() < results[j].Task.ID.Hex()
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
@@ -0,0 +1,48 @@
+package Repositories
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SecurityPolicyRepository stores the single, instance-wide security policy
+type SecurityPolicyRepository interface {
+	// Get returns the zero policy until one is saved
+	Get() (Domain.SecurityPolicy, error)
+	Save(policy Domain.SecurityPolicy) error
+}
+
+// securityPolicyID is the _id of the policy document in the settings
+// collection
+const securityPolicyID = "security_policy"
+
+type mongoSecurityPolicyRepository struct {
+	db *mongo.Database
+}
+
+func NewMongoSecurityPolicyRepository(db *mongo.Database) SecurityPolicyRepository {
+	return &mongoSecurityPolicyRepository{db: db}
+}
+
+func (r *mongoSecurityPolicyRepository) Get() (Domain.SecurityPolicy, error) {
+	var policy Domain.SecurityPolicy
+	err := r.db.Collection("settings").FindOne(context.Background(), bson.M{"_id": securityPolicyID}).Decode(&policy)
+	if err == mongo.ErrNoDocuments {
+		return Domain.SecurityPolicy{}, nil
+	}
+	return policy, err
+}
+
+func (r *mongoSecurityPolicyRepository) Save(policy Domain.SecurityPolicy) error {
+	_, err := r.db.Collection("settings").ReplaceOne(
+		context.Background(),
+		bson.M{"_id": securityPolicyID},
+		policy,
+		options.Replace().SetUpsert(true),
+	)
+	return err
+}
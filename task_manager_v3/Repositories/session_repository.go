@@ -0,0 +1,114 @@
+package Repositories
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type SessionRepository interface {
+	Create(session Domain.Session) (Domain.Session, error)
+	FindByID(id primitive.ObjectID) (Domain.Session, error)
+	ListForUser(userID primitive.ObjectID) ([]Domain.Session, error)
+	Touch(id primitive.ObjectID, at time.Time) error
+	// Delete removes one of userID's sessions
+	Delete(userID, id primitive.ObjectID) error
+	// DeleteForUser removes all of a user's sessions except keep, which may
+	// be primitive.NilObjectID, and returns how many it removed
+	DeleteForUser(userID, keep primitive.ObjectID) (int64, error)
+}
+
+var errSessionNotFound = errors.New("session not found")
+
+type mongoSessionRepository struct {
+	db *mongo.Database
+}
+
+// NewMongoSessionRepository also creates a TTL index so sessions disappear
+// once their token has expired
+func NewMongoSessionRepository(db *mongo.Database) SessionRepository {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := db.Collection("sessions").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}},
+			Options: options.Index().SetName("session_user"),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetName("session_expiry").SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		log.Printf("Could not create session indexes: %v", err)
+	}
+	return &mongoSessionRepository{db: db}
+}
+
+func (r *mongoSessionRepository) Create(session Domain.Session) (Domain.Session, error) {
+	result, err := r.db.Collection("sessions").InsertOne(context.Background(), session)
+	if err != nil {
+		return Domain.Session{}, err
+	}
+	session.ID = result.InsertedID.(primitive.ObjectID)
+	return session, nil
+}
+
+func (r *mongoSessionRepository) FindByID(id primitive.ObjectID) (Domain.Session, error) {
+	var session Domain.Session
+	err := r.db.Collection("sessions").FindOne(context.Background(), bson.M{"_id": id}).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return Domain.Session{}, errSessionNotFound
+	}
+	return session, err
+}
+
+func (r *mongoSessionRepository) ListForUser(userID primitive.ObjectID) ([]Domain.Session, error) {
+	cursor, err := r.db.Collection("sessions").Find(
+		context.Background(),
+		bson.M{"user_id": userID},
+		options.Find().SetSort(bson.D{{Key: "last_seen_at", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	sessions := []Domain.Session{}
+	if err := cursor.All(context.Background(), &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *mongoSessionRepository) Touch(id primitive.ObjectID, at time.Time) error {
+	_, err := r.db.Collection("sessions").UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": bson.M{"last_seen_at": at}})
+	return err
+}
+
+func (r *mongoSessionRepository) Delete(userID, id primitive.ObjectID) error {
+	result, err := r.db.Collection("sessions").DeleteOne(context.Background(), bson.M{"_id": id, "user_id": userID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errSessionNotFound
+	}
+	return nil
+}
+
+func (r *mongoSessionRepository) DeleteForUser(userID, keep primitive.ObjectID) (int64, error) {
+	result, err := r.db.Collection("sessions").DeleteMany(context.Background(), bson.M{"user_id": userID, "_id": bson.M{"$ne": keep}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
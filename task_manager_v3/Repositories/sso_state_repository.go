@@ -0,0 +1,65 @@
+package Repositories
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type SSOStateRepository interface {
+	Create(state Domain.SSOState) error
+	// Consume atomically removes and returns the unexpired state with hash,
+	// so each callback is handled at most once
+	Consume(hash string, now time.Time) (Domain.SSOState, error)
+}
+
+type mongoSSOStateRepository struct {
+	db *mongo.Database
+}
+
+// NewMongoSSOStateRepository also creates a TTL index so abandoned sign-ins
+// are cleared out
+func NewMongoSSOStateRepository(db *mongo.Database) SSOStateRepository {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := db.Collection("sso_states").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "state_hash", Value: 1}},
+			Options: options.Index().SetName("sso_state_hash").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetName("sso_state_expiry").SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		log.Printf("Could not create SSO state indexes: %v", err)
+	}
+	return &mongoSSOStateRepository{db: db}
+}
+
+func (r *mongoSSOStateRepository) Create(state Domain.SSOState) error {
+	_, err := r.db.Collection("sso_states").InsertOne(context.Background(), state)
+	return err
+}
+
+func (r *mongoSSOStateRepository) Consume(hash string, now time.Time) (Domain.SSOState, error) {
+	var state Domain.SSOState
+	err := r.db.Collection("sso_states").FindOneAndDelete(context.Background(), bson.M{
+		"state_hash": hash,
+		"expires_at": bson.M{"$gt": now},
+	}).Decode(&state)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return Domain.SSOState{}, Domain.ErrInvalidSSOState
+		}
+		return Domain.SSOState{}, err
+	}
+	return state, nil
+}
@@ -0,0 +1,182 @@
+package Repositories
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// taskFieldPaths maps sortable JSON field names to their BSON paths
+var taskFieldPaths = map[string]string{
+	"title":      "title",
+	"due_date":   "duedate",
+	"status":     "status",
+	"priority":   "priority",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+func fieldPath(field string) string {
+	if key, ok := strings.CutPrefix(field, "cf."); ok {
+		return "custom_fields." + key
+	}
+	return taskFieldPaths[field]
+}
+
+// mongoTaskQuery turns a filter into a Mongo query and sort document
+func mongoTaskQuery(filter Domain.TaskFilter) (bson.M, bson.D) {
+	query := bson.M{}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	if filter.Priority != "" {
+		query["priority"] = filter.Priority
+	}
+	if filter.Project == Domain.DefaultProject {
+		// Tasks created before projects existed have no project at all
+		query["project"] = bson.M{"$in": bson.A{Domain.DefaultProject, nil}}
+	} else if filter.Project != "" {
+		query["project"] = filter.Project
+	}
+	if len(filter.Labels) > 0 {
+		query["labels"] = bson.M{"$all": filter.Labels}
+	}
+	for key, value := range filter.CustomFields {
+		query["custom_fields."+key] = value
+	}
+
+	sortDoc := bson.D{}
+	for _, s := range filter.Sort {
+		direction := 1
+		if s.Desc {
+			direction = -1
+		}
+		sortDoc = append(sortDoc, bson.E{Key: fieldPath(s.Field), Value: direction})
+	}
+	sortDoc = append(sortDoc, bson.E{Key: "_id", Value: 1})
+	return query, sortDoc
+}
+
+// matchesFilter is the in-memory equivalent of mongoTaskQuery's query
+func matchesFilter(task Domain.Task, filter Domain.TaskFilter) bool {
+	if filter.Status != "" && task.Status != filter.Status {
+		return false
+	}
+	if filter.Priority != "" && task.Priority != filter.Priority {
+		return false
+	}
+	if filter.Project != "" {
+		project := task.Project
+		if project == "" {
+			project = Domain.DefaultProject
+		}
+		if project != filter.Project {
+			return false
+		}
+	}
+	for _, want := range filter.Labels {
+		found := false
+		for _, have := range task.Labels {
+			if have == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for key, value := range filter.CustomFields {
+		if task.CustomFields[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func sortValue(task Domain.Task, field string) interface{} {
+	if key, ok := strings.CutPrefix(field, "cf."); ok {
+		return task.CustomFields[key]
+	}
+	switch field {
+	case "title":
+		return task.Title
+	case "due_date":
+		return task.DueDate
+	case "status":
+		return task.Status
+	case "priority":
+		if task.Priority == "" {
+			return nil
+		}
+		return task.Priority
+	case "created_at":
+		return task.CreatedAt
+	case "updated_at":
+		return task.UpdatedAt
+	}
+	return nil
+}
+
+// compareValues orders missing values first, like Mongo does
+func compareValues(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	switch x := a.(type) {
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y)
+		}
+	case float64:
+		if y, ok := b.(float64); ok {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			return x.Compare(y)
+		}
+	}
+	return 0
+}
+
+func sortTasks(tasks []Domain.Task, fields []Domain.SortField) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		for _, f := range fields {
+			c := compareValues(sortValue(tasks[i], f.Field), sortValue(tasks[j], f.Field))
+			if c == 0 {
+				continue
+			}
+			if f.Desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+func removeLabel(labels []primitive.ObjectID, id primitive.ObjectID) []primitive.ObjectID {
+	var kept []primitive.ObjectID
+	for _, label := range labels {
+		if label != id {
+			kept = append(kept, label)
+		}
+	}
+	return kept
+}
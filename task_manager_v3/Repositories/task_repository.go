@@ -4,26 +4,85 @@ import (
 	"a2sv-backend/task_manager_v3/Domain"
 	"context"
 	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type TaskRepository interface {
 	Create(task Domain.Task) (Domain.Task, error)
 	FindAll() ([]Domain.Task, error)
+	Find(filter Domain.TaskFilter) ([]Domain.Task, error)
+	// FindPage returns one page of Find's result, pages starting at 1, and
+	// how many tasks match in all
+	FindPage(filter Domain.TaskFilter, page, pageSize int) ([]Domain.Task, int64, error)
 	FindByID(id primitive.ObjectID) (Domain.Task, error)
 	Update(task Domain.Task) (Domain.Task, error)
 	Delete(id primitive.ObjectID) error
+	Search(query Domain.SearchQuery, limit int) ([]Domain.TaskSearchResult, error)
+	// RemoveLabel strips a deleted label from every task carrying it
+	RemoveLabel(labelID primitive.ObjectID) error
+	// RemoveCustomField drops a deleted custom field's values from every task
+	RemoveCustomField(key string) error
+	// ForEach calls fn for every task without loading them all at once and
+	// stops at the first error fn returns
+	ForEach(fn func(task Domain.Task) error) error
+	FindByExternalID(externalID string) (Domain.Task, error)
+	// UpsertByExternalID updates the task with the same ExternalID or creates
+	// it, reporting which one happened
+	UpsertByExternalID(task Domain.Task) (Domain.Task, bool, error)
+	// BulkWrite applies already validated ops. With atomic set either every op
+	// is applied or none is and Domain.ErrBulkAborted is returned.
+	BulkWrite(ops []Domain.BulkOperation, atomic bool) ([]Domain.BulkResult, error)
 }
 
 type mongoTaskRepository struct {
 	db *mongo.Database
 }
 
+// searchCandidateLimit caps how many text index hits are re-ranked in Go
+const searchCandidateLimit = 500
+
 func NewMongoTaskRepository(db *mongo.Database) TaskRepository {
-	return &mongoTaskRepository{db: db}
+	r := &mongoTaskRepository{db: db}
+	if err := r.ensureIndexes(); err != nil {
+		log.Printf("Could not create task indexes: %v", err)
+	}
+	return r
+}
+
+// ensureIndexes creates the text index used by Search and the unique index
+// imports upsert on. Stemming and stop
+// words are turned off so Mongo matches whole words like the in-memory index.
+func (r *mongoTaskRepository) ensureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.db.Collection("tasks").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "title", Value: "text"}, {Key: "description", Value: "text"}},
+			Options: options.Index().
+				SetName("task_text").
+				SetDefaultLanguage("none").
+				SetWeights(bson.M{"title": titleWeight, "description": descriptionWeight}),
+		},
+		{
+			Keys: bson.D{{Key: "external_id", Value: 1}},
+			Options: options.Index().
+				SetName("task_external_id").
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"external_id": bson.M{"$type": "string"}}),
+		},
+	})
+	return err
 }
 
 func (r *mongoTaskRepository) Create(task Domain.Task) (Domain.Task, error) {
@@ -49,27 +108,67 @@ func (r *mongoTaskRepository) FindAll() ([]Domain.Task, error) {
 	return tasks, nil
 }
 
+func (r *mongoTaskRepository) Find(filter Domain.TaskFilter) ([]Domain.Task, error) {
+	query, sortDoc := mongoTaskQuery(filter)
+	cursor, err := r.db.Collection("tasks").Find(context.Background(), query, options.Find().SetSort(sortDoc))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var tasks []Domain.Task
+	if err = cursor.All(context.Background(), &tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (r *mongoTaskRepository) FindPage(filter Domain.TaskFilter, page, pageSize int) ([]Domain.Task, int64, error) {
+	query, sortDoc := mongoTaskQuery(filter)
+	total, err := r.db.Collection("tasks").CountDocuments(context.Background(), query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(sortDoc).
+		SetSkip(int64((page - 1) * pageSize)).
+		SetLimit(int64(pageSize))
+	cursor, err := r.db.Collection("tasks").Find(context.Background(), query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(context.Background())
+
+	tasks := []Domain.Task{}
+	if err = cursor.All(context.Background(), &tasks); err != nil {
+		return nil, 0, err
+	}
+	return tasks, total, nil
+}
+
 func (r *mongoTaskRepository) FindByID(id primitive.ObjectID) (Domain.Task, error) {
 	var task Domain.Task
 	err := r.db.Collection("tasks").FindOne(context.Background(), bson.M{"_id": id}).Decode(&task)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return Domain.Task{}, errors.New("task not found")
+			return Domain.Task{}, Domain.ErrTaskNotFound
 		}
 		return Domain.Task{}, err
 	}
 	return task, nil
 }
 
+// Update replaces the whole document: with $set the omitempty fields of a
+// task would be left out, so emptying them would keep the old values
 func (r *mongoTaskRepository) Update(task Domain.Task) (Domain.Task, error) {
-	_, err := r.db.Collection("tasks").UpdateOne(
-		context.Background(),
-		bson.M{"_id": task.ID},
-		bson.M{"$set": task},
-	)
+	result, err := r.db.Collection("tasks").ReplaceOne(context.Background(), bson.M{"_id": task.ID}, task)
 	if err != nil {
 		return Domain.Task{}, err
 	}
+	if result.MatchedCount == 0 {
+		return Domain.Task{}, Domain.ErrTaskNotFound
+	}
 	return task, nil
 }
 
@@ -77,3 +176,224 @@ func (r *mongoTaskRepository) Delete(id primitive.ObjectID) error {
 	_, err := r.db.Collection("tasks").DeleteOne(context.Background(), bson.M{"_id": id})
 	return err
 }
+
+func (r *mongoTaskRepository) Search(query Domain.SearchQuery, limit int) ([]Domain.TaskSearchResult, error) {
+	filter := bson.M{}
+	opts := options.Find().SetLimit(searchCandidateLimit)
+
+	// Quoting every word makes $text require all of them, like the in-memory index
+	var quoted []string
+	for _, term := range query.Terms {
+		quoted = append(quoted, fmt.Sprintf("%q", term))
+	}
+	for _, phrase := range query.Phrases {
+		quoted = append(quoted, fmt.Sprintf("%q", strings.Join(phrase, " ")))
+	}
+	if len(quoted) > 0 {
+		filter["$text"] = bson.M{"$search": strings.Join(quoted, " ")}
+		opts.SetProjection(bson.M{"score": bson.M{"$meta": "textScore"}})
+		opts.SetSort(bson.M{"score": bson.M{"$meta": "textScore"}})
+	}
+
+	// The text index can't do prefixes, so those become word-boundary regexes
+	var prefixes []bson.M
+	for _, prefix := range query.Prefixes {
+		pattern := primitive.Regex{Pattern: `\b` + regexp.QuoteMeta(prefix), Options: "i"}
+		prefixes = append(prefixes, bson.M{"$or": []bson.M{
+			{"title": pattern},
+			{"description": pattern},
+		}})
+	}
+	if len(prefixes) > 0 {
+		filter["$and"] = prefixes
+	}
+
+	cursor, err := r.db.Collection("tasks").Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var candidates []Domain.Task
+	if err = cursor.All(context.Background(), &candidates); err != nil {
+		return nil, err
+	}
+	return rankTasks(query, candidates, limit), nil
+}
+
+func (r *mongoTaskRepository) BulkWrite(ops []Domain.BulkOperation, atomic bool) ([]Domain.BulkResult, error) {
+	ctx := context.Background()
+	coll := r.db.Collection("tasks")
+
+	var ids []primitive.ObjectID
+	for _, op := range ops {
+		if op.Op != Domain.BulkCreate {
+			ids = append(ids, op.TaskID)
+		}
+	}
+	existing := map[primitive.ObjectID]Domain.Task{}
+	if len(ids) > 0 {
+		cursor, err := coll.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+		if err != nil {
+			return nil, err
+		}
+		var found []Domain.Task
+		if err := cursor.All(ctx, &found); err != nil {
+			return nil, err
+		}
+		for _, task := range found {
+			existing[task.ID] = task
+		}
+	}
+
+	results, ok := planBulk(ops, existing)
+	if atomic && !ok {
+		abortBulk(results)
+		return results, Domain.ErrBulkAborted
+	}
+
+	// models[j] was built from ops[opIndex[j]]
+	var models []mongo.WriteModel
+	var opIndex []int
+	for i, result := range results {
+		if result.Status >= http.StatusBadRequest {
+			continue
+		}
+		switch ops[i].Op {
+		case Domain.BulkCreate:
+			models = append(models, mongo.NewInsertOneModel().SetDocument(result.Task))
+		case Domain.BulkUpdate, Domain.BulkTransition:
+			models = append(models, mongo.NewReplaceOneModel().
+				SetFilter(bson.M{"_id": ops[i].TaskID}).
+				SetReplacement(result.Task))
+		case Domain.BulkDelete:
+			models = append(models, mongo.NewDeleteOneModel().SetFilter(bson.M{"_id": ops[i].TaskID}))
+		}
+		opIndex = append(opIndex, i)
+	}
+	if len(models) == 0 {
+		return results, nil
+	}
+
+	if !atomic {
+		_, err := coll.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+		var bulkErr mongo.BulkWriteException
+		if errors.As(err, &bulkErr) {
+			for _, writeErr := range bulkErr.WriteErrors {
+				failBulkItem(&results[opIndex[writeErr.Index]], writeErr.Message)
+			}
+			return results, nil
+		}
+		return results, err
+	}
+
+	session, err := r.db.Client().StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return coll.BulkWrite(sc, models, options.BulkWrite().SetOrdered(true))
+	})
+	if err == nil {
+		return results, nil
+	}
+
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == 20 {
+		// IllegalOperation: standalone servers can't run transactions
+		return nil, Domain.ErrTransactionsUnsupported
+	}
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && len(bulkErr.WriteErrors) > 0 {
+		failBulkItem(&results[opIndex[bulkErr.WriteErrors[0].Index]], bulkErr.WriteErrors[0].Message)
+		abortBulk(results)
+		return results, Domain.ErrBulkAborted
+	}
+	return nil, err
+}
+
+func failBulkItem(result *Domain.BulkResult, message string) {
+	result.Status = http.StatusInternalServerError
+	result.Error = message
+	result.Task = nil
+}
+
+func (r *mongoTaskRepository) RemoveLabel(labelID primitive.ObjectID) error {
+	_, err := r.db.Collection("tasks").UpdateMany(
+		context.Background(),
+		bson.M{"labels": labelID},
+		bson.M{"$pull": bson.M{"labels": labelID}},
+	)
+	return err
+}
+
+func (r *mongoTaskRepository) RemoveCustomField(key string) error {
+	path := "custom_fields." + key
+	_, err := r.db.Collection("tasks").UpdateMany(
+		context.Background(),
+		bson.M{path: bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{path: ""}},
+	)
+	return err
+}
+
+func (r *mongoTaskRepository) ForEach(fn func(task Domain.Task) error) error {
+	ctx := context.Background()
+	cursor, err := r.db.Collection("tasks").Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var task Domain.Task
+		if err := cursor.Decode(&task); err != nil {
+			return err
+		}
+		if err := fn(task); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+func (r *mongoTaskRepository) FindByExternalID(externalID string) (Domain.Task, error) {
+	var task Domain.Task
+	err := r.db.Collection("tasks").FindOne(context.Background(), bson.M{"external_id": externalID}).Decode(&task)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return Domain.Task{}, Domain.ErrTaskNotFound
+		}
+		return Domain.Task{}, err
+	}
+	return task, nil
+}
+
+func (r *mongoTaskRepository) UpsertByExternalID(task Domain.Task) (Domain.Task, bool, error) {
+	if task.ExternalID == "" {
+		return Domain.Task{}, false, errors.New("external ID is required")
+	}
+	task.ID = primitive.NilObjectID
+
+	// Replacing keeps _id, and unlike $set clears the fields the import left empty
+	result, err := r.db.Collection("tasks").ReplaceOne(
+		context.Background(),
+		bson.M{"external_id": task.ExternalID},
+		task,
+		options.Replace().SetUpsert(true),
+	)
+	if err != nil {
+		return Domain.Task{}, false, err
+	}
+
+	if id, ok := result.UpsertedID.(primitive.ObjectID); ok {
+		task.ID = id
+		return task, true, nil
+	}
+
+	var stored Domain.Task
+	err = r.db.Collection("tasks").FindOne(context.Background(), bson.M{"external_id": task.ExternalID}).Decode(&stored)
+	return stored, false, err
+}
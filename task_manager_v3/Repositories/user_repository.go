@@ -4,26 +4,232 @@ import (
 	"a2sv-backend/task_manager_v3/Domain"
 	"context"
 	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type UserRepository interface {
 	Create(user Domain.User) (Domain.User, error)
 	FindByUsername(username string) (Domain.User, error)
+	// FindByEmail matches emails after Domain.NormalizeEmail
+	FindByEmail(email string) (Domain.User, error)
 	FindByID(id primitive.ObjectID) (Domain.User, error)
+	// FindByIDs loads the users with the given IDs, in no particular order.
+	// IDs with no user are left out.
+	FindByIDs(ids []primitive.ObjectID) ([]Domain.User, error)
 	Update(user Domain.User) (Domain.User, error)
 	Count() (int64, error)
+	// List returns one page of users ordered by username and the number of
+	// users matching the query across all pages
+	List(query Domain.UserQuery) ([]Domain.User, int64, error)
+	// CountActiveAdmins counts admins that are not deactivated
+	CountActiveAdmins() (int64, error)
+	Delete(id primitive.ObjectID) error
+	// UpdateKeepingAnAdmin is Update, except that it writes nothing and
+	// returns Domain.ErrLastAdmin if the update would leave no active admin.
+	// The check and the write are atomic.
+	UpdateKeepingAnAdmin(user Domain.User) (Domain.User, error)
+	// DeleteKeepingAnAdmin is Delete with the guarantee of
+	// UpdateKeepingAnAdmin
+	DeleteKeepingAnAdmin(id primitive.ObjectID) error
+	// ClaimFirstAdmin atomically takes the bootstrap lock for id, so that
+	// concurrent bootstraps run one at a time. It reports false while
+	// another holder has it. A claim that is never released lapses after
+	// firstAdminClaimTTL.
+	ClaimFirstAdmin(id primitive.ObjectID) (bool, error)
+	// ReleaseFirstAdmin gives up a lock taken by ClaimFirstAdmin for id
+	ReleaseFirstAdmin(id primitive.ObjectID) error
+	// UseTOTPStep atomically records step as the last TOTP step the user
+	// signed in with. It reports false if that step or a later one was
+	// already used, so each code works once.
+	UseTOTPStep(id primitive.ObjectID, step int64) (bool, error)
+	// UseRecoveryCode atomically removes a recovery code hash from the
+	// user, reporting false if it wasn't there
+	UseRecoveryCode(id primitive.ObjectID, hash string) (bool, error)
 }
 
 type mongoUserRepository struct {
 	db *mongo.Database
 }
 
+// NewMongoUserRepository exits when the users collection can't be migrated:
+// without the unique index usernames that differ only in case could sign up
 func NewMongoUserRepository(db *mongo.Database) UserRepository {
-	return &mongoUserRepository{db: db}
+	r := &mongoUserRepository{db: db}
+	if err := r.migrate(); err != nil {
+		log.Fatalf("Could not prepare user collection: %v", err)
+	}
+	return r
+}
+
+const userEmailIndex = "user_email"
+
+// userWriteError maps duplicate keys on the unique user indexes to typed
+// conflicts
+func userWriteError(err error) error {
+	if !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	if strings.Contains(err.Error(), userEmailIndex) {
+		return Domain.ErrEmailTaken
+	}
+	return Domain.ErrUsernameTaken
+}
+
+// firstAdminClaim is the _id of the document ClaimFirstAdmin inserts
+const firstAdminClaim = "first_admin"
+
+// firstAdminClaimTTL bounds how long a bootstrap that crashed while holding
+// the claim blocks the next one
+const firstAdminClaimTTL = time.Minute
+
+// adminGuard is the _id of a document every admin-removing transaction
+// writes, so two that run at once conflict and the retry re-counts instead of
+// each seeing the other's admin still active
+const adminGuard = "admin_guard"
+
+// migrate backfills username_key on users created before it existed and adds
+// the unique index on it. It also drops the permanent first-admin claim older
+// versions kept, since bootstrapping now goes by the admins that exist.
+func (r *mongoUserRepository) migrate() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	users := r.db.Collection("users")
+
+	cursor, err := users.Find(ctx, bson.M{"username_key": bson.M{"$exists": false}})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var user Domain.User
+		if err := cursor.Decode(&user); err != nil {
+			return err
+		}
+		_, err := users.UpdateOne(ctx, bson.M{"_id": user.ID},
+			bson.M{"$set": bson.M{"username_key": Domain.NormalizeUsername(user.Username)}})
+		if err != nil {
+			return err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	if err := checkUsernameCollisions(ctx, users); err != nil {
+		return err
+	}
+
+	_, err = users.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "username_key", Value: 1}},
+			Options: options.Index().SetName("user_username_key").SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().
+				SetName(userEmailIndex).
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"email": bson.M{"$type": "string"}}),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Collection("user_claims").DeleteOne(ctx,
+		bson.M{"_id": firstAdminClaim, "expires_at": bson.M{"$exists": false}})
+	if err != nil {
+		return err
+	}
+
+	// Create the guard up front: two transactions upserting it would
+	// collide on the _id rather than conflict and retry
+	_, err = r.db.Collection("user_claims").UpdateOne(ctx, bson.M{"_id": adminGuard},
+		bson.M{"$setOnInsert": bson.M{"writes": 0}}, options.Update().SetUpsert(true))
+	return err
+}
+
+// checkUsernameCollisions names the usernames that share a username_key, which
+// the unique index can't be built over. An admin has to rename all but one.
+func checkUsernameCollisions(ctx context.Context, users *mongo.Collection) error {
+	cursor, err := users.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{"_id": "$username_key", "usernames": bson.M{"$push": "$username"}, "count": bson.M{"$sum": 1}}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+	})
+	if err != nil {
+		return err
+	}
+	var groups []struct {
+		Usernames []string `bson:"usernames"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return err
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+	collisions := make([]string, len(groups))
+	for i, group := range groups {
+		quoted := make([]string, len(group.Usernames))
+		for j, username := range group.Usernames {
+			quoted[j] = strconv.Quote(username)
+		}
+		collisions[i] = strings.Join(quoted, " and ")
+	}
+	return fmt.Errorf("usernames collide on username_key, rename all but one of each: %s", strings.Join(collisions, "; "))
+}
+
+func (r *mongoUserRepository) ClaimFirstAdmin(id primitive.ObjectID) (bool, error) {
+	ctx := context.Background()
+	claims := r.db.Collection("user_claims")
+
+	now := time.Now()
+	if _, err := claims.DeleteOne(ctx, bson.M{"_id": firstAdminClaim, "expires_at": bson.M{"$lte": now}}); err != nil {
+		return false, err
+	}
+	_, err := claims.InsertOne(ctx, bson.M{"_id": firstAdminClaim, "user_id": id, "expires_at": now.Add(firstAdminClaimTTL)})
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *mongoUserRepository) ReleaseFirstAdmin(id primitive.ObjectID) error {
+	_, err := r.db.Collection("user_claims").DeleteOne(context.Background(), bson.M{"_id": firstAdminClaim, "user_id": id})
+	return err
+}
+
+func (r *mongoUserRepository) UseTOTPStep(id primitive.ObjectID, step int64) (bool, error) {
+	result, err := r.db.Collection("users").UpdateOne(
+		context.Background(),
+		bson.M{"_id": id, "totp_last_step": bson.M{"$not": bson.M{"$gte": step}}},
+		bson.M{"$set": bson.M{"totp_last_step": step}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *mongoUserRepository) UseRecoveryCode(id primitive.ObjectID, hash string) (bool, error) {
+	result, err := r.db.Collection("users").UpdateOne(
+		context.Background(),
+		bson.M{"_id": id, "recovery_codes": hash},
+		bson.M{"$pull": bson.M{"recovery_codes": hash}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
 }
 
 func (r *mongoUserRepository) Count() (int64, error) {
@@ -31,19 +237,38 @@ func (r *mongoUserRepository) Count() (int64, error) {
 }
 
 func (r *mongoUserRepository) Create(user Domain.User) (Domain.User, error) {
-	_, err := r.db.Collection("users").InsertOne(context.Background(), user)
+	user.UsernameKey = Domain.NormalizeUsername(user.Username)
+	user.Email = Domain.NormalizeEmail(user.Email)
+	result, err := r.db.Collection("users").InsertOne(context.Background(), user)
 	if err != nil {
-		return Domain.User{}, err
+		return Domain.User{}, userWriteError(err)
 	}
+	user.ID = result.InsertedID.(primitive.ObjectID)
 	return user, nil
 }
 
 func (r *mongoUserRepository) FindByUsername(username string) (Domain.User, error) {
 	var user Domain.User
-	err := r.db.Collection("users").FindOne(context.Background(), bson.M{"username": username}).Decode(&user)
+	err := r.db.Collection("users").FindOne(context.Background(), bson.M{"username_key": Domain.NormalizeUsername(username)}).Decode(&user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return Domain.User{}, errors.New("user not found")
+			return Domain.User{}, Domain.ErrUserNotFound
+		}
+		return Domain.User{}, err
+	}
+	return user, nil
+}
+
+func (r *mongoUserRepository) FindByEmail(email string) (Domain.User, error) {
+	email = Domain.NormalizeEmail(email)
+	if email == "" {
+		return Domain.User{}, Domain.ErrUserNotFound
+	}
+	var user Domain.User
+	err := r.db.Collection("users").FindOne(context.Background(), bson.M{"email": email}).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return Domain.User{}, Domain.ErrUserNotFound
 		}
 		return Domain.User{}, err
 	}
@@ -55,21 +280,157 @@ func (r *mongoUserRepository) FindByID(id primitive.ObjectID) (Domain.User, erro
 	err := r.db.Collection("users").FindOne(context.Background(), bson.M{"_id": id}).Decode(&user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return Domain.User{}, errors.New("user not found")
+			return Domain.User{}, Domain.ErrUserNotFound
 		}
 		return Domain.User{}, err
 	}
 	return user, nil
 }
 
+func (r *mongoUserRepository) FindByIDs(ids []primitive.ObjectID) ([]Domain.User, error) {
+	cursor, err := r.db.Collection("users").Find(context.Background(), bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	users := []Domain.User{}
+	if err = cursor.All(context.Background(), &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 func (r *mongoUserRepository) Update(user Domain.User) (Domain.User, error) {
-	_, err := r.db.Collection("users").UpdateOne(
-		context.Background(),
-		bson.M{"_id": user.ID},
-		bson.M{"$set": user},
-	)
+	return r.update(context.Background(), user)
+}
+
+func (r *mongoUserRepository) update(ctx context.Context, user Domain.User) (Domain.User, error) {
+	user.UsernameKey = Domain.NormalizeUsername(user.Username)
+	user.Email = Domain.NormalizeEmail(user.Email)
+	update := bson.M{"$set": user}
+	if user.Email == "" {
+		// Unset rather than store "" so the partial unique index ignores it
+		update["$unset"] = bson.M{"email": ""}
+	}
+	_, err := r.db.Collection("users").UpdateOne(ctx, bson.M{"_id": user.ID}, update)
 	if err != nil {
-		return Domain.User{}, err
+		return Domain.User{}, userWriteError(err)
 	}
 	return user, nil
 }
+
+// userListFilter builds the Mongo filter for a user query
+func userListFilter(query Domain.UserQuery) bson.M {
+	filter := bson.M{}
+	if query.Search != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(query.Search), Options: "i"}
+		filter["$or"] = bson.A{bson.M{"username": pattern}, bson.M{"display_name": pattern}}
+	}
+	if query.Role != "" {
+		filter["role"] = query.Role
+	}
+	switch query.Status {
+	case Domain.UserStatusActive:
+		filter["deactivated"] = bson.M{"$ne": true}
+	case Domain.UserStatusDeactivated:
+		filter["deactivated"] = true
+	}
+	return filter
+}
+
+func (r *mongoUserRepository) List(query Domain.UserQuery) ([]Domain.User, int64, error) {
+	filter := userListFilter(query)
+	total, err := r.db.Collection("users").CountDocuments(context.Background(), filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "username", Value: 1}}).
+		SetSkip(int64((query.Page - 1) * query.PageSize)).
+		SetLimit(int64(query.PageSize))
+	cursor, err := r.db.Collection("users").Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(context.Background())
+
+	users := []Domain.User{}
+	if err = cursor.All(context.Background(), &users); err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+func (r *mongoUserRepository) CountActiveAdmins() (int64, error) {
+	return r.countActiveAdmins(context.Background())
+}
+
+func (r *mongoUserRepository) countActiveAdmins(ctx context.Context) (int64, error) {
+	return r.db.Collection("users").CountDocuments(ctx, bson.M{
+		"role":        Domain.RoleAdmin,
+		"deactivated": bson.M{"$ne": true},
+	})
+}
+
+func (r *mongoUserRepository) Delete(id primitive.ObjectID) error {
+	_, err := r.db.Collection("users").DeleteOne(context.Background(), bson.M{"_id": id})
+	return err
+}
+
+func (r *mongoUserRepository) UpdateKeepingAnAdmin(user Domain.User) (Domain.User, error) {
+	var updated Domain.User
+	err := r.keepingAnAdmin(func(ctx context.Context) error {
+		var err error
+		updated, err = r.update(ctx, user)
+		return err
+	})
+	if err != nil {
+		return Domain.User{}, err
+	}
+	return updated, nil
+}
+
+func (r *mongoUserRepository) DeleteKeepingAnAdmin(id primitive.ObjectID) error {
+	return r.keepingAnAdmin(func(ctx context.Context) error {
+		_, err := r.db.Collection("users").DeleteOne(ctx, bson.M{"_id": id})
+		return err
+	})
+}
+
+// keepingAnAdmin runs change in a transaction that re-counts the active
+// admins afterwards and aborts if none are left
+func (r *mongoUserRepository) keepingAnAdmin(change func(ctx context.Context) error) error {
+	ctx := context.Background()
+	session, err := r.db.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		_, err := r.db.Collection("user_claims").UpdateOne(sc, bson.M{"_id": adminGuard}, bson.M{"$inc": bson.M{"writes": 1}})
+		if err != nil {
+			return nil, err
+		}
+		if err := change(sc); err != nil {
+			return nil, err
+		}
+		admins, err := r.countActiveAdmins(sc)
+		if err != nil {
+			return nil, err
+		}
+		if admins == 0 {
+			return nil, Domain.ErrLastAdmin
+		}
+		return nil, nil
+	})
+
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == 20 {
+		// IllegalOperation: standalone servers can't run transactions
+		return Domain.ErrTransactionsUnsupported
+	}
+	return err
+}
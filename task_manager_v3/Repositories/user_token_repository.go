@@ -0,0 +1,78 @@
+package Repositories
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type UserTokenRepository interface {
+	Create(token Domain.UserToken) (Domain.UserToken, error)
+	// Consume atomically removes and returns the unexpired token with hash
+	// and purpose, so each token works at most once
+	Consume(hash, purpose string, now time.Time) (Domain.UserToken, error)
+	// DeleteForUser drops a user's outstanding tokens of one purpose
+	DeleteForUser(userID primitive.ObjectID, purpose string) error
+}
+
+type mongoUserTokenRepository struct {
+	db *mongo.Database
+}
+
+// NewMongoUserTokenRepository also creates a TTL index so Mongo clears out
+// expired tokens on its own
+func NewMongoUserTokenRepository(db *mongo.Database) UserTokenRepository {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := db.Collection("user_tokens").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetName("user_token_hash").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetName("user_token_expiry").SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		log.Printf("Could not create user token indexes: %v", err)
+	}
+	return &mongoUserTokenRepository{db: db}
+}
+
+func (r *mongoUserTokenRepository) Create(token Domain.UserToken) (Domain.UserToken, error) {
+	result, err := r.db.Collection("user_tokens").InsertOne(context.Background(), token)
+	if err != nil {
+		return Domain.UserToken{}, err
+	}
+	token.ID = result.InsertedID.(primitive.ObjectID)
+	return token, nil
+}
+
+func (r *mongoUserTokenRepository) Consume(hash, purpose string, now time.Time) (Domain.UserToken, error) {
+	var token Domain.UserToken
+	err := r.db.Collection("user_tokens").FindOneAndDelete(context.Background(), bson.M{
+		"token_hash": hash,
+		"purpose":    purpose,
+		"expires_at": bson.M{"$gt": now},
+	}).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return Domain.UserToken{}, Domain.ErrInvalidToken
+		}
+		return Domain.UserToken{}, err
+	}
+	return token, nil
+}
+
+func (r *mongoUserTokenRepository) DeleteForUser(userID primitive.ObjectID, purpose string) error {
+	_, err := r.db.Collection("user_tokens").DeleteMany(context.Background(), bson.M{"user_id": userID, "purpose": purpose})
+	return err
+}
@@ -0,0 +1,515 @@
+package client_test
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Tests/mocks"
+	"a2sv-backend/task_manager_v3/client"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fastRetries keeps tests from sleeping through real backoff
+var fastRetries = client.RetryPolicy{MaxAttempts: 4, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Second}
+
+// recorder counts the requests a client sends and the statuses it gets
+type recorder struct {
+	mu       sync.Mutex
+	requests []string
+	statuses []int
+}
+
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req.Method+" "+req.URL.Path)
+	if err == nil {
+		r.statuses = append(r.statuses, resp.StatusCode)
+	}
+	return resp, err
+}
+
+func (r *recorder) count(status int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, s := range r.statuses {
+		if s == status {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *recorder) sent(request string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, sent := range r.requests {
+		if sent == request {
+			n++
+		}
+	}
+	return n
+}
+
+// setup starts the real router over in-memory repositories, with an admin
+// "root" and a user "alice"
+func setup(t *testing.T) (*mocks.App, *httptest.Server) {
+	app := mocks.NewApp()
+	server := httptest.NewServer(app.Router)
+	t.Cleanup(server.Close)
+
+	_, err := app.UserUsecase.BootstrapAdmin("root", "admin-secret-1")
+	require.NoError(t, err)
+	require.NoError(t, app.UserUsecase.Register(Domain.Registration{Username: "alice", Password: "alice-secret-1", Email: "alice@example.com"}))
+	return app, server
+}
+
+func newTask(title string) Domain.Task {
+	return Domain.Task{Title: title, DueDate: time.Now().AddDate(0, 0, 7).UTC().Truncate(time.Second)}
+}
+
+func TestTasks(t *testing.T) {
+	_, server := setup(t)
+	ctx := context.Background()
+	c := client.New(server.URL, client.WithCredentials("root", "admin-secret-1"))
+
+	created, err := c.Tasks.Create(ctx, newTask("Write the SDK"))
+	require.NoError(t, err)
+	assert.False(t, created.ID.IsZero())
+
+	got, err := c.Tasks.GetByID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Write the SDK", got.Title)
+
+	got.Title = "Ship the SDK"
+	updated, err := c.Tasks.Update(ctx, created.ID, got)
+	require.NoError(t, err)
+	assert.Equal(t, "Ship the SDK", updated.Title)
+
+	results, err := c.Tasks.Search(ctx, "ship", 0)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, created.ID, results[0].Task.ID)
+
+	var export bytes.Buffer
+	require.NoError(t, c.Tasks.Export(ctx, Domain.FormatCSV, &export))
+	assert.Contains(t, export.String(), "Ship the SDK")
+
+	report, err := c.Tasks.Import(ctx, Domain.FormatCSV, strings.NewReader("title,due_date\nImported,2030-01-02\n,2030-01-02\n"), true)
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 2, report.Total)
+	assert.Equal(t, 1, report.Failed)
+
+	require.NoError(t, c.Tasks.Delete(ctx, created.ID))
+	_, err = c.Tasks.GetByID(ctx, created.ID)
+	assert.ErrorIs(t, err, client.ErrNotFound)
+}
+
+func TestBulk(t *testing.T) {
+	_, server := setup(t)
+	ctx := context.Background()
+	c := client.New(server.URL, client.WithCredentials("root", "admin-secret-1"))
+
+	task := newTask("Bulk created")
+	results, err := c.Tasks.Bulk(ctx, []Domain.BulkOperation{
+		{Op: Domain.BulkCreate, Task: &task},
+		{Op: Domain.BulkDelete, ID: primitive.NewObjectID().Hex()},
+	}, false)
+	require.NoError(t, err, "a partial failure is reported per operation")
+	require.Len(t, results, 2)
+	assert.Equal(t, http.StatusCreated, results[0].Status)
+	assert.Equal(t, http.StatusNotFound, results[1].Status)
+
+	// Rejected requests still come with the results explaining why
+	results, err = c.Tasks.Bulk(ctx, []Domain.BulkOperation{{Op: "explode"}}, true)
+	assert.ErrorIs(t, err, client.ErrBadRequest)
+	require.Len(t, results, 1)
+	assert.NotEmpty(t, results[0].Error)
+}
+
+func TestErrors(t *testing.T) {
+	_, server := setup(t)
+	ctx := context.Background()
+
+	c := client.New(server.URL)
+	_, _, err := c.Tasks.ListPage(ctx, Domain.TaskFilter{}, 1, 10)
+	assert.ErrorIs(t, err, client.ErrUnauthorized)
+
+	_, err = c.Users.Login(ctx, "root", "wrong-password")
+	var apiErr *client.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+	assert.NotEmpty(t, apiErr.Message)
+
+	_, err = c.Users.Login(ctx, "alice", "alice-secret-1")
+	require.NoError(t, err)
+	_, err = c.Tasks.Create(ctx, newTask("Not allowed"))
+	assert.ErrorIs(t, err, client.ErrForbidden)
+
+	admin := client.New(server.URL, client.WithCredentials("root", "admin-secret-1"))
+	_, err = admin.Tasks.Create(ctx, Domain.Task{})
+	require.ErrorAs(t, err, &apiErr)
+	assert.ErrorIs(t, err, client.ErrBadRequest)
+	require.NotEmpty(t, apiErr.Fields, "field errors are decoded from the body")
+	fields := make([]string, 0, len(apiErr.Fields))
+	for _, field := range apiErr.Fields {
+		fields = append(fields, field.Field)
+	}
+	assert.Contains(t, fields, "title")
+	assert.Contains(t, err.Error(), apiErr.Fields[0].Message)
+}
+
+func TestIterator(t *testing.T) {
+	app, server := setup(t)
+	ctx := context.Background()
+	for i := 0; i < 25; i++ {
+		_, err := app.TaskUsecase.Create(newTask(fmt.Sprintf("Task %02d", i)))
+		require.NoError(t, err)
+	}
+
+	rec := &recorder{}
+	c := client.New(server.URL, client.WithCredentials("alice", "alice-secret-1"), client.WithHTTPClient(&http.Client{Transport: rec}))
+
+	it := c.Tasks.Iterate(Domain.TaskFilter{Sort: []Domain.SortField{{Field: "title"}}}, 10)
+	var titles []string
+	for it.Next(ctx) {
+		titles = append(titles, it.Task().Title)
+	}
+	require.NoError(t, it.Err())
+	require.Len(t, titles, 25)
+	assert.Equal(t, "Task 00", titles[0])
+	assert.Equal(t, "Task 24", titles[24])
+	assert.Equal(t, int64(25), it.Total())
+	assert.Equal(t, 3, rec.sent("GET /api/v2/tasks"), "three pages of ten")
+
+	all, err := c.Tasks.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, all, 25)
+}
+
+func TestUsers(t *testing.T) {
+	_, server := setup(t)
+	ctx := context.Background()
+	admin := client.New(server.URL, client.WithCredentials("root", "admin-secret-1"))
+
+	me, err := admin.Users.CurrentUser(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "root", me.Username)
+	assert.Equal(t, "admin", me.Role)
+
+	invitation, token, err := admin.Users.CreateInvitation(ctx, "admin", "", 24*time.Hour)
+	require.NoError(t, err)
+	assert.NotEmpty(t, token)
+	invitations, err := admin.Users.ListInvitations(ctx)
+	require.NoError(t, err)
+	require.Len(t, invitations, 1)
+	assert.Equal(t, invitation.ID, invitations[0].ID)
+
+	anonymous := client.New(server.URL)
+	require.NoError(t, anonymous.Users.Register(ctx, Domain.Registration{Username: "bob", Password: "bob-secret-12", Invitation: token}))
+	err = anonymous.Users.Register(ctx, Domain.Registration{Username: "bob", Password: "bob-secret-12"})
+	assert.ErrorIs(t, err, client.ErrConflict)
+
+	users, total, err := admin.Users.ListUsers(ctx, Domain.UserQuery{Search: "bob"})
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, int64(1), total)
+	bob := users[0]
+	assert.Equal(t, "admin", bob.Role, "the invitation's role")
+
+	bob, err = admin.Users.ChangeRole(ctx, bob.ID, "user")
+	require.NoError(t, err)
+	assert.Equal(t, "user", bob.Role)
+	bob, err = admin.Users.Deactivate(ctx, bob.ID)
+	require.NoError(t, err)
+	assert.True(t, bob.Deactivated)
+	bob, err = admin.Users.Reactivate(ctx, bob.ID)
+	require.NoError(t, err)
+	assert.False(t, bob.Deactivated)
+
+	_, err = admin.Users.ChangeRole(ctx, me.ID, "user")
+	assert.ErrorIs(t, err, client.ErrConflict, "admins can't demote themselves")
+
+	var names []string
+	it := admin.Users.Iterate(Domain.UserQuery{PageSize: 1})
+	for it.Next(ctx) {
+		names = append(names, it.User().Username)
+	}
+	require.NoError(t, it.Err())
+	assert.ElementsMatch(t, []string{"root", "alice", "bob"}, names)
+
+	bobClient := client.New(server.URL, client.WithCredentials("bob", "bob-secret-12"))
+	name := "Bob"
+	profile, err := bobClient.Users.UpdateProfile(ctx, Domain.ProfileUpdate{DisplayName: &name})
+	require.NoError(t, err)
+	assert.Equal(t, "Bob", profile.DisplayName)
+	require.NoError(t, bobClient.Users.ChangePassword(ctx, "bob-secret-12", "bob-secret-34"))
+
+	require.NoError(t, admin.Users.Delete(ctx, bob.ID))
+	_, err = admin.Users.GetUser(ctx, bob.ID)
+	assert.ErrorIs(t, err, client.ErrNotFound)
+
+	require.NoError(t, admin.Users.RevokeInvitation(ctx, invitation.ID))
+}
+
+// fakeJWT is a token the client can read the expiry of; the server would
+// reject it
+func fakeJWT(exp time.Time) string {
+	encode := func(v interface{}) string {
+		data, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	return encode(map[string]string{"alg": "HS256"}) + "." + encode(map[string]int64{"exp": exp.Unix()}) + ".signature"
+}
+
+func TestTokenRefresh(t *testing.T) {
+	_, server := setup(t)
+	ctx := context.Background()
+
+	t.Run("Rejected", func(t *testing.T) {
+		rec := &recorder{}
+		c := client.New(server.URL, client.WithCredentials("alice", "alice-secret-1"), client.WithHTTPClient(&http.Client{Transport: rec}))
+		c.SetToken("garbage")
+
+		_, err := c.Users.CurrentUser(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, rec.count(http.StatusUnauthorized))
+		assert.Equal(t, 1, rec.sent("POST /api/v2/login"))
+		assert.NotEqual(t, "garbage", c.Token())
+	})
+
+	t.Run("AboutToExpire", func(t *testing.T) {
+		rec := &recorder{}
+		c := client.New(server.URL, client.WithCredentials("alice", "alice-secret-1"), client.WithHTTPClient(&http.Client{Transport: rec}))
+		c.SetToken(fakeJWT(time.Now().Add(10 * time.Second)))
+
+		_, err := c.Users.CurrentUser(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, rec.count(http.StatusUnauthorized), "the token is replaced before it is sent")
+		assert.Equal(t, 1, rec.sent("POST /api/v2/login"))
+
+		// The new token lasts, so there is no login before the next request
+		_, err = c.Users.CurrentUser(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, rec.sent("POST /api/v2/login"))
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		rec := &recorder{}
+		c := client.New(server.URL, client.WithCredentials("alice", "alice-secret-1"), client.WithHTTPClient(&http.Client{Transport: rec}))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := c.Users.CurrentUser(ctx)
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, 1, rec.sent("POST /api/v2/login"), "requests wait for one login")
+	})
+
+	t.Run("WithoutCredentials", func(t *testing.T) {
+		c := client.New(server.URL, client.WithToken("garbage"))
+		_, err := c.Users.CurrentUser(ctx)
+		assert.ErrorIs(t, err, client.ErrUnauthorized)
+	})
+}
+
+// flaky answers the first failures requests to path with status, then
+// hands requests to next
+type flaky struct {
+	next     http.Handler
+	path     string
+	status   int
+	failures int
+	header   http.Header
+
+	mu   sync.Mutex
+	seen int
+}
+
+func (f *flaky) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	fail := r.URL.Path == f.path && f.seen < f.failures
+	if r.URL.Path == f.path {
+		f.seen++
+	}
+	f.mu.Unlock()
+	if !fail {
+		f.next.ServeHTTP(w, r)
+		return
+	}
+	for key, values := range f.header {
+		w.Header()[key] = values
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(f.status)
+	w.Write([]byte(`{"error":"try again later"}`))
+}
+
+func (f *flaky) requests() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.seen
+}
+
+func TestRetries(t *testing.T) {
+	ctx := context.Background()
+	app := mocks.NewApp()
+	_, err := app.UserUsecase.BootstrapAdmin("root", "admin-secret-1")
+	require.NoError(t, err)
+	token, err := app.Login("root", "admin-secret-1")
+	require.NoError(t, err)
+
+	start := func(f *flaky) *client.Client {
+		f.next = app.Router
+		server := httptest.NewServer(f)
+		t.Cleanup(server.Close)
+		return client.New(server.URL, client.WithToken(token), client.WithRetry(fastRetries))
+	}
+
+	t.Run("Idempotent", func(t *testing.T) {
+		f := &flaky{path: "/api/v2/tasks", status: http.StatusServiceUnavailable, failures: 2}
+		c := start(f)
+		_, _, err := c.Tasks.ListPage(ctx, Domain.TaskFilter{}, 1, 10)
+		require.NoError(t, err)
+		assert.Equal(t, 3, f.requests())
+	})
+
+	t.Run("GivesUp", func(t *testing.T) {
+		f := &flaky{path: "/api/v2/tasks", status: http.StatusBadGateway, failures: 10}
+		c := start(f)
+		_, _, err := c.Tasks.ListPage(ctx, Domain.TaskFilter{}, 1, 10)
+		assert.ErrorIs(t, err, client.ErrServer)
+		assert.Equal(t, fastRetries.MaxAttempts, f.requests())
+	})
+
+	t.Run("NotIdempotent", func(t *testing.T) {
+		f := &flaky{path: "/api/v2/tasks", status: http.StatusServiceUnavailable, failures: 1}
+		c := start(f)
+		_, err := c.Tasks.Create(ctx, newTask("Only once"))
+		assert.ErrorIs(t, err, client.ErrServer)
+		assert.Equal(t, 1, f.requests(), "a POST is never sent twice")
+	})
+
+	t.Run("NotRetryable", func(t *testing.T) {
+		f := &flaky{path: "/api/v2/tasks", status: http.StatusInternalServerError, failures: 1}
+		c := start(f)
+		_, _, err := c.Tasks.ListPage(ctx, Domain.TaskFilter{}, 1, 10)
+		assert.ErrorIs(t, err, client.ErrServer)
+		assert.Equal(t, 1, f.requests())
+	})
+
+	t.Run("RetryAfter", func(t *testing.T) {
+		f := &flaky{path: "/api/v2/tasks", status: http.StatusTooManyRequests, failures: 1, header: http.Header{"Retry-After": {"1"}}}
+		c := start(f)
+		began := time.Now()
+		_, _, err := c.Tasks.ListPage(ctx, Domain.TaskFilter{}, 1, 10)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(began), time.Second)
+		assert.Equal(t, 2, f.requests())
+	})
+
+	t.Run("RateLimitedError", func(t *testing.T) {
+		f := &flaky{path: "/api/v2/tasks", status: http.StatusTooManyRequests, failures: 10, header: http.Header{"Retry-After": {"30"}}}
+		server := httptest.NewServer(f)
+		f.next = app.Router
+		t.Cleanup(server.Close)
+		c := client.New(server.URL, client.WithToken(token), client.WithRetry(client.RetryPolicy{MaxAttempts: 1}))
+
+		_, _, err := c.Tasks.ListPage(ctx, Domain.TaskFilter{}, 1, 10)
+		var apiErr *client.Error
+		require.ErrorAs(t, err, &apiErr)
+		assert.ErrorIs(t, err, client.ErrRateLimited)
+		assert.Equal(t, 30*time.Second, apiErr.RetryAfter)
+	})
+}
+
+func TestSubscribe(t *testing.T) {
+	app, server := setup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := client.New(server.URL, client.WithCredentials("alice", "alice-secret-1"))
+	events, err := c.Tasks.Subscribe(ctx, "")
+	require.NoError(t, err)
+
+	created, err := app.TaskUsecase.Create(newTask("Streamed"))
+	require.NoError(t, err)
+
+	select {
+	case event := <-events:
+		assert.Equal(t, Domain.TaskCreated, event.Type)
+		assert.Equal(t, created.ID, event.TaskID)
+		assert.NotEmpty(t, event.ID)
+		require.NotNil(t, event.Task)
+		assert.Equal(t, "Streamed", event.Task.Title)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event")
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestSubscribeReconnects(t *testing.T) {
+	var mu sync.Mutex
+	var lastEventIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		lastEventIDs = append(lastEventIDs, r.Header.Get("Last-Event-ID"))
+		n := len(lastEventIDs)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		// Each connection sends one event, then drops
+		fmt.Fprintf(w, ": keep-alive\n\nid: %d\nevent: task.updated\ndata: {\"type\":\"task.updated\"}\n\n", n)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := client.New(server.URL, client.WithToken("token"), client.WithRetry(fastRetries))
+	events, err := c.Tasks.Subscribe(ctx, "")
+	require.NoError(t, err)
+
+	for want := 1; want <= 3; want++ {
+		event := <-events
+		assert.Equal(t, fmt.Sprint(want), event.ID)
+		assert.Equal(t, Domain.TaskUpdated, event.Type)
+	}
+	cancel()
+	for range events {
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"", "1", "2"}, lastEventIDs[:3], "each reconnect resumes after the last event")
+}
+
+func TestSubscribeStopsOnError(t *testing.T) {
+	_, server := setup(t)
+	c := client.New(server.URL, client.WithToken("garbage"))
+	_, err := c.Tasks.Subscribe(context.Background(), "")
+	assert.ErrorIs(t, err, client.ErrUnauthorized)
+}
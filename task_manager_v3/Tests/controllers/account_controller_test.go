@@ -0,0 +1,49 @@
+package controllers_test
+
+import (
+	"a2sv-backend/task_manager_v3/Delivery/controllers"
+	"a2sv-backend/task_manager_v3/Usecases"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// slowAccounts stands in for a real account whose reset mail takes a while
+// to send
+type slowAccounts struct {
+	Usecases.AccountUsecase
+	release chan struct{}
+	sent    chan string
+}
+
+func (a *slowAccounts) ForgotPassword(email string) error {
+	<-a.release
+	a.sent <- email
+	return nil
+}
+
+func TestAccountController_ForgotPasswordDoesNotWait(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	accounts := &slowAccounts{release: make(chan struct{}), sent: make(chan string, 1)}
+	r := gin.New()
+	r.POST("/password/forgot", controllers.NewAccountController(accounts).ForgotPassword)
+
+	req, _ := http.NewRequest("POST", "/password/forgot", strings.NewReader(`{"email":"alice@example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusAccepted, w.Code, "answered while the mail is still pending")
+
+	close(accounts.release)
+	select {
+	case email := <-accounts.sent:
+		assert.Equal(t, "alice@example.com", email)
+	case <-time.After(time.Second):
+		t.Fatal("the reset was never requested")
+	}
+}
@@ -0,0 +1,62 @@
+package controllers_test
+
+import (
+	"a2sv-backend/task_manager_v3/Delivery/controllers"
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Infrastructure"
+	"a2sv-backend/task_manager_v3/Repositories"
+	"a2sv-backend/task_manager_v3/Usecases"
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttachmentController_UploadAndDownload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	taskRepo := Repositories.NewInMemoryTaskRepository()
+	task, _ := taskRepo.Create(Domain.Task{Title: "Spec"})
+	attachmentUsecase := Usecases.NewAttachmentUsecase(Repositories.NewInMemoryAttachmentRepository(),
+		taskRepo, Infrastructure.NewInMemoryBlobStore(), 1024)
+	attachmentController := controllers.NewAttachmentController(attachmentUsecase)
+
+	r := gin.New()
+	r.POST("/tasks/:id/attachments", attachmentController.UploadAttachment)
+	r.GET("/tasks/:id/attachments/:attachmentId", attachmentController.DownloadAttachment)
+
+	upload := func(content []byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, _ := form.CreateFormFile("file", "notes.txt")
+		part.Write(content)
+		form.Close()
+
+		req, _ := http.NewRequest("POST", "/tasks/"+task.ID.Hex()+"/attachments", &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := upload([]byte("plain text notes"))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var attachment Domain.Attachment
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &attachment))
+	assert.Equal(t, "text/plain; charset=utf-8", attachment.ContentType)
+
+	req, _ := http.NewRequest("GET", "/tasks/"+task.ID.Hex()+"/attachments/"+attachment.ID.Hex(), nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "plain text notes", w.Body.String())
+	assert.Equal(t, `attachment; filename=notes.txt`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+
+	w = upload(bytes.Repeat([]byte("x"), 2048))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
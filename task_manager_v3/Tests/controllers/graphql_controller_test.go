@@ -0,0 +1,276 @@
+package controllers_test
+
+import (
+	"a2sv-backend/task_manager_v3/Delivery/controllers"
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Tests/mocks"
+	"a2sv-backend/task_manager_v3/Usecases"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// countingCatalog counts batched label lookups
+type countingCatalog struct {
+	Usecases.CatalogUsecase
+	labelLookups int
+}
+
+func (c *countingCatalog) GetLabels(ids []primitive.ObjectID) ([]Domain.Label, error) {
+	c.labelLookups++
+	return c.CatalogUsecase.GetLabels(ids)
+}
+
+// countingUsers counts batched user lookups
+type countingUsers struct {
+	Usecases.UserUsecase
+	userLookups int
+}
+
+func (u *countingUsers) GetUsers(ids []primitive.ObjectID) ([]Domain.User, error) {
+	u.userLookups++
+	return u.UserUsecase.GetUsers(ids)
+}
+
+type graphQLResult struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+// code is the extension code of the first error
+func (r graphQLResult) code() string {
+	if len(r.Errors) == 0 {
+		return ""
+	}
+	code, _ := r.Errors[0].Extensions["code"].(string)
+	return code
+}
+
+// graphQLCall sends a query as viewer, the way AuthMiddleware would have
+// left them on the context
+func graphQLCall(t *testing.T, gc *controllers.GraphQLController, viewer Domain.Viewer, method, query string, variables map[string]interface{}) (int, graphQLResult) {
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", viewer.UserID)
+		c.Set("username", viewer.Username)
+		c.Set("role", viewer.Role)
+		c.Set("auth_method", "jwt")
+	})
+	r.GET("/graphql", gc.GraphQL)
+	r.POST("/graphql", gc.GraphQL)
+
+	var req *http.Request
+	if method == http.MethodGet {
+		params := url.Values{"query": {query}}
+		if variables != nil {
+			raw, _ := json.Marshal(variables)
+			params.Set("variables", string(raw))
+		}
+		req = httptest.NewRequest(method, "/graphql?"+params.Encode(), nil)
+	} else {
+		body, _ := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+		req = httptest.NewRequest(method, "/graphql", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var result graphQLResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result), w.Body.String())
+	return w.Code, result
+}
+
+func TestGraphQLController(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := mocks.NewApp()
+	adminUser, err := app.UserUsecase.BootstrapAdmin("root", "admin-secret-1")
+	require.NoError(t, err)
+	require.NoError(t, app.UserUsecase.Register(Domain.Registration{Username: "alice", Password: "alice-secret-1", Email: "alice@example.com"}))
+	require.NoError(t, app.UserUsecase.Register(Domain.Registration{Username: "bob", Password: "bob-secret-12", Email: "bob@example.com"}))
+	alice, _ := app.Users.FindByUsername("alice")
+	bob, _ := app.Users.FindByUsername("bob")
+	admin := Domain.Viewer{UserID: adminUser.ID.Hex(), Username: "root", Role: Domain.RoleAdmin}
+	viewer := Domain.Viewer{UserID: alice.ID.Hex(), Username: "alice", Role: Domain.RoleUser}
+
+	bug, err := app.CatalogUsecase.CreateLabel(Domain.Label{Name: "bug", Color: "#ff0000"})
+	require.NoError(t, err)
+	ui, err := app.CatalogUsecase.CreateLabel(Domain.Label{Name: "ui", Color: "#00ff00"})
+	require.NoError(t, err)
+	_, err = app.CatalogUsecase.CreateField(Domain.CustomField{Key: "owner", Name: "Owner", Type: Domain.FieldUser})
+	require.NoError(t, err)
+	due := time.Now().Add(24 * time.Hour)
+	for i, title := range []string{"Alpha", "Bravo", "Charlie"} {
+		owner := alice
+		if i == 1 {
+			owner = bob
+		}
+		_, err := app.TaskUsecase.Create(Domain.Task{Title: title, DueDate: due, Status: Domain.StatusPending,
+			Labels: []primitive.ObjectID{bug.ID, ui.ID}, CustomFields: map[string]interface{}{"owner": owner.ID.Hex()}})
+		require.NoError(t, err)
+	}
+	_, err = app.TaskUsecase.Create(Domain.Task{Title: "Done", DueDate: due, Status: Domain.StatusCompleted})
+	require.NoError(t, err)
+
+	catalog := &countingCatalog{CatalogUsecase: app.CatalogUsecase}
+	users := &countingUsers{UserUsecase: app.UserUsecase}
+	gc := controllers.NewGraphQLController(app.TaskUsecase, users, catalog, 0, 0)
+
+	t.Run("TasksFilterPageAndBatchLookups", func(t *testing.T) {
+		code, result := graphQLCall(t, gc, viewer, http.MethodGet, `query($size: Int) {
+			tasks(filter: {status: PENDING}, sort: [{field: "title", desc: true}], pageSize: $size) {
+				total page pageSize
+				tasks { title status labels { name } customFields { key value user { username email } } }
+			}
+		}`, map[string]interface{}{"size": 2})
+		require.Equal(t, http.StatusOK, code, result.Errors)
+		require.Empty(t, result.Errors)
+
+		page := result.Data["tasks"].(map[string]interface{})
+		assert.Equal(t, float64(3), page["total"])
+		assert.Equal(t, float64(2), page["pageSize"])
+		tasks := page["tasks"].([]interface{})
+		require.Len(t, tasks, 2)
+		first := tasks[0].(map[string]interface{})
+		assert.Equal(t, "Charlie", first["title"])
+		assert.Equal(t, "PENDING", first["status"])
+		assert.Len(t, first["labels"], 2)
+		second := tasks[1].(map[string]interface{})
+		owner := second["customFields"].([]interface{})[0].(map[string]interface{})["user"].(map[string]interface{})
+		assert.Equal(t, "bob", owner["username"])
+		assert.Nil(t, owner["email"], "another user's email is private")
+
+		assert.Equal(t, 1, catalog.labelLookups, "labels of every task are fetched at once")
+		assert.Equal(t, 1, users.userLookups, "users of every task are fetched at once")
+	})
+
+	t.Run("Me", func(t *testing.T) {
+		_, result := graphQLCall(t, gc, viewer, http.MethodPost, `{ me { id username email role } }`, nil)
+		require.Empty(t, result.Errors)
+		me := result.Data["me"].(map[string]interface{})
+		assert.Equal(t, alice.ID.Hex(), me["id"])
+		assert.Equal(t, "alice@example.com", me["email"])
+		assert.Equal(t, "USER", me["role"])
+	})
+
+	t.Run("UsersAreForAdmins", func(t *testing.T) {
+		_, result := graphQLCall(t, gc, viewer, http.MethodPost, `{ users { total } }`, nil)
+		assert.Equal(t, controllers.GraphQLForbidden, result.code())
+
+		_, result = graphQLCall(t, gc, admin, http.MethodPost, `{ users(role: USER, pageSize: 1) { total users { username email } } }`, nil)
+		require.Empty(t, result.Errors)
+		page := result.Data["users"].(map[string]interface{})
+		assert.Equal(t, float64(2), page["total"])
+		assert.Equal(t, "alice@example.com", page["users"].([]interface{})[0].(map[string]interface{})["email"])
+	})
+
+	t.Run("Mutations", func(t *testing.T) {
+		create := `mutation($input: TaskInput!) { createTask(input: $input) { id title priority labels { name } } }`
+		input := map[string]interface{}{"title": "Ship it", "dueDate": due.Format(time.RFC3339), "priority": "P1", "labels": []string{bug.ID.Hex()}}
+
+		_, result := graphQLCall(t, gc, viewer, http.MethodPost, create, map[string]interface{}{"input": input})
+		assert.Equal(t, controllers.GraphQLForbidden, result.code())
+
+		code, result := graphQLCall(t, gc, admin, http.MethodGet, create, map[string]interface{}{"input": input})
+		assert.Equal(t, http.StatusMethodNotAllowed, code)
+
+		_, result = graphQLCall(t, gc, admin, http.MethodPost, create, map[string]interface{}{"input": input})
+		require.Empty(t, result.Errors)
+		created := result.Data["createTask"].(map[string]interface{})
+		assert.Equal(t, "P1", created["priority"])
+		id := created["id"].(string)
+
+		_, result = graphQLCall(t, gc, admin, http.MethodPost, `mutation($id: ID!) { setTaskStatus(id: $id, status: IN_PROGRESS) { status statusHistory { status } } }`,
+			map[string]interface{}{"id": id})
+		require.Empty(t, result.Errors)
+		moved := result.Data["setTaskStatus"].(map[string]interface{})
+		assert.Equal(t, "IN_PROGRESS", moved["status"])
+		assert.Len(t, moved["statusHistory"], 1)
+
+		input["title"] = "Shipped"
+		_, result = graphQLCall(t, gc, admin, http.MethodPost, `mutation($id: ID!, $input: TaskInput!) { updateTask(id: $id, input: $input) { title status } }`,
+			map[string]interface{}{"id": id, "input": input})
+		require.Empty(t, result.Errors)
+		assert.Equal(t, "Shipped", result.Data["updateTask"].(map[string]interface{})["title"])
+
+		_, result = graphQLCall(t, gc, admin, http.MethodPost, `mutation($id: ID!) { deleteTask(id: $id) }`, map[string]interface{}{"id": id})
+		require.Empty(t, result.Errors)
+		_, result = graphQLCall(t, gc, admin, http.MethodPost, `query($id: ID!) { task(id: $id) { title } }`, map[string]interface{}{"id": id})
+		assert.Equal(t, controllers.GraphQLNotFound, result.code())
+	})
+
+	t.Run("InvalidInputListsFields", func(t *testing.T) {
+		_, result := graphQLCall(t, gc, admin, http.MethodPost, `mutation { createTask(input: {title: "Late", dueDate: "2020-01-01T00:00:00Z"}) { id } }`, nil)
+		require.Equal(t, controllers.GraphQLBadUserInput, result.code())
+		fields := result.Errors[0].Extensions["fields"].([]interface{})
+		require.Len(t, fields, 1)
+		assert.Equal(t, "dueDate", fields[0].(map[string]interface{})["field"])
+		assert.Equal(t, "dueDate must not be in the past", fields[0].(map[string]interface{})["message"])
+	})
+
+	t.Run("Limits", func(t *testing.T) {
+		code, result := graphQLCall(t, gc, viewer, http.MethodPost, testutil.IntrospectionQuery, nil)
+		assert.Equal(t, http.StatusOK, code, "the standard introspection query fits the default limits")
+		assert.Empty(t, result.Errors)
+
+		deep := "{ __schema { types { fields { type " + strings.Repeat("{ ofType ", 12) + "{ name }" + strings.Repeat(" }", 12) + " } } } }"
+		code, result = graphQLCall(t, gc, viewer, http.MethodPost, deep, nil)
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, controllers.GraphQLQueryTooDeep, result.code())
+
+		wide := `{ tasks(pageSize: 100) { tasks { id title description dueDate status project createdAt updatedAt labels { id name color } } } }`
+		code, result = graphQLCall(t, gc, viewer, http.MethodPost, wide, nil)
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, controllers.GraphQLQueryTooComplex, result.code())
+
+		code, result = graphQLCall(t, gc, viewer, http.MethodPost, strings.Replace(wide, "100", "10", 1), nil)
+		assert.Equal(t, http.StatusOK, code, result.Errors)
+	})
+
+	t.Run("RequestErrors", func(t *testing.T) {
+		code, result := graphQLCall(t, gc, viewer, http.MethodPost, `{ tasks {`, nil)
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, controllers.GraphQLParseFailed, result.code())
+
+		code, result = graphQLCall(t, gc, viewer, http.MethodPost, `{ tasks { secret } }`, nil)
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, controllers.GraphQLValidationFailed, result.code())
+	})
+}
+
+// TestGraphQLRoute checks /graphql sits behind AuthMiddleware like the API
+func TestGraphQLRoute(t *testing.T) {
+	app := mocks.NewApp()
+	require.NoError(t, app.UserUsecase.Register(Domain.Registration{Username: "alice", Password: "alice-secret-1"}))
+	token, err := app.Login("alice", "alice-secret-1")
+	require.NoError(t, err)
+
+	query := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query": "{ me { username } }"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, query("").Code)
+	w := query(token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data": {"me": {"username": "alice"}}}`, w.Body.String())
+}
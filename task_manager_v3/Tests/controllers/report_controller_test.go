@@ -0,0 +1,55 @@
+package controllers_test
+
+import (
+	"a2sv-backend/task_manager_v3/Delivery/controllers"
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Repositories"
+	"a2sv-backend/task_manager_v3/Usecases"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// brokenReports fails its aggregations the way an unreachable database would
+type brokenReports struct {
+	Usecases.ReportUsecase
+}
+
+func (brokenReports) Throughput(from, to time.Time, interval string) ([]Domain.ThroughputBucket, error) {
+	return nil, errors.New("connection reset")
+}
+
+func (brokenReports) CycleTime(from, to time.Time) (Domain.CycleTimeReport, error) {
+	return Domain.CycleTimeReport{}, errors.New("connection reset")
+}
+
+func TestReportController_ErrorStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	get := func(reports Usecases.ReportUsecase, path string) int {
+		rc := controllers.NewReportController(reports)
+		r := gin.New()
+		r.GET("/reports/throughput", rc.Throughput)
+		r.GET("/reports/cycle-time", rc.CycleTime)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	working := Usecases.NewReportUsecase(Repositories.NewInMemoryReportRepository(Repositories.NewInMemoryTaskRepository()))
+
+	t.Run("BadRequests", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, get(working, "/reports/throughput?interval=month"))
+		assert.Equal(t, http.StatusBadRequest, get(working, "/reports/cycle-time?from=2026-02-01&to=2026-01-01"))
+	})
+
+	t.Run("FailuresAreServerErrors", func(t *testing.T) {
+		assert.Equal(t, http.StatusInternalServerError, get(brokenReports{}, "/reports/throughput"))
+		assert.Equal(t, http.StatusInternalServerError, get(brokenReports{}, "/reports/cycle-time"))
+	})
+}
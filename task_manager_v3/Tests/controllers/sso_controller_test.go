@@ -0,0 +1,92 @@
+package controllers_test
+
+import (
+	"a2sv-backend/task_manager_v3/Delivery/controllers"
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Infrastructure"
+	"a2sv-backend/task_manager_v3/Repositories"
+	"a2sv-backend/task_manager_v3/Tests/mocks"
+	"a2sv-backend/task_manager_v3/Usecases"
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSOController_BrowserFlow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	provider := mocks.NewOIDCServer("task-manager", "client-secret")
+	defer provider.Close()
+
+	r := gin.New()
+	app := httptest.NewServer(r)
+	defer app.Close()
+
+	oidc, err := Infrastructure.NewOIDCProvider(Infrastructure.OIDCConfig{
+		IssuerURL:    provider.URL,
+		ClientID:     "task-manager",
+		ClientSecret: "client-secret",
+		RedirectURL:  app.URL + "/api/v1/auth/oidc/callback",
+	})
+	require.NoError(t, err)
+	ssoUsecase := Usecases.NewSSOUsecase(oidc, Repositories.NewInMemorySSOStateRepository(), Repositories.NewInMemoryIdentityRepository(),
+		Repositories.NewInMemoryUserRepository(), Infrastructure.NewJWTService(mocks.JWTSecret), nil, nil, Domain.SSOPolicy{Provision: true})
+	ssoController := controllers.NewSSOController(ssoUsecase)
+	r.GET("/api/v1/auth/oidc/login", ssoController.Login)
+	r.GET("/api/v1/auth/oidc/callback", ssoController.Callback)
+
+	provider.SignIn(mocks.OIDCIdentity{Subject: "alice-sub", PreferredUsername: "alice"})
+
+	t.Run("SignsIn", func(t *testing.T) {
+		jar, _ := cookiejar.New(nil)
+		browser := &http.Client{Jar: jar}
+		resp, err := browser.Get(app.URL + "/api/v1/auth/oidc/login")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var body struct {
+			Token       string `json:"token"`
+			Provisioned bool   `json:"provisioned"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.NotEmpty(t, body.Token)
+		assert.True(t, body.Provisioned)
+	})
+
+	t.Run("CallbackNeedsTheBrowserThatStarted", func(t *testing.T) {
+		jar, _ := cookiejar.New(nil)
+		victim := &http.Client{Jar: jar, CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.URL.Path == "/api/v1/auth/oidc/callback" {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		}}
+		resp, err := victim.Get(app.URL + "/api/v1/auth/oidc/login")
+		require.NoError(t, err)
+		resp.Body.Close()
+		callback, _ := url.Parse(resp.Header.Get("Location"))
+
+		// Someone else opening the callback URL has no state cookie
+		resp, err = http.Get(callback.String())
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("NotConfigured", func(t *testing.T) {
+		r := gin.New()
+		r.GET("/auth/oidc/login", controllers.NewSSOController(nil).Login)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/auth/oidc/login", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
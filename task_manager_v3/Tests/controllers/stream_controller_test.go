@@ -0,0 +1,43 @@
+package controllers_test
+
+import (
+	"a2sv-backend/task_manager_v3/Delivery/controllers"
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Tests/mocks"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestTaskController_StreamTasks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockTaskUsecase := new(mocks.MockTaskUsecase)
+	taskController := controllers.NewTaskController(mockTaskUsecase)
+
+	events := make(chan Domain.TaskEvent, 1)
+	events <- Domain.TaskEvent{ID: "7", Type: Domain.TaskCreated, TaskID: primitive.NewObjectID()}
+	close(events)
+
+	viewer := Domain.Viewer{UserID: "123", Username: "testuser", Role: "user"}
+	mockTaskUsecase.On("Subscribe", mock.Anything, viewer, "6").Return(events, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/tasks/stream", nil)
+	c.Request.Header.Set("Last-Event-ID", "6")
+	c.Set("user_id", "123")
+	c.Set("username", "testuser")
+	c.Set("role", "user")
+
+	taskController.StreamTasks(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "id: 7\nevent: task.created\ndata: {")
+	mockTaskUsecase.AssertExpectations(t)
+}
@@ -6,7 +6,6 @@ import (
 	"a2sv-backend/task_manager_v3/Tests/mocks"
 	"bytes"
 	"encoding/json"
-	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -72,12 +71,18 @@ func TestTaskController_GetAllTasks(t *testing.T) {
 			{Title: "Task 2"},
 		}
 
-		mockTaskUsecase.On("GetAll").Return(tasks, nil)
+		filter := Domain.TaskFilter{
+			Status:       Domain.StatusPending,
+			Priority:     Domain.PriorityP1,
+			CustomFields: map[string]interface{}{"points": "3"},
+			Sort:         []Domain.SortField{{Field: "priority"}, {Field: "due_date", Desc: true}},
+		}
+		mockTaskUsecase.On("List", filter).Return(tasks, nil)
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
 
-		c.Request, _ = http.NewRequest("GET", "/tasks", nil)
+		c.Request, _ = http.NewRequest("GET", "/tasks?status=pending&priority=P1&cf.points=3&sort=priority,-due_date", nil)
 
 		taskController.GetAllTasks(c)
 
@@ -115,7 +120,7 @@ func TestTaskController_GetTaskByID(t *testing.T) {
 	t.Run("NotFound", func(t *testing.T) {
 		taskID := primitive.NewObjectID()
 
-		mockTaskUsecase.On("GetByID", taskID).Return(Domain.Task{}, errors.New("task not found"))
+		mockTaskUsecase.On("GetByID", taskID).Return(Domain.Task{}, Domain.ErrTaskNotFound)
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
@@ -0,0 +1,48 @@
+package controllers_test
+
+import (
+	"a2sv-backend/task_manager_v3/Delivery/controllers"
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Tests/mocks"
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestTaskController_ImportTasksMultipart(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockTaskUsecase := new(mocks.MockTaskUsecase)
+	mockTaskUsecase.On("Import", mock.Anything, false).Return(Domain.ImportReport{Created: 1}, nil)
+
+	r := gin.New()
+	r.POST("/tasks/import", controllers.NewTaskController(mockTaskUsecase).ImportTasks)
+
+	upload := func(content []byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, _ := form.CreateFormFile("file", "tasks.csv")
+		part.Write(content)
+		form.Close()
+
+		req, _ := http.NewRequest("POST", "/tasks/import?format=csv", &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := upload([]byte("title\nShip it\n"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockTaskUsecase.AssertNumberOfCalls(t, "Import", 1)
+
+	// Multipart uploads are held to the same 10 MiB cap as plain bodies
+	w = upload(bytes.Repeat([]byte("x"), 11<<20))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	mockTaskUsecase.AssertNumberOfCalls(t, "Import", 1)
+}
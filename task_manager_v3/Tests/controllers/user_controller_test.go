@@ -0,0 +1,50 @@
+package controllers_test
+
+import (
+	"a2sv-backend/task_manager_v3/Delivery/controllers"
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Tests/mocks"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestUserController_ErrorStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userID := primitive.NewObjectID()
+
+	for _, tc := range []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"NotFound", Domain.ErrUserNotFound, http.StatusNotFound},
+		{"UsernameRequired", Domain.ErrUsernameRequired, http.StatusBadRequest},
+		{"InvalidEmail", fmt.Errorf("invitee: %w", Domain.ErrInvalidEmail), http.StatusBadRequest},
+		{"Unexpected", fmt.Errorf("connection reset"), http.StatusInternalServerError},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mockUserUsecase := new(mocks.MockUserUsecase)
+			mockUserUsecase.On("UpdateProfile", userID, mock.Anything).Return(Domain.User{}, tc.err)
+
+			r := gin.New()
+			r.PUT("/me", func(c *gin.Context) {
+				c.Set("user_id", userID.Hex())
+				controllers.NewUserController(mockUserUsecase).UpdateProfile(c)
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("PUT", "/me", strings.NewReader(`{"display_name": "Alice"}`))
+			req.Header.Set("Content-Type", "application/json")
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tc.want, w.Code)
+		})
+	}
+}
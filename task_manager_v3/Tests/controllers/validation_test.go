@@ -0,0 +1,154 @@
+package controllers_test
+
+import (
+	"a2sv-backend/task_manager_v3/Delivery/controllers"
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Tests/mocks"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// postJSON runs handler on a POST carrying body and decodes the field
+// errors of the response, if any
+func postJSON(handler gin.HandlerFunc, body string) (*httptest.ResponseRecorder, []controllers.FieldError) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	handler(c)
+
+	var response struct {
+		Errors []controllers.FieldError `json:"errors"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w, response.Errors
+}
+
+func codesByField(errs []controllers.FieldError) map[string]string {
+	codes := map[string]string{}
+	for _, e := range errs {
+		codes[e.Field] = e.Code
+	}
+	return codes
+}
+
+func TestValidation_CreateTask(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockTaskUsecase := new(mocks.MockTaskUsecase)
+	taskController := controllers.NewTaskController(mockTaskUsecase)
+	tomorrow := time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+
+	t.Run("ReportsEveryField", func(t *testing.T) {
+		w, errs := postJSON(taskController.CreateTask, `{"status":"done","priority":"urgent"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, map[string]string{
+			"title":    "required",
+			"due_date": "required",
+			"status":   "taskstatus",
+			"priority": "priority",
+		}, codesByField(errs))
+		for _, e := range errs {
+			assert.True(t, strings.HasPrefix(e.Message, e.Field+" "), e.Message)
+		}
+	})
+
+	t.Run("DueDateNotInThePast", func(t *testing.T) {
+		_, errs := postJSON(taskController.CreateTask, `{"title":"Ship","due_date":"2020-01-01T00:00:00Z"}`)
+		assert.Equal(t, map[string]string{"due_date": "notpast"}, codesByField(errs))
+	})
+
+	t.Run("WrongType", func(t *testing.T) {
+		_, errs := postJSON(taskController.CreateTask, `{"title":5}`)
+		assert.Equal(t, map[string]string{"title": controllers.CodeType}, codesByField(errs))
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		_, errs := postJSON(taskController.CreateTask, `{"title":`)
+		assert.Equal(t, map[string]string{"": controllers.CodeMalformed}, codesByField(errs))
+	})
+
+	t.Run("IgnoresServerFields", func(t *testing.T) {
+		mockTaskUsecase.On("Create", mock.MatchedBy(func(task Domain.Task) bool {
+			return task.Title == "Ship" && task.ID.IsZero() && task.CreatedAt.IsZero() && task.StatusHistory == nil
+		})).Return(Domain.Task{Title: "Ship"}, nil).Once()
+
+		body := `{"id":"` + primitive.NewObjectID().Hex() + `","title":"Ship","due_date":"` + tomorrow + `",
+			"created_at":"2020-01-01T00:00:00Z","status_history":[{"status":"completed"}]}`
+		w, _ := postJSON(taskController.CreateTask, body)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		mockTaskUsecase.AssertExpectations(t)
+	})
+}
+
+func TestValidation_UpdateTaskAllowsOverdue(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockTaskUsecase := new(mocks.MockTaskUsecase)
+	taskController := controllers.NewTaskController(mockTaskUsecase)
+	id := primitive.NewObjectID()
+	mockTaskUsecase.On("Update", id, mock.MatchedBy(func(task Domain.Task) bool {
+		return task.ID.IsZero()
+	})).Return(Domain.Task{ID: id}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: id.Hex()}}
+	body := `{"id":"` + primitive.NewObjectID().Hex() + `","title":"Ship","due_date":"2020-01-01T00:00:00Z"}`
+	c.Request, _ = http.NewRequest("PUT", "/tasks/"+id.Hex(), strings.NewReader(body))
+	taskController.UpdateTask(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockTaskUsecase.AssertExpectations(t)
+}
+
+func TestValidation_Register(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockUserUsecase := new(mocks.MockUserUsecase)
+	userController := controllers.NewUserController(mockUserUsecase)
+
+	t.Run("Rules", func(t *testing.T) {
+		w, errs := postJSON(userController.Register, `{"password":"short","email":"not-an-address"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, map[string]string{"username": "required", "password": "min", "email": "email"}, codesByField(errs))
+		mockUserUsecase.AssertNotCalled(t, "Register", mock.Anything)
+	})
+
+	t.Run("RoleIsNotBindable", func(t *testing.T) {
+		mockUserUsecase.On("Register", Domain.Registration{Username: "mallory", Password: "password"}).Return(nil).Once()
+		w, _ := postJSON(userController.Register, `{"username":"mallory","password":"password","role":"admin","id":"x"}`)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		mockUserUsecase.AssertExpectations(t)
+	})
+}
+
+func TestValidation_PasswordBytes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userController := controllers.NewUserController(new(mocks.MockUserUsecase))
+	accountController := controllers.NewAccountController(nil)
+	// 40 characters but 80 bytes: more than bcrypt takes
+	long := strings.Repeat("é", 40)
+
+	_, errs := postJSON(userController.Register, `{"username":"alice","password":"`+long+`"}`)
+	assert.Equal(t, map[string]string{"password": "maxbytes"}, codesByField(errs))
+	assert.Equal(t, "password must be at most 72 bytes long", errs[0].Message)
+
+	_, errs = postJSON(userController.Login, `{"username":"alice","password":"`+long+`"}`)
+	assert.Equal(t, map[string]string{"password": "maxbytes"}, codesByField(errs))
+
+	_, errs = postJSON(accountController.ResetPassword, `{"token":"t","password":"`+long+`"}`)
+	assert.Equal(t, map[string]string{"password": "maxbytes"}, codesByField(errs))
+
+	// 72 bytes exactly is fine
+	mockUserUsecase := new(mocks.MockUserUsecase)
+	mockUserUsecase.On("Register", mock.Anything).Return(nil).Once()
+	w, _ := postJSON(controllers.NewUserController(mockUserUsecase).Register, `{"username":"alice","password":"`+strings.Repeat("é", 36)+`"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
@@ -0,0 +1,58 @@
+package infrastructure_test
+
+import (
+	"a2sv-backend/task_manager_v3/Infrastructure"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalBlobStore(t *testing.T) {
+	root := t.TempDir()
+	store, err := Infrastructure.NewLocalBlobStore(root)
+	assert.NoError(t, err)
+
+	n, err := store.Put("abc123", strings.NewReader("hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+
+	r, err := store.Open("abc123")
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(r)
+	r.Close()
+	assert.Equal(t, "hello", string(data))
+
+	t.Run("RejectsPathKeys", func(t *testing.T) {
+		_, err := store.Put("../escape", strings.NewReader("x"))
+		assert.Error(t, err)
+		_, err = store.Open("../abc123")
+		assert.Error(t, err)
+	})
+
+	t.Run("FailedPutLeavesNothing", func(t *testing.T) {
+		_, err := store.Put("broken", io.MultiReader(strings.NewReader("partial"), failingReader{}))
+		assert.Error(t, err)
+		_, err = store.Open("broken")
+		assert.True(t, errors.Is(err, Infrastructure.ErrBlobNotFound))
+
+		entries, _ := os.ReadDir(root)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		assert.NoError(t, store.Delete("abc123"))
+		assert.NoError(t, store.Delete("abc123"))
+		_, err := store.Open("abc123")
+		assert.True(t, errors.Is(err, Infrastructure.ErrBlobNotFound))
+	})
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
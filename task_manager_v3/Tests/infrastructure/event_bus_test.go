@@ -0,0 +1,128 @@
+package infrastructure_test
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Infrastructure"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func receive(t *testing.T, ch <-chan Domain.TaskEvent) Domain.TaskEvent {
+	t.Helper()
+	select {
+	case event, ok := <-ch:
+		assert.True(t, ok, "channel closed")
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+	return Domain.TaskEvent{}
+}
+
+func TestInMemoryEventBus_PublishSubscribe(t *testing.T) {
+	bus := Infrastructure.NewInMemoryEventBus(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := bus.Subscribe(ctx, "")
+	assert.NoError(t, err)
+
+	taskID := primitive.NewObjectID()
+	published, err := bus.Publish(Domain.TaskEvent{Type: Domain.TaskCreated, TaskID: taskID})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(published.ID, "-1"), published.ID)
+	assert.False(t, published.OccurredAt.IsZero())
+
+	event := receive(t, events)
+	assert.Equal(t, published.ID, event.ID)
+	assert.Equal(t, Domain.TaskCreated, event.Type)
+	assert.Equal(t, taskID, event.TaskID)
+}
+
+func TestInMemoryEventBus_ResumeFromLastEventID(t *testing.T) {
+	bus := Infrastructure.NewInMemoryEventBus(10)
+	var published []Domain.TaskEvent
+	for i := 0; i < 3; i++ {
+		event, _ := bus.Publish(Domain.TaskEvent{Type: Domain.TaskUpdated, TaskID: primitive.NewObjectID()})
+		published = append(published, event)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := bus.Subscribe(ctx, published[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, published[1].ID, receive(t, events).ID)
+	assert.Equal(t, published[2].ID, receive(t, events).ID)
+
+	latest, _ := bus.Publish(Domain.TaskEvent{Type: Domain.TaskDeleted, TaskID: primitive.NewObjectID()})
+	assert.Equal(t, latest.ID, receive(t, events).ID)
+}
+
+func TestInMemoryEventBus_HistoryIsBounded(t *testing.T) {
+	bus := Infrastructure.NewInMemoryEventBus(2)
+	var published []Domain.TaskEvent
+	for i := 0; i < 5; i++ {
+		event, _ := bus.Publish(Domain.TaskEvent{Type: Domain.TaskUpdated})
+		published = append(published, event)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	t.Run("ResumesWithinTheHistory", func(t *testing.T) {
+		events, _ := bus.Subscribe(ctx, published[2].ID)
+		assert.Equal(t, published[3].ID, receive(t, events).ID)
+		assert.Equal(t, published[4].ID, receive(t, events).ID)
+	})
+
+	t.Run("ResetsWhenEventsWereDropped", func(t *testing.T) {
+		events, _ := bus.Subscribe(ctx, published[1].ID)
+		reset := receive(t, events)
+		assert.Equal(t, Domain.TaskEventsReset, reset.Type)
+		// Resuming from the reset picks up only what comes next
+		assert.Equal(t, published[4].ID, reset.ID)
+
+		latest, _ := bus.Publish(Domain.TaskEvent{Type: Domain.TaskUpdated})
+		assert.Equal(t, latest.ID, receive(t, events).ID)
+	})
+}
+
+func TestInMemoryEventBus_ResetsAcrossRestarts(t *testing.T) {
+	before := Infrastructure.NewInMemoryEventBus(10)
+	old, _ := before.Publish(Domain.TaskEvent{Type: Domain.TaskUpdated})
+
+	// A restarted server numbers its events from 1 again
+	after := Infrastructure.NewInMemoryEventBus(10)
+	after.Publish(Domain.TaskEvent{Type: Domain.TaskUpdated})
+	after.Publish(Domain.TaskEvent{Type: Domain.TaskUpdated})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, lastEventID := range []string{old.ID, "900", "not-an-id"} {
+		events, err := after.Subscribe(ctx, lastEventID)
+		assert.NoError(t, err)
+		assert.Equal(t, Domain.TaskEventsReset, receive(t, events).Type, lastEventID)
+	}
+}
+
+func TestInMemoryEventBus_UnsubscribeOnCancel(t *testing.T) {
+	bus := Infrastructure.NewInMemoryEventBus(10)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	events, _ := bus.Subscribe(ctx, "")
+	cancel()
+
+	select {
+	case _, ok := <-events:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("channel was not closed after cancel")
+	}
+}
@@ -3,6 +3,7 @@ package infrastructure_test
 import (
 	"a2sv-backend/task_manager_v3/Domain"
 	"a2sv-backend/task_manager_v3/Infrastructure"
+	"a2sv-backend/task_manager_v3/Tests/mocks"
 	"testing"
 
 	"github.com/dgrijalva/jwt-go"
@@ -11,27 +12,27 @@ import (
 )
 
 func TestJWTService_GenerateToken(t *testing.T) {
-	service := Infrastructure.NewJWTService()
+	service := Infrastructure.NewJWTService(mocks.JWTSecret)
 	user := Domain.User{
 		ID:       primitive.NewObjectID(),
 		Username: "testuser",
 		Role:     "user",
 	}
 
-	token, err := service.GenerateToken(user)
+	token, err := service.GenerateToken(user, "")
 
 	assert.NoError(t, err)
 	assert.NotEmpty(t, token)
 }
 
 func TestJWTService_ValidateToken(t *testing.T) {
-	service := Infrastructure.NewJWTService()
+	service := Infrastructure.NewJWTService(mocks.JWTSecret)
 	user := Domain.User{
 		ID:       primitive.NewObjectID(),
 		Username: "testuser",
 		Role:     "user",
 	}
-	tokenString, _ := service.GenerateToken(user)
+	tokenString, _ := service.GenerateToken(user, "")
 
 	t.Run("Success", func(t *testing.T) {
 		token, err := service.ValidateToken(tokenString)
@@ -0,0 +1,56 @@
+package infrastructure_test
+
+import (
+	"a2sv-backend/task_manager_v3/Infrastructure"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	metrics := Infrastructure.NewMetrics()
+
+	r := gin.New()
+	r.Use(Infrastructure.MetricsMiddleware(metrics))
+	r.GET("/metrics", Infrastructure.MetricsHandler(metrics))
+	v1 := r.Group("/api/v1", Infrastructure.APIVersionMiddleware(Infrastructure.APIVersion1, "/api/v1"))
+	v1.GET("/tasks/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	root := r.Group("/", Infrastructure.APIVersionMiddleware(Infrastructure.APIUnversioned, ""))
+	root.GET("/tasks/:id", func(c *gin.Context) { c.Status(http.StatusNotFound) })
+
+	request := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		r.ServeHTTP(w, req)
+		return w
+	}
+	request("/api/v1/tasks/1")
+	request("/api/v1/tasks/2")
+	request("/tasks/1")
+	request("/nowhere")
+
+	assert.Equal(t, uint64(2), metrics.HTTPRequests.Value("v1", "GET", "/tasks/:id", "200"))
+	assert.Equal(t, uint64(1), metrics.HTTPRequests.Value("unversioned", "GET", "/tasks/:id", "404"))
+	assert.Equal(t, uint64(1), metrics.HTTPRequests.Value("none", "GET", "unmatched", "404"))
+
+	w := request("/metrics")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain"))
+	assert.Contains(t, w.Body.String(), "# TYPE http_requests_total counter\n")
+	assert.Contains(t, w.Body.String(), `http_requests_total{version="v1",method="GET",route="/tasks/:id",code="200"} 2`+"\n")
+
+	t.Run("Nil", func(t *testing.T) {
+		r := gin.New()
+		r.Use(Infrastructure.MetricsMiddleware(nil))
+		r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
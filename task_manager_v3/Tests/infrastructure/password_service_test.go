@@ -5,10 +5,11 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestPasswordService_HashPassword(t *testing.T) {
-	service := Infrastructure.NewBcryptPasswordService()
+	service := Infrastructure.NewBcryptPasswordService(bcrypt.MinCost)
 	password := "password123"
 
 	hashedPassword, err := service.HashPassword(password)
@@ -19,7 +20,7 @@ func TestPasswordService_HashPassword(t *testing.T) {
 }
 
 func TestPasswordService_ComparePassword(t *testing.T) {
-	service := Infrastructure.NewBcryptPasswordService()
+	service := Infrastructure.NewBcryptPasswordService(bcrypt.MinCost)
 	password := "password123"
 	hashedPassword, _ := service.HashPassword(password)
 
@@ -0,0 +1,158 @@
+package infrastructure_test
+
+import (
+	"a2sv-backend/task_manager_v3/Infrastructure"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryRateLimitStore(t *testing.T) {
+	store := Infrastructure.NewInMemoryRateLimitStore()
+	limit := Infrastructure.RateLimit{Rate: 1, Period: time.Second, Burst: 3}
+	now := time.Now()
+
+	for i := 2; i >= 0; i-- {
+		result, err := store.Take(context.Background(), "client", limit, now)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 3, result.Limit)
+		assert.Equal(t, i, result.Remaining)
+	}
+
+	denied, _ := store.Take(context.Background(), "client", limit, now)
+	assert.False(t, denied.Allowed)
+	assert.Equal(t, time.Second, denied.RetryAfter)
+	assert.Equal(t, 3*time.Second, denied.Reset)
+
+	// Other keys have buckets of their own
+	other, _ := store.Take(context.Background(), "other", limit, now)
+	assert.True(t, other.Allowed)
+
+	// One token comes back per second, and never more than the burst
+	refilled, _ := store.Take(context.Background(), "client", limit, now.Add(time.Second))
+	assert.True(t, refilled.Allowed)
+	assert.Equal(t, 0, refilled.Remaining)
+	later, _ := store.Take(context.Background(), "client", limit, now.Add(time.Hour))
+	assert.Equal(t, 2, later.Remaining)
+}
+
+func TestInMemoryRateLimitStore_Concurrent(t *testing.T) {
+	store := Infrastructure.NewInMemoryRateLimitStore()
+	limit := Infrastructure.RateLimit{Rate: 1, Period: time.Hour, Burst: 50}
+	now := time.Now()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, _ := store.Take(context.Background(), "shared", limit, now)
+			if result.Allowed {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 50, allowed)
+}
+
+func TestParseRateLimitPolicies(t *testing.T) {
+	policies, err := Infrastructure.ParseRateLimitPolicies(" POST  /login=5/1m,by=ip ; /tasks/:id=100/h,burst=10,by=api_key;*=600/30s;")
+	require.NoError(t, err)
+	require.Len(t, policies, 3)
+
+	assert.Equal(t, "POST /login", policies[0].Route)
+	assert.Equal(t, Infrastructure.RateLimit{Rate: 5, Period: time.Minute, Burst: 5}, policies[0].Limit)
+	assert.Equal(t, Infrastructure.RateLimitByIP, policies[0].KeyBy)
+	assert.Equal(t, Infrastructure.RateLimit{Rate: 100, Period: time.Hour, Burst: 10}, policies[1].Limit)
+	assert.Equal(t, Infrastructure.RateLimitByAPIKey, policies[1].KeyBy)
+	assert.Equal(t, Infrastructure.RateLimitByUser, policies[2].KeyBy)
+	assert.Equal(t, 30*time.Second, policies[2].Limit.Period)
+
+	preAuth, err := Infrastructure.ParseRateLimitPolicies("auth=1000/1m")
+	require.NoError(t, err)
+	assert.Equal(t, Infrastructure.RateLimitByIP, preAuth[0].KeyBy)
+
+	for _, spec := range []string{"/login", "/login=0/1m", "/login=5/fortnight", "/login=5/1m,by=cookie", "/login=5/1m,burst=-1", "/login=5/1m,cost=2", "auth=5/1m,by=user"} {
+		_, err := Infrastructure.ParseRateLimitPolicies(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policies, err := Infrastructure.ParseRateLimitPolicies("POST /login=2/1m,by=ip;*=1/1m,by=user")
+	require.NoError(t, err)
+	limiter := Infrastructure.NewRateLimiter(Infrastructure.NewInMemoryRateLimitStore(), policies)
+
+	r := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.POST("/login", Infrastructure.RateLimitMiddleware(limiter), ok)
+	authenticated := r.Group("/", func(c *gin.Context) { c.Set("user_id", c.GetHeader("X-User")) }, Infrastructure.RateLimitMiddleware(limiter))
+	authenticated.GET("/tasks", ok)
+	authenticated.GET("/reports/summary", ok)
+	for _, version := range []string{Infrastructure.APIVersion1, Infrastructure.APIVersion2} {
+		prefix := "/api/" + version
+		r.POST(prefix+"/login", Infrastructure.APIVersionMiddleware(version, prefix), Infrastructure.RateLimitMiddleware(limiter), ok)
+	}
+
+	request := func(method, path, ip, user string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		req.RemoteAddr = ip + ":1234"
+		req.Header.Set("X-User", user)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("PerIP", func(t *testing.T) {
+		first := request("POST", "/login", "10.0.0.1", "")
+		assert.Equal(t, http.StatusOK, first.Code)
+		assert.Equal(t, "2", first.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "1", first.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "2;w=60;burst=2", first.Header().Get("RateLimit-Policy"))
+
+		request("POST", "/login", "10.0.0.1", "")
+		limited := request("POST", "/login", "10.0.0.1", "")
+		assert.Equal(t, http.StatusTooManyRequests, limited.Code)
+		assert.Equal(t, "30", limited.Header().Get("Retry-After"))
+		assert.Equal(t, "0", limited.Header().Get("RateLimit-Remaining"))
+
+		assert.Equal(t, http.StatusOK, request("POST", "/login", "10.0.0.2", "").Code)
+	})
+
+	t.Run("SharedAcrossVersions", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, request("POST", "/api/v1/login", "10.0.0.5", "").Code)
+		assert.Equal(t, http.StatusOK, request("POST", "/api/v2/login", "10.0.0.5", "").Code)
+		assert.Equal(t, http.StatusTooManyRequests, request("POST", "/login", "10.0.0.5", "").Code)
+	})
+
+	t.Run("PerUserAcrossRoutes", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, request("GET", "/tasks", "10.0.0.3", "alice").Code)
+		// The default policy's bucket is shared by every route it covers
+		assert.Equal(t, http.StatusTooManyRequests, request("GET", "/reports/summary", "10.0.0.4", "alice").Code)
+		assert.Equal(t, http.StatusOK, request("GET", "/tasks", "10.0.0.3", "bob").Code)
+	})
+
+	t.Run("NilLimiter", func(t *testing.T) {
+		r := gin.New()
+		r.GET("/", Infrastructure.RateLimitMiddleware(nil), ok)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+	})
+}
@@ -0,0 +1,124 @@
+package infrastructure_test
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Infrastructure"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func decodeAll(t *testing.T, decoder Infrastructure.TaskDecoder) []Domain.ImportRecord {
+	t.Helper()
+	var records []Domain.ImportRecord
+	for {
+		record, err := decoder.Next()
+		if err == io.EOF {
+			return records
+		}
+		assert.NoError(t, err)
+		records = append(records, record)
+	}
+}
+
+func TestTaskFormats_RoundTrip(t *testing.T) {
+	task := Domain.Task{
+		ID:           primitive.NewObjectID(),
+		ExternalID:   "JIRA-42",
+		Title:        "Ship it, finally",
+		Description:  "Line one\nline two; with \"quotes\" and a long tail that needs folding in iCalendar output",
+		DueDate:      time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC),
+		Status:       Domain.StatusInProgress,
+		Priority:     Domain.PriorityP1,
+		Project:      "web",
+		Labels:       []primitive.ObjectID{primitive.NewObjectID(), primitive.NewObjectID()},
+		CustomFields: map[string]interface{}{"points": 3.0, "team": "core, platform"},
+	}
+
+	for _, format := range []string{Domain.FormatCSV, Domain.FormatJSON, Domain.FormatNDJSON, Domain.FormatICS} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			encoder, err := Infrastructure.NewTaskEncoder(format, &buf)
+			assert.NoError(t, err)
+			assert.NoError(t, encoder.Encode(task))
+			assert.NoError(t, encoder.Encode(task))
+			assert.NoError(t, encoder.Close())
+
+			decoder, err := Infrastructure.NewTaskDecoder(format, &buf, nil)
+			assert.NoError(t, err)
+			records := decodeAll(t, decoder)
+
+			assert.Len(t, records, 2)
+			fields := records[0].Fields
+			assert.Equal(t, "JIRA-42", fields["external_id"])
+			assert.Equal(t, task.Title, fields["title"])
+			assert.Equal(t, task.Description, fields["description"])
+			assert.Equal(t, "2026-03-01T09:30:00Z", fields["due_date"])
+			assert.Equal(t, Domain.StatusInProgress, fields["status"])
+			assert.Equal(t, Domain.PriorityP1, fields["priority"])
+			assert.Equal(t, "web", fields["project"])
+			assert.Equal(t, task.Labels[0].Hex()+","+task.Labels[1].Hex(), fields["labels"])
+			assert.JSONEq(t, `{"points": 3, "team": "core, platform"}`, fields["custom_fields"])
+		})
+	}
+}
+
+func TestTaskFormats_CSVFormulasAreEscaped(t *testing.T) {
+	titles := []string{`=HYPERLINK("http://evil.example","click")`, "+1", "-1", "@SUM(A1)", "\tTab", "'quoted", "plain"}
+
+	var buf bytes.Buffer
+	encoder, _ := Infrastructure.NewTaskEncoder(Domain.FormatCSV, &buf)
+	for _, title := range titles {
+		assert.NoError(t, encoder.Encode(Domain.Task{Title: title}))
+	}
+	assert.NoError(t, encoder.Close())
+
+	// No cell a spreadsheet reads starts a formula
+	rows, err := csv.NewReader(bytes.NewReader(buf.Bytes())).ReadAll()
+	assert.NoError(t, err)
+	for _, row := range rows[1:] {
+		assert.NotContains(t, "=+-@\t\r", row[2][:1], row[2])
+	}
+	assert.Equal(t, `'=HYPERLINK("http://evil.example","click")`, rows[1][2])
+	assert.Equal(t, "plain", rows[7][2])
+
+	// Importing the export gives the titles back
+	decoder, _ := Infrastructure.NewTaskDecoder(Domain.FormatCSV, &buf, nil)
+	for i, record := range decodeAll(t, decoder) {
+		assert.Equal(t, titles[i], record.Fields["title"])
+	}
+}
+
+func TestTaskFormats_EmptyJSONExport(t *testing.T) {
+	var buf bytes.Buffer
+	encoder, _ := Infrastructure.NewTaskEncoder(Domain.FormatJSON, &buf)
+	assert.NoError(t, encoder.Close())
+	assert.Equal(t, "[]", buf.String())
+}
+
+func TestTaskFormats_FieldMapping(t *testing.T) {
+	input := "Key,Summary,Due\nOPS-1,Rotate keys,2026-01-15\n"
+	mapping := map[string]string{"Key": "external_id", "Summary": "title", "Due": "due_date"}
+
+	decoder, err := Infrastructure.NewTaskDecoder(Domain.FormatCSV, strings.NewReader(input), mapping)
+	assert.NoError(t, err)
+	records := decodeAll(t, decoder)
+
+	assert.Len(t, records, 1)
+	assert.Equal(t, 1, records[0].Row)
+	assert.Equal(t, map[string]string{"external_id": "OPS-1", "title": "Rotate keys", "due_date": "2026-01-15"}, records[0].Fields)
+}
+
+func TestTaskFormats_UnsupportedFormat(t *testing.T) {
+	_, err := Infrastructure.NewTaskEncoder("xml", io.Discard)
+	assert.Error(t, err)
+
+	_, err = Infrastructure.NewTaskDecoder("xml", strings.NewReader(""), nil)
+	assert.Error(t, err)
+}
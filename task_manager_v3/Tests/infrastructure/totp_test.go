@@ -0,0 +1,58 @@
+package infrastructure_test
+
+import (
+	"a2sv-backend/task_manager_v3/Infrastructure"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rfcSecret is the RFC 6238 SHA-1 test key "12345678901234567890" in base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTP_RFCVectors(t *testing.T) {
+	// The RFC lists 8-digit codes; these are their last 6 digits
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, want := range vectors {
+		code, err := Infrastructure.TOTPCode(rfcSecret, time.Unix(unix, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, want, code, "at %d", unix)
+	}
+}
+
+func TestTOTP_Verify(t *testing.T) {
+	secret, err := Infrastructure.NewTOTPSecret()
+	assert.NoError(t, err)
+	now := time.Now()
+
+	for _, drift := range []time.Duration{-Infrastructure.TOTPPeriod, 0, Infrastructure.TOTPPeriod} {
+		code, _ := Infrastructure.TOTPCode(secret, now.Add(drift))
+		step, ok := Infrastructure.VerifyTOTP(secret, code, now)
+		assert.True(t, ok)
+		assert.Equal(t, Infrastructure.TOTPStep(now.Add(drift)), step)
+	}
+
+	stale, _ := Infrastructure.TOTPCode(secret, now.Add(-3*Infrastructure.TOTPPeriod))
+	_, ok := Infrastructure.VerifyTOTP(secret, stale, now)
+	assert.False(t, ok)
+
+	_, ok = Infrastructure.VerifyTOTP(secret, "12345", now)
+	assert.False(t, ok)
+}
+
+func TestTOTP_URI(t *testing.T) {
+	uri, err := url.Parse(Infrastructure.TOTPURI("Task Manager", "alice@example.com", rfcSecret))
+	assert.NoError(t, err)
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/Task Manager:alice@example.com", uri.Path)
+	assert.Equal(t, rfcSecret, uri.Query().Get("secret"))
+	assert.Equal(t, "Task Manager", uri.Query().Get("issuer"))
+}
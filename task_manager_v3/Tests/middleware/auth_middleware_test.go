@@ -1,8 +1,11 @@
 package middleware_test
 
 import (
+	"a2sv-backend/task_manager_v3/Domain"
 	"a2sv-backend/task_manager_v3/Infrastructure"
+	"a2sv-backend/task_manager_v3/Repositories"
 	"a2sv-backend/task_manager_v3/Tests/mocks"
+	"a2sv-backend/task_manager_v3/Usecases"
 	"errors"
 	"net/http"
 	"net/http/httptest"
@@ -11,6 +14,8 @@ import (
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func TestAuthMiddleware(t *testing.T) {
@@ -18,7 +23,7 @@ func TestAuthMiddleware(t *testing.T) {
 
 	t.Run("NoHeader", func(t *testing.T) {
 		mockJWTService := new(mocks.MockJWTService)
-		middleware := Infrastructure.AuthMiddleware(mockJWTService)
+		middleware := Infrastructure.AuthMiddleware(mockJWTService, nil, nil, nil)
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
@@ -32,7 +37,7 @@ func TestAuthMiddleware(t *testing.T) {
 
 	t.Run("InvalidFormat", func(t *testing.T) {
 		mockJWTService := new(mocks.MockJWTService)
-		middleware := Infrastructure.AuthMiddleware(mockJWTService)
+		middleware := Infrastructure.AuthMiddleware(mockJWTService, nil, nil, nil)
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
@@ -47,7 +52,7 @@ func TestAuthMiddleware(t *testing.T) {
 
 	t.Run("InvalidToken", func(t *testing.T) {
 		mockJWTService := new(mocks.MockJWTService)
-		middleware := Infrastructure.AuthMiddleware(mockJWTService)
+		middleware := Infrastructure.AuthMiddleware(mockJWTService, nil, nil, nil)
 
 		mockJWTService.On("ValidateToken", "invalid_token").Return(&jwt.Token{}, errors.New("invalid token"))
 
@@ -64,7 +69,7 @@ func TestAuthMiddleware(t *testing.T) {
 
 	t.Run("Success", func(t *testing.T) {
 		mockJWTService := new(mocks.MockJWTService)
-		middleware := Infrastructure.AuthMiddleware(mockJWTService)
+		middleware := Infrastructure.AuthMiddleware(mockJWTService, nil, nil, nil)
 
 		token := &jwt.Token{
 			Valid: true,
@@ -89,4 +94,152 @@ func TestAuthMiddleware(t *testing.T) {
 		userID, _ := c.Get("user_id")
 		assert.Equal(t, "123", userID)
 	})
+
+	t.Run("DeactivatedAccount", func(t *testing.T) {
+		mockJWTService := new(mocks.MockJWTService)
+		mockUserUsecase := new(mocks.MockUserUsecase)
+		middleware := Infrastructure.AuthMiddleware(mockJWTService, mockUserUsecase, nil, nil)
+
+		token := &jwt.Token{
+			Valid:  true,
+			Claims: jwt.MapClaims{"user_id": "123", "username": "testuser", "role": "admin"},
+		}
+		mockJWTService.On("ValidateToken", "valid_token").Return(token, nil)
+		mockUserUsecase.On("ActiveAccount", "123").Return(Domain.User{}, Domain.ErrUserDeactivated)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/", nil)
+		c.Request.Header.Set("Authorization", "Bearer valid_token")
+
+		middleware(c)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.True(t, c.IsAborted())
+	})
+
+	t.Run("UsesCurrentRole", func(t *testing.T) {
+		mockJWTService := new(mocks.MockJWTService)
+		mockUserUsecase := new(mocks.MockUserUsecase)
+		middleware := Infrastructure.AuthMiddleware(mockJWTService, mockUserUsecase, nil, nil)
+
+		token := &jwt.Token{
+			Valid:  true,
+			Claims: jwt.MapClaims{"user_id": "123", "username": "testuser", "role": "admin"},
+		}
+		mockJWTService.On("ValidateToken", "valid_token").Return(token, nil)
+		mockUserUsecase.On("ActiveAccount", "123").Return(Domain.User{Username: "testuser", Role: "user"}, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/", nil)
+		c.Request.Header.Set("Authorization", "Bearer valid_token")
+
+		middleware(c)
+
+		assert.False(t, c.IsAborted())
+		assert.Equal(t, "user", c.GetString("role"))
+	})
+
+	t.Run("RejectsPendingMFAToken", func(t *testing.T) {
+		mockJWTService := new(mocks.MockJWTService)
+		middleware := Infrastructure.AuthMiddleware(mockJWTService, nil, nil, nil)
+
+		token := &jwt.Token{
+			Valid:  true,
+			Claims: jwt.MapClaims{"user_id": "123", "mfa_pending": true},
+		}
+		mockJWTService.On("ValidateToken", "pending_token").Return(token, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/", nil)
+		c.Request.Header.Set("Authorization", "Bearer pending_token")
+
+		middleware(c)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.True(t, c.IsAborted())
+	})
+}
+
+func TestAuthMiddleware_AccessTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tokens := Usecases.NewAccessTokenUsecase(Repositories.NewInMemoryAccessTokenRepository())
+	userID := primitive.NewObjectID()
+	readOnly, readValue, err := tokens.Create(userID, "reporting", []string{Domain.ScopeRead}, 0)
+	require.NoError(t, err)
+	_, adminValue, err := tokens.Create(userID, "admin script", []string{Domain.ScopeRead, Domain.ScopeWrite, Domain.ScopeAdmin}, 0)
+	require.NoError(t, err)
+
+	mockUserUsecase := new(mocks.MockUserUsecase)
+	mockUserUsecase.On("ActiveAccount", userID.Hex()).Return(Domain.User{ID: userID, Username: "ops", Role: Domain.RoleAdmin}, nil)
+
+	r := gin.New()
+	protected := r.Group("/", Infrastructure.AuthMiddleware(new(mocks.MockJWTService), mockUserUsecase, tokens, nil))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	protected.GET("/tasks", ok)
+	protected.POST("/tasks", Infrastructure.AdminMiddleware(), ok)
+	protected.GET("/users", Infrastructure.AdminMiddleware(), ok)
+	protected.POST("/me/tokens", Infrastructure.InteractiveOnly(), ok)
+
+	request := func(method, path, token string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request("GET", "/tasks", readValue))
+	assert.Equal(t, http.StatusForbidden, request("POST", "/tasks", readValue))
+	// The owner is an admin, but the token wasn't given the admin scope
+	assert.Equal(t, http.StatusForbidden, request("GET", "/users", readValue))
+	assert.Equal(t, http.StatusOK, request("POST", "/tasks", adminValue))
+	assert.Equal(t, http.StatusForbidden, request("POST", "/me/tokens", adminValue))
+	assert.Equal(t, http.StatusUnauthorized, request("GET", "/tasks", Domain.AccessTokenPrefix+"made-up"))
+
+	listed, _ := tokens.List(userID)
+	for _, token := range listed {
+		if token.ID == readOnly.ID {
+			assert.False(t, token.LastUsedAt.IsZero())
+		}
+	}
+
+	require.NoError(t, tokens.Revoke(userID, readOnly.ID))
+	assert.Equal(t, http.StatusUnauthorized, request("GET", "/tasks", readValue))
+}
+
+func TestAuthMiddleware_Sessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwtService := Infrastructure.NewJWTService(mocks.JWTSecret)
+	sessions := Usecases.NewSessionUsecase(Repositories.NewInMemorySessionRepository(), jwtService)
+	user := Domain.User{ID: primitive.NewObjectID(), Username: "alice", Role: Domain.RoleUser}
+
+	r := gin.New()
+	r.GET("/me", Infrastructure.AuthMiddleware(jwtService, nil, nil, sessions), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("session_id"))
+	})
+	request := func(token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	token, err := sessions.Start(user, Domain.ClientInfo{UserAgent: "curl/8", IP: "127.0.0.1"})
+	require.NoError(t, err)
+	w := request(token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.Body.String())
+
+	// Tokens from before sessions were tracked aren't accepted
+	unbound, _ := jwtService.GenerateToken(user, "")
+	assert.Equal(t, http.StatusUnauthorized, request(unbound).Code)
+
+	sessions.RevokeAll(user.ID)
+	w = request(token)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Session has ended")
 }
@@ -0,0 +1,108 @@
+package mocks
+
+import (
+	"a2sv-backend/task_manager_v3/Delivery/controllers"
+	"a2sv-backend/task_manager_v3/Delivery/routers"
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Infrastructure"
+	"a2sv-backend/task_manager_v3/Repositories"
+	"a2sv-backend/task_manager_v3/Usecases"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// CheapPasswordService hashes with bcrypt at its minimum cost, so tests get
+// real hashes without spending seconds on each
+type CheapPasswordService struct{}
+
+func (CheapPasswordService) HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	return string(hash), err
+}
+
+func (CheapPasswordService) ComparePassword(hashedPassword, password string) error {
+	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+}
+
+// JWTSecret signs the tokens of every JWT service tests create, so tokens
+// issued by one are accepted by the others
+const JWTSecret = "test-secret-that-is-at-least-32-bytes"
+
+// App is the whole API wired like main.go, but over in-memory repositories
+// and with open registration. Single sign-on and rate limiting are off.
+type App struct {
+	Router *gin.Engine
+	Users  Repositories.UserRepository
+	Tasks  Repositories.TaskRepository
+	Outbox *Infrastructure.InMemoryOutbox
+	// Metrics counts the requests the router serves
+	Metrics *Infrastructure.Metrics
+
+	UserUsecase        Usecases.UserUsecase
+	TaskUsecase        Usecases.TaskUsecase
+	CatalogUsecase     Usecases.CatalogUsecase
+	AccessTokenUsecase Usecases.AccessTokenUsecase
+	// Authenticator checks tokens the way the router does, for other
+	// transports
+	Authenticator *Infrastructure.Authenticator
+}
+
+func NewApp() *App {
+	gin.SetMode(gin.TestMode)
+
+	userRepo := Repositories.NewInMemoryUserRepository()
+	taskRepo := Repositories.NewInMemoryTaskRepository()
+	labelRepo := Repositories.NewInMemoryLabelRepository()
+	fieldRepo := Repositories.NewInMemoryCustomFieldRepository()
+
+	passwordService := CheapPasswordService{}
+	jwtService := Infrastructure.NewJWTService(JWTSecret)
+	outbox := Infrastructure.NewInMemoryOutbox()
+
+	sessionUsecase := Usecases.NewSessionUsecase(Repositories.NewInMemorySessionRepository(), jwtService)
+	accountUsecase := Usecases.NewAccountUsecase(userRepo, Repositories.NewInMemoryUserTokenRepository(), passwordService, outbox, sessionUsecase, "http://localhost:8080")
+	mfaUsecase := Usecases.NewMFAUsecase(userRepo, Repositories.NewInMemorySecurityPolicyRepository(), Repositories.NewInMemoryMFAChallengeRepository(), passwordService, jwtService, sessionUsecase, "")
+	userUsecase := Usecases.NewUserUsecase(userRepo, Repositories.NewInMemoryInvitationRepository(), passwordService, jwtService,
+		Domain.RegistrationPolicy{Mode: Domain.RegistrationOpen}, accountUsecase, mfaUsecase, sessionUsecase)
+	accessTokenUsecase := Usecases.NewAccessTokenUsecase(Repositories.NewInMemoryAccessTokenRepository())
+	attachmentUsecase := Usecases.NewAttachmentUsecase(Repositories.NewInMemoryAttachmentRepository(), taskRepo, Infrastructure.NewInMemoryBlobStore(), 0)
+	taskUsecase := Usecases.NewTaskUsecase(taskRepo, labelRepo, fieldRepo, userRepo, Infrastructure.NewInMemoryEventBus(64), attachmentUsecase)
+	reportUsecase := Usecases.NewReportUsecase(Repositories.NewInMemoryReportRepository(taskRepo))
+	catalogUsecase := Usecases.NewCatalogUsecase(labelRepo, fieldRepo, taskRepo)
+
+	metrics := Infrastructure.NewMetrics()
+	router := routers.SetupRouter(
+		controllers.NewTaskController(taskUsecase),
+		controllers.NewUserController(userUsecase),
+		controllers.NewReportController(reportUsecase),
+		controllers.NewCatalogController(catalogUsecase),
+		controllers.NewAttachmentController(attachmentUsecase),
+		controllers.NewAccountController(accountUsecase),
+		controllers.NewMFAController(mfaUsecase),
+		controllers.NewSSOController(nil),
+		controllers.NewAccessTokenController(accessTokenUsecase),
+		controllers.NewSessionController(sessionUsecase),
+		controllers.NewGraphQLController(taskUsecase, userUsecase, catalogUsecase, 0, 0),
+		jwtService, userUsecase, accessTokenUsecase, sessionUsecase, nil, metrics, Infrastructure.DefaultConfig().API, nil,
+	)
+
+	return &App{
+		Router:             router,
+		Users:              userRepo,
+		Tasks:              taskRepo,
+		Outbox:             outbox,
+		Metrics:            metrics,
+		UserUsecase:        userUsecase,
+		TaskUsecase:        taskUsecase,
+		CatalogUsecase:     catalogUsecase,
+		AccessTokenUsecase: accessTokenUsecase,
+		Authenticator:      Infrastructure.NewAuthenticator(jwtService, userUsecase, accessTokenUsecase, sessionUsecase),
+	}
+}
+
+// Login signs username in and returns the access token
+func (a *App) Login(username, password string) (string, error) {
+	result, err := a.UserUsecase.Login(username, password, Domain.ClientInfo{})
+	return result.Token, err
+}
@@ -11,8 +11,13 @@ type MockJWTService struct {
 	mock.Mock
 }
 
-func (m *MockJWTService) GenerateToken(user Domain.User) (string, error) {
-	args := m.Called(user)
+func (m *MockJWTService) GenerateToken(user Domain.User, sessionID string) (string, error) {
+	args := m.Called(user, sessionID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockJWTService) GenerateMFAToken(user Domain.User, challengeID string) (string, error) {
+	args := m.Called(user, challengeID)
 	return args.String(0), args.Error(1)
 }
 
@@ -35,3 +35,49 @@ func (m *MockTaskRepository) Delete(id primitive.ObjectID) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
+
+func (m *MockTaskRepository) Search(query Domain.SearchQuery, limit int) ([]Domain.TaskSearchResult, error) {
+	args := m.Called(query, limit)
+	return args.Get(0).([]Domain.TaskSearchResult), args.Error(1)
+}
+
+func (m *MockTaskRepository) BulkWrite(ops []Domain.BulkOperation, atomic bool) ([]Domain.BulkResult, error) {
+	args := m.Called(ops, atomic)
+	results, _ := args.Get(0).([]Domain.BulkResult)
+	return results, args.Error(1)
+}
+
+func (m *MockTaskRepository) ForEach(fn func(task Domain.Task) error) error {
+	args := m.Called(fn)
+	return args.Error(0)
+}
+
+func (m *MockTaskRepository) UpsertByExternalID(task Domain.Task) (Domain.Task, bool, error) {
+	args := m.Called(task)
+	return args.Get(0).(Domain.Task), args.Bool(1), args.Error(2)
+}
+
+func (m *MockTaskRepository) FindByExternalID(externalID string) (Domain.Task, error) {
+	args := m.Called(externalID)
+	return args.Get(0).(Domain.Task), args.Error(1)
+}
+
+func (m *MockTaskRepository) Find(filter Domain.TaskFilter) ([]Domain.Task, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]Domain.Task), args.Error(1)
+}
+
+func (m *MockTaskRepository) FindPage(filter Domain.TaskFilter, page, pageSize int) ([]Domain.Task, int64, error) {
+	args := m.Called(filter, page, pageSize)
+	return args.Get(0).([]Domain.Task), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockTaskRepository) RemoveLabel(labelID primitive.ObjectID) error {
+	args := m.Called(labelID)
+	return args.Error(0)
+}
+
+func (m *MockTaskRepository) RemoveCustomField(key string) error {
+	args := m.Called(key)
+	return args.Error(0)
+}
@@ -2,6 +2,9 @@ package mocks
 
 import (
 	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Infrastructure"
+	"context"
+
 	"github.com/stretchr/testify/mock"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -34,3 +37,40 @@ func (m *MockTaskUsecase) Delete(id primitive.ObjectID) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
+
+func (m *MockTaskUsecase) Subscribe(ctx context.Context, viewer Domain.Viewer, lastEventID string) (<-chan Domain.TaskEvent, error) {
+	args := m.Called(ctx, viewer, lastEventID)
+	ch, _ := args.Get(0).(chan Domain.TaskEvent)
+	return ch, args.Error(1)
+}
+
+func (m *MockTaskUsecase) Search(viewer Domain.Viewer, q string, limit int) ([]Domain.TaskSearchResult, error) {
+	args := m.Called(viewer, q, limit)
+	return args.Get(0).([]Domain.TaskSearchResult), args.Error(1)
+}
+
+func (m *MockTaskUsecase) Bulk(ops []Domain.BulkOperation, atomic bool) ([]Domain.BulkResult, error) {
+	args := m.Called(ops, atomic)
+	results, _ := args.Get(0).([]Domain.BulkResult)
+	return results, args.Error(1)
+}
+
+func (m *MockTaskUsecase) Export(viewer Domain.Viewer, encoder Infrastructure.TaskEncoder) error {
+	args := m.Called(viewer, encoder)
+	return args.Error(0)
+}
+
+func (m *MockTaskUsecase) Import(decoder Infrastructure.TaskDecoder, dryRun bool) (Domain.ImportReport, error) {
+	args := m.Called(decoder, dryRun)
+	return args.Get(0).(Domain.ImportReport), args.Error(1)
+}
+
+func (m *MockTaskUsecase) List(filter Domain.TaskFilter) ([]Domain.Task, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]Domain.Task), args.Error(1)
+}
+
+func (m *MockTaskUsecase) ListPage(filter Domain.TaskFilter, page, pageSize int) ([]Domain.Task, int64, error) {
+	args := m.Called(filter, page, pageSize)
+	return args.Get(0).([]Domain.Task), args.Get(1).(int64), args.Error(2)
+}
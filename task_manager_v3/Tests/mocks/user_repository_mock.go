@@ -25,6 +25,11 @@ func (m *MockUserRepository) FindByID(id primitive.ObjectID) (Domain.User, error
 	return args.Get(0).(Domain.User), args.Error(1)
 }
 
+func (m *MockUserRepository) FindByIDs(ids []primitive.ObjectID) ([]Domain.User, error) {
+	args := m.Called(ids)
+	return args.Get(0).([]Domain.User), args.Error(1)
+}
+
 func (m *MockUserRepository) Update(user Domain.User) (Domain.User, error) {
 	args := m.Called(user)
 	return args.Get(0).(Domain.User), args.Error(1)
@@ -34,3 +39,53 @@ func (m *MockUserRepository) Count() (int64, error) {
 	args := m.Called()
 	return args.Get(0).(int64), args.Error(1)
 }
+
+func (m *MockUserRepository) List(query Domain.UserQuery) ([]Domain.User, int64, error) {
+	args := m.Called(query)
+	return args.Get(0).([]Domain.User), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockUserRepository) CountActiveAdmins() (int64, error) {
+	args := m.Called()
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockUserRepository) Delete(id primitive.ObjectID) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdateKeepingAnAdmin(user Domain.User) (Domain.User, error) {
+	args := m.Called(user)
+	return args.Get(0).(Domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) DeleteKeepingAnAdmin(id primitive.ObjectID) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ClaimFirstAdmin(id primitive.ObjectID) (bool, error) {
+	args := m.Called(id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) ReleaseFirstAdmin(id primitive.ObjectID) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UseTOTPStep(id primitive.ObjectID, step int64) (bool, error) {
+	args := m.Called(id, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) UseRecoveryCode(id primitive.ObjectID, hash string) (bool, error) {
+	args := m.Called(id, hash)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) FindByEmail(email string) (Domain.User, error) {
+	args := m.Called(email)
+	return args.Get(0).(Domain.User), args.Error(1)
+}
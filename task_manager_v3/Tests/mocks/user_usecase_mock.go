@@ -2,6 +2,8 @@ package mocks
 
 import (
 	"a2sv-backend/task_manager_v3/Domain"
+	"time"
+
 	"github.com/stretchr/testify/mock"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -10,17 +12,87 @@ type MockUserUsecase struct {
 	mock.Mock
 }
 
-func (m *MockUserUsecase) Register(user Domain.User) error {
-	args := m.Called(user)
+func (m *MockUserUsecase) Register(reg Domain.Registration) error {
+	args := m.Called(reg)
 	return args.Error(0)
 }
 
-func (m *MockUserUsecase) Login(username, password string) (string, Domain.User, error) {
-	args := m.Called(username, password)
-	return args.String(0), args.Get(1).(Domain.User), args.Error(2)
+func (m *MockUserUsecase) Login(username, password string, client Domain.ClientInfo) (Domain.LoginResult, error) {
+	args := m.Called(username, password, client)
+	return args.Get(0).(Domain.LoginResult), args.Error(1)
 }
 
 func (m *MockUserUsecase) Promote(userID primitive.ObjectID) error {
 	args := m.Called(userID)
 	return args.Error(0)
 }
+
+func (m *MockUserUsecase) ListUsers(query Domain.UserQuery) ([]Domain.User, int64, error) {
+	args := m.Called(query)
+	return args.Get(0).([]Domain.User), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockUserUsecase) GetUser(id primitive.ObjectID) (Domain.User, error) {
+	args := m.Called(id)
+	return args.Get(0).(Domain.User), args.Error(1)
+}
+
+func (m *MockUserUsecase) GetUsers(ids []primitive.ObjectID) ([]Domain.User, error) {
+	args := m.Called(ids)
+	return args.Get(0).([]Domain.User), args.Error(1)
+}
+
+func (m *MockUserUsecase) ChangeRole(actorID, id primitive.ObjectID, role string) (Domain.User, error) {
+	args := m.Called(actorID, id, role)
+	return args.Get(0).(Domain.User), args.Error(1)
+}
+
+func (m *MockUserUsecase) Deactivate(actorID, id primitive.ObjectID) (Domain.User, error) {
+	args := m.Called(actorID, id)
+	return args.Get(0).(Domain.User), args.Error(1)
+}
+
+func (m *MockUserUsecase) Reactivate(actorID, id primitive.ObjectID) (Domain.User, error) {
+	args := m.Called(actorID, id)
+	return args.Get(0).(Domain.User), args.Error(1)
+}
+
+func (m *MockUserUsecase) Delete(actorID, id primitive.ObjectID) error {
+	args := m.Called(actorID, id)
+	return args.Error(0)
+}
+
+func (m *MockUserUsecase) UpdateProfile(id primitive.ObjectID, update Domain.ProfileUpdate) (Domain.User, error) {
+	args := m.Called(id, update)
+	return args.Get(0).(Domain.User), args.Error(1)
+}
+
+func (m *MockUserUsecase) ChangePassword(id primitive.ObjectID, sessionID, current, next string) error {
+	args := m.Called(id, sessionID, current, next)
+	return args.Error(0)
+}
+
+func (m *MockUserUsecase) ActiveAccount(userID string) (Domain.User, error) {
+	args := m.Called(userID)
+	return args.Get(0).(Domain.User), args.Error(1)
+}
+
+func (m *MockUserUsecase) BootstrapAdmin(username, password string) (Domain.User, error) {
+	args := m.Called(username, password)
+	return args.Get(0).(Domain.User), args.Error(1)
+}
+
+func (m *MockUserUsecase) CreateInvitation(actorID primitive.ObjectID, role, email string, ttl time.Duration) (Domain.Invitation, string, error) {
+	args := m.Called(actorID, role, email, ttl)
+	return args.Get(0).(Domain.Invitation), args.String(1), args.Error(2)
+}
+
+func (m *MockUserUsecase) ListInvitations() ([]Domain.Invitation, error) {
+	args := m.Called()
+	return args.Get(0).([]Domain.Invitation), args.Error(1)
+}
+
+func (m *MockUserUsecase) RevokeInvitation(id primitive.ObjectID) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
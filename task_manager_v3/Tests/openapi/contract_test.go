@@ -0,0 +1,317 @@
+package openapi_test
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Tests/mocks"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// streamingRoutes never finish on their own
+var streamingRoutes = map[string]bool{
+	"GET /tasks/stream": true,
+	"GET /tasks/ws":     true,
+}
+
+var (
+	pathParam  = regexp.MustCompile(`:(\w+)`)
+	apiVersion = regexp.MustCompile(`^/api/v\d+`)
+)
+
+func fetchDocument(t *testing.T, app *mocks.App) map[string]interface{} {
+	w := httptest.NewRecorder()
+	app.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	require.Equal(t, "3.1.0", doc["openapi"])
+	return doc
+}
+
+// TestSpecCoversEveryRoute checks the document and the router describe the
+// same operations
+func TestSpecCoversEveryRoute(t *testing.T) {
+	app := mocks.NewApp()
+	doc := fetchDocument(t, app)
+
+	var documented []string
+	for path, item := range doc["paths"].(map[string]interface{}) {
+		for method := range item.(map[string]interface{}) {
+			documented = append(documented, strings.ToUpper(method)+" "+path)
+		}
+	}
+	var routed []string
+	for _, route := range app.Router.Routes() {
+		routed = append(routed, route.Method+" "+pathParam.ReplaceAllString(route.Path, "{$1}"))
+	}
+	assert.ElementsMatch(t, routed, documented)
+}
+
+func TestDocsServesSwaggerUI(t *testing.T) {
+	app := mocks.NewApp()
+	w := httptest.NewRecorder()
+	app.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), `url: "openapi.json"`)
+	assert.NotContains(t, w.Body.String(), "https://", "everything the page loads is served from here")
+	assert.NotContains(t, w.Body.String(), "persistAuthorization")
+
+	for path, contentType := range map[string]string{
+		"/docs/swagger-ui.css":       "text/css",
+		"/docs/swagger-ui-bundle.js": "text/javascript",
+	} {
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusOK, w.Code, path)
+		assert.Contains(t, w.Header().Get("Content-Type"), contentType, path)
+		assert.Greater(t, w.Body.Len(), 1000, path)
+	}
+}
+
+// TestResponsesMatchSpec calls every route as an admin, a user and an
+// anonymous caller and checks each answer is a documented status, in a
+// documented content type, and for JSON, matches the documented schema
+func TestResponsesMatchSpec(t *testing.T) {
+	app := mocks.NewApp()
+	doc := fetchDocument(t, app)
+	v := validator{components: doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})}
+
+	const adminPassword, userPassword = "admin-secret-1", "alice-secret-1"
+	_, err := app.UserUsecase.BootstrapAdmin("root", adminPassword)
+	require.NoError(t, err)
+	require.NoError(t, app.UserUsecase.Register(Domain.Registration{Username: "alice", Password: userPassword, Email: "alice@example.com"}))
+	alice, _ := app.Users.FindByUsername("alice")
+	adminToken, err := app.Login("root", adminPassword)
+	require.NoError(t, err)
+	userToken, err := app.Login("alice", userPassword)
+	require.NoError(t, err)
+	task, err := app.TaskUsecase.Create(Domain.Task{Title: "Rotate keys", DueDate: time.Now().Add(24 * time.Hour)})
+	require.NoError(t, err)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"username":         "alice",
+		"password":         userPassword,
+		"current_password": userPassword,
+		"new_password":     "alice-secret-2",
+		"display_name":     "Walker",
+		"email":            "walker@example.com",
+		"title":            "Walked",
+		"due_date":         time.Now().Add(48 * time.Hour),
+		"user_id":          alice.ID.Hex(),
+		"role":             Domain.RoleUser,
+		"roles":            []string{Domain.RoleAdmin},
+		"name":             "walked",
+		"color":            "#aabbcc",
+		"key":              "walked",
+		"type":             Domain.FieldText,
+		"scopes":           []string{Domain.ScopeRead},
+		"operations":       []map[string]interface{}{{"op": "update", "id": task.ID.Hex(), "task": map[string]string{"title": "Bulk"}}},
+	})
+	params := strings.NewReplacer(
+		"/users/:id", "/users/"+alice.ID.Hex(),
+		"/tasks/:id", "/tasks/"+task.ID.Hex(),
+		":project", Domain.DefaultProject,
+		":id", primitive.NewObjectID().Hex(),
+		":attachmentId", primitive.NewObjectID().Hex(),
+	)
+
+	routes := app.Router.Routes()
+	// Deletes go last so the other routes still find what they act on
+	sort.SliceStable(routes, func(i, j int) bool {
+		return routes[i].Method != http.MethodDelete && routes[j].Method == http.MethodDelete
+	})
+	paths := doc["paths"].(map[string]interface{})
+	for _, route := range routes {
+		name := route.Method + " " + route.Path
+		if streamingRoutes[route.Method+" "+apiVersion.ReplaceAllString(route.Path, "")] {
+			continue
+		}
+		operation := paths[pathParam.ReplaceAllString(route.Path, "{$1}")].(map[string]interface{})[strings.ToLower(route.Method)].(map[string]interface{})
+		responses := operation["responses"].(map[string]interface{})
+
+		for _, token := range []string{adminToken, userToken, ""} {
+			req := httptest.NewRequest(route.Method, params.Replace(route.Path), bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			w := httptest.NewRecorder()
+			app.Router.ServeHTTP(w, req)
+
+			documented, ok := responses[strconv.Itoa(w.Code)].(map[string]interface{})
+			if !assert.True(t, ok, "%s answered an undocumented %d: %s", name, w.Code, w.Body.String()) {
+				continue
+			}
+			content, _ := documented["content"].(map[string]interface{})
+			if content == nil {
+				assert.Empty(t, w.Body.String(), "%s %d is documented without a body", name, w.Code)
+				continue
+			}
+			contentType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+			media, ok := content[contentType].(map[string]interface{})
+			if !assert.True(t, ok, "%s %d answered with an undocumented %q", name, w.Code, contentType) {
+				continue
+			}
+			if contentType != "application/json" {
+				continue
+			}
+			var decoded interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &decoded), name)
+			for _, problem := range v.validate(media["schema"], decoded, "$") {
+				t.Errorf("%s %d: %s in %s", name, w.Code, problem, w.Body.String())
+			}
+		}
+	}
+}
+
+// validator checks JSON values against the subset of JSON Schema the
+// document uses
+type validator struct {
+	components map[string]interface{}
+}
+
+func (v validator) validate(schemaValue interface{}, value interface{}, at string) []string {
+	schema, _ := schemaValue.(map[string]interface{})
+	if ref, ok := schema["$ref"].(string); ok {
+		return v.validate(v.components[strings.TrimPrefix(ref, "#/components/schemas/")], value, at)
+	}
+	if options, ok := schema["anyOf"].([]interface{}); ok {
+		return v.alternatives(options, value, at, false)
+	}
+	if options, ok := schema["oneOf"].([]interface{}); ok {
+		return v.alternatives(options, value, at, true)
+	}
+
+	if types := schemaTypes(schema["type"]); len(types) > 0 && !typeMatches(types, value) {
+		return []string{fmt.Sprintf("%s is %T, want %v", at, value, types)}
+	}
+	var problems []string
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			found = found || allowed == value
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("%s is %v, not one of %v", at, value, enum))
+		}
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		if s, isString := value.(string); isString && !regexp.MustCompile(pattern).MatchString(s) {
+			problems = append(problems, fmt.Sprintf("%s %q doesn't match %s", at, s, pattern))
+		}
+	}
+
+	switch value := value.(type) {
+	case []interface{}:
+		for i, item := range value {
+			problems = append(problems, v.validate(schema["items"], item, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := value[name.(string)]; !ok {
+				problems = append(problems, fmt.Sprintf("%s is missing %s", at, name))
+			}
+		}
+		for key, field := range value {
+			if property, ok := properties[key]; ok {
+				problems = append(problems, v.validate(property, field, at+"."+key)...)
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					problems = append(problems, fmt.Sprintf("%s has undocumented field %s", at, key))
+				}
+			case map[string]interface{}:
+				problems = append(problems, v.validate(extra, field, at+"."+key)...)
+			}
+		}
+	}
+	return problems
+}
+
+// alternatives matches at least one option, or exactly one for oneOf
+func (v validator) alternatives(options []interface{}, value interface{}, at string, exactlyOne bool) []string {
+	var matched int
+	var problems []string
+	for _, option := range options {
+		p := v.validate(option, value, at)
+		if len(p) == 0 {
+			matched++
+		}
+		problems = append(problems, p...)
+	}
+	switch {
+	case matched == 0:
+		return append([]string{at + " matches no alternative"}, problems...)
+	case exactlyOne && matched > 1:
+		return []string{fmt.Sprintf("%s matches %d alternatives of a oneOf", at, matched)}
+	}
+	return nil
+}
+
+func schemaTypes(t interface{}) []string {
+	switch t := t.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var types []string
+		for _, name := range t {
+			types = append(types, name.(string))
+		}
+		return types
+	}
+	return nil
+}
+
+func typeMatches(types []string, value interface{}) bool {
+	for _, t := range types {
+		switch value := value.(type) {
+		case nil:
+			if t == "null" {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case float64:
+			if t == "number" || (t == "integer" && value == math.Trunc(value)) {
+				return true
+			}
+		case []interface{}:
+			if t == "array" {
+				return true
+			}
+		case map[string]interface{}:
+			if t == "object" {
+				return true
+			}
+		}
+	}
+	return false
+}
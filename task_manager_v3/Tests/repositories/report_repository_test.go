@@ -0,0 +1,82 @@
+package repositories_test
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Repositories"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func history(entries ...interface{}) []Domain.StatusChange {
+	var changes []Domain.StatusChange
+	for i := 0; i < len(entries); i += 2 {
+		changes = append(changes, Domain.StatusChange{Status: entries[i].(string), At: entries[i+1].(time.Time)})
+	}
+	return changes
+}
+
+func TestInMemoryReportRepository(t *testing.T) {
+	// Wednesday
+	now := time.Date(2026, 1, 14, 12, 0, 0, 0, time.UTC)
+	day := func(d int, h int) time.Time { return time.Date(2026, 1, d, h, 0, 0, 0, time.UTC) }
+
+	tasks := Repositories.NewInMemoryTaskRepository()
+	for _, task := range []Domain.Task{
+		{Title: "Overdue", Status: Domain.StatusPending, DueDate: day(10, 0)},
+		{Title: "Due Friday", Status: Domain.StatusInProgress, DueDate: day(16, 0)},
+		{Title: "Done fast", Status: Domain.StatusCompleted, DueDate: day(1, 0),
+			StatusHistory: history(Domain.StatusPending, day(5, 0), Domain.StatusInProgress, day(5, 8), Domain.StatusCompleted, day(5, 10))},
+		{Title: "Done slow", Status: Domain.StatusCompleted,
+			StatusHistory: history(Domain.StatusInProgress, day(6, 0), Domain.StatusCompleted, day(13, 0))},
+		{Title: "Done mid", Status: Domain.StatusCompleted,
+			StatusHistory: history(Domain.StatusInProgress, day(12, 0), Domain.StatusCompleted, day(13, 6))},
+		{Title: "Never started", Status: Domain.StatusCompleted,
+			StatusHistory: history(Domain.StatusCompleted, day(13, 1))},
+		{Title: "No due date", Status: Domain.StatusPending},
+	} {
+		tasks.Create(task)
+	}
+	reports := Repositories.NewInMemoryReportRepository(tasks)
+
+	t.Run("Summary", func(t *testing.T) {
+		summary, err := reports.Summary(now)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7), summary.Total)
+		assert.Equal(t, map[string]int64{"pending": 2, "in_progress": 1, "completed": 4}, summary.ByStatus)
+		assert.Equal(t, int64(1), summary.Overdue)
+		assert.Equal(t, int64(1), summary.DueThisWeek)
+		assert.Equal(t, day(12, 0), summary.WeekStart)
+	})
+
+	t.Run("ThroughputByDay", func(t *testing.T) {
+		buckets, err := reports.Throughput(day(1, 0), now, Domain.IntervalDay)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []Domain.ThroughputBucket{
+			{Period: "2026-01-05", Completed: 1},
+			{Period: "2026-01-13", Completed: 3},
+		}, buckets)
+	})
+
+	t.Run("ThroughputByWeek", func(t *testing.T) {
+		buckets, err := reports.Throughput(day(1, 0), now, Domain.IntervalWeek)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []Domain.ThroughputBucket{
+			{Period: "2026-W02", Completed: 1},
+			{Period: "2026-W03", Completed: 3},
+		}, buckets)
+	})
+
+	t.Run("CycleTime", func(t *testing.T) {
+		report, err := reports.CycleTime(day(1, 0), now)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), report.Tasks)
+		assert.Equal(t, 30.0, report.MedianHours)
+		assert.InDelta(t, (2.0+168.0+30.0)/3, report.AverageHours, 0.001)
+	})
+}
@@ -0,0 +1,100 @@
+package repositories_test
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Repositories"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func seedTasks(t *testing.T, repo Repositories.TaskRepository) {
+	t.Helper()
+	for _, task := range []Domain.Task{
+		{Title: "Fix login bug", Description: "Users see a blank page after login on Safari"},
+		{Title: "Write release notes", Description: "Mention the login bug fix and the new search"},
+		{Title: "Search page", Description: "Prefix matching for the search box"},
+	} {
+		_, err := repo.Create(task)
+		assert.NoError(t, err)
+	}
+}
+
+func TestParseSearchQuery(t *testing.T) {
+	query := Repositories.ParseSearchQuery(`Login "blank page" sear* "x"`)
+
+	assert.Equal(t, []string{"login", "x"}, query.Terms)
+	assert.Equal(t, []string{"sear"}, query.Prefixes)
+	assert.Equal(t, [][]string{{"blank", "page"}}, query.Phrases)
+	assert.True(t, Repositories.ParseSearchQuery(` "" * `).IsEmpty())
+}
+
+func TestInMemoryTaskRepository_Search(t *testing.T) {
+	repo := Repositories.NewInMemoryTaskRepository()
+	seedTasks(t, repo)
+
+	t.Run("TitleHitsRankFirst", func(t *testing.T) {
+		results, err := repo.Search(Repositories.ParseSearchQuery("login"), 10)
+
+		assert.NoError(t, err)
+		assert.Len(t, results, 2)
+		assert.Equal(t, "Fix login bug", results[0].Task.Title)
+		assert.Equal(t, "Fix <mark>login</mark> bug", results[0].Highlights["title"])
+		assert.Greater(t, results[0].Score, results[1].Score)
+	})
+
+	t.Run("AllTermsRequired", func(t *testing.T) {
+		results, _ := repo.Search(Repositories.ParseSearchQuery("login safari"), 10)
+
+		assert.Len(t, results, 1)
+		assert.Equal(t, "Fix login bug", results[0].Task.Title)
+	})
+
+	t.Run("Phrase", func(t *testing.T) {
+		results, _ := repo.Search(Repositories.ParseSearchQuery(`"bug fix"`), 10)
+
+		assert.Len(t, results, 1)
+		assert.Equal(t, "Write release notes", results[0].Task.Title)
+		assert.Contains(t, results[0].Highlights["description"], "<mark>bug</mark> <mark>fix</mark>")
+	})
+
+	t.Run("Prefix", func(t *testing.T) {
+		results, _ := repo.Search(Repositories.ParseSearchQuery("pref*"), 10)
+
+		assert.Len(t, results, 1)
+		assert.Equal(t, "Search page", results[0].Task.Title)
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		results, _ := repo.Search(Repositories.ParseSearchQuery("sea*"), 1)
+
+		assert.Len(t, results, 1)
+	})
+
+	t.Run("HighlightsAreEscaped", func(t *testing.T) {
+		_, err := repo.Create(Domain.Task{Title: "<script>alert(1)</script> R&D budget", Description: "Ask <b>finance</b> & legal about the budget"})
+		assert.NoError(t, err)
+
+		results, _ := repo.Search(Repositories.ParseSearchQuery("budget"), 10)
+		assert.Len(t, results, 1)
+		assert.Equal(t, "&lt;script&gt;alert(1)&lt;/script&gt; R&amp;D <mark>budget</mark>", results[0].Highlights["title"])
+		assert.Equal(t, "Ask &lt;b&gt;finance&lt;/b&gt; &amp; legal about the <mark>budget</mark>", results[0].Highlights["description"])
+		assert.NoError(t, repo.Delete(results[0].Task.ID))
+	})
+
+	t.Run("IndexFollowsUpdatesAndDeletes", func(t *testing.T) {
+		results, _ := repo.Search(Repositories.ParseSearchQuery("safari"), 10)
+		task := results[0].Task
+
+		task.Description = "Chrome only"
+		_, err := repo.Update(task)
+		assert.NoError(t, err)
+
+		results, _ = repo.Search(Repositories.ParseSearchQuery("safari"), 10)
+		assert.Empty(t, results)
+
+		assert.NoError(t, repo.Delete(task.ID))
+		results, _ = repo.Search(Repositories.ParseSearchQuery("chrome"), 10)
+		assert.Empty(t, results)
+	})
+}
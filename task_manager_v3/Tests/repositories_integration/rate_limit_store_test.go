@@ -0,0 +1,69 @@
+package repositories_integration_test
+
+import (
+	"a2sv-backend/task_manager_v3/Infrastructure"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestMongoRateLimitStore(t *testing.T) {
+	// MONGODB_URI, as for the server; the default is a local instance
+	config, err := Infrastructure.LoadConfig("", "")
+	require.NoError(t, err, "Could not load the test configuration")
+	mongoURI := config.Database.URI
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		t.Skip("Could not connect to MongoDB: ", err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		t.Skip("Could not ping MongoDB: ", err)
+	}
+	db := client.Database("test_task_manager_v3_rate_limits")
+	defer func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	}()
+
+	store := Infrastructure.NewMongoRateLimitStore(db)
+	limit := Infrastructure.RateLimit{Rate: 1, Period: time.Second, Burst: 5}
+	now := time.Now()
+
+	// Concurrent first requests all land in one bucket
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := store.Take(context.Background(), "client", limit, now)
+			assert.NoError(t, err)
+			if result.Allowed {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 5, allowed)
+
+	denied, err := store.Take(context.Background(), "client", limit, now)
+	require.NoError(t, err)
+	assert.False(t, denied.Allowed)
+	assert.Equal(t, time.Second, denied.RetryAfter)
+
+	refilled, err := store.Take(context.Background(), "client", limit, now.Add(2*time.Second))
+	require.NoError(t, err)
+	assert.True(t, refilled.Allowed)
+	assert.Equal(t, 1, refilled.Remaining)
+}
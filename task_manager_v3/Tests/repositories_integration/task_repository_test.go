@@ -2,15 +2,17 @@ package repositories_integration_test
 
 import (
 	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Infrastructure"
 	"a2sv-backend/task_manager_v3/Repositories"
 	"context"
-	"os"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -23,10 +25,10 @@ type TaskRepositorySuite struct {
 }
 
 func (s *TaskRepositorySuite) SetupSuite() {
-	mongoURI := os.Getenv("MONGO_URI")
-	if mongoURI == "" {
-		mongoURI = "mongodb://localhost:27017"
-	}
+	// MONGODB_URI, as for the server; the default is a local instance
+	config, err := Infrastructure.LoadConfig("", "")
+	s.Require().NoError(err, "Could not load the test configuration")
+	mongoURI := config.Database.URI
 
 	clientOptions := options.Client().ApplyURI(mongoURI)
 	client, err := mongo.Connect(context.Background(), clientOptions)
@@ -95,6 +97,35 @@ func (s *TaskRepositorySuite) TestFindAll() {
 	assert.Len(s.T(), tasks, 2)
 }
 
+func (s *TaskRepositorySuite) TestUpdateClearsFields() {
+	if s.db == nil {
+		s.T().Skip("MongoDB not available")
+	}
+
+	task, err := s.repo.Create(Domain.Task{
+		Title:        "Labelled",
+		Priority:     Domain.PriorityP1,
+		Project:      "web",
+		Labels:       []primitive.ObjectID{primitive.NewObjectID()},
+		CustomFields: map[string]interface{}{"points": 3.0},
+	})
+	s.Require().NoError(err)
+
+	task.Priority, task.Project, task.Labels, task.CustomFields = "", "", nil, nil
+	_, err = s.repo.Update(task)
+	s.Require().NoError(err)
+
+	stored, err := s.repo.FindByID(task.ID)
+	s.Require().NoError(err)
+	assert.Empty(s.T(), stored.Priority)
+	assert.Empty(s.T(), stored.Project)
+	assert.Empty(s.T(), stored.Labels)
+	assert.Empty(s.T(), stored.CustomFields)
+
+	_, err = s.repo.Update(Domain.Task{ID: primitive.NewObjectID(), Title: "Missing"})
+	assert.True(s.T(), errors.Is(err, Domain.ErrTaskNotFound))
+}
+
 func TestTaskRepositorySuite(t *testing.T) {
 	suite.Run(t, new(TaskRepositorySuite))
 }
@@ -0,0 +1,131 @@
+package routers_test
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Tests/mocks"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// streamingRoutes never finish on their own. Their events carry the same
+// task model as the rest of the API.
+var streamingRoutes = map[string]bool{
+	"GET /tasks/stream": true,
+	"GET /tasks/ws":     true,
+}
+
+// apiVersion matches the version prefix of a route
+var apiVersion = regexp.MustCompile(`^/api/v\d+`)
+
+// documentationRoutes describe the request bodies, password fields
+// included, so only their values are checked
+var documentationRoutes = map[string]bool{
+	"GET /openapi.json": true,
+}
+
+// TestNoRouteLeaksPasswords calls every route as an admin, a user and an
+// anonymous caller and checks that no response carries a password, its
+// hash or a field named after one
+func TestNoRouteLeaksPasswords(t *testing.T) {
+	app := mocks.NewApp()
+	const adminPassword, userPassword = "admin-secret-1", "alice-secret-1"
+
+	admin, err := app.UserUsecase.BootstrapAdmin("root", adminPassword)
+	require.NoError(t, err)
+	require.NoError(t, app.UserUsecase.Register(Domain.Registration{Username: "alice", Password: userPassword, Email: "alice@example.com"}))
+	alice, _ := app.Users.FindByUsername("alice")
+	adminToken, err := app.Login("root", adminPassword)
+	require.NoError(t, err)
+	userToken, err := app.Login("alice", userPassword)
+	require.NoError(t, err)
+	task, err := app.TaskUsecase.Create(Domain.Task{Title: "Rotate keys", DueDate: time.Now().Add(24 * time.Hour)})
+	require.NoError(t, err)
+
+	// One body for every route: handlers ignore what they don't bind. It
+	// logs alice in and changes her password, among other things.
+	body, _ := json.Marshal(map[string]interface{}{
+		"username":         "alice",
+		"password":         userPassword,
+		"current_password": userPassword,
+		"new_password":     "alice-secret-2",
+		"display_name":     "Walker",
+		"email":            "walker@example.com",
+		"title":            "Walked",
+		"due_date":         time.Now().Add(48 * time.Hour),
+		"user_id":          alice.ID.Hex(),
+		"role":             Domain.RoleUser,
+		"name":             "walked",
+		"color":            "#aabbcc",
+		"key":              "walked",
+		"type":             Domain.FieldText,
+		"scopes":           []string{Domain.ScopeRead},
+		"operations":       []map[string]interface{}{{"op": "update", "id": task.ID.Hex(), "task": map[string]string{"title": "Bulk"}}},
+	})
+	params := strings.NewReplacer(
+		"/users/:id", "/users/"+alice.ID.Hex(),
+		"/tasks/:id", "/tasks/"+task.ID.Hex(),
+		":project", Domain.DefaultProject,
+		":id", primitive.NewObjectID().Hex(),
+		":attachmentId", primitive.NewObjectID().Hex(),
+	)
+	secrets := []string{adminPassword, userPassword, "alice-secret-2", "$2a$", "$2b$", admin.Password, alice.Password}
+
+	routes := app.Router.Routes()
+	// Deletes go last so the other routes still find what they act on
+	sort.SliceStable(routes, func(i, j int) bool {
+		return routes[i].Method != http.MethodDelete && routes[j].Method == http.MethodDelete
+	})
+	for _, route := range routes {
+		if streamingRoutes[route.Method+" "+apiVersion.ReplaceAllString(route.Path, "")] {
+			continue
+		}
+		for _, token := range []string{adminToken, userToken, ""} {
+			req := httptest.NewRequest(route.Method, params.Replace(route.Path), bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			w := httptest.NewRecorder()
+			app.Router.ServeHTTP(w, req)
+
+			name := route.Method + " " + route.Path
+			for _, secret := range secrets {
+				assert.NotContains(t, w.Body.String(), secret, name)
+			}
+			var decoded interface{}
+			if !documentationRoutes[name] && json.Unmarshal(w.Body.Bytes(), &decoded) == nil {
+				assert.Empty(t, passwordKeys(decoded), name)
+			}
+		}
+	}
+}
+
+// passwordKeys lists the object keys anywhere in v that name a password
+func passwordKeys(v interface{}) []string {
+	var keys []string
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if strings.Contains(strings.ToLower(key), "password") {
+				keys = append(keys, key)
+			}
+			keys = append(keys, passwordKeys(value)...)
+		}
+	case []interface{}:
+		for _, value := range v {
+			keys = append(keys, passwordKeys(value)...)
+		}
+	}
+	return keys
+}
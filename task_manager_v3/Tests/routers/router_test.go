@@ -3,13 +3,17 @@ package routers_test
 import (
 	"a2sv-backend/task_manager_v3/Delivery/controllers"
 	"a2sv-backend/task_manager_v3/Delivery/routers"
+	"a2sv-backend/task_manager_v3/Infrastructure"
 	"a2sv-backend/task_manager_v3/Tests/mocks"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSetupRouter(t *testing.T) {
@@ -21,8 +25,17 @@ func TestSetupRouter(t *testing.T) {
 
 	taskController := controllers.NewTaskController(mockTaskUsecase)
 	userController := controllers.NewUserController(mockUserUsecase)
+	reportController := controllers.NewReportController(nil)
+	catalogController := controllers.NewCatalogController(nil)
+	attachmentController := controllers.NewAttachmentController(nil)
+	accountController := controllers.NewAccountController(nil)
+	mfaController := controllers.NewMFAController(nil)
+	ssoController := controllers.NewSSOController(nil)
+	accessTokenController := controllers.NewAccessTokenController(nil)
+	sessionController := controllers.NewSessionController(nil)
+	graphQLController := controllers.NewGraphQLController(nil, nil, nil, 0, 0)
 
-	router := routers.SetupRouter(taskController, userController, mockJWTService)
+	router := routers.SetupRouter(taskController, userController, reportController, catalogController, attachmentController, accountController, mfaController, ssoController, accessTokenController, sessionController, graphQLController, mockJWTService, nil, nil, nil, nil, nil, Infrastructure.DefaultConfig().API, nil)
 
 	t.Run("RegisterRoute", func(t *testing.T) {
 		w := httptest.NewRecorder()
@@ -53,3 +66,70 @@ func TestSetupRouter(t *testing.T) {
 		assert.Equal(t, http.StatusUnauthorized, w.Code)
 	})
 }
+
+func TestSetupRouter_TrustedProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(trustedProxies []string) http.Handler {
+		policies, err := Infrastructure.ParseRateLimitPolicies("POST /login=1/1m,by=ip")
+		require.NoError(t, err)
+		limiter := Infrastructure.NewRateLimiter(Infrastructure.NewInMemoryRateLimitStore(), policies)
+		return routers.SetupRouter(controllers.NewTaskController(nil), controllers.NewUserController(nil), controllers.NewReportController(nil),
+			controllers.NewCatalogController(nil), controllers.NewAttachmentController(nil), controllers.NewAccountController(nil),
+			controllers.NewMFAController(nil), controllers.NewSSOController(nil), controllers.NewAccessTokenController(nil),
+			controllers.NewSessionController(nil), controllers.NewGraphQLController(nil, nil, nil, 0, 0), new(mocks.MockJWTService),
+			nil, nil, nil, limiter, nil, Infrastructure.DefaultConfig().API, trustedProxies)
+	}
+	// login sends an empty login from the proxy at 10.0.0.1 claiming to
+	// forward forwardedFor; it gets 400 unless rate limited
+	login := func(router http.Handler, forwardedFor string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/login", nil)
+		req.RemoteAddr = "10.0.0.1:4321"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("SpoofedHeaderIgnored", func(t *testing.T) {
+		router := newRouter(nil)
+		assert.Equal(t, http.StatusBadRequest, login(router, "203.0.113.1"))
+		assert.Equal(t, http.StatusTooManyRequests, login(router, "203.0.113.2"), "a new X-Forwarded-For is not a new bucket")
+	})
+
+	t.Run("TrustedProxyForwards", func(t *testing.T) {
+		router := newRouter([]string{"10.0.0.0/8"})
+		assert.Equal(t, http.StatusBadRequest, login(router, "203.0.113.1"))
+		assert.Equal(t, http.StatusBadRequest, login(router, "203.0.113.2"), "clients behind the proxy are told apart")
+		assert.Equal(t, http.StatusTooManyRequests, login(router, "203.0.113.1"))
+	})
+}
+
+func TestSetupRouter_RateLimitsBeforeAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policies, err := Infrastructure.ParseRateLimitPolicies("auth=2/1m;*=100/1m,by=user")
+	require.NoError(t, err)
+	limiter := Infrastructure.NewRateLimiter(Infrastructure.NewInMemoryRateLimitStore(), policies)
+	jwtService := new(mocks.MockJWTService)
+	jwtService.On("ValidateToken", "forged").Return((*jwt.Token)(nil), errors.New("invalid signature"))
+	router := routers.SetupRouter(controllers.NewTaskController(nil), controllers.NewUserController(nil), controllers.NewReportController(nil),
+		controllers.NewCatalogController(nil), controllers.NewAttachmentController(nil), controllers.NewAccountController(nil),
+		controllers.NewMFAController(nil), controllers.NewSSOController(nil), controllers.NewAccessTokenController(nil),
+		controllers.NewSessionController(nil), controllers.NewGraphQLController(nil, nil, nil, 0, 0), jwtService,
+		nil, nil, nil, limiter, nil, Infrastructure.DefaultConfig().API, nil)
+
+	// get sends a forged token from ip, which auth always rejects
+	get := func(path, ip string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		req.RemoteAddr = ip + ":1234"
+		req.Header.Set("Authorization", "Bearer forged")
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, get("/tasks", "203.0.113.1"))
+	assert.Equal(t, http.StatusUnauthorized, get("/api/v2/me", "203.0.113.1"))
+	assert.Equal(t, http.StatusTooManyRequests, get("/graphql", "203.0.113.1"), "rejected tokens count against the IP")
+	assert.Equal(t, http.StatusUnauthorized, get("/tasks", "203.0.113.2"))
+}
@@ -0,0 +1,97 @@
+package routers_test
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Tests/mocks"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIVersions(t *testing.T) {
+	app := mocks.NewApp()
+	require.NoError(t, app.UserUsecase.Register(Domain.Registration{Username: "alice", Password: "alice-secret-1", Email: "alice@example.com"}))
+	token, err := app.Login("alice", "alice-secret-1")
+	require.NoError(t, err)
+	for _, title := range []string{"One", "Two", "Three"} {
+		_, err := app.TaskUsecase.Create(Domain.Task{Title: title, DueDate: time.Now().Add(24 * time.Hour)})
+		require.NoError(t, err)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		app.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("V1ListsEveryTask", func(t *testing.T) {
+		w := get("/api/v1/tasks")
+		require.Equal(t, http.StatusOK, w.Code)
+		var tasks []map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tasks))
+		assert.Len(t, tasks, 3)
+		assert.Empty(t, w.Header().Get("Deprecation"))
+	})
+
+	t.Run("V2PagesTasks", func(t *testing.T) {
+		w := get("/api/v2/tasks?page=2&page_size=2&sort=created_at")
+		require.Equal(t, http.StatusOK, w.Code)
+		var page struct {
+			Tasks []struct {
+				Title string `json:"title"`
+			} `json:"tasks"`
+			Total    int64 `json:"total"`
+			Page     int   `json:"page"`
+			PageSize int   `json:"page_size"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		assert.Equal(t, int64(3), page.Total)
+		assert.Equal(t, 2, page.Page)
+		assert.Equal(t, 2, page.PageSize)
+		require.Len(t, page.Tasks, 1)
+		assert.Equal(t, "Three", page.Tasks[0].Title)
+
+		assert.Equal(t, http.StatusBadRequest, get("/api/v2/tasks?page_size=101").Code)
+	})
+
+	t.Run("V2MeIsTheStoredAccount", func(t *testing.T) {
+		var v1, v2 map[string]interface{}
+		require.NoError(t, json.Unmarshal(get("/api/v1/me").Body.Bytes(), &v1))
+		require.NoError(t, json.Unmarshal(get("/api/v2/me").Body.Bytes(), &v2))
+		assert.NotContains(t, v1, "email")
+		assert.Equal(t, "alice@example.com", v2["email"])
+		assert.Equal(t, v1["id"], v2["id"])
+	})
+
+	t.Run("UnversionedIsDeprecatedV1", func(t *testing.T) {
+		w := get("/tasks/search?q=two")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, strings.HasPrefix(w.Header().Get("Deprecation"), "@"))
+		sunset, err := http.ParseTime(w.Header().Get("Sunset"))
+		require.NoError(t, err)
+		assert.True(t, sunset.After(time.Now()))
+		assert.Equal(t, `</api/v1/tasks/search>; rel="successor-version"`, w.Header().Get("Link"))
+
+		var tasks []map[string]interface{}
+		require.NoError(t, json.Unmarshal(get("/tasks").Body.Bytes(), &tasks))
+		assert.Len(t, tasks, 3)
+	})
+
+	t.Run("CountsRequestsPerVersion", func(t *testing.T) {
+		assert.Equal(t, uint64(1), app.Metrics.HTTPRequests.Value("v1", "GET", "/tasks", "200"))
+		assert.Equal(t, uint64(1), app.Metrics.HTTPRequests.Value("v2", "GET", "/tasks", "200"))
+		assert.Equal(t, uint64(1), app.Metrics.HTTPRequests.Value("unversioned", "GET", "/tasks", "200"))
+
+		w := get("/metrics")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `http_requests_total{version="v2",method="GET",route="/tasks",code="400"} 1`)
+	})
+}
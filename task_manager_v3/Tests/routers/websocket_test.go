@@ -0,0 +1,59 @@
+package routers_test
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Infrastructure"
+	"a2sv-backend/task_manager_v3/Tests/mocks"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Browsers can't set headers on a WebSocket handshake, so the token travels
+// as a subprotocol
+func TestTaskEventsSocket_TokenInSubprotocol(t *testing.T) {
+	app := mocks.NewApp()
+	require.NoError(t, app.UserUsecase.Register(Domain.Registration{Username: "alice", Password: "alice-secret-1", Email: "alice@example.com"}))
+	token, err := app.Login("alice", "alice-secret-1")
+	require.NoError(t, err)
+
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/tasks/ws"
+
+	t.Run("Connects", func(t *testing.T) {
+		dialer := websocket.Dialer{Subprotocols: []string{Infrastructure.WebSocketTokenProtocol, token}}
+		conn, resp, err := dialer.Dial(url, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		assert.Equal(t, Infrastructure.WebSocketTokenProtocol, resp.Header.Get("Sec-WebSocket-Protocol"))
+
+		// The server answers pings only once it has subscribed, so creating
+		// the task on the pong can't race the subscription
+		var created Domain.Task
+		conn.SetPongHandler(func(string) error {
+			created, err = app.TaskUsecase.Create(Domain.Task{Title: "Pushed", DueDate: time.Now().Add(time.Hour)})
+			return err
+		})
+		require.NoError(t, conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second)))
+
+		var event map[string]interface{}
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		require.NoError(t, conn.ReadJSON(&event))
+		assert.Equal(t, Domain.TaskCreated, event["type"])
+		assert.Equal(t, created.ID.Hex(), event["task_id"])
+	})
+
+	t.Run("BadTokenIsRefused", func(t *testing.T) {
+		dialer := websocket.Dialer{Subprotocols: []string{Infrastructure.WebSocketTokenProtocol, "not-a-token"}}
+		_, resp, err := dialer.Dial(url, nil)
+		require.Error(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+}
@@ -0,0 +1,254 @@
+package rpc_test
+
+import (
+	"a2sv-backend/task_manager_v3/Delivery/rpc"
+	"a2sv-backend/task_manager_v3/Delivery/rpc/pb"
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Tests/mocks"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// dial serves the app over an in-process connection
+func dial(t *testing.T, app *mocks.App) *grpc.ClientConn {
+	listener := bufconn.Listen(1 << 20)
+	server := rpc.NewServer(app.TaskUsecase, app.UserUsecase, app.Authenticator, app.Metrics)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// as authenticates calls made with the returned context
+func as(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func tomorrow() *timestamppb.Timestamp {
+	return timestamppb.New(time.Now().Add(24 * time.Hour))
+}
+
+func TestGRPC(t *testing.T) {
+	app := mocks.NewApp()
+	conn := dial(t, app)
+	tasks := pb.NewTaskServiceClient(conn)
+	users := pb.NewUserServiceClient(conn)
+
+	root, err := app.UserUsecase.BootstrapAdmin("root", "admin-secret-1")
+	require.NoError(t, err)
+	require.NoError(t, app.UserUsecase.Register(Domain.Registration{Username: "alice", Password: "alice-secret-1", Email: "alice@example.com"}))
+	alice, _ := app.Users.FindByUsername("alice")
+	adminToken, err := app.Login("root", "admin-secret-1")
+	require.NoError(t, err)
+	aliceToken, err := app.Login("alice", "alice-secret-1")
+	require.NoError(t, err)
+
+	t.Run("Authentication", func(t *testing.T) {
+		_, err := tasks.ListTasks(context.Background(), &pb.ListTasksRequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		assert.Contains(t, status.Convert(err).Message(), "Authorization header is required")
+
+		_, err = tasks.ListTasks(as("not-a-token"), &pb.ListTasksRequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", adminToken)
+		_, err = tasks.ListTasks(ctx, &pb.ListTasksRequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		assert.Contains(t, status.Convert(err).Message(), "Invalid authorization header format")
+
+		_, err = tasks.ListTasks(as(aliceToken), &pb.ListTasksRequest{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("AdminOnly", func(t *testing.T) {
+		_, err := tasks.CreateTask(as(aliceToken), &pb.CreateTaskRequest{Task: &pb.TaskInput{Title: "Nope", DueDate: tomorrow()}})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		_, err = users.ListUsers(as(aliceToken), &pb.ListUsersRequest{})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("AccessTokens", func(t *testing.T) {
+		_, readOnly, err := app.AccessTokenUsecase.Create(root.ID, "reader", []string{Domain.ScopeRead}, 0)
+		require.NoError(t, err)
+		_, writer, err := app.AccessTokenUsecase.Create(root.ID, "writer", []string{Domain.ScopeRead, Domain.ScopeWrite}, 0)
+		require.NoError(t, err)
+
+		_, err = tasks.ListTasks(as(readOnly), &pb.ListTasksRequest{})
+		assert.NoError(t, err)
+
+		_, err = tasks.CreateTask(as(readOnly), &pb.CreateTaskRequest{Task: &pb.TaskInput{Title: "Nope", DueDate: tomorrow()}})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		assert.Contains(t, status.Convert(err).Message(), "lacks the write scope")
+
+		// Writing tasks is for admins, which tokens must be granted too
+		_, err = tasks.CreateTask(as(writer), &pb.CreateTaskRequest{Task: &pb.TaskInput{Title: "Nope", DueDate: tomorrow()}})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		assert.Contains(t, status.Convert(err).Message(), "lacks the admin scope")
+	})
+
+	t.Run("Tasks", func(t *testing.T) {
+		ctx := as(adminToken)
+		created, err := tasks.CreateTask(ctx, &pb.CreateTaskRequest{Task: &pb.TaskInput{
+			Title:    "Ship gRPC",
+			DueDate:  tomorrow(),
+			Priority: Domain.PriorityP1,
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, "Ship gRPC", created.Title)
+		assert.NotEmpty(t, created.Id)
+		assert.NotNil(t, created.CreatedAt)
+
+		got, err := tasks.GetTask(as(aliceToken), &pb.GetTaskRequest{Id: created.Id})
+		require.NoError(t, err)
+		assert.Equal(t, created.Id, got.Id)
+		assert.Equal(t, Domain.PriorityP1, got.Priority)
+
+		page, err := tasks.ListTasks(ctx, &pb.ListTasksRequest{Priority: Domain.PriorityP1, PageSize: 10})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, page.Total)
+		assert.EqualValues(t, 1, page.Page)
+		assert.EqualValues(t, 10, page.PageSize)
+
+		updated, err := tasks.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: created.Id, Task: &pb.TaskInput{Title: "Ship gRPC today", DueDate: tomorrow()}})
+		require.NoError(t, err)
+		assert.Equal(t, "Ship gRPC today", updated.Title)
+
+		moved, err := tasks.SetTaskStatus(ctx, &pb.SetTaskStatusRequest{Id: created.Id, Status: Domain.StatusInProgress})
+		require.NoError(t, err)
+		assert.Equal(t, Domain.StatusInProgress, moved.Status)
+		assert.Equal(t, "Ship gRPC today", moved.Title)
+		require.NotEmpty(t, moved.StatusHistory)
+		assert.Equal(t, Domain.StatusInProgress, moved.StatusHistory[len(moved.StatusHistory)-1].Status)
+
+		_, err = tasks.DeleteTask(ctx, &pb.DeleteTaskRequest{Id: created.Id})
+		require.NoError(t, err)
+		_, err = tasks.GetTask(ctx, &pb.GetTaskRequest{Id: created.Id})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("InvalidInput", func(t *testing.T) {
+		ctx := as(adminToken)
+		_, err := tasks.CreateTask(ctx, &pb.CreateTaskRequest{Task: &pb.TaskInput{Priority: "urgent"}})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+		var violations []string
+		for _, detail := range status.Convert(err).Details() {
+			if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+				for _, violation := range badRequest.FieldViolations {
+					violations = append(violations, violation.Field)
+				}
+			}
+		}
+		assert.ElementsMatch(t, []string{"title", "due_date", "priority"}, violations)
+
+		_, err = tasks.GetTask(ctx, &pb.GetTaskRequest{Id: "nope"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = tasks.ListTasks(ctx, &pb.ListTasksRequest{PageSize: Domain.MaxTaskPageSize + 1})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = tasks.SetTaskStatus(ctx, &pb.SetTaskStatusRequest{Id: alice.ID.Hex(), Status: "done"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Users", func(t *testing.T) {
+		me, err := users.GetCurrentUser(as(aliceToken), &pb.GetCurrentUserRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, "alice", me.Username)
+		assert.Equal(t, Domain.RoleUser, me.Role)
+
+		list, err := users.ListUsers(as(adminToken), &pb.ListUsersRequest{Search: "ali"})
+		require.NoError(t, err)
+		require.Len(t, list.Users, 1)
+		assert.Equal(t, alice.ID.Hex(), list.Users[0].Id)
+
+		_, err = users.GetUser(as(adminToken), &pb.GetUserRequest{Id: root.ID.Hex()})
+		assert.NoError(t, err)
+
+		promoted, err := users.PromoteUser(as(adminToken), &pb.PromoteUserRequest{Id: alice.ID.Hex()})
+		require.NoError(t, err)
+		assert.Equal(t, Domain.RoleAdmin, promoted.Role)
+
+		// The role is re-read on every call, so alice is an admin right away
+		_, err = users.ListUsers(as(aliceToken), &pb.ListUsersRequest{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Metrics", func(t *testing.T) {
+		assert.NotZero(t, app.Metrics.GRPCRequests.Value("taskmanager.v1.TaskService", "CreateTask", "OK"))
+		assert.NotZero(t, app.Metrics.GRPCRequests.Value("taskmanager.v1.TaskService", "ListTasks", "Unauthenticated"))
+		assert.NotZero(t, app.Metrics.GRPCRequests.Value("taskmanager.v1.UserService", "ListUsers", "PermissionDenied"))
+	})
+}
+
+func TestGRPCWatchTasks(t *testing.T) {
+	app := mocks.NewApp()
+	conn := dial(t, app)
+	tasks := pb.NewTaskServiceClient(conn)
+
+	_, err := app.UserUsecase.BootstrapAdmin("root", "admin-secret-1")
+	require.NoError(t, err)
+	token, err := app.Login("root", "admin-secret-1")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(as(token), 5*time.Second)
+	defer cancel()
+
+	// An ID the server doesn't know starts the stream with a reset, whose
+	// ID is where to resume from
+	stream, err := tasks.WatchTasks(ctx, &pb.WatchTasksRequest{LastEventId: "0"})
+	require.NoError(t, err)
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, Domain.TaskEventsReset, event.Type)
+	resumeFrom := event.Id
+
+	first, err := tasks.CreateTask(as(token), &pb.CreateTaskRequest{Task: &pb.TaskInput{Title: "First", DueDate: tomorrow()}})
+	require.NoError(t, err)
+
+	// Resuming from before the first event replays it
+	stream, err = tasks.WatchTasks(ctx, &pb.WatchTasksRequest{LastEventId: resumeFrom})
+	require.NoError(t, err)
+
+	event, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, Domain.TaskCreated, event.Type)
+	assert.Equal(t, first.Id, event.TaskId)
+	assert.Equal(t, "First", event.Task.GetTitle())
+	assert.NotEmpty(t, event.Id)
+
+	_, err = tasks.DeleteTask(as(token), &pb.DeleteTaskRequest{Id: first.Id})
+	require.NoError(t, err)
+
+	event, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, Domain.TaskDeleted, event.Type)
+	assert.Equal(t, first.Id, event.TaskId)
+
+	// Streams are authenticated when they open
+	unauthenticated, err := tasks.WatchTasks(context.Background(), &pb.WatchTasksRequest{})
+	require.NoError(t, err)
+	_, err = unauthenticated.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
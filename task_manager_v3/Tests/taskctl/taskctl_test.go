@@ -0,0 +1,212 @@
+package taskctl_test
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Tests/mocks"
+	"a2sv-backend/task_manager_v3/taskctl"
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// cli runs taskctl against one configuration file
+type cli struct {
+	t      *testing.T
+	config string
+}
+
+// run executes taskctl with args and stdin, returning what it printed
+func (c cli) run(stdin string, args ...string) (string, error) {
+	cmd := taskctl.NewRootCommand()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetIn(strings.NewReader(stdin))
+	// Completion requests must come first
+	if len(args) > 0 && args[0] == "__complete" {
+		cmd.SetArgs(append([]string{"__complete", "--config", c.config}, args[1:]...))
+	} else {
+		cmd.SetArgs(append([]string{"--config", c.config}, args...))
+	}
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func (c cli) mustRun(args ...string) string {
+	out, err := c.run("", args...)
+	require.NoError(c.t, err, out)
+	return out
+}
+
+func TestTaskctl(t *testing.T) {
+	app := mocks.NewApp()
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+
+	_, err := app.UserUsecase.BootstrapAdmin("root", "admin-secret-1")
+	require.NoError(t, err)
+	require.NoError(t, app.UserUsecase.Register(Domain.Registration{Username: "alice", Password: "alice-secret-1", Email: "alice@example.com"}))
+	alice, _ := app.Users.FindByUsername("alice")
+
+	c := cli{t: t, config: filepath.Join(t.TempDir(), "taskctl", "config.yaml")}
+
+	t.Run("NotLoggedIn", func(t *testing.T) {
+		_, err := c.run("", "tasks", "list")
+		assert.ErrorContains(t, err, `no profile "default"`)
+	})
+
+	t.Run("Login", func(t *testing.T) {
+		_, err := c.run("wrong-password\n", "--server", server.URL, "login", "-u", "root", "--password-stdin")
+		assert.ErrorContains(t, err, "401")
+
+		out, err := c.run("admin-secret-1\n", "--server", server.URL, "login", "-u", "root", "--password-stdin")
+		require.NoError(t, err)
+		assert.Contains(t, out, "Logged in to "+server.URL+" as root (profile default)")
+
+		info, err := os.Stat(c.config)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "the file holds tokens")
+		config, err := taskctl.LoadConfig(c.config)
+		require.NoError(t, err)
+		assert.Equal(t, "default", config.Current)
+		assert.NotEmpty(t, config.Profiles["default"].Token)
+	})
+
+	var id string
+	t.Run("Create", func(t *testing.T) {
+		due := time.Now().AddDate(0, 0, 3).Format("2006-01-02")
+		out := c.mustRun("-o", "json", "tasks", "create", "--title", "Write the CLI", "--due", due, "--priority", "P1", "--project", "tools")
+		var task Domain.Task
+		require.NoError(t, json.Unmarshal([]byte(out), &task), out)
+		assert.Equal(t, "Write the CLI", task.Title)
+		assert.Equal(t, "P1", task.Priority)
+		id = task.ID.Hex()
+
+		c.mustRun("tasks", "create", "--title", "Ship it", "--due", due, "--project", "tools")
+
+		_, err := c.run("", "tasks", "create", "--title", "No due date")
+		assert.ErrorContains(t, err, `"due" not set`)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		out := c.mustRun("tasks", "list", "--sort", "title")
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		require.Len(t, lines, 3, out)
+		assert.Regexp(t, `^ID\s+TITLE\s+STATUS\s+PRIORITY\s+PROJECT\s+DUE$`, lines[0])
+		assert.Contains(t, lines[1], "Ship it")
+		assert.Contains(t, lines[2], "Write the CLI")
+
+		out = c.mustRun("tasks", "list", "--priority", "P1", "-o", "json")
+		var tasks []Domain.Task
+		require.NoError(t, json.Unmarshal([]byte(out), &tasks), out)
+		require.Len(t, tasks, 1)
+		assert.Equal(t, id, tasks[0].ID.Hex())
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		out := c.mustRun("tasks", "get", id)
+		assert.Regexp(t, `Title:\s+Write the CLI`, out)
+		assert.Regexp(t, `Priority:\s+P1`, out)
+
+		out = c.mustRun("task", "get", id, "-o", "yaml")
+		var task map[string]interface{}
+		require.NoError(t, yaml.Unmarshal([]byte(out), &task), out)
+		assert.Equal(t, id, task["id"])
+		assert.Equal(t, "Write the CLI", task["title"])
+		assert.True(t, strings.HasPrefix(out, "id: "), "fields keep the API's order:\n%s", out)
+
+		_, err := c.run("", "tasks", "get", "nope")
+		assert.ErrorContains(t, err, `invalid task ID "nope"`)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		out := c.mustRun("-o", "json", "tasks", "update", id, "--status", "in_progress")
+		var task Domain.Task
+		require.NoError(t, json.Unmarshal([]byte(out), &task), out)
+		assert.Equal(t, "in_progress", task.Status)
+		assert.Equal(t, "Write the CLI", task.Title, "fields not given are kept")
+		assert.Equal(t, "P1", task.Priority)
+
+		_, err := c.run("", "tasks", "update", id, "--priority", "urgent")
+		assert.ErrorContains(t, err, "400")
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		out := c.mustRun("tasks", "delete", id)
+		assert.Contains(t, out, "Deleted task "+id)
+		_, err := c.run("", "tasks", "get", id)
+		assert.ErrorContains(t, err, "404")
+	})
+
+	t.Run("Promote", func(t *testing.T) {
+		out := c.mustRun("users", "promote", alice.ID.Hex())
+		assert.Contains(t, out, "Promoted user")
+		promoted, err := app.Users.FindByID(alice.ID)
+		require.NoError(t, err)
+		assert.Equal(t, Domain.RoleAdmin, promoted.Role)
+	})
+
+	t.Run("Profiles", func(t *testing.T) {
+		c.mustRun("profile", "add", "staging", "--url", server.URL)
+		out, err := c.run("alice-secret-1\n", "-p", "staging", "login", "-u", "alice")
+		require.NoError(t, err)
+		assert.Contains(t, out, "as alice (profile staging)")
+
+		out = c.mustRun("profile", "list")
+		assert.Regexp(t, `\*\s+default\s+\S+\s+root`, out)
+		assert.Regexp(t, `\n\s+staging\s+\S+\s+alice`, out)
+
+		c.mustRun("profile", "use", "staging")
+		out = c.mustRun("profile", "list", "-o", "json")
+		var profiles []map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(out), &profiles))
+		require.Len(t, profiles, 2)
+		assert.Equal(t, true, profiles[1]["current"])
+
+		c.mustRun("logout")
+		_, err = c.run("", "tasks", "list")
+		assert.ErrorContains(t, err, `not logged in to profile "staging"`)
+
+		// The other profile keeps its token
+		c.mustRun("-p", "default", "tasks", "list")
+
+		_, err = c.run("", "profile", "use", "production")
+		assert.ErrorContains(t, err, `no profile "production"`)
+	})
+
+	t.Run("AccessToken", func(t *testing.T) {
+		_, token, err := app.AccessTokenUsecase.Create(alice.ID, "ci", []string{Domain.ScopeRead}, 0)
+		require.NoError(t, err)
+		out := c.mustRun("-p", "ci", "--server", server.URL, "login", "--token", token)
+		assert.Contains(t, out, "Stored access token")
+		c.mustRun("-p", "ci", "tasks", "list")
+
+		_, err = c.run("", "-p", "ci", "tasks", "delete", alice.ID.Hex())
+		assert.ErrorContains(t, err, "lacks the write scope")
+	})
+
+	t.Run("Completion", func(t *testing.T) {
+		out := c.mustRun("completion", "bash")
+		assert.Contains(t, out, "taskctl")
+
+		out = c.mustRun("__complete", "profile", "use", "")
+		assert.Contains(t, out, "ci\ndefault\nstaging\n")
+
+		out = c.mustRun("__complete", "tasks", "list", "--status", "")
+		assert.Contains(t, out, "in_progress")
+	})
+
+	t.Run("UnknownOutput", func(t *testing.T) {
+		_, err := c.run("", "-o", "xml", "tasks", "list")
+		assert.ErrorContains(t, err, `unknown output format "xml"`)
+	})
+}
@@ -0,0 +1,85 @@
+package usecases_test
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Repositories"
+	"a2sv-backend/task_manager_v3/Usecases"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAccessTokenUsecase(t *testing.T) {
+	repo := Repositories.NewInMemoryAccessTokenRepository()
+	tokens := Usecases.NewAccessTokenUsecase(repo)
+	owner := primitive.NewObjectID()
+
+	token, value, err := tokens.Create(owner, "  nightly export ", []string{"write", "read", "read"}, 0)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(value, Domain.AccessTokenPrefix))
+	assert.True(t, strings.HasPrefix(value, token.Prefix))
+	assert.Equal(t, "nightly export", token.Name)
+	assert.Equal(t, []string{Domain.ScopeRead, Domain.ScopeWrite}, token.Scopes)
+	assert.WithinDuration(t, time.Now().Add(Domain.DefaultAccessTokenLifetime), token.ExpiresAt, time.Minute)
+
+	t.Run("OnlyTheHashIsStored", func(t *testing.T) {
+		stored, _ := repo.ListForUser(owner)
+		require.Len(t, stored, 1)
+		assert.NotEmpty(t, stored[0].TokenHash)
+		assert.NotContains(t, stored[0].TokenHash, value[len(Domain.AccessTokenPrefix):])
+	})
+
+	t.Run("Authenticate", func(t *testing.T) {
+		used, err := tokens.Authenticate(value, "10.0.0.7")
+		require.NoError(t, err)
+		assert.Equal(t, owner, used.UserID)
+
+		listed, _ := tokens.List(owner)
+		assert.Equal(t, "10.0.0.7", listed[0].LastUsedIP)
+		assert.False(t, listed[0].LastUsedAt.IsZero())
+
+		_, err = tokens.Authenticate(value+"x", "10.0.0.7")
+		assert.True(t, errors.Is(err, Domain.ErrInvalidAccessToken))
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		_, _, err := tokens.Create(owner, "", []string{"read"}, 0)
+		assert.True(t, errors.Is(err, Domain.ErrAccessTokenName))
+		_, _, err = tokens.Create(owner, "ci", []string{"read", "delete"}, 0)
+		assert.True(t, errors.Is(err, Domain.ErrInvalidScope))
+		_, _, err = tokens.Create(owner, "ci", nil, 0)
+		assert.True(t, errors.Is(err, Domain.ErrInvalidScope))
+		_, _, err = tokens.Create(owner, "ci", []string{"read"}, 2*Domain.MaxAccessTokenLifetime)
+		assert.True(t, errors.Is(err, Domain.ErrTokenLifetime))
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		value := Domain.AccessTokenPrefix + "expired"
+		sum := sha256.Sum256([]byte(value))
+		expired, err := repo.Create(Domain.AccessToken{UserID: owner, Name: "old", TokenHash: hex.EncodeToString(sum[:]),
+			Scopes: []string{"read"}, ExpiresAt: time.Now().Add(-time.Hour)})
+		require.NoError(t, err)
+		defer repo.Delete(owner, expired.ID)
+
+		_, err = tokens.Authenticate(value, "")
+		assert.True(t, errors.Is(err, Domain.ErrInvalidAccessToken))
+	})
+
+	t.Run("RevokeOnlyOwnTokens", func(t *testing.T) {
+		err := tokens.Revoke(primitive.NewObjectID(), token.ID)
+		assert.Error(t, err)
+		_, err = tokens.Authenticate(value, "")
+		assert.NoError(t, err)
+
+		require.NoError(t, tokens.Revoke(owner, token.ID))
+		_, err = tokens.Authenticate(value, "")
+		assert.True(t, errors.Is(err, Domain.ErrInvalidAccessToken))
+	})
+}
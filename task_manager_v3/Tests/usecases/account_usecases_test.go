@@ -0,0 +1,163 @@
+package usecases_test
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Infrastructure"
+	"a2sv-backend/task_manager_v3/Repositories"
+	"a2sv-backend/task_manager_v3/Tests/mocks"
+	"a2sv-backend/task_manager_v3/Usecases"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// laterTokens consumes tokens as if the clock had moved on by skew
+type laterTokens struct {
+	Repositories.UserTokenRepository
+	skew time.Duration
+}
+
+func (r laterTokens) Consume(hash, purpose string, now time.Time) (Domain.UserToken, error) {
+	return r.UserTokenRepository.Consume(hash, purpose, now.Add(r.skew))
+}
+
+var mailLink = regexp.MustCompile(`https?://\S+`)
+
+// tokenFrom pulls the token out of the link in the last mail sent to to
+func tokenFrom(t *testing.T, outbox *Infrastructure.InMemoryOutbox, to string) string {
+	messages := outbox.Messages()
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].To != to {
+			continue
+		}
+		link, err := url.Parse(mailLink.FindString(messages[i].Body))
+		require.NoError(t, err)
+		return link.Query().Get("token")
+	}
+	t.Fatalf("no mail sent to %s", to)
+	return ""
+}
+
+func TestAccountUsecase_ForgotAndResetPassword(t *testing.T) {
+	repo := Repositories.NewInMemoryUserRepository()
+	tokens := Repositories.NewInMemoryUserTokenRepository()
+	outbox := Infrastructure.NewInMemoryOutbox()
+	passwords := new(mocks.MockPasswordService)
+	passwords.On("HashPassword", mock.Anything).Return("hashed_password", nil)
+	accounts := Usecases.NewAccountUsecase(repo, tokens, passwords, outbox, nil, "https://tasks.example.com/")
+
+	alice, _ := repo.Create(Domain.User{Username: "alice", Email: "alice@example.com", Password: "old", Role: Domain.RoleUser})
+	repo.Create(Domain.User{Username: "gone", Email: "gone@example.com", Role: Domain.RoleUser, Deactivated: true})
+
+	t.Run("UnknownEmailLooksTheSame", func(t *testing.T) {
+		assert.NoError(t, accounts.ForgotPassword("nobody@example.com"))
+		assert.NoError(t, accounts.ForgotPassword("gone@example.com"))
+		assert.Empty(t, outbox.Messages())
+	})
+
+	t.Run("TokenIsSingleUse", func(t *testing.T) {
+		require.NoError(t, accounts.ForgotPassword("Alice@Example.com"))
+		token := tokenFrom(t, outbox, "alice@example.com")
+		assert.Contains(t, outbox.Messages()[0].Body, "https://tasks.example.com/reset-password?token=")
+
+		assert.True(t, errors.Is(accounts.ResetPassword(token, "short"), Domain.ErrWeakPassword))
+		assert.NoError(t, accounts.ResetPassword(token, "a-new-password"))
+		assert.True(t, errors.Is(accounts.ResetPassword(token, "a-new-password"), Domain.ErrInvalidToken))
+
+		user, _ := repo.FindByID(alice.ID)
+		assert.Equal(t, "hashed_password", user.Password)
+		assert.True(t, user.EmailVerified)
+	})
+
+	t.Run("NewRequestReplacesOldLink", func(t *testing.T) {
+		require.NoError(t, accounts.ForgotPassword("alice@example.com"))
+		first := tokenFrom(t, outbox, "alice@example.com")
+		require.NoError(t, accounts.ForgotPassword("alice@example.com"))
+
+		assert.True(t, errors.Is(accounts.ResetPassword(first, "a-new-password"), Domain.ErrInvalidToken))
+		assert.NoError(t, accounts.ResetPassword(tokenFrom(t, outbox, "alice@example.com"), "a-new-password"))
+	})
+
+	t.Run("TokenExpires", func(t *testing.T) {
+		late := Usecases.NewAccountUsecase(repo, laterTokens{tokens, Usecases.ResetTokenTTL + time.Minute}, passwords, outbox, nil, "")
+		require.NoError(t, late.ForgotPassword("alice@example.com"))
+
+		err := late.ResetPassword(tokenFrom(t, outbox, "alice@example.com"), "a-new-password")
+		assert.True(t, errors.Is(err, Domain.ErrInvalidToken))
+	})
+}
+
+func TestAccountUsecase_EmailVerification(t *testing.T) {
+	repo := Repositories.NewInMemoryUserRepository()
+	outbox := Infrastructure.NewInMemoryOutbox()
+	passwords := new(mocks.MockPasswordService)
+	passwords.On("HashPassword", mock.Anything).Return("hashed_password", nil)
+	accounts := Usecases.NewAccountUsecase(repo, Repositories.NewInMemoryUserTokenRepository(), passwords, outbox, nil, "http://localhost:8080")
+	userUsecase := Usecases.NewUserUsecase(repo, Repositories.NewInMemoryInvitationRepository(), passwords, new(mocks.MockJWTService), openRegistration, accounts, nil, nil)
+
+	require.NoError(t, userUsecase.Register(Domain.Registration{Username: "alice", Password: "password123", Email: "alice@example.com"}))
+	alice, _ := repo.FindByUsername("alice")
+	assert.False(t, alice.EmailVerified)
+
+	t.Run("RegisterSendsVerification", func(t *testing.T) {
+		user, err := accounts.VerifyEmail(tokenFrom(t, outbox, "alice@example.com"))
+		assert.NoError(t, err)
+		assert.True(t, user.EmailVerified)
+
+		_, err = accounts.VerifyEmail(tokenFrom(t, outbox, "alice@example.com"))
+		assert.True(t, errors.Is(err, Domain.ErrInvalidToken))
+	})
+
+	t.Run("DuplicateEmailRejected", func(t *testing.T) {
+		err := userUsecase.Register(Domain.Registration{Username: "bob", Password: "password123", Email: "ALICE@example.com"})
+		assert.True(t, errors.Is(err, Domain.ErrEmailTaken))
+	})
+
+	t.Run("ChangingEmailNeedsNewVerification", func(t *testing.T) {
+		require.NoError(t, accounts.SendVerification(alice.ID)) // already verified: no-op
+		stale := len(outbox.Messages())
+
+		email := "alice@work.example.com"
+		user, err := userUsecase.UpdateProfile(alice.ID, Domain.ProfileUpdate{Email: &email})
+		require.NoError(t, err)
+		assert.False(t, user.EmailVerified)
+		assert.Len(t, outbox.Messages(), stale+1)
+
+		// A link sent to the old address no longer verifies anything
+		require.NoError(t, accounts.SendVerification(alice.ID))
+		token := tokenFrom(t, outbox, email)
+		other := "alice@elsewhere.example.com"
+		userUsecase.UpdateProfile(alice.ID, Domain.ProfileUpdate{Email: &other})
+		_, err = accounts.VerifyEmail(token)
+		assert.True(t, errors.Is(err, Domain.ErrInvalidToken))
+	})
+}
+
+func TestAccountUsecase_DomainRegistrationNeedsVerification(t *testing.T) {
+	repo := Repositories.NewInMemoryUserRepository()
+	outbox := Infrastructure.NewInMemoryOutbox()
+	passwords := new(mocks.MockPasswordService)
+	passwords.On("HashPassword", mock.Anything).Return("hashed_password", nil)
+	passwords.On("ComparePassword", "hashed_password", "password123").Return(nil)
+	accounts := Usecases.NewAccountUsecase(repo, Repositories.NewInMemoryUserTokenRepository(), passwords, outbox, nil, "http://localhost:8080")
+	policy := Domain.RegistrationPolicy{Mode: Domain.RegistrationDomain, AllowedDomains: []string{"example.com"}}
+	userUsecase := Usecases.NewUserUsecase(repo, Repositories.NewInMemoryInvitationRepository(), passwords,
+		Infrastructure.NewJWTService(mocks.JWTSecret), policy, accounts, nil, nil)
+
+	// Anyone can type an address under the domain; only its owner gets in
+	require.NoError(t, userUsecase.Register(Domain.Registration{Username: "mallory", Password: "password123", Email: "ceo@example.com"}))
+	_, err := userUsecase.Login("mallory", "password123", Domain.ClientInfo{})
+	assert.True(t, errors.Is(err, Domain.ErrEmailNotVerified))
+
+	_, err = accounts.VerifyEmail(tokenFrom(t, outbox, "ceo@example.com"))
+	require.NoError(t, err)
+	result, err := userUsecase.Login("mallory", "password123", Domain.ClientInfo{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.Token)
+}
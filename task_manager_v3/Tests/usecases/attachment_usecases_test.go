@@ -0,0 +1,80 @@
+package usecases_test
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Infrastructure"
+	"a2sv-backend/task_manager_v3/Repositories"
+	"a2sv-backend/task_manager_v3/Tests/mocks"
+	"a2sv-backend/task_manager_v3/Usecases"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestAttachmentUsecase(t *testing.T) {
+	taskRepo := Repositories.NewInMemoryTaskRepository()
+	attachmentRepo := Repositories.NewInMemoryAttachmentRepository()
+	blobs := Infrastructure.NewInMemoryBlobStore()
+	attachments := Usecases.NewAttachmentUsecase(attachmentRepo, taskRepo, blobs, 64)
+	tasks := Usecases.NewTaskUsecase(taskRepo, Repositories.NewInMemoryLabelRepository(),
+		Repositories.NewInMemoryCustomFieldRepository(), new(mocks.MockUserRepository), Infrastructure.NewInMemoryEventBus(0), attachments)
+
+	task, err := tasks.Create(Domain.Task{Title: "Screenshot bug"})
+	assert.NoError(t, err)
+
+	t.Run("UploadSniffsAndHashes", func(t *testing.T) {
+		attachment, err := attachments.Upload(task.ID, "../../shots/screen.png", bytes.NewReader(pngHeader), "alice")
+		assert.NoError(t, err)
+
+		sum := sha256.Sum256(pngHeader)
+		assert.Equal(t, "image/png", attachment.ContentType)
+		assert.Equal(t, hex.EncodeToString(sum[:]), attachment.SHA256)
+		assert.Equal(t, int64(len(pngHeader)), attachment.Size)
+		assert.Equal(t, "screen.png", attachment.Filename)
+
+		found, content, err := attachments.Open(task.ID, attachment.ID)
+		assert.NoError(t, err)
+		data, _ := io.ReadAll(content)
+		content.Close()
+		assert.Equal(t, pngHeader, data)
+		assert.Equal(t, attachment.ID, found.ID)
+
+		_, _, err = attachments.Open(primitive.NewObjectID(), attachment.ID)
+		assert.True(t, errors.Is(err, Domain.ErrAttachmentNotFound))
+	})
+
+	t.Run("RejectsOversized", func(t *testing.T) {
+		_, err := attachments.Upload(task.ID, "big.txt", strings.NewReader(strings.Repeat("x", 65)), "alice")
+		assert.True(t, errors.Is(err, Domain.ErrAttachmentTooLarge))
+
+		list, _ := attachments.List(task.ID)
+		assert.Len(t, list, 1)
+	})
+
+	t.Run("UnknownTask", func(t *testing.T) {
+		_, err := attachments.Upload(primitive.NewObjectID(), "a.txt", strings.NewReader("hi"), "alice")
+		assert.Error(t, err)
+	})
+
+	t.Run("TaskDeletePurgesBlobs", func(t *testing.T) {
+		list, _ := attachmentRepo.FindByTask(task.ID)
+		assert.Len(t, list, 1)
+		key := list[0].BlobKey
+
+		assert.NoError(t, tasks.Delete(task.ID))
+
+		list, _ = attachmentRepo.FindByTask(task.ID)
+		assert.Empty(t, list)
+		_, err := blobs.Open(key)
+		assert.True(t, errors.Is(err, Infrastructure.ErrBlobNotFound))
+	})
+}
@@ -0,0 +1,138 @@
+package usecases_test
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Infrastructure"
+	"a2sv-backend/task_manager_v3/Repositories"
+	"a2sv-backend/task_manager_v3/Tests/mocks"
+	"a2sv-backend/task_manager_v3/Usecases"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newTaskUsecase builds a TaskUsecase with empty in-memory label and custom
+// field catalogs
+func newTaskUsecase(repo Repositories.TaskRepository, bus Infrastructure.EventBus) Usecases.TaskUsecase {
+	return Usecases.NewTaskUsecase(repo, Repositories.NewInMemoryLabelRepository(),
+		Repositories.NewInMemoryCustomFieldRepository(), new(mocks.MockUserRepository), bus, nil)
+}
+
+type catalogFixture struct {
+	tasks   Usecases.TaskUsecase
+	catalog Usecases.CatalogUsecase
+	repo    Repositories.TaskRepository
+}
+
+func newCatalogFixture() catalogFixture {
+	repo := Repositories.NewInMemoryTaskRepository()
+	labels := Repositories.NewInMemoryLabelRepository()
+	fields := Repositories.NewInMemoryCustomFieldRepository()
+	return catalogFixture{
+		tasks:   Usecases.NewTaskUsecase(repo, labels, fields, new(mocks.MockUserRepository), Infrastructure.NewInMemoryEventBus(0), nil),
+		catalog: Usecases.NewCatalogUsecase(labels, fields, repo),
+		repo:    repo,
+	}
+}
+
+func TestCatalogUsecase_Labels(t *testing.T) {
+	f := newCatalogFixture()
+
+	bug, err := f.catalog.CreateLabel(Domain.Label{Project: "web", Name: "bug", Color: "#ff0000"})
+	assert.NoError(t, err)
+
+	_, err = f.catalog.CreateLabel(Domain.Label{Project: "web", Name: "bug", Color: "#00ff00"})
+	assert.True(t, errors.Is(err, Domain.ErrLabelExists))
+
+	_, err = f.catalog.CreateLabel(Domain.Label{Project: "web", Name: "ui", Color: "red"})
+	assert.True(t, errors.Is(err, Domain.ErrInvalidLabel))
+
+	_, err = f.catalog.UpdateLabel(primitive.NewObjectID(), Domain.Label{Name: "ui", Color: "#00ff00"})
+	assert.True(t, errors.Is(err, Domain.ErrLabelNotFound))
+
+	other, err := f.catalog.CreateLabel(Domain.Label{Project: "api", Name: "bug", Color: "#0000ff"})
+	assert.NoError(t, err)
+
+	t.Run("LabelMustBelongToTaskProject", func(t *testing.T) {
+		_, err := f.tasks.Create(Domain.Task{Title: "Crash", Project: "web", Labels: []primitive.ObjectID{other.ID}})
+		assert.True(t, errors.Is(err, Domain.ErrInvalidTask))
+	})
+
+	t.Run("DeleteDetachesFromTasks", func(t *testing.T) {
+		task, err := f.tasks.Create(Domain.Task{Title: "Crash", Project: "web", Labels: []primitive.ObjectID{bug.ID}})
+		assert.NoError(t, err)
+
+		assert.NoError(t, f.catalog.DeleteLabel(bug.ID))
+		stored, _ := f.repo.FindByID(task.ID)
+		assert.Empty(t, stored.Labels)
+
+		labels, _ := f.catalog.ListLabels("web")
+		assert.Empty(t, labels)
+	})
+}
+
+func TestCatalogUsecase_CustomFields(t *testing.T) {
+	f := newCatalogFixture()
+
+	_, err := f.catalog.CreateField(Domain.CustomField{Key: "points", Name: "Story points", Type: Domain.FieldNumber, Required: true})
+	assert.NoError(t, err)
+	_, err = f.catalog.CreateField(Domain.CustomField{Key: "size", Name: "Size", Type: Domain.FieldEnum, Options: []string{"S", "M", "L"}})
+	assert.NoError(t, err)
+
+	_, err = f.catalog.CreateField(Domain.CustomField{Key: "points", Name: "Again", Type: Domain.FieldText})
+	assert.True(t, errors.Is(err, Domain.ErrCustomFieldExists))
+	_, err = f.catalog.CreateField(Domain.CustomField{Key: "Bad Key", Name: "Bad", Type: Domain.FieldText})
+	assert.True(t, errors.Is(err, Domain.ErrInvalidCustomField))
+	assert.True(t, errors.Is(f.catalog.DeleteField(primitive.NewObjectID()), Domain.ErrCustomFieldNotFound))
+
+	t.Run("Validation", func(t *testing.T) {
+		_, err := f.tasks.Create(Domain.Task{Title: "No points"})
+		assert.True(t, errors.Is(err, Domain.ErrInvalidTask))
+
+		_, err = f.tasks.Create(Domain.Task{Title: "Bad size", CustomFields: map[string]interface{}{"points": 1, "size": "XL"}})
+		assert.True(t, errors.Is(err, Domain.ErrInvalidTask))
+
+		_, err = f.tasks.Create(Domain.Task{Title: "Unknown", CustomFields: map[string]interface{}{"points": 1, "team": "core"}})
+		assert.True(t, errors.Is(err, Domain.ErrInvalidTask))
+
+		task, err := f.tasks.Create(Domain.Task{Title: "Sized", CustomFields: map[string]interface{}{"points": "5", "size": "M"}})
+		assert.NoError(t, err)
+		assert.Equal(t, 5.0, task.CustomFields["points"])
+		assert.Equal(t, Domain.DefaultProject, task.Project)
+	})
+
+	t.Run("FilterAndSort", func(t *testing.T) {
+		f := newCatalogFixture()
+		_, _ = f.catalog.CreateField(Domain.CustomField{Key: "points", Name: "Story points", Type: Domain.FieldNumber})
+
+		for _, task := range []Domain.Task{
+			{Title: "a", Priority: Domain.PriorityP2, CustomFields: map[string]interface{}{"points": 3}},
+			{Title: "b", Priority: Domain.PriorityP0, CustomFields: map[string]interface{}{"points": 8}},
+			{Title: "c", Priority: Domain.PriorityP0},
+		} {
+			_, err := f.tasks.Create(task)
+			assert.NoError(t, err)
+		}
+
+		tasks, err := f.tasks.List(Domain.TaskFilter{Sort: []Domain.SortField{{Field: "priority"}, {Field: "cf.points", Desc: true}}})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"b", "c", "a"}, titles(tasks))
+
+		tasks, err = f.tasks.List(Domain.TaskFilter{CustomFields: map[string]interface{}{"points": "8"}})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"b"}, titles(tasks))
+
+		_, err = f.tasks.List(Domain.TaskFilter{Sort: []Domain.SortField{{Field: "password"}}})
+		assert.True(t, errors.Is(err, Domain.ErrInvalidTask))
+	})
+}
+
+func titles(tasks []Domain.Task) []string {
+	var out []string
+	for _, task := range tasks {
+		out = append(out, task.Title)
+	}
+	return out
+}
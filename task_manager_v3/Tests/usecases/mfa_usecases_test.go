@@ -0,0 +1,192 @@
+package usecases_test
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Infrastructure"
+	"a2sv-backend/task_manager_v3/Repositories"
+	"a2sv-backend/task_manager_v3/Tests/mocks"
+	"a2sv-backend/task_manager_v3/Usecases"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// totpAt returns the code for the step offset steps away from now. Each code
+// is accepted once, so tests move forward a step for every code they use.
+func totpAt(t *testing.T, secret string, offset int) string {
+	code, err := Infrastructure.TOTPCode(secret, time.Now().Add(time.Duration(offset)*Infrastructure.TOTPPeriod))
+	require.NoError(t, err)
+	return code
+}
+
+func newMFAFixture() (Repositories.UserRepository, Usecases.MFAUsecase, Usecases.UserUsecase) {
+	repo := Repositories.NewInMemoryUserRepository()
+	passwords := new(mocks.MockPasswordService)
+	passwords.On("ComparePassword", "hash:secret", "secret").Return(nil)
+	passwords.On("ComparePassword", mock.Anything, mock.Anything).Return(errors.New("mismatch"))
+	jwtService := Infrastructure.NewJWTService(mocks.JWTSecret)
+
+	mfa := Usecases.NewMFAUsecase(repo, Repositories.NewInMemorySecurityPolicyRepository(), Repositories.NewInMemoryMFAChallengeRepository(), passwords, jwtService, nil, "")
+	users := Usecases.NewUserUsecase(repo, Repositories.NewInMemoryInvitationRepository(), passwords, jwtService, Domain.RegistrationPolicy{}, nil, mfa, nil)
+	return repo, mfa, users
+}
+
+func TestMFAUsecase_EnrollAndLogin(t *testing.T) {
+	repo, mfa, users := newMFAFixture()
+	alice, _ := repo.Create(Domain.User{Username: "alice", Email: "alice@example.com", Password: "hash:secret", Role: Domain.RoleUser})
+
+	result, err := users.Login("alice", "secret", Domain.ClientInfo{})
+	require.NoError(t, err)
+	assert.False(t, result.MFAPending)
+
+	enrollment, err := mfa.BeginEnrollment(alice.ID)
+	require.NoError(t, err)
+	assert.Contains(t, enrollment.URI, "otpauth://totp/Task%20Manager:alice@example.com?")
+
+	// Still off until confirmed
+	result, _ = users.Login("alice", "secret", Domain.ClientInfo{})
+	assert.False(t, result.MFAPending)
+
+	_, err = mfa.ConfirmEnrollment(alice.ID, "000000")
+	assert.True(t, errors.Is(err, Domain.ErrInvalidMFACode))
+	codes, err := mfa.ConfirmEnrollment(alice.ID, totpAt(t, enrollment.Secret, -1))
+	require.NoError(t, err)
+	assert.Len(t, codes, Domain.RecoveryCodeCount)
+
+	stored, _ := repo.FindByID(alice.ID)
+	assert.True(t, stored.MFAEnabled)
+	assert.NotContains(t, stored.RecoveryCodes, codes[0])
+
+	t.Run("LoginNeedsSecondFactor", func(t *testing.T) {
+		result, err := users.Login("alice", "secret", Domain.ClientInfo{})
+		require.NoError(t, err)
+		assert.True(t, result.MFAPending)
+		assert.False(t, result.EnrollmentRequired)
+
+		_, err = mfa.CompleteLogin(result.Token, "123456", Domain.ClientInfo{})
+		assert.True(t, errors.Is(err, Domain.ErrInvalidMFACode))
+
+		code := totpAt(t, enrollment.Secret, 0)
+		final, err := mfa.CompleteLogin(result.Token, code, Domain.ClientInfo{})
+		require.NoError(t, err)
+		assert.NotEqual(t, result.Token, final.Token)
+		assert.Equal(t, alice.ID, final.User.ID)
+
+		// The pending token completes one login only
+		_, err = mfa.CompleteLogin(result.Token, totpAt(t, enrollment.Secret, 1), Domain.ClientInfo{})
+		assert.True(t, errors.Is(err, Domain.ErrInvalidMFAToken))
+
+		// The same code can't be replayed with a new one either
+		result, _ = users.Login("alice", "secret", Domain.ClientInfo{})
+		_, err = mfa.CompleteLogin(result.Token, code, Domain.ClientInfo{})
+		assert.True(t, errors.Is(err, Domain.ErrInvalidMFACode))
+	})
+
+	t.Run("WrongCodesLockTheToken", func(t *testing.T) {
+		result, _ := users.Login("alice", "secret", Domain.ClientInfo{})
+		for i := 1; i < Domain.MaxMFAAttempts; i++ {
+			_, err := mfa.CompleteLogin(result.Token, "000000", Domain.ClientInfo{})
+			assert.True(t, errors.Is(err, Domain.ErrInvalidMFACode))
+		}
+		_, err := mfa.CompleteLogin(result.Token, "000000", Domain.ClientInfo{})
+		assert.True(t, errors.Is(err, Domain.ErrMFAAttemptsExceeded))
+
+		// Not even the right code gets through now
+		_, err = mfa.CompleteLogin(result.Token, totpAt(t, enrollment.Secret, 2), Domain.ClientInfo{})
+		assert.True(t, errors.Is(err, Domain.ErrMFAAttemptsExceeded))
+	})
+
+	t.Run("PendingTokenIsNotAnAccessToken", func(t *testing.T) {
+		token, _ := Infrastructure.NewJWTService(mocks.JWTSecret).GenerateToken(alice, "")
+		_, err := mfa.CompleteLogin(token, totpAt(t, enrollment.Secret, 1), Domain.ClientInfo{})
+		assert.True(t, errors.Is(err, Domain.ErrInvalidMFAToken))
+	})
+
+	t.Run("RecoveryCodesWorkOnce", func(t *testing.T) {
+		result, _ := users.Login("alice", "secret", Domain.ClientInfo{})
+		_, err := mfa.CompleteLogin(result.Token, "  "+codes[1]+" ", Domain.ClientInfo{})
+		assert.NoError(t, err)
+		result, _ = users.Login("alice", "secret", Domain.ClientInfo{})
+		_, err = mfa.CompleteLogin(result.Token, codes[1], Domain.ClientInfo{})
+		assert.True(t, errors.Is(err, Domain.ErrInvalidMFACode))
+
+		stored, _ := repo.FindByID(alice.ID)
+		assert.Len(t, stored.RecoveryCodes, Domain.RecoveryCodeCount-1)
+	})
+
+	t.Run("Disable", func(t *testing.T) {
+		err := mfa.Disable(alice.ID, "wrong", codes[2])
+		assert.True(t, errors.Is(err, Domain.ErrWrongPassword))
+
+		assert.NoError(t, mfa.Disable(alice.ID, "secret", codes[2]))
+		result, _ := users.Login("alice", "secret", Domain.ClientInfo{})
+		assert.False(t, result.MFAPending)
+	})
+}
+
+func TestMFAUsecase_Policy(t *testing.T) {
+	repo, mfa, users := newMFAFixture()
+	root, _ := repo.Create(Domain.User{Username: "root", Password: "hash:secret", Role: Domain.RoleAdmin})
+	other, _ := repo.Create(Domain.User{Username: "other", Password: "hash:secret", Role: Domain.RoleAdmin})
+	repo.Create(Domain.User{Username: "bob", Password: "hash:secret", Role: Domain.RoleUser})
+
+	_, err := mfa.SetPolicy(root.ID, []string{"owner"})
+	assert.True(t, errors.Is(err, Domain.ErrInvalidRole))
+
+	// Requiring 2FA for their own role would lock the admin out
+	_, err = mfa.SetPolicy(root.ID, []string{Domain.RoleAdmin})
+	assert.True(t, errors.Is(err, Domain.ErrMFANotEnrolled))
+
+	enrollment, _ := mfa.BeginEnrollment(root.ID)
+	_, err = mfa.ConfirmEnrollment(root.ID, totpAt(t, enrollment.Secret, -1))
+	require.NoError(t, err)
+	policy, err := mfa.SetPolicy(root.ID, []string{Domain.RoleAdmin, Domain.RoleAdmin})
+	require.NoError(t, err)
+	assert.Equal(t, []string{Domain.RoleAdmin}, policy.MFARequiredRoles)
+
+	t.Run("ExistingTokensStopWorking", func(t *testing.T) {
+		_, err := users.ActiveAccount(other.ID.Hex())
+		assert.True(t, errors.Is(err, Domain.ErrMFAEnforced))
+		_, err = users.ActiveAccount(root.ID.Hex())
+		assert.NoError(t, err)
+	})
+
+	t.Run("UnaffectedRolesLogInDirectly", func(t *testing.T) {
+		result, err := users.Login("bob", "secret", Domain.ClientInfo{})
+		require.NoError(t, err)
+		assert.False(t, result.MFAPending)
+	})
+
+	t.Run("EnrollmentAtLogin", func(t *testing.T) {
+		result, err := users.Login("other", "secret", Domain.ClientInfo{})
+		require.NoError(t, err)
+		assert.True(t, result.MFAPending)
+		assert.True(t, result.EnrollmentRequired)
+
+		enrollment, err := mfa.BeginLoginEnrollment(result.Token)
+		require.NoError(t, err)
+		final, err := mfa.CompleteLogin(result.Token, totpAt(t, enrollment.Secret, 0), Domain.ClientInfo{})
+		require.NoError(t, err)
+		assert.Len(t, final.RecoveryCodes, Domain.RecoveryCodeCount)
+
+		_, err = users.ActiveAccount(other.ID.Hex())
+		assert.NoError(t, err)
+	})
+
+	t.Run("CannotDisableWhileRequired", func(t *testing.T) {
+		err := mfa.Disable(root.ID, "secret", totpAt(t, enrollment.Secret, 1))
+		assert.True(t, errors.Is(err, Domain.ErrMFAEnforced))
+	})
+
+	t.Run("AdminReset", func(t *testing.T) {
+		assert.True(t, errors.Is(mfa.Reset(root.ID, root.ID), Domain.ErrSelfManagement))
+		assert.NoError(t, mfa.Reset(root.ID, other.ID))
+
+		result, _ := users.Login("other", "secret", Domain.ClientInfo{})
+		assert.True(t, result.EnrollmentRequired)
+	})
+}
@@ -0,0 +1,45 @@
+package usecases_test
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Repositories"
+	"a2sv-backend/task_manager_v3/Usecases"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportUsecase_Validation(t *testing.T) {
+	reportUsecase := Usecases.NewReportUsecase(Repositories.NewInMemoryReportRepository(Repositories.NewInMemoryTaskRepository()))
+
+	t.Run("DefaultsToDays", func(t *testing.T) {
+		buckets, err := reportUsecase.Throughput(time.Time{}, time.Time{}, "")
+
+		assert.NoError(t, err)
+		assert.Empty(t, buckets)
+	})
+
+	t.Run("BadInterval", func(t *testing.T) {
+		_, err := reportUsecase.Throughput(time.Time{}, time.Time{}, "month")
+
+		assert.True(t, errors.Is(err, Domain.ErrInvalidInterval))
+	})
+
+	t.Run("InvertedRange", func(t *testing.T) {
+		now := time.Now()
+		_, err := reportUsecase.CycleTime(now, now.Add(-time.Hour))
+
+		assert.True(t, errors.Is(err, Domain.ErrInvalidReportRange))
+	})
+
+	t.Run("Summary", func(t *testing.T) {
+		summary, err := reportUsecase.Summary()
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]int64{}, summary.ByStatus)
+		assert.Equal(t, time.Monday, summary.WeekStart.Weekday())
+		assert.Equal(t, Domain.TaskSummary{}.Total, summary.Total)
+	})
+}
@@ -0,0 +1,95 @@
+package usecases_test
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Infrastructure"
+	"a2sv-backend/task_manager_v3/Repositories"
+	"a2sv-backend/task_manager_v3/Tests/mocks"
+	"a2sv-backend/task_manager_v3/Usecases"
+	"errors"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionUsecase(t *testing.T) {
+	repo := Repositories.NewInMemoryUserRepository()
+	passwords := new(mocks.MockPasswordService)
+	passwords.On("ComparePassword", "hash:secret", "secret").Return(nil)
+	jwtService := Infrastructure.NewJWTService(mocks.JWTSecret)
+	sessions := Usecases.NewSessionUsecase(Repositories.NewInMemorySessionRepository(), jwtService)
+	users := Usecases.NewUserUsecase(repo, Repositories.NewInMemoryInvitationRepository(), passwords, jwtService, Domain.RegistrationPolicy{}, nil, nil, sessions)
+	alice, _ := repo.Create(Domain.User{Username: "alice", Password: "hash:secret", Role: Domain.RoleUser})
+
+	// sessionOf reads the session ID out of an access token
+	sessionOf := func(token string) string {
+		parsed, err := jwtService.ValidateToken(token)
+		require.NoError(t, err)
+		sid, _ := parsed.Claims.(jwt.MapClaims)["sid"].(string)
+		require.NotEmpty(t, sid)
+		return sid
+	}
+
+	laptop, err := users.Login("alice", "secret", Domain.ClientInfo{UserAgent: "Firefox", IP: "10.0.0.1"})
+	require.NoError(t, err)
+	phone, err := users.Login("alice", "secret", Domain.ClientInfo{UserAgent: "Safari", IP: "10.0.0.2"})
+	require.NoError(t, err)
+	laptopSession, phoneSession := sessionOf(laptop.Token), sessionOf(phone.Token)
+	assert.NotEqual(t, laptopSession, phoneSession)
+
+	listed, err := sessions.List(alice.ID)
+	require.NoError(t, err)
+	require.Len(t, listed, 2)
+	agents := []string{listed[0].UserAgent, listed[1].UserAgent}
+	assert.ElementsMatch(t, []string{"Firefox", "Safari"}, agents)
+	assert.NotEmpty(t, listed[0].IP)
+	assert.False(t, listed[0].CreatedAt.IsZero())
+
+	assert.NoError(t, sessions.Check(laptopSession, alice.ID.Hex()))
+	// A session only vouches for the user it was created for
+	assert.True(t, errors.Is(sessions.Check(laptopSession, "someone-else"), Domain.ErrSessionEnded))
+	assert.True(t, errors.Is(sessions.Check("", alice.ID.Hex()), Domain.ErrSessionEnded))
+
+	t.Run("Revoke", func(t *testing.T) {
+		for _, session := range listed {
+			if session.ID.Hex() == phoneSession {
+				require.NoError(t, sessions.Revoke(alice.ID, session.ID))
+			}
+		}
+		assert.True(t, errors.Is(sessions.Check(phoneSession, alice.ID.Hex()), Domain.ErrSessionEnded))
+		assert.NoError(t, sessions.Check(laptopSession, alice.ID.Hex()))
+	})
+
+	t.Run("RevokeAll", func(t *testing.T) {
+		users.Login("alice", "secret", Domain.ClientInfo{})
+		revoked, err := sessions.RevokeAll(alice.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), revoked)
+		assert.True(t, errors.Is(sessions.Check(laptopSession, alice.ID.Hex()), Domain.ErrSessionEnded))
+	})
+
+	t.Run("PasswordChangeEndsOtherSessions", func(t *testing.T) {
+		passwords.On("HashPassword", "new-secret").Return("hash:secret", nil)
+		laptop, _ := users.Login("alice", "secret", Domain.ClientInfo{})
+		phone, _ := users.Login("alice", "secret", Domain.ClientInfo{})
+
+		require.NoError(t, users.ChangePassword(alice.ID, sessionOf(laptop.Token), "secret", "new-secret"))
+		assert.NoError(t, sessions.Check(sessionOf(laptop.Token), alice.ID.Hex()))
+		assert.True(t, errors.Is(sessions.Check(sessionOf(phone.Token), alice.ID.Hex()), Domain.ErrSessionEnded))
+	})
+
+	t.Run("PasswordResetEndsAllSessions", func(t *testing.T) {
+		outbox := Infrastructure.NewInMemoryOutbox()
+		accounts := Usecases.NewAccountUsecase(repo, Repositories.NewInMemoryUserTokenRepository(), passwords, outbox, sessions, "http://localhost:8080")
+		repo.Update(Domain.User{ID: alice.ID, Username: "alice", Email: "alice@example.com", Password: "hash:secret", Role: Domain.RoleUser})
+		laptop, _ := users.Login("alice", "secret", Domain.ClientInfo{})
+
+		require.NoError(t, accounts.ForgotPassword("alice@example.com"))
+		require.NoError(t, accounts.ResetPassword(tokenFrom(t, outbox, "alice@example.com"), "new-secret"))
+		assert.True(t, errors.Is(sessions.Check(sessionOf(laptop.Token), alice.ID.Hex()), Domain.ErrSessionEnded))
+		listed, _ := sessions.List(alice.ID)
+		assert.Empty(t, listed)
+	})
+}
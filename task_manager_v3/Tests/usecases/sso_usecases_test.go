@@ -0,0 +1,181 @@
+package usecases_test
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Infrastructure"
+	"a2sv-backend/task_manager_v3/Repositories"
+	"a2sv-backend/task_manager_v3/Tests/mocks"
+	"a2sv-backend/task_manager_v3/Usecases"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ssoFixture struct {
+	provider *mocks.OIDCServer
+	users    Repositories.UserRepository
+	sso      Usecases.SSOUsecase
+}
+
+func newSSOFixture(t *testing.T, policy Domain.SSOPolicy) *ssoFixture {
+	server := mocks.NewOIDCServer("task-manager", "client-secret")
+	t.Cleanup(server.Close)
+
+	provider, err := Infrastructure.NewOIDCProvider(Infrastructure.OIDCConfig{
+		IssuerURL:    server.URL,
+		ClientID:     "task-manager",
+		ClientSecret: "client-secret",
+		RedirectURL:  "http://tasks.example.com/auth/oidc/callback",
+	})
+	require.NoError(t, err)
+
+	users := Repositories.NewInMemoryUserRepository()
+	sso := Usecases.NewSSOUsecase(provider, Repositories.NewInMemorySSOStateRepository(), Repositories.NewInMemoryIdentityRepository(),
+		users, Infrastructure.NewJWTService(mocks.JWTSecret), nil, nil, policy)
+	return &ssoFixture{provider: server, users: users, sso: sso}
+}
+
+// signIn runs the browser's side of the flow: follow the authorization URL
+// and hand the code the provider redirects back with to Complete
+func (f *ssoFixture) signIn(t *testing.T, identity mocks.OIDCIdentity, linkUserID primitive.ObjectID) (Domain.SSOResult, error) {
+	f.provider.SignIn(identity)
+	authURL, state, err := f.sso.Begin(linkUserID)
+	require.NoError(t, err)
+
+	browser := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := browser.Get(authURL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, state, callback.Query().Get("state"))
+	return f.sso.Complete(state, callback.Query().Get("code"), Domain.ClientInfo{})
+}
+
+var adminGroups = Domain.SSOPolicy{GroupRoles: map[string]string{"task-admins": Domain.RoleAdmin}, Provision: true}
+
+func TestSSOUsecase_Provisioning(t *testing.T) {
+	f := newSSOFixture(t, adminGroups)
+	f.users.Create(Domain.User{Username: "root", Password: "hash", Role: Domain.RoleAdmin})
+	alice := mocks.OIDCIdentity{Subject: "alice-sub", Email: "Alice@Corp.example", EmailVerified: true,
+		Name: "Alice Liddell", PreferredUsername: "alice", Groups: []string{"staff", "task-admins"}}
+
+	result, err := f.signIn(t, alice, primitive.NilObjectID)
+	require.NoError(t, err)
+	assert.True(t, result.Provisioned)
+	assert.NotEmpty(t, result.Login.Token)
+
+	user, err := f.users.FindByUsername("alice")
+	require.NoError(t, err)
+	assert.Equal(t, "alice@corp.example", user.Email)
+	assert.True(t, user.EmailVerified)
+	assert.Equal(t, "Alice Liddell", user.DisplayName)
+	assert.Equal(t, Domain.RoleAdmin, user.Role)
+	assert.Empty(t, user.Password)
+
+	t.Run("ReturningUserGetsSameAccount", func(t *testing.T) {
+		alice.Groups = []string{"staff"}
+		result, err := f.signIn(t, alice, primitive.NilObjectID)
+		require.NoError(t, err)
+		assert.False(t, result.Provisioned)
+		assert.Equal(t, user.ID, result.Login.User.ID)
+		// Groups are re-read at every sign-in
+		assert.Equal(t, Domain.RoleUser, result.Login.User.Role)
+	})
+
+	t.Run("UsernameCollision", func(t *testing.T) {
+		result, err := f.signIn(t, mocks.OIDCIdentity{Subject: "other-alice", PreferredUsername: "ALICE"}, primitive.NilObjectID)
+		require.NoError(t, err)
+		assert.Equal(t, "ALICE2", result.Login.User.Username)
+		assert.Empty(t, result.Login.User.Email)
+	})
+
+	t.Run("StateWorksOnce", func(t *testing.T) {
+		f.provider.SignIn(alice)
+		_, state, _ := f.sso.Begin(primitive.NilObjectID)
+		_, err := f.sso.Complete(state, "made-up-code", Domain.ClientInfo{})
+		assert.True(t, errors.Is(err, Domain.ErrSSOFailed))
+		_, err = f.sso.Complete(state, "made-up-code", Domain.ClientInfo{})
+		assert.True(t, errors.Is(err, Domain.ErrInvalidSSOState))
+	})
+
+	t.Run("RejectsUnpublishedSigningKey", func(t *testing.T) {
+		key, _ := rsa.GenerateKey(rand.Reader, 2048)
+		f.provider.SignWith = key
+		defer func() { f.provider.SignWith = nil }()
+
+		_, err := f.signIn(t, alice, primitive.NilObjectID)
+		assert.True(t, errors.Is(err, Domain.ErrSSOFailed))
+	})
+}
+
+func TestSSOUsecase_GroupsNeverDemoteTheLastAdmin(t *testing.T) {
+	f := newSSOFixture(t, adminGroups)
+	carol := mocks.OIDCIdentity{Subject: "carol-sub", PreferredUsername: "carol", Groups: []string{"task-admins"}}
+	_, err := f.signIn(t, carol, primitive.NilObjectID)
+	require.NoError(t, err)
+
+	// A mapping that no longer matches would leave nobody in charge
+	carol.Groups = nil
+	result, err := f.signIn(t, carol, primitive.NilObjectID)
+	require.NoError(t, err)
+	assert.Equal(t, Domain.RoleAdmin, result.Login.User.Role)
+	admins, _ := f.users.CountActiveAdmins()
+	assert.Equal(t, int64(1), admins)
+}
+
+func TestSSOUsecase_Linking(t *testing.T) {
+	f := newSSOFixture(t, Domain.SSOPolicy{Provision: true})
+	local, _ := f.users.Create(Domain.User{Username: "bob", Email: "bob@corp.example", Password: "hash", Role: Domain.RoleUser})
+	bob := mocks.OIDCIdentity{Subject: "bob-sub", Email: "bob@corp.example", EmailVerified: true}
+
+	// The email is taken, so no second account is created behind bob's back
+	_, err := f.signIn(t, bob, primitive.NilObjectID)
+	assert.True(t, errors.Is(err, Domain.ErrSSOLinkRequired))
+
+	result, err := f.signIn(t, bob, local.ID)
+	require.NoError(t, err)
+	require.NotNil(t, result.Linked)
+	assert.Equal(t, local.ID, result.Linked.UserID)
+
+	result, err = f.signIn(t, bob, primitive.NilObjectID)
+	require.NoError(t, err)
+	assert.Equal(t, local.ID, result.Login.User.ID)
+
+	t.Run("IdentityBelongsToOneAccount", func(t *testing.T) {
+		carol, _ := f.users.Create(Domain.User{Username: "carol", Password: "hash", Role: Domain.RoleUser})
+		_, err := f.signIn(t, bob, carol.ID)
+		assert.True(t, errors.Is(err, Domain.ErrIdentityLinked))
+	})
+
+	t.Run("Unlink", func(t *testing.T) {
+		identities, _ := f.sso.ListIdentities(local.ID)
+		require.Len(t, identities, 1)
+		assert.NoError(t, f.sso.Unlink(local.ID, identities[0].ID))
+
+		// Without a password the only identity has to stay
+		sso, err := f.signIn(t, mocks.OIDCIdentity{Subject: "dave-sub", PreferredUsername: "dave"}, primitive.NilObjectID)
+		require.NoError(t, err)
+		identities, _ = f.sso.ListIdentities(sso.Login.User.ID)
+		err = f.sso.Unlink(sso.Login.User.ID, identities[0].ID)
+		assert.True(t, errors.Is(err, Domain.ErrLastLoginMethod))
+	})
+}
+
+func TestSSOUsecase_ProvisioningDisabled(t *testing.T) {
+	f := newSSOFixture(t, Domain.SSOPolicy{})
+
+	_, err := f.signIn(t, mocks.OIDCIdentity{Subject: "eve-sub", PreferredUsername: "eve"}, primitive.NilObjectID)
+	assert.True(t, errors.Is(err, Domain.ErrSSOSignupClosed))
+	count, _ := f.users.Count()
+	assert.Zero(t, count)
+}
@@ -0,0 +1,89 @@
+package usecases_test
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Infrastructure"
+	"a2sv-backend/task_manager_v3/Repositories"
+	"a2sv-backend/task_manager_v3/Usecases"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestTaskUsecase_Bulk(t *testing.T) {
+	newUsecase := func() (Usecases.TaskUsecase, Repositories.TaskRepository, Domain.Task) {
+		repo := Repositories.NewInMemoryTaskRepository()
+		existing, _ := repo.Create(Domain.Task{Title: "Existing", Status: Domain.StatusPending})
+		return newTaskUsecase(repo, Infrastructure.NewInMemoryEventBus(0)), repo, existing
+	}
+
+	t.Run("PartialFailure", func(t *testing.T) {
+		taskUsecase, repo, existing := newUsecase()
+		missing := primitive.NewObjectID().Hex()
+
+		results, err := taskUsecase.Bulk([]Domain.BulkOperation{
+			{Op: Domain.BulkCreate, Task: &Domain.Task{Title: "New"}},
+			{Op: Domain.BulkTransition, ID: existing.ID.Hex(), Status: Domain.StatusCompleted},
+			{Op: Domain.BulkDelete, ID: missing},
+		}, false)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, results[0].Status)
+		assert.Equal(t, http.StatusOK, results[1].Status)
+		assert.Equal(t, Domain.StatusCompleted, results[1].Task.Status)
+		assert.Equal(t, http.StatusNotFound, results[2].Status)
+		assert.Equal(t, missing, results[2].ID)
+
+		tasks, _ := repo.FindAll()
+		assert.Len(t, tasks, 2)
+		updated, _ := repo.FindByID(existing.ID)
+		assert.Equal(t, Domain.StatusCompleted, updated.Status)
+	})
+
+	t.Run("AtomicAbortsEverything", func(t *testing.T) {
+		taskUsecase, repo, _ := newUsecase()
+
+		results, err := taskUsecase.Bulk([]Domain.BulkOperation{
+			{Op: Domain.BulkCreate, Task: &Domain.Task{Title: "New"}},
+			{Op: Domain.BulkDelete, ID: primitive.NewObjectID().Hex()},
+		}, true)
+
+		assert.ErrorIs(t, err, Domain.ErrBulkAborted)
+		assert.Equal(t, http.StatusFailedDependency, results[0].Status)
+		assert.Equal(t, http.StatusNotFound, results[1].Status)
+
+		tasks, _ := repo.FindAll()
+		assert.Len(t, tasks, 1)
+	})
+
+	t.Run("ValidatesBeforeWriting", func(t *testing.T) {
+		taskUsecase, repo, existing := newUsecase()
+
+		results, err := taskUsecase.Bulk([]Domain.BulkOperation{
+			{Op: Domain.BulkDelete, ID: existing.ID.Hex()},
+			{Op: Domain.BulkCreate, Task: &Domain.Task{}},
+			{Op: Domain.BulkTransition, ID: existing.ID.Hex(), Status: "done"},
+			{Op: "archive"},
+		}, false)
+
+		assert.ErrorIs(t, err, Domain.ErrBulkInvalid)
+		assert.Empty(t, results[0].Error)
+		assert.Equal(t, http.StatusUnprocessableEntity, results[1].Status)
+		assert.Equal(t, http.StatusUnprocessableEntity, results[2].Status)
+		assert.Equal(t, http.StatusUnprocessableEntity, results[3].Status)
+
+		_, findErr := repo.FindByID(existing.ID)
+		assert.NoError(t, findErr)
+	})
+
+	t.Run("TooLarge", func(t *testing.T) {
+		taskUsecase, _, _ := newUsecase()
+		ops := make([]Domain.BulkOperation, Usecases.MaxBulkOperations+1)
+
+		_, err := taskUsecase.Bulk(ops, false)
+
+		assert.ErrorIs(t, err, Domain.ErrBulkTooLarge)
+	})
+}
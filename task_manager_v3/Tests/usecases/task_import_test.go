@@ -0,0 +1,125 @@
+package usecases_test
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Infrastructure"
+	"a2sv-backend/task_manager_v3/Repositories"
+	"a2sv-backend/task_manager_v3/Usecases"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const importCSV = `external_id,title,status,due_date
+EXT-1,Existing task renamed,completed,2026-02-01
+,Brand new,,
+EXT-3,,bogus,tomorrow
+`
+
+func TestTaskUsecase_Import(t *testing.T) {
+	newUsecase := func() (Usecases.TaskUsecase, Repositories.TaskRepository) {
+		repo := Repositories.NewInMemoryTaskRepository()
+		repo.Create(Domain.Task{ExternalID: "EXT-1", Title: "Existing task", Status: Domain.StatusPending})
+		return newTaskUsecase(repo, Infrastructure.NewInMemoryEventBus(0)), repo
+	}
+	decoder := func() Infrastructure.TaskDecoder {
+		d, _ := Infrastructure.NewTaskDecoder(Domain.FormatCSV, strings.NewReader(importCSV), nil)
+		return d
+	}
+
+	t.Run("DryRun", func(t *testing.T) {
+		taskUsecase, repo := newUsecase()
+
+		report, err := taskUsecase.Import(decoder(), true)
+
+		assert.NoError(t, err)
+		assert.True(t, report.DryRun)
+		assert.Equal(t, 3, report.Total)
+		assert.Equal(t, Domain.ImportUpdated, report.Rows[0].Action)
+		assert.Equal(t, Domain.ImportCreated, report.Rows[1].Action)
+		assert.Equal(t, Domain.ImportFailed, report.Rows[2].Action)
+		assert.Len(t, report.Rows[2].Errors, 3)
+
+		tasks, _ := repo.FindAll()
+		assert.Len(t, tasks, 1)
+		assert.Equal(t, "Existing task", tasks[0].Title)
+	})
+
+	t.Run("UpsertsByExternalID", func(t *testing.T) {
+		taskUsecase, repo := newUsecase()
+
+		report, err := taskUsecase.Import(decoder(), false)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, report.Created)
+		assert.Equal(t, 1, report.Updated)
+		assert.Equal(t, 1, report.Failed)
+
+		updated, err := repo.FindByExternalID("EXT-1")
+		assert.NoError(t, err)
+		assert.Equal(t, "Existing task renamed", updated.Title)
+		assert.Equal(t, Domain.StatusCompleted, updated.Status)
+
+		tasks, _ := repo.FindAll()
+		assert.Len(t, tasks, 2)
+	})
+}
+
+func TestTaskUsecase_ExportImportRoundTrip(t *testing.T) {
+	for _, format := range []string{Domain.FormatCSV, Domain.FormatJSON, Domain.FormatNDJSON, Domain.FormatICS} {
+		t.Run(format, func(t *testing.T) {
+			f := newCatalogFixture()
+			bug, _ := f.catalog.CreateLabel(Domain.Label{Project: "web", Name: "bug", Color: "#ff0000"})
+			f.catalog.CreateField(Domain.CustomField{Key: "points", Name: "Story points", Type: Domain.FieldNumber})
+			original, err := f.tasks.Create(Domain.Task{
+				ExternalID:   "EXT-7",
+				Title:        "Crash on save",
+				Priority:     Domain.PriorityP0,
+				Project:      "web",
+				Labels:       []primitive.ObjectID{bug.ID},
+				CustomFields: map[string]interface{}{"points": 5},
+			})
+			assert.NoError(t, err)
+
+			var buf bytes.Buffer
+			encoder, _ := Infrastructure.NewTaskEncoder(format, &buf)
+			assert.NoError(t, f.tasks.Export(Domain.Viewer{UserID: "u1", Role: "user"}, encoder))
+
+			// Clear what the import should bring back
+			stripped := original
+			stripped.Priority, stripped.Project, stripped.Labels, stripped.CustomFields = "", "", nil, nil
+			_, err = f.repo.Update(stripped)
+			assert.NoError(t, err)
+
+			decoder, _ := Infrastructure.NewTaskDecoder(format, &buf, nil)
+			report, err := f.tasks.Import(decoder, false)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, report.Updated, report.Rows)
+
+			imported, err := f.repo.FindByExternalID("EXT-7")
+			assert.NoError(t, err)
+			assert.Equal(t, Domain.PriorityP0, imported.Priority)
+			assert.Equal(t, "web", imported.Project)
+			assert.Equal(t, []primitive.ObjectID{bug.ID}, imported.Labels)
+			assert.Equal(t, 5.0, imported.CustomFields["points"])
+		})
+	}
+}
+
+func TestTaskUsecase_Export(t *testing.T) {
+	repo := Repositories.NewInMemoryTaskRepository()
+	repo.Create(Domain.Task{Title: "One"})
+	repo.Create(Domain.Task{Title: "Two"})
+	taskUsecase := newTaskUsecase(repo, Infrastructure.NewInMemoryEventBus(0))
+
+	var buf bytes.Buffer
+	encoder, _ := Infrastructure.NewTaskEncoder(Domain.FormatNDJSON, &buf)
+
+	err := taskUsecase.Export(Domain.Viewer{UserID: "u1", Role: "user"}, encoder)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(buf.String(), "\n"))
+}
@@ -2,19 +2,21 @@ package usecases_test
 
 import (
 	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Infrastructure"
 	"a2sv-backend/task_manager_v3/Tests/mocks"
-	"a2sv-backend/task_manager_v3/Usecases"
+	"context"
 	"errors"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func TestTaskUsecase_Create(t *testing.T) {
 	mockTaskRepo := new(mocks.MockTaskRepository)
-	taskUsecase := Usecases.NewTaskUsecase(mockTaskRepo)
+	taskUsecase := newTaskUsecase(mockTaskRepo, Infrastructure.NewInMemoryEventBus(0))
 
 	t.Run("Success", func(t *testing.T) {
 		task := Domain.Task{
@@ -24,7 +26,10 @@ func TestTaskUsecase_Create(t *testing.T) {
 			Status:      "pending",
 		}
 
-		mockTaskRepo.On("Create", task).Return(task, nil)
+		mockTaskRepo.On("Create", mock.MatchedBy(func(created Domain.Task) bool {
+			return created.Title == task.Title && !created.CreatedAt.IsZero() &&
+				len(created.StatusHistory) == 1 && created.StatusHistory[0].Status == "pending"
+		})).Return(task, nil)
 
 		createdTask, err := taskUsecase.Create(task)
 
@@ -36,7 +41,7 @@ func TestTaskUsecase_Create(t *testing.T) {
 
 func TestTaskUsecase_GetAll(t *testing.T) {
 	mockTaskRepo := new(mocks.MockTaskRepository)
-	taskUsecase := Usecases.NewTaskUsecase(mockTaskRepo)
+	taskUsecase := newTaskUsecase(mockTaskRepo, Infrastructure.NewInMemoryEventBus(0))
 
 	t.Run("Success", func(t *testing.T) {
 		tasks := []Domain.Task{
@@ -54,9 +59,32 @@ func TestTaskUsecase_GetAll(t *testing.T) {
 	})
 }
 
+func TestTaskUsecase_ListPage(t *testing.T) {
+	mockTaskRepo := new(mocks.MockTaskRepository)
+	taskUsecase := newTaskUsecase(mockTaskRepo, Infrastructure.NewInMemoryEventBus(0))
+
+	t.Run("ClampsThePage", func(t *testing.T) {
+		tasks := []Domain.Task{{Title: "Task 1"}}
+		mockTaskRepo.On("FindPage", mock.Anything, 1, Domain.MaxTaskPageSize).Return(tasks, int64(41), nil).Once()
+
+		page, total, err := taskUsecase.ListPage(Domain.TaskFilter{}, 0, 500)
+
+		assert.NoError(t, err)
+		assert.Equal(t, tasks, page)
+		assert.Equal(t, int64(41), total)
+		mockTaskRepo.AssertExpectations(t)
+	})
+
+	t.Run("RejectsUnknownSort", func(t *testing.T) {
+		_, _, err := taskUsecase.ListPage(Domain.TaskFilter{Sort: []Domain.SortField{{Field: "password"}}}, 1, 10)
+
+		assert.ErrorIs(t, err, Domain.ErrInvalidTask)
+	})
+}
+
 func TestTaskUsecase_GetByID(t *testing.T) {
 	mockTaskRepo := new(mocks.MockTaskRepository)
-	taskUsecase := Usecases.NewTaskUsecase(mockTaskRepo)
+	taskUsecase := newTaskUsecase(mockTaskRepo, Infrastructure.NewInMemoryEventBus(0))
 
 	t.Run("Success", func(t *testing.T) {
 		taskID := primitive.NewObjectID()
@@ -77,29 +105,40 @@ func TestTaskUsecase_GetByID(t *testing.T) {
 	t.Run("NotFound", func(t *testing.T) {
 		taskID := primitive.NewObjectID()
 
-		mockTaskRepo.On("FindByID", taskID).Return(Domain.Task{}, errors.New("task not found"))
+		mockTaskRepo.On("FindByID", taskID).Return(Domain.Task{}, Domain.ErrTaskNotFound)
 
 		_, err := taskUsecase.GetByID(taskID)
 
 		assert.Error(t, err)
-		assert.Equal(t, "task not found", err.Error())
+		assert.True(t, errors.Is(err, Domain.ErrTaskNotFound))
 	})
 }
 
 func TestTaskUsecase_Update(t *testing.T) {
 	mockTaskRepo := new(mocks.MockTaskRepository)
-	taskUsecase := Usecases.NewTaskUsecase(mockTaskRepo)
+	taskUsecase := newTaskUsecase(mockTaskRepo, Infrastructure.NewInMemoryEventBus(0))
 
 	t.Run("Success", func(t *testing.T) {
 		taskID := primitive.NewObjectID()
+		createdAt := time.Now().Add(-time.Hour)
+		existing := Domain.Task{
+			ID:            taskID,
+			Title:         "Task",
+			Status:        "pending",
+			CreatedAt:     createdAt,
+			StatusHistory: []Domain.StatusChange{{Status: "pending", At: createdAt}},
+		}
 		task := Domain.Task{
-			Title: "Updated Task",
+			Title:  "Updated Task",
+			Status: "in_progress",
 		}
-		
-		expectedTask := task
-		expectedTask.ID = taskID
 
-		mockTaskRepo.On("Update", expectedTask).Return(expectedTask, nil)
+		expectedTask := Domain.Task{ID: taskID, Title: "Updated Task", Status: "in_progress"}
+		mockTaskRepo.On("FindByID", taskID).Return(existing, nil)
+		mockTaskRepo.On("Update", mock.MatchedBy(func(updated Domain.Task) bool {
+			return updated.ID == taskID && updated.CreatedAt.Equal(createdAt) &&
+				len(updated.StatusHistory) == 2 && updated.StatusHistory[1].Status == "in_progress"
+		})).Return(expectedTask, nil)
 
 		updatedTask, err := taskUsecase.Update(taskID, task)
 
@@ -111,7 +150,7 @@ func TestTaskUsecase_Update(t *testing.T) {
 
 func TestTaskUsecase_Delete(t *testing.T) {
 	mockTaskRepo := new(mocks.MockTaskRepository)
-	taskUsecase := Usecases.NewTaskUsecase(mockTaskRepo)
+	taskUsecase := newTaskUsecase(mockTaskRepo, Infrastructure.NewInMemoryEventBus(0))
 
 	t.Run("Success", func(t *testing.T) {
 		taskID := primitive.NewObjectID()
@@ -124,3 +163,77 @@ func TestTaskUsecase_Delete(t *testing.T) {
 		mockTaskRepo.AssertExpectations(t)
 	})
 }
+
+func TestTaskUsecase_PublishesEvents(t *testing.T) {
+	mockTaskRepo := new(mocks.MockTaskRepository)
+	bus := Infrastructure.NewInMemoryEventBus(0)
+	taskUsecase := newTaskUsecase(mockTaskRepo, bus)
+	viewer := Domain.Viewer{UserID: primitive.NewObjectID().Hex(), Role: "user"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := taskUsecase.Subscribe(ctx, viewer, "")
+	assert.NoError(t, err)
+
+	task := Domain.Task{ID: primitive.NewObjectID(), Title: "Streamed"}
+	mockTaskRepo.On("Create", mock.AnythingOfType("Domain.Task")).Return(task, nil)
+	mockTaskRepo.On("Delete", task.ID).Return(nil)
+
+	_, err = taskUsecase.Create(task)
+	assert.NoError(t, err)
+	assert.NoError(t, taskUsecase.Delete(task.ID))
+
+	created := <-events
+	assert.Equal(t, Domain.TaskCreated, created.Type)
+	assert.Equal(t, "Streamed", created.Task.Title)
+
+	deleted := <-events
+	assert.Equal(t, Domain.TaskDeleted, deleted.Type)
+	assert.Equal(t, task.ID, deleted.TaskID)
+	assert.Nil(t, deleted.Task)
+}
+
+func TestTaskUsecase_SubscribeHidesEventsFromAnonymousViewer(t *testing.T) {
+	bus := Infrastructure.NewInMemoryEventBus(0)
+	taskUsecase := newTaskUsecase(new(mocks.MockTaskRepository), bus)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := taskUsecase.Subscribe(ctx, Domain.Viewer{}, "")
+	assert.NoError(t, err)
+
+	bus.Publish(Domain.TaskEvent{Type: Domain.TaskCreated, TaskID: primitive.NewObjectID()})
+
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected event %v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestTaskUsecase_Search(t *testing.T) {
+	mockTaskRepo := new(mocks.MockTaskRepository)
+	taskUsecase := newTaskUsecase(mockTaskRepo, Infrastructure.NewInMemoryEventBus(0))
+	viewer := Domain.Viewer{UserID: primitive.NewObjectID().Hex(), Role: "user"}
+
+	t.Run("Success", func(t *testing.T) {
+		results := []Domain.TaskSearchResult{{Task: Domain.Task{Title: "Fix login"}, Score: 10}}
+		query := Domain.SearchQuery{Terms: []string{"login"}}
+
+		mockTaskRepo.On("Search", query, 5).Return(results, nil)
+
+		found, err := taskUsecase.Search(viewer, "Login", 5)
+
+		assert.NoError(t, err)
+		assert.Equal(t, results, found)
+		mockTaskRepo.AssertExpectations(t)
+	})
+
+	t.Run("EmptyQuery", func(t *testing.T) {
+		_, err := taskUsecase.Search(viewer, `"" ***`, 5)
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,127 @@
+package usecases_test
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Repositories"
+	"a2sv-backend/task_manager_v3/Tests/mocks"
+	"a2sv-backend/task_manager_v3/Usecases"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestUserUsecase_Management(t *testing.T) {
+	repo := Repositories.NewInMemoryUserRepository()
+	passwords := new(mocks.MockPasswordService)
+	jwt := new(mocks.MockJWTService)
+	userUsecase := Usecases.NewUserUsecase(repo, Repositories.NewInMemoryInvitationRepository(), passwords, jwt, Domain.RegistrationPolicy{}, nil, nil, nil)
+
+	admin, _ := repo.Create(Domain.User{Username: "root", Password: "hash:root", Role: Domain.RoleAdmin})
+	alice, _ := repo.Create(Domain.User{Username: "alice", DisplayName: "Alice Liddell", Password: "hash:alice", Role: Domain.RoleUser})
+	repo.Create(Domain.User{Username: "bob", Role: Domain.RoleUser})
+
+	t.Run("ListPagesAndSearches", func(t *testing.T) {
+		users, total, err := userUsecase.ListUsers(Domain.UserQuery{PageSize: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), total)
+		assert.Equal(t, "alice", users[0].Username)
+		assert.Len(t, users, 2)
+
+		users, total, _ = userUsecase.ListUsers(Domain.UserQuery{Search: "LIDDELL"})
+		assert.Equal(t, int64(1), total)
+		assert.Equal(t, alice.ID, users[0].ID)
+	})
+
+	t.Run("LastAdminIsProtected", func(t *testing.T) {
+		_, err := userUsecase.ChangeRole(alice.ID, admin.ID, Domain.RoleUser)
+		assert.True(t, errors.Is(err, Domain.ErrLastAdmin))
+
+		_, err = userUsecase.Deactivate(admin.ID, admin.ID)
+		assert.True(t, errors.Is(err, Domain.ErrSelfManagement))
+	})
+
+	t.Run("ConcurrentDemotionsKeepAnAdmin", func(t *testing.T) {
+		repo := Repositories.NewInMemoryUserRepository()
+		users := Usecases.NewUserUsecase(repo, Repositories.NewInMemoryInvitationRepository(), passwords, jwt, Domain.RegistrationPolicy{}, nil, nil, nil)
+		first, _ := repo.Create(Domain.User{Username: "first", Role: Domain.RoleAdmin})
+		second, _ := repo.Create(Domain.User{Username: "second", Role: Domain.RoleAdmin})
+
+		// Each demotes the other; only one may go through
+		errs := make(chan error, 2)
+		go func() { _, err := users.ChangeRole(first.ID, second.ID, Domain.RoleUser); errs <- err }()
+		go func() { _, err := users.Deactivate(second.ID, first.ID); errs <- err }()
+		failed := 0
+		for range 2 {
+			if err := <-errs; err != nil {
+				assert.True(t, errors.Is(err, Domain.ErrLastAdmin))
+				failed++
+			}
+		}
+		assert.Equal(t, 1, failed)
+		admins, _ := repo.CountActiveAdmins()
+		assert.Equal(t, int64(1), admins)
+	})
+
+	t.Run("RoleChangesBothWays", func(t *testing.T) {
+		user, err := userUsecase.ChangeRole(admin.ID, alice.ID, Domain.RoleAdmin)
+		assert.NoError(t, err)
+		assert.Equal(t, Domain.RoleAdmin, user.Role)
+
+		user, err = userUsecase.ChangeRole(admin.ID, alice.ID, Domain.RoleUser)
+		assert.NoError(t, err)
+		assert.Equal(t, Domain.RoleUser, user.Role)
+
+		_, err = userUsecase.ChangeRole(admin.ID, alice.ID, "owner")
+		assert.True(t, errors.Is(err, Domain.ErrInvalidRole))
+	})
+
+	t.Run("DeactivatedUserIsLockedOut", func(t *testing.T) {
+		_, err := userUsecase.Deactivate(admin.ID, alice.ID)
+		assert.NoError(t, err)
+
+		passwords.On("ComparePassword", "hash:alice", "secret").Return(nil)
+		_, err = userUsecase.Login("alice", "secret", Domain.ClientInfo{})
+		assert.True(t, errors.Is(err, Domain.ErrUserDeactivated))
+
+		_, err = userUsecase.ActiveAccount(alice.ID.Hex())
+		assert.True(t, errors.Is(err, Domain.ErrUserDeactivated))
+
+		_, err = userUsecase.Reactivate(admin.ID, alice.ID)
+		assert.NoError(t, err)
+		_, err = userUsecase.ActiveAccount(alice.ID.Hex())
+		assert.NoError(t, err)
+	})
+
+	t.Run("SelfService", func(t *testing.T) {
+		name := "Alice L."
+		user, err := userUsecase.UpdateProfile(alice.ID, Domain.ProfileUpdate{DisplayName: &name})
+		assert.NoError(t, err)
+		assert.Equal(t, "Alice L.", user.DisplayName)
+
+		taken := "bob"
+		_, err = userUsecase.UpdateProfile(alice.ID, Domain.ProfileUpdate{Username: &taken})
+		assert.Error(t, err)
+
+		passwords.On("ComparePassword", "hash:alice", "wrong").Return(errors.New("mismatch"))
+		assert.True(t, errors.Is(userUsecase.ChangePassword(alice.ID, "", "wrong", "new-password"), Domain.ErrWrongPassword))
+		assert.True(t, errors.Is(userUsecase.ChangePassword(alice.ID, "", "secret", "short"), Domain.ErrWeakPassword))
+		assert.True(t, errors.Is(userUsecase.ChangePassword(alice.ID, "", "secret", strings.Repeat("x", 73)), Domain.ErrPasswordTooLong))
+
+		passwords.On("HashPassword", "new-password").Return("hash:new", nil)
+		assert.NoError(t, userUsecase.ChangePassword(alice.ID, "", "secret", "new-password"))
+		stored, _ := repo.FindByID(alice.ID)
+		assert.Equal(t, "hash:new", stored.Password)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		assert.NoError(t, userUsecase.Delete(admin.ID, alice.ID))
+		_, err := userUsecase.GetUser(alice.ID)
+		assert.Error(t, err)
+	})
+
+	passwords.AssertExpectations(t)
+	jwt.AssertNotCalled(t, "GenerateToken", mock.Anything, mock.Anything)
+}
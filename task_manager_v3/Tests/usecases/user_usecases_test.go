@@ -2,35 +2,42 @@ package usecases_test
 
 import (
 	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Repositories"
 	"a2sv-backend/task_manager_v3/Tests/mocks"
 	"a2sv-backend/task_manager_v3/Usecases"
 	"errors"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+var openRegistration = Domain.RegistrationPolicy{Mode: Domain.RegistrationOpen}
+
 func TestUserUsecase_Register(t *testing.T) {
 	mockUserRepo := new(mocks.MockUserRepository)
 	mockPasswordService := new(mocks.MockPasswordService)
 	mockJWTService := new(mocks.MockJWTService)
 
-	userUsecase := Usecases.NewUserUsecase(mockUserRepo, mockPasswordService, mockJWTService)
+	userUsecase := Usecases.NewUserUsecase(mockUserRepo, Repositories.NewInMemoryInvitationRepository(), mockPasswordService, mockJWTService, openRegistration, nil, nil, nil)
 
 	t.Run("Success", func(t *testing.T) {
-		user := Domain.User{
+		reg := Domain.Registration{
 			Username: "testuser",
 			Password: "password",
 		}
 
-		mockUserRepo.On("FindByUsername", user.Username).Return(Domain.User{}, errors.New("user not found"))
-		mockPasswordService.On("HashPassword", user.Password).Return("hashed_password", nil)
-		mockUserRepo.On("Count").Return(int64(1), nil)
-		mockUserRepo.On("Create", mock.AnythingOfType("Domain.User")).Return(user, nil)
+		mockPasswordService.On("HashPassword", reg.Password).Return("hashed_password", nil)
+		mockUserRepo.On("Create", mock.MatchedBy(func(u Domain.User) bool {
+			return u.Username == "testuser" && u.Role == Domain.RoleUser
+		})).Return(Domain.User{}, nil)
 
-		err := userUsecase.Register(user)
+		err := userUsecase.Register(reg)
 
 		assert.NoError(t, err)
 		mockUserRepo.AssertExpectations(t)
@@ -38,30 +45,179 @@ func TestUserUsecase_Register(t *testing.T) {
 	})
 
 	t.Run("UsernameExists", func(t *testing.T) {
-		user := Domain.User{
+		reg := Domain.Registration{
 			Username: "existinguser",
 			Password: "password",
 		}
 
-		existingUser := Domain.User{
-			Username: "existinguser",
-		}
-
-		mockUserRepo.On("FindByUsername", user.Username).Return(existingUser, nil)
+		mockUserRepo.On("Create", mock.MatchedBy(func(u Domain.User) bool {
+			return u.Username == "existinguser"
+		})).Return(Domain.User{}, Domain.ErrUsernameTaken)
 
-		err := userUsecase.Register(user)
+		err := userUsecase.Register(reg)
 
 		assert.Error(t, err)
 		assert.Equal(t, "username already exists", err.Error())
 	})
 }
 
+func TestUserUsecase_RegisterNormalizesUsernames(t *testing.T) {
+	repo := Repositories.NewInMemoryUserRepository()
+	mockPasswordService := new(mocks.MockPasswordService)
+	mockPasswordService.On("HashPassword", mock.Anything).Return("hashed_password", nil)
+	userUsecase := Usecases.NewUserUsecase(repo, Repositories.NewInMemoryInvitationRepository(), mockPasswordService, new(mocks.MockJWTService), openRegistration, nil, nil, nil)
+
+	assert.NoError(t, userUsecase.Register(Domain.Registration{Username: "Alice", Password: "password"}))
+	for _, name := range []string{"alice", "ALICE", " Ａｌｉｃｅ "} {
+		err := userUsecase.Register(Domain.Registration{Username: name, Password: "password"})
+		assert.True(t, errors.Is(err, Domain.ErrUsernameTaken), name)
+	}
+
+	user, err := repo.FindByUsername("aLiCe")
+	assert.NoError(t, err)
+	assert.Equal(t, "Alice", user.Username)
+}
+
+func TestUserUsecase_RegisterNeverGrantsAdmin(t *testing.T) {
+	repo := Repositories.NewInMemoryUserRepository()
+	mockPasswordService := new(mocks.MockPasswordService)
+	mockPasswordService.On("HashPassword", mock.Anything).Return("hashed_password", nil)
+	userUsecase := Usecases.NewUserUsecase(repo, Repositories.NewInMemoryInvitationRepository(), mockPasswordService, new(mocks.MockJWTService), openRegistration, nil, nil, nil)
+
+	for _, name := range []string{"first", "admin_mallory"} {
+		assert.NoError(t, userUsecase.Register(Domain.Registration{Username: name, Password: "password"}))
+		user, _ := repo.FindByUsername(name)
+		assert.Equal(t, Domain.RoleUser, user.Role, name)
+	}
+}
+
+func TestUserUsecase_RegistrationModes(t *testing.T) {
+	mockPasswordService := new(mocks.MockPasswordService)
+	mockPasswordService.On("HashPassword", mock.Anything).Return("hashed_password", nil)
+	newUsecase := func(policy Domain.RegistrationPolicy) Usecases.UserUsecase {
+		return Usecases.NewUserUsecase(Repositories.NewInMemoryUserRepository(), Repositories.NewInMemoryInvitationRepository(),
+			mockPasswordService, new(mocks.MockJWTService), policy, nil, nil, nil)
+	}
+
+	t.Run("InviteOnlyByDefault", func(t *testing.T) {
+		err := newUsecase(Domain.RegistrationPolicy{}).Register(Domain.Registration{Username: "bob", Password: "password"})
+		assert.True(t, errors.Is(err, Domain.ErrRegistrationClosed))
+	})
+
+	t.Run("DomainAllowlist", func(t *testing.T) {
+		userUsecase := newUsecase(Domain.RegistrationPolicy{Mode: Domain.RegistrationDomain, AllowedDomains: []string{"example.com"}})
+
+		err := userUsecase.Register(Domain.Registration{Username: "bob", Password: "password", Email: "bob@evil.test"})
+		assert.True(t, errors.Is(err, Domain.ErrEmailDomainNotAllowed))
+		err = userUsecase.Register(Domain.Registration{Username: "bob", Password: "password", Email: "bob@evil.test@Example.COM"})
+		assert.Error(t, err)
+		assert.NoError(t, userUsecase.Register(Domain.Registration{Username: "bob", Password: "password", Email: "bob@Example.com"}))
+	})
+}
+
+func TestUserUsecase_Invitations(t *testing.T) {
+	repo := Repositories.NewInMemoryUserRepository()
+	mockPasswordService := new(mocks.MockPasswordService)
+	mockPasswordService.On("HashPassword", mock.Anything).Return("hashed_password", nil)
+	userUsecase := Usecases.NewUserUsecase(repo, Repositories.NewInMemoryInvitationRepository(), mockPasswordService, new(mocks.MockJWTService), Domain.RegistrationPolicy{}, nil, nil, nil)
+	adminID := primitive.NewObjectID()
+
+	invitation, token, err := userUsecase.CreateInvitation(adminID, Domain.RoleAdmin, "", 0)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+	assert.NotContains(t, invitation.TokenHash, token)
+
+	t.Run("GrantsPresetRoleOnce", func(t *testing.T) {
+		assert.NoError(t, userUsecase.Register(Domain.Registration{Username: "carol", Password: "password", Invitation: token}))
+		user, _ := repo.FindByUsername("carol")
+		assert.Equal(t, Domain.RoleAdmin, user.Role)
+
+		err := userUsecase.Register(Domain.Registration{Username: "dave", Password: "password", Invitation: token})
+		assert.True(t, errors.Is(err, Domain.ErrInvalidInvitation))
+	})
+
+	t.Run("FailedSignupKeepsInvitation", func(t *testing.T) {
+		_, token, _ := userUsecase.CreateInvitation(adminID, Domain.RoleUser, "", time.Hour)
+		err := userUsecase.Register(Domain.Registration{Username: "Carol", Password: "password", Invitation: token})
+		assert.True(t, errors.Is(err, Domain.ErrUsernameTaken))
+		assert.NoError(t, userUsecase.Register(Domain.Registration{Username: "erin", Password: "password", Invitation: token}))
+	})
+
+	t.Run("BoundToEmail", func(t *testing.T) {
+		_, token, _ := userUsecase.CreateInvitation(adminID, Domain.RoleUser, "frank@example.com", time.Hour)
+		err := userUsecase.Register(Domain.Registration{Username: "frank", Password: "password", Email: "other@example.com", Invitation: token})
+		assert.True(t, errors.Is(err, Domain.ErrInvalidInvitation))
+		assert.NoError(t, userUsecase.Register(Domain.Registration{Username: "frank", Password: "password", Email: "Frank@example.com", Invitation: token}))
+	})
+
+	t.Run("UnknownToken", func(t *testing.T) {
+		err := userUsecase.Register(Domain.Registration{Username: "gina", Password: "password", Invitation: "made-up"})
+		assert.True(t, errors.Is(err, Domain.ErrInvalidInvitation))
+	})
+}
+
+func TestUserUsecase_BootstrapAdmin(t *testing.T) {
+	repo := Repositories.NewInMemoryUserRepository()
+	mockPasswordService := new(mocks.MockPasswordService)
+	mockPasswordService.On("HashPassword", mock.Anything).Return("hashed_password", nil)
+	userUsecase := Usecases.NewUserUsecase(repo, Repositories.NewInMemoryInvitationRepository(), mockPasswordService, new(mocks.MockJWTService), Domain.RegistrationPolicy{}, nil, nil, nil)
+
+	_, err := userUsecase.BootstrapAdmin("root", "short")
+	assert.True(t, errors.Is(err, Domain.ErrWeakPassword))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			userUsecase.BootstrapAdmin(fmt.Sprintf("root%d", i), "password")
+		}(i)
+	}
+	wg.Wait()
+
+	admins, err := repo.CountActiveAdmins()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), admins)
+
+	_, err = userUsecase.BootstrapAdmin("another", "password")
+	assert.True(t, errors.Is(err, Domain.ErrAlreadyBootstrapped))
+}
+
+func TestUserUsecase_BootstrapAdminFailures(t *testing.T) {
+	mockPasswordService := new(mocks.MockPasswordService)
+	mockPasswordService.On("HashPassword", mock.Anything).Return("hashed_password", nil)
+
+	t.Run("FailedCreateReleasesTheClaim", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		mockUserRepo.On("CountActiveAdmins").Return(int64(0), nil)
+		mockUserRepo.On("ClaimFirstAdmin", mock.Anything).Return(true, nil)
+		mockUserRepo.On("Create", mock.Anything).Return(Domain.User{}, errors.New("write failed"))
+		mockUserRepo.On("ReleaseFirstAdmin", mock.Anything).Return(nil)
+		userUsecase := Usecases.NewUserUsecase(mockUserRepo, Repositories.NewInMemoryInvitationRepository(), mockPasswordService, new(mocks.MockJWTService), Domain.RegistrationPolicy{}, nil, nil, nil)
+
+		_, err := userUsecase.BootstrapAdmin("root", "password")
+		assert.EqualError(t, err, "write failed")
+		mockUserRepo.AssertCalled(t, "ReleaseFirstAdmin", mock.Anything)
+	})
+
+	t.Run("ExistingUsersWithoutAnAdmin", func(t *testing.T) {
+		repo := Repositories.NewInMemoryUserRepository()
+		repo.Create(Domain.User{Username: "bob", Role: Domain.RoleUser})
+		repo.Create(Domain.User{Username: "old-admin", Role: Domain.RoleAdmin, Deactivated: true})
+		userUsecase := Usecases.NewUserUsecase(repo, Repositories.NewInMemoryInvitationRepository(), mockPasswordService, new(mocks.MockJWTService), Domain.RegistrationPolicy{}, nil, nil, nil)
+
+		admin, err := userUsecase.BootstrapAdmin("root", "password")
+		require.NoError(t, err)
+		assert.Equal(t, Domain.RoleAdmin, admin.Role)
+	})
+}
+
 func TestUserUsecase_Login(t *testing.T) {
 	mockUserRepo := new(mocks.MockUserRepository)
 	mockPasswordService := new(mocks.MockPasswordService)
 	mockJWTService := new(mocks.MockJWTService)
 
-	userUsecase := Usecases.NewUserUsecase(mockUserRepo, mockPasswordService, mockJWTService)
+	userUsecase := Usecases.NewUserUsecase(mockUserRepo, Repositories.NewInMemoryInvitationRepository(), mockPasswordService, mockJWTService, Domain.RegistrationPolicy{}, nil, nil, nil)
 
 	t.Run("Success", func(t *testing.T) {
 		username := "testuser"
@@ -75,13 +231,14 @@ func TestUserUsecase_Login(t *testing.T) {
 
 		mockUserRepo.On("FindByUsername", username).Return(user, nil)
 		mockPasswordService.On("ComparePassword", hashedPassword, password).Return(nil)
-		mockJWTService.On("GenerateToken", user).Return(token, nil)
+		mockJWTService.On("GenerateToken", user, "").Return(token, nil)
 
-		resultToken, resultUser, err := userUsecase.Login(username, password)
+		result, err := userUsecase.Login(username, password, Domain.ClientInfo{})
 
 		assert.NoError(t, err)
-		assert.Equal(t, token, resultToken)
-		assert.Equal(t, user, resultUser)
+		assert.Equal(t, token, result.Token)
+		assert.Equal(t, user, result.User)
+		assert.False(t, result.MFAPending)
 	})
 
 	t.Run("InvalidCredentials", func(t *testing.T) {
@@ -96,7 +253,7 @@ func TestUserUsecase_Login(t *testing.T) {
 		mockUserRepo.On("FindByUsername", username).Return(user, nil)
 		mockPasswordService.On("ComparePassword", hashedPassword, password).Return(errors.New("invalid password"))
 
-		_, _, err := userUsecase.Login(username, password)
+		_, err := userUsecase.Login(username, password, Domain.ClientInfo{})
 
 		assert.Error(t, err)
 		assert.Equal(t, "invalid credentials", err.Error())
@@ -108,7 +265,7 @@ func TestUserUsecase_Promote(t *testing.T) {
 	mockPasswordService := new(mocks.MockPasswordService)
 	mockJWTService := new(mocks.MockJWTService)
 
-	userUsecase := Usecases.NewUserUsecase(mockUserRepo, mockPasswordService, mockJWTService)
+	userUsecase := Usecases.NewUserUsecase(mockUserRepo, Repositories.NewInMemoryInvitationRepository(), mockPasswordService, mockJWTService, Domain.RegistrationPolicy{}, nil, nil, nil)
 
 	t.Run("Success", func(t *testing.T) {
 		userID := primitive.NewObjectID()
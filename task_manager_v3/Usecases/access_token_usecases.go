@@ -0,0 +1,140 @@
+package Usecases
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Repositories"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AccessTokenTouchInterval limits how often a token's last-used time is
+// written, so busy scripts don't cost a write per request
+const AccessTokenTouchInterval = time.Minute
+
+// maxAccessTokenName bounds token names, in characters
+const maxAccessTokenName = 100
+
+// AccessTokenUsecase manages personal access tokens
+type AccessTokenUsecase interface {
+	// Create returns the stored token and its value. The value is not
+	// stored and can't be shown again. A zero lifetime means the default.
+	Create(userID primitive.ObjectID, name string, scopes []string, lifetime time.Duration) (Domain.AccessToken, string, error)
+	List(userID primitive.ObjectID) ([]Domain.AccessToken, error)
+	Revoke(userID, id primitive.ObjectID) error
+	// Authenticate checks a token presented by a client at ip and records
+	// the use
+	Authenticate(token, ip string) (Domain.AccessToken, error)
+}
+
+type accessTokenUsecase struct {
+	tokenRepo Repositories.AccessTokenRepository
+}
+
+func NewAccessTokenUsecase(tokenRepo Repositories.AccessTokenRepository) AccessTokenUsecase {
+	return &accessTokenUsecase{
+		tokenRepo: tokenRepo,
+	}
+}
+
+func (u *accessTokenUsecase) Create(userID primitive.ObjectID, name string, scopes []string, lifetime time.Duration) (Domain.AccessToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxAccessTokenName {
+		return Domain.AccessToken{}, "", Domain.ErrAccessTokenName
+	}
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return Domain.AccessToken{}, "", err
+	}
+	if lifetime == 0 {
+		lifetime = Domain.DefaultAccessTokenLifetime
+	}
+	if lifetime < 24*time.Hour || lifetime > Domain.MaxAccessTokenLifetime {
+		return Domain.AccessToken{}, "", Domain.ErrTokenLifetime
+	}
+
+	count, err := u.tokenRepo.CountForUser(userID)
+	if err != nil {
+		return Domain.AccessToken{}, "", err
+	}
+	if count >= Domain.MaxAccessTokensPerUser {
+		return Domain.AccessToken{}, "", Domain.ErrTooManyTokens
+	}
+
+	secret, err := generateToken()
+	if err != nil {
+		return Domain.AccessToken{}, "", err
+	}
+	value := Domain.AccessTokenPrefix + secret
+	now := time.Now().UTC()
+	token, err := u.tokenRepo.Create(Domain.AccessToken{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    value[:len(Domain.AccessTokenPrefix)+6],
+		TokenHash: hashToken(value),
+		Scopes:    scopes,
+		CreatedAt: now,
+		ExpiresAt: now.Add(lifetime),
+	})
+	if err != nil {
+		return Domain.AccessToken{}, "", err
+	}
+	return token, value, nil
+}
+
+// normalizeScopes checks scopes and drops duplicates
+func normalizeScopes(scopes []string) ([]string, error) {
+	var normalized []string
+	for _, valid := range Domain.AccessTokenScopes {
+		for _, scope := range scopes {
+			if scope == valid {
+				normalized = append(normalized, valid)
+				break
+			}
+		}
+	}
+	for _, scope := range scopes {
+		if !(Domain.AccessToken{Scopes: normalized}).HasScope(scope) {
+			return nil, Domain.ErrInvalidScope
+		}
+	}
+	if len(normalized) == 0 {
+		return nil, Domain.ErrInvalidScope
+	}
+	return normalized, nil
+}
+
+func (u *accessTokenUsecase) List(userID primitive.ObjectID) ([]Domain.AccessToken, error) {
+	return u.tokenRepo.ListForUser(userID)
+}
+
+func (u *accessTokenUsecase) Revoke(userID, id primitive.ObjectID) error {
+	return u.tokenRepo.Delete(userID, id)
+}
+
+func (u *accessTokenUsecase) Authenticate(value, ip string) (Domain.AccessToken, error) {
+	if !strings.HasPrefix(value, Domain.AccessTokenPrefix) {
+		return Domain.AccessToken{}, Domain.ErrInvalidAccessToken
+	}
+	token, err := u.tokenRepo.FindByHash(hashToken(value))
+	if err != nil {
+		if err.Error() == "access token not found" {
+			err = Domain.ErrInvalidAccessToken
+		}
+		return Domain.AccessToken{}, err
+	}
+	now := time.Now().UTC()
+	if !token.ExpiresAt.After(now) {
+		return Domain.AccessToken{}, Domain.ErrInvalidAccessToken
+	}
+
+	if now.Sub(token.LastUsedAt) >= AccessTokenTouchInterval || token.LastUsedIP != ip {
+		if err := u.tokenRepo.TouchUsed(token.ID, now, ip); err != nil {
+			return Domain.AccessToken{}, err
+		}
+		token.LastUsedAt, token.LastUsedIP = now, ip
+	}
+	return token, nil
+}
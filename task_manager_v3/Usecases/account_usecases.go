@@ -0,0 +1,178 @@
+package Usecases
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Infrastructure"
+	"a2sv-backend/task_manager_v3/Repositories"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	VerificationTokenTTL = 48 * time.Hour
+	ResetTokenTTL        = time.Hour
+)
+
+// AccountUsecase runs the emailed-token flows: proving ownership of an email
+// address and resetting a forgotten password
+type AccountUsecase interface {
+	// SendVerification mails a fresh verification link, invalidating earlier ones
+	SendVerification(userID primitive.ObjectID) error
+	VerifyEmail(token string) (Domain.User, error)
+	// ForgotPassword mails a reset link if email belongs to an active
+	// account. It returns nil when it doesn't so callers can't tell, but it
+	// takes longer when it does; callers facing users run it in the
+	// background.
+	ForgotPassword(email string) error
+	// ResetPassword also ends all of the user's sessions
+	ResetPassword(token, password string) error
+}
+
+type accountUsecase struct {
+	userRepo        Repositories.UserRepository
+	tokenRepo       Repositories.UserTokenRepository
+	passwordService Infrastructure.PasswordService
+	mailer          Infrastructure.MailSender
+	// sessions are ended when a password is reset, when they're tracked
+	sessions SessionUsecase
+	appURL   string
+}
+
+// NewAccountUsecase builds links in mails from appURL, the address of the
+// page that takes the token
+func NewAccountUsecase(userRepo Repositories.UserRepository, tokenRepo Repositories.UserTokenRepository, passwordService Infrastructure.PasswordService, mailer Infrastructure.MailSender, sessions SessionUsecase, appURL string) AccountUsecase {
+	return &accountUsecase{
+		userRepo:        userRepo,
+		tokenRepo:       tokenRepo,
+		passwordService: passwordService,
+		mailer:          mailer,
+		sessions:        sessions,
+		appURL:          strings.TrimRight(appURL, "/"),
+	}
+}
+
+// issue replaces the user's outstanding tokens for purpose with a new one
+// and returns the link carrying it
+func (u *accountUsecase) issue(user Domain.User, purpose, path string, ttl time.Duration) (string, error) {
+	if err := u.tokenRepo.DeleteForUser(user.ID, purpose); err != nil {
+		return "", err
+	}
+	token, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now().UTC()
+	_, err = u.tokenRepo.Create(Domain.UserToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		Email:     Domain.NormalizeEmail(user.Email),
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return u.appURL + path + "?token=" + url.QueryEscape(token), nil
+}
+
+func (u *accountUsecase) SendVerification(userID primitive.ObjectID) error {
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.Email == "" {
+		return errors.New("account has no email address")
+	}
+	if user.EmailVerified {
+		return nil
+	}
+
+	link, err := u.issue(user, Domain.TokenVerifyEmail, "/verify-email", VerificationTokenTTL)
+	if err != nil {
+		return err
+	}
+	return u.mailer.Send(Infrastructure.MailMessage{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nConfirm this address for your task manager account by opening:\n\n%s\n\n"+
+			"The link expires in 48 hours. If you didn't sign up, ignore this email.\n", user.Username, link),
+	})
+}
+
+func (u *accountUsecase) VerifyEmail(token string) (Domain.User, error) {
+	issued, err := u.tokenRepo.Consume(hashToken(token), Domain.TokenVerifyEmail, time.Now().UTC())
+	if err != nil {
+		return Domain.User{}, err
+	}
+	user, err := u.userRepo.FindByID(issued.UserID)
+	if err != nil {
+		return Domain.User{}, Domain.ErrInvalidToken
+	}
+	// The address changed after the link went out
+	if Domain.NormalizeEmail(user.Email) != issued.Email {
+		return Domain.User{}, Domain.ErrInvalidToken
+	}
+
+	user.EmailVerified = true
+	return u.userRepo.Update(user)
+}
+
+func (u *accountUsecase) ForgotPassword(email string) error {
+	user, err := u.userRepo.FindByEmail(email)
+	if err != nil || user.Deactivated {
+		return nil
+	}
+
+	link, err := u.issue(user, Domain.TokenResetPassword, "/reset-password", ResetTokenTTL)
+	if err != nil {
+		return err
+	}
+	return u.mailer.Send(Infrastructure.MailMessage{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your task manager account. "+
+			"To choose a new one, open:\n\n%s\n\nThe link expires in one hour and works once. "+
+			"If it wasn't you, ignore this email; your password is unchanged.\n", user.Username, link),
+	})
+}
+
+func (u *accountUsecase) ResetPassword(token, password string) error {
+	// Check the password first so a weak one doesn't burn the token
+	if err := checkPassword(password); err != nil {
+		return err
+	}
+	issued, err := u.tokenRepo.Consume(hashToken(token), Domain.TokenResetPassword, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	user, err := u.userRepo.FindByID(issued.UserID)
+	if err != nil || user.Deactivated {
+		return Domain.ErrInvalidToken
+	}
+
+	hashed, err := u.passwordService.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	user.Password = hashed
+	// Following the link proved control of the mailbox
+	if Domain.NormalizeEmail(user.Email) == issued.Email {
+		user.EmailVerified = true
+	}
+	if _, err := u.userRepo.Update(user); err != nil {
+		return err
+	}
+	// Whoever knew the old password is logged out
+	if u.sessions != nil {
+		if _, err := u.sessions.RevokeAll(user.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package Usecases
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Infrastructure"
+	"a2sv-backend/task_manager_v3/Repositories"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultMaxAttachmentSize is used when NewAttachmentUsecase gets no limit
+const DefaultMaxAttachmentSize = 25 << 20
+
+type AttachmentUsecase interface {
+	// Upload streams r into the blob store. The content type is sniffed from
+	// the bytes rather than trusted from the client.
+	Upload(taskID primitive.ObjectID, filename string, r io.Reader, uploadedBy string) (Domain.Attachment, error)
+	List(taskID primitive.ObjectID) ([]Domain.Attachment, error)
+	// Open returns the attachment and its content; the caller closes it
+	Open(taskID, id primitive.ObjectID) (Domain.Attachment, io.ReadCloser, error)
+	Delete(taskID, id primitive.ObjectID) error
+	// PurgeTask removes every attachment of a deleted task
+	PurgeTask(taskID primitive.ObjectID) error
+}
+
+type attachmentUsecase struct {
+	attachmentRepo Repositories.AttachmentRepository
+	taskRepo       Repositories.TaskRepository
+	blobs          Infrastructure.BlobStore
+	maxSize        int64
+}
+
+func NewAttachmentUsecase(attachmentRepo Repositories.AttachmentRepository, taskRepo Repositories.TaskRepository, blobs Infrastructure.BlobStore, maxSize int64) AttachmentUsecase {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxAttachmentSize
+	}
+	return &attachmentUsecase{
+		attachmentRepo: attachmentRepo,
+		taskRepo:       taskRepo,
+		blobs:          blobs,
+		maxSize:        maxSize,
+	}
+}
+
+// cleanFilename keeps only the base name of what the client sent and drops
+// control characters so it is safe to echo back in headers
+func cleanFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	if len(name) > 255 {
+		name = name[:255]
+	}
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	return name
+}
+
+func (u *attachmentUsecase) Upload(taskID primitive.ObjectID, filename string, r io.Reader, uploadedBy string) (Domain.Attachment, error) {
+	if _, err := u.taskRepo.FindByID(taskID); err != nil {
+		return Domain.Attachment{}, err
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return Domain.Attachment{}, err
+	}
+	head = head[:n]
+
+	attachment := Domain.Attachment{
+		ID:          primitive.NewObjectID(),
+		TaskID:      taskID,
+		Filename:    cleanFilename(filename),
+		ContentType: http.DetectContentType(head),
+		UploadedBy:  uploadedBy,
+		CreatedAt:   time.Now().UTC(),
+	}
+	attachment.BlobKey = attachment.ID.Hex()
+
+	// Read one byte past the limit so an oversized upload is detectable
+	hash := sha256.New()
+	body := io.LimitReader(io.MultiReader(bytes.NewReader(head), r), u.maxSize+1)
+	size, err := u.blobs.Put(attachment.BlobKey, io.TeeReader(body, hash))
+	if err != nil {
+		return Domain.Attachment{}, err
+	}
+	if size > u.maxSize {
+		u.blobs.Delete(attachment.BlobKey)
+		return Domain.Attachment{}, Domain.ErrAttachmentTooLarge
+	}
+	attachment.Size = size
+	attachment.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	created, err := u.attachmentRepo.Create(attachment)
+	if err != nil {
+		u.blobs.Delete(attachment.BlobKey)
+		return Domain.Attachment{}, err
+	}
+	return created, nil
+}
+
+func (u *attachmentUsecase) List(taskID primitive.ObjectID) ([]Domain.Attachment, error) {
+	if _, err := u.taskRepo.FindByID(taskID); err != nil {
+		return nil, err
+	}
+	return u.attachmentRepo.FindByTask(taskID)
+}
+
+// find loads an attachment and makes sure it belongs to taskID
+func (u *attachmentUsecase) find(taskID, id primitive.ObjectID) (Domain.Attachment, error) {
+	attachment, err := u.attachmentRepo.FindByID(id)
+	if err != nil {
+		return Domain.Attachment{}, err
+	}
+	if attachment.TaskID != taskID {
+		return Domain.Attachment{}, Domain.ErrAttachmentNotFound
+	}
+	return attachment, nil
+}
+
+func (u *attachmentUsecase) Open(taskID, id primitive.ObjectID) (Domain.Attachment, io.ReadCloser, error) {
+	attachment, err := u.find(taskID, id)
+	if err != nil {
+		return Domain.Attachment{}, nil, err
+	}
+	content, err := u.blobs.Open(attachment.BlobKey)
+	if errors.Is(err, Infrastructure.ErrBlobNotFound) {
+		return Domain.Attachment{}, nil, Domain.ErrAttachmentNotFound
+	}
+	if err != nil {
+		return Domain.Attachment{}, nil, err
+	}
+	return attachment, content, nil
+}
+
+func (u *attachmentUsecase) Delete(taskID, id primitive.ObjectID) error {
+	attachment, err := u.find(taskID, id)
+	if err != nil {
+		return err
+	}
+	return u.remove(attachment)
+}
+
+// remove drops the metadata before the blob so a failure never leaves an
+// attachment pointing at missing content
+func (u *attachmentUsecase) remove(attachment Domain.Attachment) error {
+	if err := u.attachmentRepo.Delete(attachment.ID); err != nil {
+		return err
+	}
+	return u.blobs.Delete(attachment.BlobKey)
+}
+
+func (u *attachmentUsecase) PurgeTask(taskID primitive.ObjectID) error {
+	attachments, err := u.attachmentRepo.FindByTask(taskID)
+	if err != nil {
+		return err
+	}
+	for _, attachment := range attachments {
+		if err := u.remove(attachment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package Usecases
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Repositories"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	colorPattern    = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+	fieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+)
+
+// CatalogUsecase manages the per-project labels and the custom field
+// definitions tasks can use
+type CatalogUsecase interface {
+	CreateLabel(label Domain.Label) (Domain.Label, error)
+	ListLabels(project string) ([]Domain.Label, error)
+	// GetLabels loads many labels in one go, leaving out IDs with no label
+	GetLabels(ids []primitive.ObjectID) ([]Domain.Label, error)
+	UpdateLabel(id primitive.ObjectID, label Domain.Label) (Domain.Label, error)
+	DeleteLabel(id primitive.ObjectID) error
+	CreateField(field Domain.CustomField) (Domain.CustomField, error)
+	ListFields() ([]Domain.CustomField, error)
+	UpdateField(id primitive.ObjectID, field Domain.CustomField) (Domain.CustomField, error)
+	DeleteField(id primitive.ObjectID) error
+}
+
+type catalogUsecase struct {
+	labelRepo Repositories.LabelRepository
+	fieldRepo Repositories.CustomFieldRepository
+	taskRepo  Repositories.TaskRepository
+}
+
+func NewCatalogUsecase(labelRepo Repositories.LabelRepository, fieldRepo Repositories.CustomFieldRepository, taskRepo Repositories.TaskRepository) CatalogUsecase {
+	return &catalogUsecase{
+		labelRepo: labelRepo,
+		fieldRepo: fieldRepo,
+		taskRepo:  taskRepo,
+	}
+}
+
+func validateLabel(label *Domain.Label) error {
+	label.Name = strings.TrimSpace(label.Name)
+	if label.Name == "" {
+		return fmt.Errorf("%w: name is required", Domain.ErrInvalidLabel)
+	}
+	if !colorPattern.MatchString(label.Color) {
+		return fmt.Errorf("%w: color must look like #1a2b3c", Domain.ErrInvalidLabel)
+	}
+	label.Color = strings.ToLower(label.Color)
+	return nil
+}
+
+func (u *catalogUsecase) CreateLabel(label Domain.Label) (Domain.Label, error) {
+	if label.Project == "" {
+		label.Project = Domain.DefaultProject
+	}
+	if err := validateLabel(&label); err != nil {
+		return Domain.Label{}, err
+	}
+	return u.labelRepo.Create(label)
+}
+
+func (u *catalogUsecase) ListLabels(project string) ([]Domain.Label, error) {
+	return u.labelRepo.FindByProject(project)
+}
+
+func (u *catalogUsecase) GetLabels(ids []primitive.ObjectID) ([]Domain.Label, error) {
+	if len(ids) == 0 {
+		return []Domain.Label{}, nil
+	}
+	return u.labelRepo.FindByIDs(ids)
+}
+
+// UpdateLabel renames or recolors a label. Labels can't move between projects
+// because tasks in the old project may carry them.
+func (u *catalogUsecase) UpdateLabel(id primitive.ObjectID, label Domain.Label) (Domain.Label, error) {
+	existing, err := u.labelRepo.FindByID(id)
+	if err != nil {
+		return Domain.Label{}, err
+	}
+	if err := validateLabel(&label); err != nil {
+		return Domain.Label{}, err
+	}
+	existing.Name = label.Name
+	existing.Color = label.Color
+	return u.labelRepo.Update(existing)
+}
+
+func (u *catalogUsecase) DeleteLabel(id primitive.ObjectID) error {
+	if _, err := u.labelRepo.FindByID(id); err != nil {
+		return err
+	}
+	if err := u.taskRepo.RemoveLabel(id); err != nil {
+		return err
+	}
+	return u.labelRepo.Delete(id)
+}
+
+func validateFieldDefinition(field *Domain.CustomField) error {
+	field.Name = strings.TrimSpace(field.Name)
+	if field.Name == "" {
+		return fmt.Errorf("%w: name is required", Domain.ErrInvalidCustomField)
+	}
+	if field.Type == Domain.FieldEnum {
+		if len(field.Options) == 0 {
+			return fmt.Errorf("%w: enum fields need at least one option", Domain.ErrInvalidCustomField)
+		}
+		seen := map[string]bool{}
+		for _, option := range field.Options {
+			if option == "" || seen[option] {
+				return fmt.Errorf("%w: enum options must be unique and non-empty", Domain.ErrInvalidCustomField)
+			}
+			seen[option] = true
+		}
+	} else {
+		field.Options = nil
+	}
+	return nil
+}
+
+func (u *catalogUsecase) CreateField(field Domain.CustomField) (Domain.CustomField, error) {
+	if !fieldKeyPattern.MatchString(field.Key) {
+		return Domain.CustomField{}, fmt.Errorf("%w: key must be lower case letters, digits and underscores, starting with a letter", Domain.ErrInvalidCustomField)
+	}
+	switch field.Type {
+	case Domain.FieldText, Domain.FieldNumber, Domain.FieldDate, Domain.FieldEnum, Domain.FieldUser:
+	default:
+		return Domain.CustomField{}, fmt.Errorf("%w: type must be one of text, number, date, enum, user", Domain.ErrInvalidCustomField)
+	}
+	if err := validateFieldDefinition(&field); err != nil {
+		return Domain.CustomField{}, err
+	}
+	return u.fieldRepo.Create(field)
+}
+
+func (u *catalogUsecase) ListFields() ([]Domain.CustomField, error) {
+	return u.fieldRepo.FindAll()
+}
+
+// UpdateField changes a field's name, options or required flag. The key and
+// type are fixed once tasks may hold values for them.
+func (u *catalogUsecase) UpdateField(id primitive.ObjectID, field Domain.CustomField) (Domain.CustomField, error) {
+	existing, err := u.fieldRepo.FindByID(id)
+	if err != nil {
+		return Domain.CustomField{}, err
+	}
+	field.ID, field.Key, field.Type = existing.ID, existing.Key, existing.Type
+	if err := validateFieldDefinition(&field); err != nil {
+		return Domain.CustomField{}, err
+	}
+	return u.fieldRepo.Update(field)
+}
+
+func (u *catalogUsecase) DeleteField(id primitive.ObjectID) error {
+	field, err := u.fieldRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if err := u.taskRepo.RemoveCustomField(field.Key); err != nil {
+		return err
+	}
+	return u.fieldRepo.Delete(id)
+}
+
+// normalizeFieldValue checks value against the field's type and returns it in
+// its stored form. lookupUser is only called for user fields.
+func normalizeFieldValue(field Domain.CustomField, value interface{}, lookupUser func(primitive.ObjectID) error) (interface{}, error) {
+	switch field.Type {
+	case Domain.FieldNumber:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case int:
+			return float64(v), nil
+		case string:
+			if n, err := strconv.ParseFloat(v, 64); err == nil {
+				return n, nil
+			}
+		}
+		return nil, fmt.Errorf("%s must be a number", field.Key)
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a string", field.Key)
+	}
+	switch field.Type {
+	case Domain.FieldDate:
+		t, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			t, err = time.Parse(time.RFC3339, s)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a date like 2026-01-31", field.Key)
+		}
+		return t.UTC().Format("2006-01-02"), nil
+	case Domain.FieldEnum:
+		for _, option := range field.Options {
+			if s == option {
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("%s must be one of %s", field.Key, strings.Join(field.Options, ", "))
+	case Domain.FieldUser:
+		id, err := primitive.ObjectIDFromHex(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a user ID", field.Key)
+		}
+		if lookupUser != nil {
+			if err := lookupUser(id); err != nil {
+				return nil, fmt.Errorf("%s refers to an unknown user", field.Key)
+			}
+		}
+		return id.Hex(), nil
+	}
+	return s, nil
+}
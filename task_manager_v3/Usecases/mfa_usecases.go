@@ -0,0 +1,449 @@
+package Usecases
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Infrastructure"
+	"a2sv-backend/task_manager_v3/Repositories"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MFAUsecase manages TOTP two-factor authentication and the policy that can
+// make it mandatory. Wherever a code is accepted it is either the current
+// 6-digit TOTP code or an unused recovery code, unless noted otherwise.
+type MFAUsecase interface {
+	// StartLogin finishes the password step of a login: the result holds
+	// an access token, or a pending token when a second factor is needed
+	StartLogin(user Domain.User, client Domain.ClientInfo) (Domain.LoginResult, error)
+	// CompleteLogin exchanges a pending token and a code for an access
+	// token. Users enrolling at login confirm with a TOTP code and get
+	// their recovery codes in the result. Each pending token completes one
+	// login and allows Domain.MaxMFAAttempts wrong codes.
+	CompleteLogin(mfaToken, code string, client Domain.ClientInfo) (Domain.LoginResult, error)
+	// BeginLoginEnrollment lets a user whose role requires 2FA, and who
+	// only holds a pending token, set it up
+	BeginLoginEnrollment(mfaToken string) (Domain.MFAEnrollment, error)
+	// BeginEnrollment creates a new secret; 2FA stays off until
+	// ConfirmEnrollment gets a TOTP code for it
+	BeginEnrollment(userID primitive.ObjectID) (Domain.MFAEnrollment, error)
+	ConfirmEnrollment(userID primitive.ObjectID, code string) ([]string, error)
+	// RegenerateRecoveryCodes replaces all recovery codes
+	RegenerateRecoveryCodes(userID primitive.ObjectID, code string) ([]string, error)
+	Disable(userID primitive.ObjectID, password, code string) error
+	// Reset turns 2FA off for a user who lost their authenticator; an
+	// admin action taken by actorID
+	Reset(actorID, userID primitive.ObjectID) error
+	// CheckPolicy fails with Domain.ErrMFAEnforced when the policy
+	// requires 2FA for user's role and they don't have it
+	CheckPolicy(user Domain.User) error
+	Policy() (Domain.SecurityPolicy, error)
+	// SetPolicy replaces the roles that require 2FA. Admins must have 2FA
+	// themselves before requiring it for their own role.
+	SetPolicy(actorID primitive.ObjectID, roles []string) (Domain.SecurityPolicy, error)
+}
+
+// securityPolicyTTL bounds how long a policy change made on another
+// instance takes to apply here
+const securityPolicyTTL = 30 * time.Second
+
+type mfaUsecase struct {
+	userRepo        Repositories.UserRepository
+	policyRepo      Repositories.SecurityPolicyRepository
+	challengeRepo   Repositories.MFAChallengeRepository
+	passwordService Infrastructure.PasswordService
+	jwtService      Infrastructure.JWTService
+	// sessions records the logins completed here
+	sessions SessionUsecase
+	issuer   string
+
+	// The policy is consulted on every authenticated request
+	mu           sync.Mutex
+	policy       Domain.SecurityPolicy
+	policyLoaded time.Time
+}
+
+// NewMFAUsecase names the account in authenticator apps after issuer
+func NewMFAUsecase(userRepo Repositories.UserRepository, policyRepo Repositories.SecurityPolicyRepository, challengeRepo Repositories.MFAChallengeRepository, passwordService Infrastructure.PasswordService, jwtService Infrastructure.JWTService, sessions SessionUsecase, issuer string) MFAUsecase {
+	if issuer == "" {
+		issuer = "Task Manager"
+	}
+	return &mfaUsecase{
+		userRepo:        userRepo,
+		policyRepo:      policyRepo,
+		challengeRepo:   challengeRepo,
+		passwordService: passwordService,
+		jwtService:      jwtService,
+		sessions:        sessions,
+		issuer:          issuer,
+	}
+}
+
+func (u *mfaUsecase) Policy() (Domain.SecurityPolicy, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if time.Since(u.policyLoaded) < securityPolicyTTL {
+		return u.policy, nil
+	}
+	policy, err := u.policyRepo.Get()
+	if err != nil {
+		return Domain.SecurityPolicy{}, err
+	}
+	u.policy, u.policyLoaded = policy, time.Now()
+	return policy, nil
+}
+
+func (u *mfaUsecase) SetPolicy(actorID primitive.ObjectID, roles []string) (Domain.SecurityPolicy, error) {
+	actor, err := u.userRepo.FindByID(actorID)
+	if err != nil {
+		return Domain.SecurityPolicy{}, err
+	}
+	policy := Domain.SecurityPolicy{MFARequiredRoles: []string{}, UpdatedBy: actorID, UpdatedAt: time.Now().UTC()}
+	for _, role := range roles {
+		if !Domain.ValidRole(role) {
+			return Domain.SecurityPolicy{}, Domain.ErrInvalidRole
+		}
+		if !policy.RequiresMFA(role) {
+			policy.MFARequiredRoles = append(policy.MFARequiredRoles, role)
+		}
+	}
+	// Otherwise the admin's next request would be refused
+	if policy.RequiresMFA(actor.Role) && !actor.MFAEnabled {
+		return Domain.SecurityPolicy{}, Domain.ErrMFANotEnrolled
+	}
+
+	if err := u.policyRepo.Save(policy); err != nil {
+		return Domain.SecurityPolicy{}, err
+	}
+	u.mu.Lock()
+	u.policy, u.policyLoaded = policy, time.Now()
+	u.mu.Unlock()
+	return policy, nil
+}
+
+func (u *mfaUsecase) CheckPolicy(user Domain.User) error {
+	if user.MFAEnabled {
+		return nil
+	}
+	policy, err := u.Policy()
+	if err != nil {
+		return err
+	}
+	if policy.RequiresMFA(user.Role) {
+		return Domain.ErrMFAEnforced
+	}
+	return nil
+}
+
+func (u *mfaUsecase) StartLogin(user Domain.User, client Domain.ClientInfo) (Domain.LoginResult, error) {
+	enforced := u.CheckPolicy(user)
+	if enforced != nil && enforced != Domain.ErrMFAEnforced {
+		return Domain.LoginResult{}, enforced
+	}
+
+	if !user.MFAEnabled && enforced == nil {
+		token, err := issueToken(u.jwtService, u.sessions, user, client)
+		if err != nil {
+			return Domain.LoginResult{}, err
+		}
+		return Domain.LoginResult{Token: token, User: user}, nil
+	}
+
+	challengeID, err := generateToken()
+	if err != nil {
+		return Domain.LoginResult{}, err
+	}
+	challenge := Domain.MFAChallenge{ID: challengeID, UserID: user.ID, ExpiresAt: time.Now().Add(Infrastructure.MFATokenTTL)}
+	if err := u.challengeRepo.Create(challenge); err != nil {
+		return Domain.LoginResult{}, err
+	}
+	token, err := u.jwtService.GenerateMFAToken(user, challengeID)
+	if err != nil {
+		return Domain.LoginResult{}, err
+	}
+	return Domain.LoginResult{
+		Token:              token,
+		User:               user,
+		MFAPending:         true,
+		EnrollmentRequired: !user.MFAEnabled,
+	}, nil
+}
+
+// pendingUser loads the active account a pending login token was issued for,
+// along with the ID of the token's challenge
+func (u *mfaUsecase) pendingUser(mfaToken string) (Domain.User, string, error) {
+	token, err := u.jwtService.ValidateToken(mfaToken)
+	if err != nil || !token.Valid {
+		return Domain.User{}, "", Domain.ErrInvalidMFAToken
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return Domain.User{}, "", Domain.ErrInvalidMFAToken
+	}
+	if pending, _ := claims["mfa_pending"].(bool); !pending {
+		return Domain.User{}, "", Domain.ErrInvalidMFAToken
+	}
+	challengeID, _ := claims["jti"].(string)
+	userID, _ := claims["user_id"].(string)
+	id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil || challengeID == "" {
+		return Domain.User{}, "", Domain.ErrInvalidMFAToken
+	}
+	challenge, err := u.challengeRepo.Find(challengeID, time.Now())
+	if err != nil {
+		return Domain.User{}, "", err
+	}
+	if challenge.UserID != id {
+		return Domain.User{}, "", Domain.ErrInvalidMFAToken
+	}
+	user, err := u.userRepo.FindByID(id)
+	if err != nil {
+		return Domain.User{}, "", Domain.ErrInvalidMFAToken
+	}
+	if user.Deactivated {
+		return Domain.User{}, "", Domain.ErrUserDeactivated
+	}
+	return user, challengeID, nil
+}
+
+func (u *mfaUsecase) CompleteLogin(mfaToken, code string, client Domain.ClientInfo) (Domain.LoginResult, error) {
+	user, challengeID, err := u.pendingUser(mfaToken)
+	if err != nil {
+		return Domain.LoginResult{}, err
+	}
+
+	var recoveryCodes []string
+	if user.MFAEnabled {
+		err = u.verify(&user, code, true)
+	} else {
+		recoveryCodes, err = u.enable(user, code)
+	}
+	if errors.Is(err, Domain.ErrInvalidMFACode) {
+		failures, countErr := u.challengeRepo.RecordFailure(challengeID)
+		if countErr != nil {
+			return Domain.LoginResult{}, countErr
+		}
+		if failures >= Domain.MaxMFAAttempts {
+			return Domain.LoginResult{}, Domain.ErrMFAAttemptsExceeded
+		}
+	}
+	if err != nil {
+		return Domain.LoginResult{}, err
+	}
+	// Two requests racing with the same token can both pass the checks
+	// above; only one of them gets to spend it
+	if err := u.challengeRepo.Consume(challengeID, time.Now()); err != nil {
+		return Domain.LoginResult{}, err
+	}
+
+	token, err := issueToken(u.jwtService, u.sessions, user, client)
+	if err != nil {
+		return Domain.LoginResult{}, err
+	}
+	return Domain.LoginResult{Token: token, User: user, RecoveryCodes: recoveryCodes}, nil
+}
+
+func (u *mfaUsecase) BeginLoginEnrollment(mfaToken string) (Domain.MFAEnrollment, error) {
+	user, _, err := u.pendingUser(mfaToken)
+	if err != nil {
+		return Domain.MFAEnrollment{}, err
+	}
+	return u.begin(user)
+}
+
+func (u *mfaUsecase) BeginEnrollment(userID primitive.ObjectID) (Domain.MFAEnrollment, error) {
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return Domain.MFAEnrollment{}, err
+	}
+	return u.begin(user)
+}
+
+func (u *mfaUsecase) begin(user Domain.User) (Domain.MFAEnrollment, error) {
+	if user.MFAEnabled {
+		return Domain.MFAEnrollment{}, Domain.ErrMFAAlreadyEnabled
+	}
+	secret, err := Infrastructure.NewTOTPSecret()
+	if err != nil {
+		return Domain.MFAEnrollment{}, err
+	}
+	user.TOTPSecret = secret
+	if _, err := u.userRepo.Update(user); err != nil {
+		return Domain.MFAEnrollment{}, err
+	}
+
+	account := user.Email
+	if account == "" {
+		account = user.Username
+	}
+	return Domain.MFAEnrollment{Secret: secret, URI: Infrastructure.TOTPURI(u.issuer, account, secret)}, nil
+}
+
+func (u *mfaUsecase) ConfirmEnrollment(userID primitive.ObjectID, code string) ([]string, error) {
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return u.enable(user, code)
+}
+
+// enable turns 2FA on once code proves the authenticator has the secret
+func (u *mfaUsecase) enable(user Domain.User, code string) ([]string, error) {
+	if user.MFAEnabled {
+		return nil, Domain.ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, Domain.ErrMFANotEnrolled
+	}
+	if err := u.verify(&user, code, false); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	user.MFAEnabled = true
+	user.RecoveryCodes = hashes
+	if _, err := u.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (u *mfaUsecase) RegenerateRecoveryCodes(userID primitive.ObjectID, code string) ([]string, error) {
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.MFAEnabled {
+		return nil, Domain.ErrMFANotEnrolled
+	}
+	if err := u.verify(&user, code, true); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	user.RecoveryCodes = hashes
+	if _, err := u.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (u *mfaUsecase) Disable(userID primitive.ObjectID, password, code string) error {
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if !user.MFAEnabled {
+		return Domain.ErrMFANotEnrolled
+	}
+	if err := u.passwordService.ComparePassword(user.Password, password); err != nil {
+		return Domain.ErrWrongPassword
+	}
+	policy, err := u.Policy()
+	if err != nil {
+		return err
+	}
+	if policy.RequiresMFA(user.Role) {
+		return Domain.ErrMFAEnforced
+	}
+	if err := u.verify(&user, code, true); err != nil {
+		return err
+	}
+	return u.turnOff(user)
+}
+
+func (u *mfaUsecase) Reset(actorID, userID primitive.ObjectID) error {
+	if actorID == userID {
+		return Domain.ErrSelfManagement
+	}
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	return u.turnOff(user)
+}
+
+func (u *mfaUsecase) turnOff(user Domain.User) error {
+	user.MFAEnabled = false
+	user.TOTPSecret = ""
+	user.RecoveryCodes = nil
+	_, err := u.userRepo.Update(user)
+	return err
+}
+
+// verify checks a TOTP code, or a recovery code when allowed, and spends
+// it. user is updated to match what was written.
+func (u *mfaUsecase) verify(user *Domain.User, code string, allowRecovery bool) error {
+	code = strings.Join(strings.Fields(code), "")
+	if len(code) == Infrastructure.TOTPDigits {
+		step, ok := Infrastructure.VerifyTOTP(user.TOTPSecret, code, time.Now())
+		if !ok {
+			return Domain.ErrInvalidMFACode
+		}
+		fresh, err := u.userRepo.UseTOTPStep(user.ID, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return Domain.ErrInvalidMFACode
+		}
+		user.TOTPLastStep = step
+		return nil
+	}
+
+	if !allowRecovery {
+		return Domain.ErrInvalidMFACode
+	}
+	hash := hashToken(normalizeRecoveryCode(code))
+	used, err := u.userRepo.UseRecoveryCode(user.ID, hash)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return Domain.ErrInvalidMFACode
+	}
+	for i, stored := range user.RecoveryCodes {
+		if stored == hash {
+			user.RecoveryCodes = append(append([]string{}, user.RecoveryCodes[:i]...), user.RecoveryCodes[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newRecoveryCodes returns codes to show the user once and the hashes to
+// store. Each code carries 80 bits, enough for a plain SHA-256.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, Domain.RecoveryCodeCount)
+	hashes := make([]string, Domain.RecoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(recoveryEncoding.EncodeToString(b))
+		codes[i] = raw[0:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:16]
+		hashes[i] = hashToken(raw)
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode accepts codes typed with any case or grouping
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(code, "-", ""))
+}
@@ -0,0 +1,68 @@
+package Usecases
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Repositories"
+	"time"
+)
+
+// defaultReportWindow is used when a report is asked for without a range
+const defaultReportWindow = 30 * 24 * time.Hour
+
+type ReportUsecase interface {
+	Summary() (Domain.TaskSummary, error)
+	Throughput(from, to time.Time, interval string) ([]Domain.ThroughputBucket, error)
+	CycleTime(from, to time.Time) (Domain.CycleTimeReport, error)
+}
+
+type reportUsecase struct {
+	reportRepo Repositories.ReportRepository
+	now        func() time.Time
+}
+
+func NewReportUsecase(reportRepo Repositories.ReportRepository) ReportUsecase {
+	return &reportUsecase{
+		reportRepo: reportRepo,
+		now:        time.Now,
+	}
+}
+
+func (u *reportUsecase) Summary() (Domain.TaskSummary, error) {
+	return u.reportRepo.Summary(u.now())
+}
+
+func (u *reportUsecase) Throughput(from, to time.Time, interval string) ([]Domain.ThroughputBucket, error) {
+	if interval == "" {
+		interval = Domain.IntervalDay
+	}
+	if interval != Domain.IntervalDay && interval != Domain.IntervalWeek {
+		return nil, Domain.ErrInvalidInterval
+	}
+	from, to, err := u.window(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return u.reportRepo.Throughput(from, to, interval)
+}
+
+func (u *reportUsecase) CycleTime(from, to time.Time) (Domain.CycleTimeReport, error) {
+	from, to, err := u.window(from, to)
+	if err != nil {
+		return Domain.CycleTimeReport{}, err
+	}
+	return u.reportRepo.CycleTime(from, to)
+}
+
+// window fills in a missing end (now) or start (30 days before the end)
+func (u *reportUsecase) window(from, to time.Time) (time.Time, time.Time, error) {
+	if to.IsZero() {
+		to = u.now()
+	}
+	if from.IsZero() {
+		from = to.Add(-defaultReportWindow)
+	}
+	if !from.Before(to) {
+		return from, to, Domain.ErrInvalidReportRange
+	}
+	return from, to, nil
+}
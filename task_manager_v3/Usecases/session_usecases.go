@@ -0,0 +1,126 @@
+package Usecases
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Infrastructure"
+	"a2sv-backend/task_manager_v3/Repositories"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SessionTouchInterval limits how often a session's last-seen time is
+// written
+const SessionTouchInterval = time.Minute
+
+// maxUserAgent bounds the user agent kept with a session, in characters
+const maxUserAgent = 512
+
+// SessionUsecase tracks logins so users and admins can end them
+type SessionUsecase interface {
+	// Start records a login from client and returns an access token bound
+	// to the new session
+	Start(user Domain.User, client Domain.ClientInfo) (string, error)
+	// Check fails with Domain.ErrSessionEnded unless sessionID is a live
+	// session of userID, and records that it was seen
+	Check(sessionID, userID string) error
+	List(userID primitive.ObjectID) ([]Domain.Session, error)
+	Revoke(userID, sessionID primitive.ObjectID) error
+	// RevokeAll logs a user out everywhere and returns how many sessions
+	// were ended
+	RevokeAll(userID primitive.ObjectID) (int64, error)
+	// RevokeOthers ends all of a user's sessions but currentID, the one
+	// making the request. Without a valid currentID it ends them all.
+	RevokeOthers(userID primitive.ObjectID, currentID string) (int64, error)
+}
+
+type sessionUsecase struct {
+	sessionRepo Repositories.SessionRepository
+	jwtService  Infrastructure.JWTService
+}
+
+func NewSessionUsecase(sessionRepo Repositories.SessionRepository, jwtService Infrastructure.JWTService) SessionUsecase {
+	return &sessionUsecase{
+		sessionRepo: sessionRepo,
+		jwtService:  jwtService,
+	}
+}
+
+func (u *sessionUsecase) Start(user Domain.User, client Domain.ClientInfo) (string, error) {
+	userAgent := client.UserAgent
+	if utf8.RuneCountInString(userAgent) > maxUserAgent {
+		userAgent = string([]rune(userAgent)[:maxUserAgent])
+	}
+	now := time.Now().UTC()
+	session, err := u.sessionRepo.Create(Domain.Session{
+		UserID:     user.ID,
+		UserAgent:  userAgent,
+		IP:         client.IP,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(Infrastructure.TokenTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	token, err := u.jwtService.GenerateToken(user, session.ID.Hex())
+	if err != nil {
+		u.sessionRepo.Delete(user.ID, session.ID)
+		return "", err
+	}
+	return token, nil
+}
+
+func (u *sessionUsecase) Check(sessionID, userID string) error {
+	id, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return Domain.ErrSessionEnded
+	}
+	session, err := u.sessionRepo.FindByID(id)
+	if err != nil {
+		if err.Error() == "session not found" {
+			return Domain.ErrSessionEnded
+		}
+		return err
+	}
+	now := time.Now().UTC()
+	if session.UserID.Hex() != userID || !session.ExpiresAt.After(now) {
+		return Domain.ErrSessionEnded
+	}
+
+	if now.Sub(session.LastSeenAt) >= SessionTouchInterval {
+		return u.sessionRepo.Touch(session.ID, now)
+	}
+	return nil
+}
+
+func (u *sessionUsecase) List(userID primitive.ObjectID) ([]Domain.Session, error) {
+	return u.sessionRepo.ListForUser(userID)
+}
+
+func (u *sessionUsecase) Revoke(userID, sessionID primitive.ObjectID) error {
+	return u.sessionRepo.Delete(userID, sessionID)
+}
+
+func (u *sessionUsecase) RevokeAll(userID primitive.ObjectID) (int64, error) {
+	return u.sessionRepo.DeleteForUser(userID, primitive.NilObjectID)
+}
+
+func (u *sessionUsecase) RevokeOthers(userID primitive.ObjectID, currentID string) (int64, error) {
+	keep, err := primitive.ObjectIDFromHex(currentID)
+	if err != nil {
+		keep = primitive.NilObjectID
+	}
+	return u.sessionRepo.DeleteForUser(userID, keep)
+}
+
+// issueToken signs user in from client. With sessions the token is bound
+// to a new session; without, it is a plain JWT as before sessions existed.
+func issueToken(jwtService Infrastructure.JWTService, sessions SessionUsecase, user Domain.User, client Domain.ClientInfo) (string, error) {
+	if sessions == nil {
+		return jwtService.GenerateToken(user, "")
+	}
+	return sessions.Start(user, client)
+}
@@ -0,0 +1,303 @@
+package Usecases
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Infrastructure"
+	"a2sv-backend/task_manager_v3/Repositories"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SSOStateTTL is how long a user has to finish signing in at the provider
+const SSOStateTTL = 10 * time.Minute
+
+// SSOUsecase signs users in through an OpenID Connect provider, creating
+// or linking accounts as needed
+type SSOUsecase interface {
+	// Begin returns the provider URL to send the browser to and the state
+	// the callback has to bring back. With a non-zero linkUserID the
+	// identity is linked to that account instead of signing in.
+	Begin(linkUserID primitive.ObjectID) (string, string, error)
+	// Complete handles the provider's callback, made by client
+	Complete(state, code string, client Domain.ClientInfo) (Domain.SSOResult, error)
+	ListIdentities(userID primitive.ObjectID) ([]Domain.ExternalIdentity, error)
+	Unlink(userID, identityID primitive.ObjectID) error
+}
+
+type ssoUsecase struct {
+	provider     Infrastructure.OIDCProvider
+	stateRepo    Repositories.SSOStateRepository
+	identityRepo Repositories.IdentityRepository
+	userRepo     Repositories.UserRepository
+	jwtService   Infrastructure.JWTService
+	// mfa applies the 2FA policy to SSO sign-ins like to password ones
+	mfa      MFAUsecase
+	sessions SessionUsecase
+	policy   Domain.SSOPolicy
+}
+
+func NewSSOUsecase(provider Infrastructure.OIDCProvider, stateRepo Repositories.SSOStateRepository, identityRepo Repositories.IdentityRepository, userRepo Repositories.UserRepository, jwtService Infrastructure.JWTService, mfa MFAUsecase, sessions SessionUsecase, policy Domain.SSOPolicy) SSOUsecase {
+	return &ssoUsecase{
+		provider:     provider,
+		stateRepo:    stateRepo,
+		identityRepo: identityRepo,
+		userRepo:     userRepo,
+		jwtService:   jwtService,
+		mfa:          mfa,
+		sessions:     sessions,
+		policy:       policy,
+	}
+}
+
+func (u *ssoUsecase) Begin(linkUserID primitive.ObjectID) (string, string, error) {
+	state, err := generateToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := generateToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := generateToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now().UTC()
+	err = u.stateRepo.Create(Domain.SSOState{
+		StateHash:    hashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		LinkUserID:   linkUserID,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(SSOStateTTL),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return u.provider.AuthCodeURL(state, nonce, verifier), state, nil
+}
+
+func (u *ssoUsecase) Complete(state, code string, client Domain.ClientInfo) (Domain.SSOResult, error) {
+	issued, err := u.stateRepo.Consume(hashToken(state), time.Now().UTC())
+	if err != nil {
+		return Domain.SSOResult{}, err
+	}
+	claims, err := u.provider.Exchange(context.Background(), code, issued.CodeVerifier, issued.Nonce)
+	if err != nil {
+		return Domain.SSOResult{}, fmt.Errorf("%w: %v", Domain.ErrSSOFailed, err)
+	}
+	if claims.Subject == "" {
+		return Domain.SSOResult{}, fmt.Errorf("%w: ID token has no subject", Domain.ErrSSOFailed)
+	}
+
+	identity, user, err := u.linkedAccount(claims)
+	if err != nil {
+		return Domain.SSOResult{}, err
+	}
+	if !issued.LinkUserID.IsZero() {
+		return u.link(issued.LinkUserID, identity, user, claims)
+	}
+	if identity != nil {
+		login, err := u.login(user, *identity, claims.Groups, client)
+		return Domain.SSOResult{Login: login}, err
+	}
+	return u.provision(claims, client)
+}
+
+// linkedAccount finds the identity for claims and its account, or nil if
+// there is none. Identities of deleted accounts are dropped on the way.
+func (u *ssoUsecase) linkedAccount(claims Infrastructure.OIDCClaims) (*Domain.ExternalIdentity, Domain.User, error) {
+	identity, err := u.identityRepo.FindBySubject(claims.Issuer, claims.Subject)
+	if err != nil {
+		if err.Error() == "identity not found" {
+			err = nil
+		}
+		return nil, Domain.User{}, err
+	}
+	user, err := u.userRepo.FindByID(identity.UserID)
+	if err != nil {
+		if err := u.identityRepo.Delete(identity.ID); err != nil {
+			return nil, Domain.User{}, err
+		}
+		return nil, Domain.User{}, nil
+	}
+	return &identity, user, nil
+}
+
+func (u *ssoUsecase) link(userID primitive.ObjectID, existing *Domain.ExternalIdentity, owner Domain.User, claims Infrastructure.OIDCClaims) (Domain.SSOResult, error) {
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return Domain.SSOResult{}, err
+	}
+	if user.Deactivated {
+		return Domain.SSOResult{}, Domain.ErrUserDeactivated
+	}
+	if existing != nil {
+		if owner.ID != user.ID {
+			return Domain.SSOResult{}, Domain.ErrIdentityLinked
+		}
+		return Domain.SSOResult{Linked: existing}, nil
+	}
+
+	identity, err := u.identityRepo.Create(Domain.ExternalIdentity{
+		UserID:   user.ID,
+		Issuer:   claims.Issuer,
+		Subject:  claims.Subject,
+		Email:    Domain.NormalizeEmail(claims.Email),
+		LinkedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return Domain.SSOResult{}, err
+	}
+	return Domain.SSOResult{Linked: &identity}, nil
+}
+
+func (u *ssoUsecase) login(user Domain.User, identity Domain.ExternalIdentity, groups []string, client Domain.ClientInfo) (Domain.LoginResult, error) {
+	if user.Deactivated {
+		return Domain.LoginResult{}, Domain.ErrUserDeactivated
+	}
+	user, err := u.syncRole(user, groups)
+	if err != nil {
+		return Domain.LoginResult{}, err
+	}
+	if err := u.identityRepo.TouchLogin(identity.ID, time.Now().UTC()); err != nil {
+		return Domain.LoginResult{}, err
+	}
+
+	if u.mfa != nil {
+		return u.mfa.StartLogin(user, client)
+	}
+	token, err := issueToken(u.jwtService, u.sessions, user, client)
+	if err != nil {
+		return Domain.LoginResult{}, err
+	}
+	return Domain.LoginResult{Token: token, User: user}, nil
+}
+
+// syncRole applies the group mapping, if there is one. The last active
+// admin is never demoted this way, so a provider misconfiguration can't
+// lock everyone out.
+func (u *ssoUsecase) syncRole(user Domain.User, groups []string) (Domain.User, error) {
+	if len(u.policy.GroupRoles) == 0 {
+		return user, nil
+	}
+	role := u.policy.RoleFor(groups)
+	if role == user.Role {
+		return user, nil
+	}
+
+	changed := user
+	changed.Role = role
+	updated, err := updateKeepingAnAdmin(u.userRepo, user, changed)
+	if errors.Is(err, Domain.ErrLastAdmin) {
+		return user, nil
+	}
+	return updated, err
+}
+
+// maxUsernameAttempts bounds the numbered variants tried when the
+// provider's username is taken
+const maxUsernameAttempts = 50
+
+// provision creates an account for a first-time SSO user. It has no
+// password; users can set one through the reset flow if they have an email.
+func (u *ssoUsecase) provision(claims Infrastructure.OIDCClaims, client Domain.ClientInfo) (Domain.SSOResult, error) {
+	if !u.policy.Provision {
+		return Domain.SSOResult{}, Domain.ErrSSOSignupClosed
+	}
+
+	// Only take over addresses the provider vouches for
+	email := ""
+	if claims.EmailVerified {
+		email = Domain.NormalizeEmail(claims.Email)
+	}
+	if email != "" {
+		if _, err := u.userRepo.FindByEmail(email); err == nil {
+			return Domain.SSOResult{}, Domain.ErrSSOLinkRequired
+		}
+	}
+
+	user := Domain.User{
+		DisplayName:   strings.TrimSpace(claims.Name),
+		Email:         email,
+		EmailVerified: email != "",
+		Role:          u.policy.RoleFor(claims.Groups),
+	}
+	base := ssoUsername(claims)
+	var err error
+	for attempt := 1; attempt <= maxUsernameAttempts; attempt++ {
+		candidate := user
+		candidate.ID = primitive.NewObjectID()
+		candidate.Username = base
+		if attempt > 1 {
+			candidate.Username = fmt.Sprintf("%s%d", base, attempt)
+		}
+		var created Domain.User
+		if created, err = u.userRepo.Create(candidate); !errors.Is(err, Domain.ErrUsernameTaken) {
+			user = created
+			break
+		}
+	}
+	if err != nil {
+		return Domain.SSOResult{}, err
+	}
+
+	identity, err := u.identityRepo.Create(Domain.ExternalIdentity{
+		UserID:   user.ID,
+		Issuer:   claims.Issuer,
+		Subject:  claims.Subject,
+		Email:    Domain.NormalizeEmail(claims.Email),
+		LinkedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		// Another callback for the same identity won the race
+		u.userRepo.Delete(user.ID)
+		return Domain.SSOResult{}, err
+	}
+
+	login, err := u.login(user, identity, claims.Groups, client)
+	return Domain.SSOResult{Login: login, Provisioned: true}, err
+}
+
+// ssoUsername picks a username from the provider's claims
+func ssoUsername(claims Infrastructure.OIDCClaims) string {
+	local, _, _ := strings.Cut(claims.Email, "@")
+	for _, candidate := range []string{claims.PreferredUsername, local, claims.Name} {
+		candidate = strings.Join(strings.Fields(candidate), "")
+		if Domain.NormalizeUsername(candidate) != "" {
+			return candidate
+		}
+	}
+	return "user"
+}
+
+func (u *ssoUsecase) ListIdentities(userID primitive.ObjectID) ([]Domain.ExternalIdentity, error) {
+	return u.identityRepo.ListForUser(userID)
+}
+
+func (u *ssoUsecase) Unlink(userID, identityID primitive.ObjectID) error {
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	identities, err := u.identityRepo.ListForUser(userID)
+	if err != nil {
+		return err
+	}
+	for _, identity := range identities {
+		if identity.ID != identityID {
+			continue
+		}
+		if user.Password == "" && len(identities) == 1 {
+			return Domain.ErrLastLoginMethod
+		}
+		return u.identityRepo.Delete(identityID)
+	}
+	return errors.New("identity not found")
+}
@@ -2,7 +2,18 @@ package Usecases
 
 import (
 	"a2sv-backend/task_manager_v3/Domain"
+	"a2sv-backend/task_manager_v3/Infrastructure"
 	"a2sv-backend/task_manager_v3/Repositories"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -10,38 +21,551 @@ import (
 type TaskUsecase interface {
 	Create(task Domain.Task) (Domain.Task, error)
 	GetAll() ([]Domain.Task, error)
+	List(filter Domain.TaskFilter) ([]Domain.Task, error)
+	// ListPage is List one page at a time, pages starting at 1. It also
+	// returns how many tasks match in all.
+	ListPage(filter Domain.TaskFilter, page, pageSize int) ([]Domain.Task, int64, error)
 	GetByID(id primitive.ObjectID) (Domain.Task, error)
 	Update(id primitive.ObjectID, task Domain.Task) (Domain.Task, error)
 	Delete(id primitive.ObjectID) error
+	Bulk(ops []Domain.BulkOperation, atomic bool) ([]Domain.BulkResult, error)
+	Export(viewer Domain.Viewer, encoder Infrastructure.TaskEncoder) error
+	Import(decoder Infrastructure.TaskDecoder, dryRun bool) (Domain.ImportReport, error)
+	Search(viewer Domain.Viewer, q string, limit int) ([]Domain.TaskSearchResult, error)
+	Subscribe(ctx context.Context, viewer Domain.Viewer, lastEventID string) (<-chan Domain.TaskEvent, error)
 }
 
 type taskUsecase struct {
-	taskRepo Repositories.TaskRepository
+	taskRepo  Repositories.TaskRepository
+	labelRepo Repositories.LabelRepository
+	fieldRepo Repositories.CustomFieldRepository
+	userRepo  Repositories.UserRepository
+	eventBus  Infrastructure.EventBus
+	// attachments is told about deleted tasks so their blobs are purged
+	attachments AttachmentUsecase
 }
 
-func NewTaskUsecase(taskRepo Repositories.TaskRepository) TaskUsecase {
+func NewTaskUsecase(taskRepo Repositories.TaskRepository, labelRepo Repositories.LabelRepository, fieldRepo Repositories.CustomFieldRepository, userRepo Repositories.UserRepository, eventBus Infrastructure.EventBus, attachments AttachmentUsecase) TaskUsecase {
 	return &taskUsecase{
-		taskRepo: taskRepo,
+		taskRepo:    taskRepo,
+		labelRepo:   labelRepo,
+		fieldRepo:   fieldRepo,
+		userRepo:    userRepo,
+		eventBus:    eventBus,
+		attachments: attachments,
 	}
 }
 
+// CanViewTask reports whether viewer may see task. Every authenticated user
+// can read every task (the same rule GET /tasks applies), so this is the one
+// place to tighten when tasks gain owners.
+func CanViewTask(viewer Domain.Viewer, task Domain.Task) bool {
+	return viewer.UserID != ""
+}
+
+// validateTask checks the parts of a task that depend on the catalog
+// (priority, labels, custom fields) and fills in the default project.
+// Errors wrap Domain.ErrInvalidTask.
+func (u *taskUsecase) validateTask(task *Domain.Task) error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", Domain.ErrInvalidTask, fmt.Sprintf(format, args...))
+	}
+
+	if !Domain.ValidPriority(task.Priority) {
+		return invalid("priority must be one of P0, P1, P2, P3")
+	}
+	if task.Project == "" {
+		task.Project = Domain.DefaultProject
+	}
+
+	seen := map[primitive.ObjectID]bool{}
+	for _, id := range task.Labels {
+		if seen[id] {
+			return invalid("label %s is listed twice", id.Hex())
+		}
+		seen[id] = true
+		label, err := u.labelRepo.FindByID(id)
+		if err != nil {
+			return invalid("label %s does not exist", id.Hex())
+		}
+		if label.Project != task.Project {
+			return invalid("label %s belongs to project %s", label.Name, label.Project)
+		}
+	}
+
+	definitions, err := u.fieldRepo.FindAll()
+	if err != nil {
+		return err
+	}
+	byKey := make(map[string]Domain.CustomField, len(definitions))
+	for _, field := range definitions {
+		byKey[field.Key] = field
+		if _, ok := task.CustomFields[field.Key]; field.Required && !ok {
+			return invalid("custom field %s is required", field.Key)
+		}
+	}
+
+	normalized := make(map[string]interface{}, len(task.CustomFields))
+	for key, value := range task.CustomFields {
+		field, ok := byKey[key]
+		if !ok {
+			return invalid("unknown custom field %s", key)
+		}
+		if value == nil {
+			continue
+		}
+		stored, err := normalizeFieldValue(field, value, u.userExists)
+		if err != nil {
+			return invalid("%s", err.Error())
+		}
+		normalized[key] = stored
+	}
+	task.CustomFields = nil
+	if len(normalized) > 0 {
+		task.CustomFields = normalized
+	}
+	return nil
+}
+
+func (u *taskUsecase) userExists(id primitive.ObjectID) error {
+	_, err := u.userRepo.FindByID(id)
+	return err
+}
+
 func (u *taskUsecase) Create(task Domain.Task) (Domain.Task, error) {
-	return u.taskRepo.Create(task)
+	if err := u.validateTask(&task); err != nil {
+		return Domain.Task{}, err
+	}
+	task.Track(nil, time.Now())
+	created, err := u.taskRepo.Create(task)
+	if err != nil {
+		return Domain.Task{}, err
+	}
+	u.publish(Domain.TaskCreated, created.ID, &created)
+	return created, nil
 }
 
 func (u *taskUsecase) GetAll() ([]Domain.Task, error) {
 	return u.taskRepo.FindAll()
 }
 
+// List returns the tasks matching filter. Custom field filter values arrive
+// as raw strings and are typed here using the field definitions.
+func (u *taskUsecase) List(filter Domain.TaskFilter) ([]Domain.Task, error) {
+	filter, err := u.prepareFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := u.taskRepo.Find(filter)
+	if err != nil {
+		return nil, err
+	}
+	if tasks == nil {
+		tasks = []Domain.Task{}
+	}
+	return tasks, nil
+}
+
+func (u *taskUsecase) ListPage(filter Domain.TaskFilter, page, pageSize int) ([]Domain.Task, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = Domain.DefaultTaskPageSize
+	}
+	if pageSize > Domain.MaxTaskPageSize {
+		pageSize = Domain.MaxTaskPageSize
+	}
+	filter, err := u.prepareFilter(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	return u.taskRepo.FindPage(filter, page, pageSize)
+}
+
+// prepareFilter converts custom field values to their stored types and
+// checks the sort fields
+func (u *taskUsecase) prepareFilter(filter Domain.TaskFilter) (Domain.TaskFilter, error) {
+	definitions, err := u.fieldRepo.FindAll()
+	if err != nil {
+		return filter, err
+	}
+	byKey := make(map[string]Domain.CustomField, len(definitions))
+	for _, field := range definitions {
+		byKey[field.Key] = field
+	}
+
+	typed := make(map[string]interface{}, len(filter.CustomFields))
+	for key, value := range filter.CustomFields {
+		field, ok := byKey[key]
+		if !ok {
+			return filter, fmt.Errorf("%w: unknown custom field %s", Domain.ErrInvalidTask, key)
+		}
+		stored, err := normalizeFieldValue(field, value, nil)
+		if err != nil {
+			return filter, fmt.Errorf("%w: %s", Domain.ErrInvalidTask, err.Error())
+		}
+		typed[key] = stored
+	}
+	filter.CustomFields = typed
+
+	for _, s := range filter.Sort {
+		if key, ok := strings.CutPrefix(s.Field, "cf."); ok {
+			if _, known := byKey[key]; !known {
+				return filter, fmt.Errorf("%w: unknown custom field %s", Domain.ErrInvalidTask, key)
+			}
+			continue
+		}
+		if !slices.Contains(Domain.SortableTaskFields, s.Field) {
+			return filter, fmt.Errorf("%w: cannot sort by %s", Domain.ErrInvalidTask, s.Field)
+		}
+	}
+
+	return filter, nil
+}
+
 func (u *taskUsecase) GetByID(id primitive.ObjectID) (Domain.Task, error) {
 	return u.taskRepo.FindByID(id)
 }
 
 func (u *taskUsecase) Update(id primitive.ObjectID, task Domain.Task) (Domain.Task, error) {
+	existing, err := u.taskRepo.FindByID(id)
+	if err != nil {
+		return Domain.Task{}, err
+	}
 	task.ID = id
-	return u.taskRepo.Update(task)
+	if err := u.validateTask(&task); err != nil {
+		return Domain.Task{}, err
+	}
+	task.Track(&existing, time.Now())
+
+	updated, err := u.taskRepo.Update(task)
+	if err != nil {
+		return Domain.Task{}, err
+	}
+	u.publish(Domain.TaskUpdated, updated.ID, &updated)
+	return updated, nil
 }
 
 func (u *taskUsecase) Delete(id primitive.ObjectID) error {
-	return u.taskRepo.Delete(id)
+	if err := u.taskRepo.Delete(id); err != nil {
+		return err
+	}
+	u.publish(Domain.TaskDeleted, id, nil)
+	u.purgeAttachments(id)
+	return nil
+}
+
+// MaxBulkOperations caps the size of a single bulk request
+const MaxBulkOperations = 100
+
+// Bulk validates every operation before writing anything. If any is invalid
+// the results describe the problems and Domain.ErrBulkInvalid is returned.
+func (u *taskUsecase) Bulk(ops []Domain.BulkOperation, atomic bool) ([]Domain.BulkResult, error) {
+	if len(ops) == 0 {
+		return nil, errors.New("no operations given")
+	}
+	if len(ops) > MaxBulkOperations {
+		return nil, Domain.ErrBulkTooLarge
+	}
+
+	results := make([]Domain.BulkResult, len(ops))
+	valid := true
+	for i := range ops {
+		results[i] = Domain.BulkResult{Index: i, Op: ops[i].Op, ID: ops[i].ID}
+		if err := u.validateBulkOperation(&ops[i]); err != nil {
+			results[i].Status = http.StatusUnprocessableEntity
+			results[i].Error = err.Error()
+			valid = false
+		}
+	}
+	if !valid {
+		return results, Domain.ErrBulkInvalid
+	}
+
+	results, err := u.taskRepo.BulkWrite(ops, atomic)
+	if err != nil {
+		return results, err
+	}
+
+	for i, result := range results {
+		if result.Status >= http.StatusBadRequest {
+			continue
+		}
+		switch ops[i].Op {
+		case Domain.BulkCreate:
+			u.publish(Domain.TaskCreated, result.Task.ID, result.Task)
+		case Domain.BulkUpdate, Domain.BulkTransition:
+			u.publish(Domain.TaskUpdated, result.Task.ID, result.Task)
+		case Domain.BulkDelete:
+			u.publish(Domain.TaskDeleted, ops[i].TaskID, nil)
+			u.purgeAttachments(ops[i].TaskID)
+		}
+	}
+	return results, nil
+}
+
+func (u *taskUsecase) validateBulkOperation(op *Domain.BulkOperation) error {
+	switch op.Op {
+	case Domain.BulkCreate:
+		if op.ID != "" {
+			return errors.New("create must not set id")
+		}
+	case Domain.BulkUpdate, Domain.BulkDelete, Domain.BulkTransition:
+		id, err := primitive.ObjectIDFromHex(op.ID)
+		if err != nil {
+			return errors.New("invalid task ID")
+		}
+		op.TaskID = id
+	default:
+		return errors.New("op must be one of create, update, delete, transition")
+	}
+
+	switch op.Op {
+	case Domain.BulkCreate, Domain.BulkUpdate:
+		if op.Task == nil {
+			return errors.New("task is required")
+		}
+		if op.Task.Title == "" {
+			return errors.New("task title is required")
+		}
+		if op.Task.Status != "" && !Domain.ValidStatus(op.Task.Status) {
+			return errors.New("invalid task status")
+		}
+		if err := u.validateTask(op.Task); err != nil {
+			return err
+		}
+	case Domain.BulkTransition:
+		if !Domain.ValidStatus(op.Status) {
+			return errors.New("status must be one of pending, in_progress, completed")
+		}
+	}
+	return nil
+}
+
+// Export streams every task the viewer can see through encoder
+func (u *taskUsecase) Export(viewer Domain.Viewer, encoder Infrastructure.TaskEncoder) error {
+	err := u.taskRepo.ForEach(func(task Domain.Task) error {
+		if !CanViewTask(viewer, task) {
+			return nil
+		}
+		return encoder.Encode(task)
+	})
+	if err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// Import validates and writes each record. Rows with an external_id are
+// upserted on it, the rest are created. With dryRun nothing is written and
+// the report says what would have happened.
+func (u *taskUsecase) Import(decoder Infrastructure.TaskDecoder, dryRun bool) (Domain.ImportReport, error) {
+	report := Domain.ImportReport{DryRun: dryRun, Rows: []Domain.ImportRowResult{}}
+	for {
+		record, err := decoder.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return report, err
+		}
+
+		row := Domain.ImportRowResult{Row: record.Row, ExternalID: record.Fields["external_id"]}
+		task, errs := taskFromRecord(record)
+		if len(errs) == 0 {
+			if err := u.validateTask(&task); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+		switch {
+		case len(errs) > 0:
+			row.Action = Domain.ImportFailed
+			row.Errors = errs
+		case dryRun:
+			row.Action = Domain.ImportCreated
+			if task.ExternalID != "" {
+				if existing, err := u.taskRepo.FindByExternalID(task.ExternalID); err == nil {
+					row.ID = existing.ID.Hex()
+					row.Action = Domain.ImportUpdated
+				}
+			}
+		default:
+			saved, created, err := u.saveImported(task)
+			if err != nil {
+				row.Action = Domain.ImportFailed
+				row.Errors = []string{err.Error()}
+				break
+			}
+			row.ID = saved.ID.Hex()
+			row.Action = Domain.ImportUpdated
+			if created {
+				row.Action = Domain.ImportCreated
+			}
+		}
+
+		report.Total++
+		switch row.Action {
+		case Domain.ImportCreated:
+			report.Created++
+		case Domain.ImportUpdated:
+			report.Updated++
+		case Domain.ImportFailed:
+			report.Failed++
+		}
+		report.Rows = append(report.Rows, row)
+	}
+	return report, nil
+}
+
+func (u *taskUsecase) saveImported(task Domain.Task) (Domain.Task, bool, error) {
+	if task.ExternalID == "" {
+		created, err := u.Create(task)
+		return created, true, err
+	}
+
+	var previous *Domain.Task
+	if existing, err := u.taskRepo.FindByExternalID(task.ExternalID); err == nil {
+		previous = &existing
+	}
+	task.Track(previous, time.Now())
+
+	saved, created, err := u.taskRepo.UpsertByExternalID(task)
+	if err != nil {
+		return Domain.Task{}, false, err
+	}
+	if created {
+		u.publish(Domain.TaskCreated, saved.ID, &saved)
+	} else {
+		u.publish(Domain.TaskUpdated, saved.ID, &saved)
+	}
+	return saved, created, nil
+}
+
+// taskFromRecord converts an import record into a task, collecting every
+// problem with the row rather than stopping at the first
+func taskFromRecord(record Domain.ImportRecord) (Domain.Task, []string) {
+	if record.Err != nil {
+		return Domain.Task{}, []string{record.Err.Error()}
+	}
+
+	fields := record.Fields
+	task := Domain.Task{
+		ExternalID:  strings.TrimSpace(fields["external_id"]),
+		Title:       strings.TrimSpace(fields["title"]),
+		Description: fields["description"],
+		Status:      strings.TrimSpace(fields["status"]),
+		Priority:    strings.TrimSpace(fields["priority"]),
+		Project:     strings.TrimSpace(fields["project"]),
+	}
+
+	var errs []string
+	if task.Title == "" {
+		errs = append(errs, "title is required")
+	}
+	if task.Status == "" {
+		task.Status = Domain.StatusPending
+	} else if !Domain.ValidStatus(task.Status) {
+		errs = append(errs, fmt.Sprintf("invalid status %q", task.Status))
+	}
+	if raw := strings.TrimSpace(fields["due_date"]); raw != "" {
+		due, err := parseImportDate(raw)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("invalid due_date %q, expected RFC 3339 or YYYY-MM-DD", raw))
+		}
+		task.DueDate = due
+	}
+	for _, raw := range strings.Split(fields["labels"], ",") {
+		if raw = strings.TrimSpace(raw); raw == "" {
+			continue
+		}
+		id, err := primitive.ObjectIDFromHex(raw)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("invalid label %q, expected a label ID", raw))
+			continue
+		}
+		task.Labels = append(task.Labels, id)
+	}
+	if raw := strings.TrimSpace(fields["custom_fields"]); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &task.CustomFields); err != nil {
+			errs = append(errs, "custom_fields must be a JSON object")
+		}
+	}
+	return task, errs
+}
+
+func parseImportDate(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", raw)
+}
+
+func (u *taskUsecase) Search(viewer Domain.Viewer, q string, limit int) ([]Domain.TaskSearchResult, error) {
+	query := Repositories.ParseSearchQuery(q)
+	if query.IsEmpty() {
+		return nil, errors.New("search query is required")
+	}
+
+	results, err := u.taskRepo.Search(query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := []Domain.TaskSearchResult{}
+	for _, result := range results {
+		if CanViewTask(viewer, result.Task) {
+			visible = append(visible, result)
+		}
+	}
+	return visible, nil
+}
+
+func (u *taskUsecase) Subscribe(ctx context.Context, viewer Domain.Viewer, lastEventID string) (<-chan Domain.TaskEvent, error) {
+	events, err := u.eventBus.Subscribe(ctx, lastEventID)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := make(chan Domain.TaskEvent)
+	go func() {
+		defer close(visible)
+		for event := range events {
+			task := Domain.Task{ID: event.TaskID}
+			if event.Task != nil {
+				task = *event.Task
+			}
+			if event.Type != Domain.TaskEventsReset && !CanViewTask(viewer, task) {
+				continue
+			}
+			select {
+			case visible <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return visible, nil
+}
+
+// purgeAttachments removes the files of a deleted task. The task is gone
+// either way, so a leftover blob is logged rather than reported as a failed
+// delete.
+func (u *taskUsecase) purgeAttachments(id primitive.ObjectID) {
+	if u.attachments == nil {
+		return
+	}
+	if err := u.attachments.PurgeTask(id); err != nil {
+		log.Printf("Could not purge attachments of task %s: %v", id.Hex(), err)
+	}
+}
+
+// publish is best effort: the write already succeeded, so a bus failure must
+// not turn it into an error for the caller.
+func (u *taskUsecase) publish(eventType string, id primitive.ObjectID, task *Domain.Task) {
+	if u.eventBus == nil {
+		return
+	}
+	u.eventBus.Publish(Domain.TaskEvent{Type: eventType, TaskID: id, Task: task})
 }
@@ -0,0 +1,59 @@
+package Usecases
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DefaultInvitationTTL = 72 * time.Hour
+	MaxInvitationTTL     = 30 * 24 * time.Hour
+)
+
+func (u *userUsecase) CreateInvitation(actorID primitive.ObjectID, role, email string, ttl time.Duration) (Domain.Invitation, string, error) {
+	if role == "" {
+		role = Domain.RoleUser
+	}
+	if !Domain.ValidRole(role) {
+		return Domain.Invitation{}, "", Domain.ErrInvalidRole
+	}
+	email, err := checkEmail(email)
+	if err != nil {
+		return Domain.Invitation{}, "", err
+	}
+	if ttl <= 0 {
+		ttl = DefaultInvitationTTL
+	}
+	if ttl > MaxInvitationTTL {
+		return Domain.Invitation{}, "", errors.New("invitations can last at most 30 days")
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return Domain.Invitation{}, "", err
+	}
+	now := time.Now().UTC()
+	invitation, err := u.invitationRepo.Create(Domain.Invitation{
+		TokenHash: hashToken(token),
+		Role:      role,
+		Email:     email,
+		CreatedBy: actorID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	})
+	if err != nil {
+		return Domain.Invitation{}, "", err
+	}
+	return invitation, token, nil
+}
+
+func (u *userUsecase) ListInvitations() ([]Domain.Invitation, error) {
+	return u.invitationRepo.List()
+}
+
+func (u *userUsecase) RevokeInvitation(id primitive.ObjectID) error {
+	return u.invitationRepo.Delete(id)
+}
@@ -5,78 +5,213 @@ import (
 	"a2sv-backend/task_manager_v3/Infrastructure"
 	"a2sv-backend/task_manager_v3/Repositories"
 	"errors"
+	"log"
+	"net/mail"
 	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type UserUsecase interface {
-	Register(user Domain.User) error
-	Login(username, password string) (string, Domain.User, error)
+	// Register creates a regular user, or one with the invitation's role
+	// when reg carries an invitation token
+	Register(reg Domain.Registration) error
+	// Login checks the password. The result carries a pending token instead
+	// of an access token when the account needs a second factor.
+	Login(username, password string, client Domain.ClientInfo) (Domain.LoginResult, error)
 	Promote(userID primitive.ObjectID) error
+	ListUsers(query Domain.UserQuery) ([]Domain.User, int64, error)
+	GetUser(id primitive.ObjectID) (Domain.User, error)
+	// GetUsers loads many users in one go, leaving out IDs with no user
+	GetUsers(ids []primitive.ObjectID) ([]Domain.User, error)
+	// ChangeRole, Deactivate, Reactivate and Delete are admin actions taken
+	// by actorID; admins cannot apply them to themselves
+	ChangeRole(actorID, id primitive.ObjectID, role string) (Domain.User, error)
+	Deactivate(actorID, id primitive.ObjectID) (Domain.User, error)
+	Reactivate(actorID, id primitive.ObjectID) (Domain.User, error)
+	Delete(actorID, id primitive.ObjectID) error
+	UpdateProfile(id primitive.ObjectID, update Domain.ProfileUpdate) (Domain.User, error)
+	// ChangePassword also ends the user's other sessions, keeping
+	// sessionID, the one the change was made from
+	ChangePassword(id primitive.ObjectID, sessionID, current, next string) error
+	// ActiveAccount loads the user behind a token, failing if the account
+	// was deleted or deactivated since the token was issued
+	ActiveAccount(userID string) (Domain.User, error)
+	// BootstrapAdmin creates the initial admin. It only ever succeeds once
+	// and fails with Domain.ErrAlreadyBootstrapped afterwards.
+	BootstrapAdmin(username, password string) (Domain.User, error)
+	// CreateInvitation returns the invitation and its token, which is not
+	// stored and cannot be retrieved again
+	CreateInvitation(actorID primitive.ObjectID, role, email string, ttl time.Duration) (Domain.Invitation, string, error)
+	ListInvitations() ([]Domain.Invitation, error)
+	RevokeInvitation(id primitive.ObjectID) error
 }
 
 type userUsecase struct {
 	userRepo        Repositories.UserRepository
+	invitationRepo  Repositories.InvitationRepository
 	passwordService Infrastructure.PasswordService
 	jwtService      Infrastructure.JWTService
+	registration    Domain.RegistrationPolicy
+	// accounts sends verification mail for new and changed addresses
+	accounts AccountUsecase
+	// mfa decides whether logins need a second factor
+	mfa MFAUsecase
+	// sessions records logins, when they're tracked
+	sessions SessionUsecase
 }
 
-func NewUserUsecase(userRepo Repositories.UserRepository, passwordService Infrastructure.PasswordService, jwtService Infrastructure.JWTService) UserUsecase {
+// NewUserUsecase builds the user usecase. An empty registration mode means
+// invite-only; without mfa every login is password-only, and without
+// sessions tokens aren't bound to a session.
+func NewUserUsecase(userRepo Repositories.UserRepository, invitationRepo Repositories.InvitationRepository, passwordService Infrastructure.PasswordService, jwtService Infrastructure.JWTService, registration Domain.RegistrationPolicy, accounts AccountUsecase, mfa MFAUsecase, sessions SessionUsecase) UserUsecase {
+	if registration.Mode == "" {
+		registration.Mode = Domain.RegistrationInviteOnly
+	}
 	return &userUsecase{
 		userRepo:        userRepo,
+		invitationRepo:  invitationRepo,
 		passwordService: passwordService,
 		jwtService:      jwtService,
+		registration:    registration,
+		accounts:        accounts,
+		mfa:             mfa,
+		sessions:        sessions,
 	}
 }
 
-func (u *userUsecase) Register(user Domain.User) error {
-	// Check if username exists
-	existingUser, err := u.userRepo.FindByUsername(user.Username)
-	if err == nil && existingUser.Username != "" {
-		return errors.New("username already exists")
+// checkNewAccount validates the username, email and password of an account
+// about to be created and returns the cleaned username and email
+func checkNewAccount(username, email, password string) (string, string, error) {
+	username = strings.TrimSpace(username)
+	if Domain.NormalizeUsername(username) == "" {
+		return "", "", Domain.ErrUsernameRequired
+	}
+	email, err := checkEmail(email)
+	if err != nil {
+		return "", "", err
 	}
+	if err := checkPassword(password); err != nil {
+		return "", "", err
+	}
+	return username, email, nil
+}
 
-	// Hash password
-	hashedPassword, err := u.passwordService.HashPassword(user.Password)
+// checkEmail normalizes an optional email address and rejects malformed
+// ones. Only a bare address is accepted, not "Name <address>".
+func checkEmail(email string) (string, error) {
+	email = Domain.NormalizeEmail(email)
+	if email == "" {
+		return "", nil
+	}
+	if parsed, err := mail.ParseAddress(email); err != nil || parsed.Address != email {
+		return "", Domain.ErrInvalidEmail
+	}
+	return email, nil
+}
+
+// invitationFor looks up a usable invitation for token and email
+func (u *userUsecase) invitationFor(token, email string, now time.Time) (Domain.Invitation, error) {
+	invitation, err := u.invitationRepo.FindByTokenHash(hashToken(token))
 	if err != nil {
-		return err
+		return Domain.Invitation{}, Domain.ErrInvalidInvitation
+	}
+	if invitation.UsedAt != nil || !invitation.ExpiresAt.After(now) {
+		return Domain.Invitation{}, Domain.ErrInvalidInvitation
 	}
-	user.Password = hashedPassword
+	if invitation.Email != "" && !strings.EqualFold(invitation.Email, email) {
+		return Domain.Invitation{}, Domain.ErrInvalidInvitation
+	}
+	return invitation, nil
+}
 
-	// Check if it's the first user or admin prefix
-	count, err := u.userRepo.Count()
+// Register redeems the invitation before creating the user so a token can
+// only ever be spent once; if the user can't be created it is released.
+func (u *userUsecase) Register(reg Domain.Registration) error {
+	username, email, err := checkNewAccount(reg.Username, reg.Email, reg.Password)
 	if err != nil {
 		return err
 	}
 
-	if count == 0 || strings.HasPrefix(user.Username, "admin_") {
-		user.Role = "admin"
+	now := time.Now().UTC()
+	user := Domain.User{ID: primitive.NewObjectID(), Username: username, Email: email, Role: Domain.RoleUser}
+
+	var invitation Domain.Invitation
+	if reg.Invitation != "" {
+		if invitation, err = u.invitationFor(reg.Invitation, email, now); err != nil {
+			return err
+		}
+		user.Role = invitation.Role
 	} else {
-		user.Role = "user"
+		switch u.registration.Mode {
+		case Domain.RegistrationOpen:
+		case Domain.RegistrationDomain:
+			if !u.registration.AllowsEmail(email) {
+				return Domain.ErrEmailDomainNotAllowed
+			}
+			// The domain only vouches for people who can read mail there
+			user.VerificationRequired = true
+		default:
+			return Domain.ErrRegistrationClosed
+		}
 	}
 
-	_, err = u.userRepo.Create(user)
-	return err
+	// Hash password
+	if user.Password, err = u.passwordService.HashPassword(reg.Password); err != nil {
+		return err
+	}
+
+	if reg.Invitation != "" {
+		if err := u.invitationRepo.Redeem(invitation.ID, user.ID, now); err != nil {
+			return err
+		}
+	}
+	if _, err := u.userRepo.Create(user); err != nil {
+		if reg.Invitation != "" {
+			u.invitationRepo.Release(invitation.ID)
+		}
+		return err
+	}
+	// Unless verification is required the account is usable either way;
+	// the user can ask for another mail
+	u.sendVerification(user)
+	return nil
 }
 
-func (u *userUsecase) Login(username, password string) (string, Domain.User, error) {
+// sendVerification mails a verification link to user's address, if any
+func (u *userUsecase) sendVerification(user Domain.User) {
+	if u.accounts != nil && user.Email != "" {
+		u.accounts.SendVerification(user.ID)
+	}
+}
+
+func (u *userUsecase) Login(username, password string, client Domain.ClientInfo) (Domain.LoginResult, error) {
 	user, err := u.userRepo.FindByUsername(username)
 	if err != nil {
-		return "", Domain.User{}, errors.New("invalid credentials")
+		return Domain.LoginResult{}, errors.New("invalid credentials")
 	}
 
 	err = u.passwordService.ComparePassword(user.Password, password)
 	if err != nil {
-		return "", Domain.User{}, errors.New("invalid credentials")
+		return Domain.LoginResult{}, errors.New("invalid credentials")
+	}
+	if user.Deactivated {
+		return Domain.LoginResult{}, Domain.ErrUserDeactivated
+	}
+	if user.VerificationRequired && !user.EmailVerified {
+		return Domain.LoginResult{}, Domain.ErrEmailNotVerified
+	}
+	if u.mfa != nil {
+		return u.mfa.StartLogin(user, client)
 	}
 
-	token, err := u.jwtService.GenerateToken(user)
+	token, err := issueToken(u.jwtService, u.sessions, user, client)
 	if err != nil {
-		return "", Domain.User{}, err
+		return Domain.LoginResult{}, err
 	}
 
-	return token, user, nil
+	return Domain.LoginResult{Token: token, User: user}, nil
 }
 
 func (u *userUsecase) Promote(userID primitive.ObjectID) error {
@@ -85,7 +220,285 @@ func (u *userUsecase) Promote(userID primitive.ObjectID) error {
 		return err
 	}
 
-	user.Role = "admin"
+	user.Role = Domain.RoleAdmin
 	_, err = u.userRepo.Update(user)
 	return err
 }
+
+func (u *userUsecase) ListUsers(query Domain.UserQuery) ([]Domain.User, int64, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize < 1 {
+		query.PageSize = Domain.DefaultUserPageSize
+	}
+	if query.PageSize > Domain.MaxUserPageSize {
+		query.PageSize = Domain.MaxUserPageSize
+	}
+	if query.Role != "" && !Domain.ValidRole(query.Role) {
+		return nil, 0, Domain.ErrInvalidRole
+	}
+	return u.userRepo.List(query)
+}
+
+func (u *userUsecase) GetUser(id primitive.ObjectID) (Domain.User, error) {
+	return u.userRepo.FindByID(id)
+}
+
+func (u *userUsecase) GetUsers(ids []primitive.ObjectID) ([]Domain.User, error) {
+	if len(ids) == 0 {
+		return []Domain.User{}, nil
+	}
+	return u.userRepo.FindByIDs(ids)
+}
+
+// manage loads the target of an admin action, refusing self-management
+func (u *userUsecase) manage(actorID, id primitive.ObjectID) (Domain.User, error) {
+	if actorID == id {
+		return Domain.User{}, Domain.ErrSelfManagement
+	}
+	return u.userRepo.FindByID(id)
+}
+
+// keepsAnAdmin fails if taking user out of the active admins would leave
+// none behind
+func keepsAnAdmin(userRepo Repositories.UserRepository, user Domain.User) error {
+	if user.Role != Domain.RoleAdmin || user.Deactivated {
+		return nil
+	}
+	admins, err := userRepo.CountActiveAdmins()
+	if err != nil {
+		return err
+	}
+	if admins <= 1 {
+		return Domain.ErrLastAdmin
+	}
+	return nil
+}
+
+// updateKeepingAnAdmin saves a user the admin guard has to vouch for. The
+// repository checks and writes atomically; standalone Mongo servers can't,
+// so there it falls back to checking first.
+func updateKeepingAnAdmin(userRepo Repositories.UserRepository, before, after Domain.User) (Domain.User, error) {
+	updated, err := userRepo.UpdateKeepingAnAdmin(after)
+	if !errors.Is(err, Domain.ErrTransactionsUnsupported) {
+		return updated, err
+	}
+	if err := keepsAnAdmin(userRepo, before); err != nil {
+		return Domain.User{}, err
+	}
+	return userRepo.Update(after)
+}
+
+func (u *userUsecase) ChangeRole(actorID, id primitive.ObjectID, role string) (Domain.User, error) {
+	if !Domain.ValidRole(role) {
+		return Domain.User{}, Domain.ErrInvalidRole
+	}
+	user, err := u.manage(actorID, id)
+	if err != nil {
+		return Domain.User{}, err
+	}
+	if role == user.Role {
+		return user, nil
+	}
+
+	changed := user
+	changed.Role = role
+	return updateKeepingAnAdmin(u.userRepo, user, changed)
+}
+
+func (u *userUsecase) Deactivate(actorID, id primitive.ObjectID) (Domain.User, error) {
+	user, err := u.manage(actorID, id)
+	if err != nil {
+		return Domain.User{}, err
+	}
+	if user.Deactivated {
+		return user, nil
+	}
+
+	changed := user
+	changed.Deactivated = true
+	return updateKeepingAnAdmin(u.userRepo, user, changed)
+}
+
+func (u *userUsecase) Reactivate(actorID, id primitive.ObjectID) (Domain.User, error) {
+	user, err := u.manage(actorID, id)
+	if err != nil {
+		return Domain.User{}, err
+	}
+	if !user.Deactivated {
+		return user, nil
+	}
+
+	user.Deactivated = false
+	return u.userRepo.Update(user)
+}
+
+func (u *userUsecase) Delete(actorID, id primitive.ObjectID) error {
+	user, err := u.manage(actorID, id)
+	if err != nil {
+		return err
+	}
+	err = u.userRepo.DeleteKeepingAnAdmin(id)
+	if !errors.Is(err, Domain.ErrTransactionsUnsupported) {
+		return err
+	}
+	if err := keepsAnAdmin(u.userRepo, user); err != nil {
+		return err
+	}
+	return u.userRepo.Delete(id)
+}
+
+func (u *userUsecase) UpdateProfile(id primitive.ObjectID, update Domain.ProfileUpdate) (Domain.User, error) {
+	user, err := u.userRepo.FindByID(id)
+	if err != nil {
+		return Domain.User{}, err
+	}
+
+	if update.Username != nil {
+		username := strings.TrimSpace(*update.Username)
+		if Domain.NormalizeUsername(username) == "" {
+			return Domain.User{}, Domain.ErrUsernameRequired
+		}
+		user.Username = username
+	}
+	if update.DisplayName != nil {
+		user.DisplayName = strings.TrimSpace(*update.DisplayName)
+	}
+	emailChanged := false
+	if update.Email != nil {
+		email, err := checkEmail(*update.Email)
+		if err != nil {
+			return Domain.User{}, err
+		}
+		if email != user.Email {
+			user.Email = email
+			user.EmailVerified = false
+			emailChanged = true
+		}
+	}
+
+	updated, err := u.userRepo.Update(user)
+	if err != nil {
+		return Domain.User{}, err
+	}
+	if emailChanged {
+		u.sendVerification(updated)
+	}
+	return updated, nil
+}
+
+// MinPasswordLength applies to password changes
+const MinPasswordLength = 8
+
+// MaxPasswordBytes is the most bcrypt hashes; it refuses longer passwords
+const MaxPasswordBytes = 72
+
+// checkPassword applies the length rules to a new password
+func checkPassword(password string) error {
+	if len(password) < MinPasswordLength {
+		return Domain.ErrWeakPassword
+	}
+	if len(password) > MaxPasswordBytes {
+		return Domain.ErrPasswordTooLong
+	}
+	return nil
+}
+
+func (u *userUsecase) ChangePassword(id primitive.ObjectID, sessionID, current, next string) error {
+	user, err := u.userRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if err := u.passwordService.ComparePassword(user.Password, current); err != nil {
+		return Domain.ErrWrongPassword
+	}
+	if err := checkPassword(next); err != nil {
+		return err
+	}
+
+	hashed, err := u.passwordService.HashPassword(next)
+	if err != nil {
+		return err
+	}
+	user.Password = hashed
+	if _, err := u.userRepo.Update(user); err != nil {
+		return err
+	}
+	if u.sessions != nil {
+		if _, err := u.sessions.RevokeOthers(user.ID, sessionID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *userUsecase) ActiveAccount(userID string) (Domain.User, error) {
+	id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return Domain.User{}, Domain.ErrUserNotFound
+	}
+	user, err := u.userRepo.FindByID(id)
+	if err != nil {
+		return Domain.User{}, err
+	}
+	if user.Deactivated {
+		return Domain.User{}, Domain.ErrUserDeactivated
+	}
+	// Tokens issued before 2FA became mandatory for the role stop working
+	if u.mfa != nil {
+		if err := u.mfa.CheckPolicy(user); err != nil {
+			return Domain.User{}, err
+		}
+	}
+	return user, nil
+}
+
+func (u *userUsecase) BootstrapAdmin(username, password string) (Domain.User, error) {
+	username, _, err := checkNewAccount(username, "", password)
+	if err != nil {
+		return Domain.User{}, err
+	}
+	if err := u.checkNoAdmin(); err != nil {
+		return Domain.User{}, err
+	}
+
+	hashed, err := u.passwordService.HashPassword(password)
+	if err != nil {
+		return Domain.User{}, err
+	}
+	user := Domain.User{ID: primitive.NewObjectID(), Username: username, Password: hashed, Role: Domain.RoleAdmin}
+
+	// The claim makes concurrent bootstraps (say, several replicas starting
+	// with the same config) run one at a time, and whoever holds it counts
+	// again, so they create a single admin between them
+	claimed, err := u.userRepo.ClaimFirstAdmin(user.ID)
+	if err != nil {
+		return Domain.User{}, err
+	}
+	if !claimed {
+		return Domain.User{}, Domain.ErrAlreadyBootstrapped
+	}
+	defer func() {
+		if err := u.userRepo.ReleaseFirstAdmin(user.ID); err != nil {
+			log.Printf("Could not release the first-admin claim: %v", err)
+		}
+	}()
+	if err := u.checkNoAdmin(); err != nil {
+		return Domain.User{}, err
+	}
+	return u.userRepo.Create(user)
+}
+
+// checkNoAdmin fails with Domain.ErrAlreadyBootstrapped once there is an
+// active admin
+func (u *userUsecase) checkNoAdmin() error {
+	admins, err := u.userRepo.CountActiveAdmins()
+	if err != nil {
+		return err
+	}
+	if admins > 0 {
+		return Domain.ErrAlreadyBootstrapped
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// RefreshMargin is how long before it expires a token is replaced
+const RefreshMargin = time.Minute
+
+func (c *Client) hasCredentials() bool {
+	return c.username != ""
+}
+
+// authorize returns the token for the next request. With credentials, it
+// logs in first when there is no token yet or it is about to expire.
+func (c *Client) authorize(ctx context.Context) (string, error) {
+	token := c.Token()
+	if !c.hasCredentials() || token != "" && !expiresSoon(token, time.Now()) {
+		return token, nil
+	}
+	return c.refresh(ctx, token)
+}
+
+// refresh logs in again, unless another request already replaced the stale
+// token while this one waited
+func (c *Client) refresh(ctx context.Context, stale string) (string, error) {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	if token := c.Token(); token != "" && token != stale && !expiresSoon(token, time.Now()) {
+		return token, nil
+	}
+
+	result, err := c.Users.Login(ctx, c.username, c.password)
+	if err != nil {
+		return "", err
+	}
+	if result.MFAPending {
+		return "", ErrMFARequired
+	}
+	return result.Token, nil
+}
+
+// expiresSoon reads the exp claim of a JWT, without checking the signature
+// since only the server can. Tokens that aren't JWTs, like access tokens,
+// are never refreshed ahead of time.
+func expiresSoon(token string, now time.Time) bool {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return false
+	}
+	return now.Add(RefreshMargin).After(time.Unix(claims.Exp, 0))
+}
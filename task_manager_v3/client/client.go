@@ -0,0 +1,262 @@
+// Package client is a Go SDK for the task_manager_v3 HTTP API. It talks to
+// API v2 and hands back the same Domain types the usecases work with, so
+// callers don't have to write their own requests against /tasks and /login.
+//
+// Given credentials, a client logs in by itself and logs in again before
+// its token expires or when the server rejects it. Idempotent requests are
+// retried with exponential backoff when the server is unavailable or rate
+// limits them. Failures are *Error, which errors.Is matches against
+// ErrNotFound and the other sentinels. Paged lists can be walked with
+// TaskService.Iterate and UserService.Iterate.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIPrefix is where the version of the API the client speaks is mounted
+const APIPrefix = "/api/v2"
+
+// Client calls one task_manager_v3 server. It is safe for concurrent use.
+type Client struct {
+	// Tasks mirrors Usecases.TaskUsecase
+	Tasks *TaskService
+	// Users mirrors Usecases.UserUsecase
+	Users *UserService
+
+	baseURL    string
+	httpClient *http.Client
+	retry      RetryPolicy
+
+	mu    sync.Mutex
+	token string
+
+	// username and password, when set, are used to get new tokens.
+	// refreshMu makes concurrent requests wait for a single login.
+	username, password string
+	refreshMu          sync.Mutex
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sends requests through httpClient instead of
+// http.DefaultClient
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithToken authenticates requests with token, a JWT or a personal access
+// token
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithCredentials has the client log in as username on its first request,
+// and again whenever its token is about to expire or gets rejected.
+// Accounts with two-factor authentication can't log in this way; use an
+// access token instead.
+func WithCredentials(username, password string) Option {
+	return func(c *Client) {
+		c.username, c.password = username, password
+	}
+}
+
+// WithRetry replaces DefaultRetryPolicy
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// New returns a client for the server at baseURL, like
+// "https://tasks.example.com"
+func New(baseURL string, options ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		retry:      DefaultRetryPolicy,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	c.Tasks = &TaskService{client: c}
+	c.Users = &UserService{client: c}
+	return c
+}
+
+// Token is the token requests are sent with, which Login replaces
+func (c *Client) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+// SetToken changes the token requests are sent with
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+}
+
+// request is one call to the API
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	header http.Header
+	body   []byte
+	// contentType defaults to JSON when there is a body
+	contentType string
+	// anonymous requests are sent without a token, and a 401 doesn't
+	// trigger a new login
+	anonymous bool
+}
+
+// jsonRequest returns a request with body encoded as JSON, when not nil
+func jsonRequest(method, path string, query url.Values, body interface{}) (request, error) {
+	req := request{method: method, path: path, query: query}
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return req, err
+		}
+		req.body = data
+	}
+	return req, nil
+}
+
+// do sends a JSON request and decodes the JSON response into out, which may
+// be nil
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	req, err := jsonRequest(method, path, query, body)
+	if err != nil {
+		return err
+	}
+	return c.doRequest(ctx, req, out)
+}
+
+func (c *Client) doRequest(ctx context.Context, req request, out interface{}) error {
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding %s %s response: %w", req.method, req.path, err)
+	}
+	return nil
+}
+
+// send makes the request, retrying it as the retry policy allows and
+// logging in again once if the token is rejected. It returns 2xx responses
+// for the caller to read and close, and *Error for the rest.
+func (c *Client) send(ctx context.Context, req request) (*http.Response, error) {
+	resp, token, err := c.sendWithRetries(ctx, req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || req.anonymous || !c.hasCredentials() {
+		return c.checkStatus(resp, err)
+	}
+
+	// The token was revoked or expired early; log in and try once more
+	resp.Body.Close()
+	if _, err := c.refresh(ctx, token); err != nil {
+		return nil, err
+	}
+	resp, _, err = c.sendWithRetries(ctx, req)
+	return c.checkStatus(resp, err)
+}
+
+func (c *Client) checkStatus(resp *http.Response, err error) (*http.Response, error) {
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		return nil, decodeError(resp)
+	}
+	return resp, nil
+}
+
+// sendWithRetries makes attempts until one gets a response worth
+// returning. It also returns the token that response was sent with.
+func (c *Client) sendWithRetries(ctx context.Context, req request) (*http.Response, string, error) {
+	for attempt := 1; ; attempt++ {
+		resp, token, err := c.attempt(ctx, req)
+		if attempt >= c.retry.MaxAttempts || !idempotent(req.method) || !retryable(ctx, resp, err) {
+			return resp, token, err
+		}
+
+		wait := c.retry.backoff(attempt)
+		if resp != nil {
+			if after := retryAfter(resp); after > wait {
+				wait = min(after, c.retry.MaxDelay)
+			}
+			resp.Body.Close()
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, "", ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt sends the request once
+func (c *Client) attempt(ctx context.Context, req request) (*http.Response, string, error) {
+	endpoint := c.baseURL + APIPrefix + req.path
+	if len(req.query) > 0 {
+		endpoint += "?" + req.query.Encode()
+	}
+	var body io.Reader
+	if req.body != nil {
+		body = bytes.NewReader(req.body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, endpoint, body)
+	if err != nil {
+		return nil, "", err
+	}
+	for key, values := range req.header {
+		httpReq.Header[key] = values
+	}
+	if httpReq.Header.Get("Accept") == "" {
+		httpReq.Header.Set("Accept", "application/json")
+	}
+	if req.body != nil {
+		contentType := req.contentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+
+	var token string
+	if !req.anonymous {
+		if token, err = c.authorize(ctx); err != nil {
+			return nil, "", err
+		}
+		if token != "" {
+			httpReq.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	return resp, token, err
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Sentinels an *Error matches with errors.Is, by status code
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrServer       = errors.New("server error")
+)
+
+// ErrMFARequired is returned when logging in with WithCredentials needs a
+// second factor
+var ErrMFARequired = errors.New("the account needs a second factor; use an access token instead")
+
+// FieldError is one reason the server rejected a request body
+type FieldError struct {
+	// Field is the JSON path of the offending value
+	Field string `json:"field"`
+	// Code is the rule that failed, like "required" or "max"
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error is a response the server answered with a status other than 2xx
+type Error struct {
+	StatusCode int
+	// Message is the server's "error" field, or the status text when the
+	// body had none
+	Message string
+	// Fields lists what was wrong with the request body, when the server
+	// said
+	Fields []FieldError
+	// RetryAfter is how long the server asked to wait before trying again
+	RetryAfter time.Duration
+	// Body is the raw response body, for the details particular to a route
+	Body []byte
+}
+
+func (e *Error) Error() string {
+	message := fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+	for _, field := range e.Fields {
+		message += "; " + field.Message
+	}
+	return message
+}
+
+// Is matches the sentinel for the status code
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// decodeError reads the error body the API sends with failures
+func decodeError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	var body struct {
+		Error  string       `json:"error"`
+		Errors []FieldError `json:"errors"`
+	}
+	json.Unmarshal(data, &body)
+	if body.Error == "" {
+		body.Error = http.StatusText(resp.StatusCode)
+	}
+	return &Error{
+		StatusCode: resp.StatusCode,
+		Message:    body.Error,
+		Fields:     body.Errors,
+		RetryAfter: retryAfter(resp),
+		Body:       data,
+	}
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"context"
+)
+
+// TaskIterator walks every task matching a filter, fetching a page at a
+// time as it goes:
+//
+//	it := c.Tasks.Iterate(filter, 50)
+//	for it.Next(ctx) {
+//		task := it.Task()
+//	}
+//	if err := it.Err(); err != nil {
+//
+// Pages are fetched by number, so tasks created or deleted meanwhile can
+// shift later pages and make the walk skip or repeat a task.
+type TaskIterator struct {
+	service  *TaskService
+	filter   Domain.TaskFilter
+	pageSize int
+
+	page    int
+	buffer  []Domain.Task
+	current Domain.Task
+	total   int64
+	seen    int64
+	done    bool
+	err     error
+}
+
+// Iterate returns an iterator over the tasks matching filter, fetched
+// pageSize at a time. A pageSize of 0 takes the largest page the server
+// allows.
+func (s *TaskService) Iterate(filter Domain.TaskFilter, pageSize int) *TaskIterator {
+	if pageSize <= 0 {
+		pageSize = Domain.MaxTaskPageSize
+	}
+	return &TaskIterator{service: s, filter: filter, pageSize: pageSize}
+}
+
+// Next moves to the next task, fetching the next page if needed. It
+// returns false once there are no more tasks or a fetch failed.
+func (it *TaskIterator) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+	if len(it.buffer) == 0 {
+		if it.done {
+			return false
+		}
+		it.page++
+		tasks, total, err := it.service.ListPage(ctx, it.filter, it.page, it.pageSize)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.buffer, it.total = tasks, total
+		it.done = len(tasks) < it.pageSize || it.seen+int64(len(tasks)) >= total
+		if len(it.buffer) == 0 {
+			return false
+		}
+	}
+	it.current, it.buffer = it.buffer[0], it.buffer[1:]
+	it.seen++
+	return true
+}
+
+// Task is the task Next moved to
+func (it *TaskIterator) Task() Domain.Task {
+	return it.current
+}
+
+// Total is how many tasks matched when the last page was fetched
+func (it *TaskIterator) Total() int64 {
+	return it.total
+}
+
+// Err is the error that ended the walk early, if any
+func (it *TaskIterator) Err() error {
+	return it.err
+}
+
+// UserIterator is TaskIterator for users
+type UserIterator struct {
+	service *UserService
+	query   Domain.UserQuery
+
+	buffer  []Domain.User
+	current Domain.User
+	total   int64
+	seen    int64
+	done    bool
+	err     error
+}
+
+// Iterate returns an iterator over the users matching query, starting at
+// query.Page (or the first page) and fetching query.PageSize users at a
+// time (or the largest page the server allows)
+func (s *UserService) Iterate(query Domain.UserQuery) *UserIterator {
+	if query.PageSize <= 0 {
+		query.PageSize = Domain.MaxUserPageSize
+	}
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	query.Page--
+	return &UserIterator{service: s, query: query}
+}
+
+// Next moves to the next user, fetching the next page if needed
+func (it *UserIterator) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+	if len(it.buffer) == 0 {
+		if it.done {
+			return false
+		}
+		it.query.Page++
+		users, total, err := it.service.ListUsers(ctx, it.query)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.buffer, it.total = users, total
+		it.done = len(users) < it.query.PageSize || it.seen+int64(len(users)) >= total
+		if len(it.buffer) == 0 {
+			return false
+		}
+	}
+	it.current, it.buffer = it.buffer[0], it.buffer[1:]
+	it.seen++
+	return true
+}
+
+// User is the user Next moved to
+func (it *UserIterator) User() Domain.User {
+	return it.current
+}
+
+// Total is how many users matched when the last page was fetched
+func (it *UserIterator) Total() int64 {
+	return it.total
+}
+
+// Err is the error that ended the walk early, if any
+func (it *UserIterator) Err() error {
+	return it.err
+}
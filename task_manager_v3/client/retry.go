@@ -0,0 +1,72 @@
+package client
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy says how idempotent requests (GET, HEAD, PUT and DELETE) are
+// retried after network errors and 429, 502, 503 and 504 responses. Waits
+// grow exponentially from BaseDelay, with full jitter, up to MaxDelay; a
+// longer Retry-After from the server is honored up to MaxDelay too.
+type RetryPolicy struct {
+	// MaxAttempts counts the first attempt; 1 turns retries off
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 4, BaseDelay: 100 * time.Millisecond, MaxDelay: 5 * time.Second}
+
+// backoff is how long to wait after the given failed attempt
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	ceiling := p.MaxDelay
+	if attempt < 32 {
+		if grown := p.BaseDelay << (attempt - 1); grown > 0 && grown < ceiling {
+			ceiling = grown
+		}
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(ceiling))) + 1
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// retryable tells whether a failed attempt may succeed if made again
+func retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		// Canceled or timed out by the caller, not the network
+		return ctx.Err() == nil
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter reads the Retry-After header, given in seconds or as a date
+func retryAfter(resp *http.Response) time.Duration {
+	raw := resp.Header.Get("Retry-After")
+	if raw == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(raw); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(raw); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
@@ -0,0 +1,120 @@
+package client
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Subscribe streams task events from GET /tasks/stream, starting after
+// lastEventID when it is set. When the connection drops the client
+// reconnects with the ID of the last event it saw, so no event is missed as
+// long as the server still has it. The channel is closed when ctx is done,
+// or when the server refuses to reconnect or stays unreachable for as many
+// attempts as the retry policy allows.
+func (s *TaskService) Subscribe(ctx context.Context, lastEventID string) (<-chan Domain.TaskEvent, error) {
+	resp, err := s.connect(ctx, lastEventID)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan Domain.TaskEvent)
+	go func() {
+		defer close(events)
+		for {
+			lastEventID = readEvents(ctx, resp.Body, events, lastEventID)
+			resp.Body.Close()
+			if resp, err = s.reconnect(ctx, lastEventID); err != nil {
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+func (s *TaskService) connect(ctx context.Context, lastEventID string) (*http.Response, error) {
+	req := request{
+		method: http.MethodGet,
+		path:   "/tasks/stream",
+		header: http.Header{"Accept": {"text/event-stream"}},
+	}
+	if lastEventID != "" {
+		req.header.Set("Last-Event-ID", lastEventID)
+	}
+	return s.client.send(ctx, req)
+}
+
+// reconnect keeps trying to connect, backing off in between, until it
+// succeeds, the server answers with an error, or the attempts run out
+func (s *TaskService) reconnect(ctx context.Context, lastEventID string) (*http.Response, error) {
+	policy := s.client.retry
+	for attempt := 1; ; attempt++ {
+		timer := time.NewTimer(policy.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		resp, err := s.connect(ctx, lastEventID)
+		var apiErr *Error
+		if err == nil || errors.As(err, &apiErr) || attempt >= policy.MaxAttempts {
+			return resp, err
+		}
+	}
+}
+
+// readEvents sends the events in an SSE body to events until the body ends
+// or ctx is done, and returns the ID of the last one
+func readEvents(ctx context.Context, body io.Reader, events chan<- Domain.TaskEvent, lastEventID string) string {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	var id string
+	var data []string
+	// An empty id field still counts: it clears the ID to resume from
+	var hasID bool
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			// A blank line ends the event
+			if len(data) > 0 {
+				var event Domain.TaskEvent
+				if json.Unmarshal([]byte(strings.Join(data, "\n")), &event) == nil {
+					if event.ID == "" {
+						event.ID = id
+					}
+					select {
+					case events <- event:
+					case <-ctx.Done():
+						return lastEventID
+					}
+				}
+			}
+			if hasID {
+				lastEventID = id
+			}
+			id, data, hasID = "", nil, false
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			// A comment, which the server sends to keep the connection alive
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			id, hasID = value, true
+		case "data":
+			data = append(data, value)
+		}
+	}
+	return lastEventID
+}
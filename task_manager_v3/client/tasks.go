@@ -0,0 +1,227 @@
+package client
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TaskService calls the task routes
+type TaskService struct {
+	client *Client
+}
+
+// taskBody is what the API accepts when creating or replacing a task
+type taskBody struct {
+	Title        string                 `json:"title"`
+	Description  string                 `json:"description,omitempty"`
+	DueDate      time.Time              `json:"due_date"`
+	Status       string                 `json:"status,omitempty"`
+	Priority     string                 `json:"priority,omitempty"`
+	Project      string                 `json:"project,omitempty"`
+	ExternalID   string                 `json:"external_id,omitempty"`
+	Labels       []primitive.ObjectID   `json:"labels,omitempty"`
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+}
+
+func newTaskBody(task Domain.Task) taskBody {
+	return taskBody{
+		Title:        task.Title,
+		Description:  task.Description,
+		DueDate:      task.DueDate,
+		Status:       task.Status,
+		Priority:     task.Priority,
+		Project:      task.Project,
+		ExternalID:   task.ExternalID,
+		Labels:       task.Labels,
+		CustomFields: task.CustomFields,
+	}
+}
+
+// taskPage is the body of GET /api/v2/tasks
+type taskPage struct {
+	Tasks    []Domain.Task `json:"tasks"`
+	Total    int64         `json:"total"`
+	Page     int           `json:"page"`
+	PageSize int           `json:"page_size"`
+}
+
+// Create adds a task; it needs an admin
+func (s *TaskService) Create(ctx context.Context, task Domain.Task) (Domain.Task, error) {
+	var created Domain.Task
+	err := s.client.do(ctx, http.MethodPost, "/tasks", nil, newTaskBody(task), &created)
+	return created, err
+}
+
+// GetAll returns every task, fetching them a page at a time
+func (s *TaskService) GetAll(ctx context.Context) ([]Domain.Task, error) {
+	return s.List(ctx, Domain.TaskFilter{})
+}
+
+// List returns every task matching filter, fetching them a page at a time
+func (s *TaskService) List(ctx context.Context, filter Domain.TaskFilter) ([]Domain.Task, error) {
+	it := s.Iterate(filter, 0)
+	tasks := []Domain.Task{}
+	for it.Next(ctx) {
+		tasks = append(tasks, it.Task())
+	}
+	return tasks, it.Err()
+}
+
+// ListPage returns one page of the tasks matching filter, pages starting
+// at 1, and how many match in all
+func (s *TaskService) ListPage(ctx context.Context, filter Domain.TaskFilter, page, pageSize int) ([]Domain.Task, int64, error) {
+	query := filterQuery(filter)
+	query.Set("page", strconv.Itoa(page))
+	query.Set("page_size", strconv.Itoa(pageSize))
+
+	var body taskPage
+	if err := s.client.do(ctx, http.MethodGet, "/tasks", query, nil, &body); err != nil {
+		return nil, 0, err
+	}
+	return body.Tasks, body.Total, nil
+}
+
+func (s *TaskService) GetByID(ctx context.Context, id primitive.ObjectID) (Domain.Task, error) {
+	var task Domain.Task
+	err := s.client.do(ctx, http.MethodGet, "/tasks/"+id.Hex(), nil, nil, &task)
+	return task, err
+}
+
+// Update replaces the task; it needs an admin
+func (s *TaskService) Update(ctx context.Context, id primitive.ObjectID, task Domain.Task) (Domain.Task, error) {
+	var updated Domain.Task
+	err := s.client.do(ctx, http.MethodPut, "/tasks/"+id.Hex(), nil, newTaskBody(task), &updated)
+	return updated, err
+}
+
+// Delete removes the task; it needs an admin
+func (s *TaskService) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return s.client.do(ctx, http.MethodDelete, "/tasks/"+id.Hex(), nil, nil, nil)
+}
+
+// Bulk applies ops in one request; it needs an admin. When atomic is set
+// either every operation is written or none is. Operations that failed on
+// their own are reported in their results rather than as an error. When
+// the server rejects the whole request, the results it sent back explain
+// why and are returned along with the error.
+func (s *TaskService) Bulk(ctx context.Context, ops []Domain.BulkOperation, atomic bool) ([]Domain.BulkResult, error) {
+	request := struct {
+		Operations []Domain.BulkOperation `json:"operations"`
+		Atomic     bool                   `json:"atomic"`
+	}{ops, atomic}
+	var body struct {
+		Results []Domain.BulkResult `json:"results"`
+	}
+	err := s.client.do(ctx, http.MethodPost, "/tasks/bulk", nil, request, &body)
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		json.Unmarshal(apiErr.Body, &body)
+	}
+	return body.Results, err
+}
+
+// Search returns up to limit tasks matching q, best first. A limit of 0
+// takes the server's default.
+func (s *TaskService) Search(ctx context.Context, q string, limit int) ([]Domain.TaskSearchResult, error) {
+	query := url.Values{"q": {q}}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var results []Domain.TaskSearchResult
+	err := s.client.do(ctx, http.MethodGet, "/tasks/search", query, nil, &results)
+	return results, err
+}
+
+// contentTypes are the media types of the import and export formats
+var contentTypes = map[string]string{
+	Domain.FormatCSV:    "text/csv",
+	Domain.FormatJSON:   "application/json",
+	Domain.FormatNDJSON: "application/x-ndjson",
+	Domain.FormatICS:    "text/calendar",
+}
+
+// Export writes every task to w in format, one of the Domain.Format
+// constants
+func (s *TaskService) Export(ctx context.Context, format string, w io.Writer) error {
+	req := request{
+		method: http.MethodGet,
+		path:   "/tasks/export",
+		query:  url.Values{"format": {format}},
+		header: http.Header{"Accept": {"*/*"}},
+	}
+	resp, err := s.client.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// Import reads tasks from r in format; it needs an admin. With dryRun the
+// server only validates them. The report lists what happened to each row,
+// including the rows that failed.
+func (s *TaskService) Import(ctx context.Context, format string, r io.Reader, dryRun bool) (Domain.ImportReport, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Domain.ImportReport{}, err
+	}
+	query := url.Values{"format": {format}}
+	if dryRun {
+		query.Set("dry_run", "true")
+	}
+	req := request{
+		method:      http.MethodPost,
+		path:        "/tasks/import",
+		query:       query,
+		body:        data,
+		contentType: contentTypes[format],
+	}
+
+	var report Domain.ImportReport
+	err = s.client.doRequest(ctx, req, &report)
+	return report, err
+}
+
+// filterQuery encodes a filter the way GET /tasks reads it
+func filterQuery(filter Domain.TaskFilter) url.Values {
+	query := url.Values{}
+	if filter.Status != "" {
+		query.Set("status", filter.Status)
+	}
+	if filter.Priority != "" {
+		query.Set("priority", filter.Priority)
+	}
+	if filter.Project != "" {
+		query.Set("project", filter.Project)
+	}
+	for _, label := range filter.Labels {
+		query.Add("label", label.Hex())
+	}
+	for key, value := range filter.CustomFields {
+		query.Set("cf."+key, fmt.Sprint(value))
+	}
+	if len(filter.Sort) > 0 {
+		fields := make([]string, 0, len(filter.Sort))
+		for _, field := range filter.Sort {
+			if field.Desc {
+				fields = append(fields, "-"+field.Field)
+			} else {
+				fields = append(fields, field.Field)
+			}
+		}
+		query.Set("sort", strings.Join(fields, ","))
+	}
+	return query
+}
@@ -0,0 +1,180 @@
+package client
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserService calls the account and user routes
+type UserService struct {
+	client *Client
+}
+
+// loginBody is the body of POST /login's response
+type loginBody struct {
+	Token              string `json:"token"`
+	UserID             string `json:"user_id"`
+	MFARequired        bool   `json:"mfa_required"`
+	MFAToken           string `json:"mfa_token"`
+	EnrollmentRequired bool   `json:"enrollment_required"`
+}
+
+// userPage is the body of GET /users
+type userPage struct {
+	Users    []Domain.User `json:"users"`
+	Total    int64         `json:"total"`
+	Page     int           `json:"page"`
+	PageSize int           `json:"page_size"`
+}
+
+// Register creates an account. It is sent without a token.
+func (s *UserService) Register(ctx context.Context, reg Domain.Registration) error {
+	req, err := jsonRequest(http.MethodPost, "/register", nil, reg)
+	if err != nil {
+		return err
+	}
+	req.anonymous = true
+	return s.client.doRequest(ctx, req, nil)
+}
+
+// Login checks the password. On success the client sends the new token
+// with every request that follows. Accounts with two-factor authentication
+// get a result with MFAPending set and the pending token instead, and the
+// client's token is left alone.
+func (s *UserService) Login(ctx context.Context, username, password string) (Domain.LoginResult, error) {
+	req, err := jsonRequest(http.MethodPost, "/login", nil, map[string]string{"username": username, "password": password})
+	if err != nil {
+		return Domain.LoginResult{}, err
+	}
+	req.anonymous = true
+
+	var body loginBody
+	if err := s.client.doRequest(ctx, req, &body); err != nil {
+		return Domain.LoginResult{}, err
+	}
+	if body.MFARequired {
+		return Domain.LoginResult{Token: body.MFAToken, MFAPending: true, EnrollmentRequired: body.EnrollmentRequired}, nil
+	}
+
+	result := Domain.LoginResult{Token: body.Token, User: Domain.User{Username: username}}
+	result.User.ID, _ = primitive.ObjectIDFromHex(body.UserID)
+	s.client.SetToken(body.Token)
+	return result, nil
+}
+
+// CurrentUser is the account the client is authenticated as
+func (s *UserService) CurrentUser(ctx context.Context) (Domain.User, error) {
+	var user Domain.User
+	err := s.client.do(ctx, http.MethodGet, "/me", nil, nil, &user)
+	return user, err
+}
+
+// Promote makes the user an admin; it needs an admin
+func (s *UserService) Promote(ctx context.Context, userID primitive.ObjectID) error {
+	return s.client.do(ctx, http.MethodPost, "/promote", nil, map[string]string{"user_id": userID.Hex()}, nil)
+}
+
+// ListUsers returns one page of the users matching query and how many match
+// in all; it needs an admin. Iterate walks every page.
+func (s *UserService) ListUsers(ctx context.Context, query Domain.UserQuery) ([]Domain.User, int64, error) {
+	values := url.Values{}
+	if query.Search != "" {
+		values.Set("q", query.Search)
+	}
+	if query.Role != "" {
+		values.Set("role", query.Role)
+	}
+	if query.Status != "" {
+		values.Set("status", query.Status)
+	}
+	if query.Page > 0 {
+		values.Set("page", strconv.Itoa(query.Page))
+	}
+	if query.PageSize > 0 {
+		values.Set("page_size", strconv.Itoa(query.PageSize))
+	}
+
+	var body userPage
+	if err := s.client.do(ctx, http.MethodGet, "/users", values, nil, &body); err != nil {
+		return nil, 0, err
+	}
+	return body.Users, body.Total, nil
+}
+
+// GetUser needs an admin
+func (s *UserService) GetUser(ctx context.Context, id primitive.ObjectID) (Domain.User, error) {
+	var user Domain.User
+	err := s.client.do(ctx, http.MethodGet, "/users/"+id.Hex(), nil, nil, &user)
+	return user, err
+}
+
+// ChangeRole, Deactivate, Reactivate and Delete need an admin, who cannot
+// apply them to themselves. The server takes the acting admin from the
+// token.
+func (s *UserService) ChangeRole(ctx context.Context, id primitive.ObjectID, role string) (Domain.User, error) {
+	var user Domain.User
+	err := s.client.do(ctx, http.MethodPut, "/users/"+id.Hex()+"/role", nil, map[string]string{"role": role}, &user)
+	return user, err
+}
+
+func (s *UserService) Deactivate(ctx context.Context, id primitive.ObjectID) (Domain.User, error) {
+	var user Domain.User
+	err := s.client.do(ctx, http.MethodPost, "/users/"+id.Hex()+"/deactivate", nil, nil, &user)
+	return user, err
+}
+
+func (s *UserService) Reactivate(ctx context.Context, id primitive.ObjectID) (Domain.User, error) {
+	var user Domain.User
+	err := s.client.do(ctx, http.MethodPost, "/users/"+id.Hex()+"/reactivate", nil, nil, &user)
+	return user, err
+}
+
+func (s *UserService) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return s.client.do(ctx, http.MethodDelete, "/users/"+id.Hex(), nil, nil, nil)
+}
+
+// UpdateProfile changes the caller's own account
+func (s *UserService) UpdateProfile(ctx context.Context, update Domain.ProfileUpdate) (Domain.User, error) {
+	var user Domain.User
+	err := s.client.do(ctx, http.MethodPut, "/me", nil, update, &user)
+	return user, err
+}
+
+// ChangePassword changes the caller's own password. Access tokens can't.
+func (s *UserService) ChangePassword(ctx context.Context, current, next string) error {
+	body := map[string]string{"current_password": current, "new_password": next}
+	return s.client.do(ctx, http.MethodPut, "/me/password", nil, body, nil)
+}
+
+// CreateInvitation returns the invitation and its token, which the server
+// does not keep; it needs an admin. An empty role and a zero ttl take the
+// server's defaults.
+func (s *UserService) CreateInvitation(ctx context.Context, role, email string, ttl time.Duration) (Domain.Invitation, string, error) {
+	request := map[string]interface{}{"role": role, "email": email, "expires_in_hours": int(ttl / time.Hour)}
+	var body struct {
+		Invitation Domain.Invitation `json:"invitation"`
+		Token      string            `json:"token"`
+	}
+	if err := s.client.do(ctx, http.MethodPost, "/invitations", nil, request, &body); err != nil {
+		return Domain.Invitation{}, "", err
+	}
+	return body.Invitation, body.Token, nil
+}
+
+// ListInvitations needs an admin
+func (s *UserService) ListInvitations(ctx context.Context) ([]Domain.Invitation, error) {
+	var invitations []Domain.Invitation
+	err := s.client.do(ctx, http.MethodGet, "/invitations", nil, nil, &invitations)
+	return invitations, err
+}
+
+// RevokeInvitation needs an admin
+func (s *UserService) RevokeInvitation(ctx context.Context, id primitive.ObjectID) error {
+	return s.client.do(ctx, http.MethodDelete, "/invitations/"+id.Hex(), nil, nil, nil)
+}
@@ -0,0 +1,18 @@
+// Command taskctl manages tasks on a task_manager_v3 server:
+//
+//	taskctl login -u alice
+//	taskctl tasks list --status pending -o yaml
+//	taskctl completion bash > /etc/bash_completion.d/taskctl
+package main
+
+import (
+	"os"
+
+	"a2sv-backend/task_manager_v3/taskctl"
+)
+
+func main() {
+	if err := taskctl.NewRootCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,110 @@
+package taskctl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultProfile is used until another profile is selected
+const DefaultProfile = "default"
+
+// DefaultServer is where profiles point unless given a server
+const DefaultServer = "http://localhost:8080"
+
+// Profile is one server taskctl can talk to, with the token it logged in
+// with
+type Profile struct {
+	Server   string `yaml:"server"`
+	Username string `yaml:"username,omitempty"`
+	Token    string `yaml:"token,omitempty"`
+}
+
+// Config is the taskctl configuration file. It holds tokens, so it is
+// written readable by its owner only.
+type Config struct {
+	// Current names the profile used when --profile isn't given
+	Current  string              `yaml:"current,omitempty"`
+	Profiles map[string]*Profile `yaml:"profiles,omitempty"`
+
+	path string
+}
+
+// DefaultConfigPath is $TASKCTL_CONFIG, or taskctl/config.yaml under the
+// user's configuration directory
+func DefaultConfigPath() (string, error) {
+	if path := os.Getenv("TASKCTL_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "taskctl", "config.yaml"), nil
+}
+
+// LoadConfig reads the configuration at path. A missing file is an empty
+// configuration.
+func LoadConfig(path string) (*Config, error) {
+	config := &Config{Profiles: map[string]*Profile{}, path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if config.Profiles == nil {
+		config.Profiles = map[string]*Profile{}
+	}
+	return config, nil
+}
+
+// Save writes the configuration back where it was loaded from
+func (c *Config) Save() error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0o600)
+}
+
+// CurrentName is the profile used when none is asked for
+func (c *Config) CurrentName() string {
+	if c.Current == "" {
+		return DefaultProfile
+	}
+	return c.Current
+}
+
+// Profile returns the named profile, or the current one for ""
+func (c *Config) Profile(name string) (*Profile, error) {
+	if name == "" {
+		name = c.CurrentName()
+	}
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("no profile %q; create it with taskctl profile add or taskctl login", name)
+	}
+	return profile, nil
+}
+
+// ProfileNames lists the profiles in alphabetical order
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,212 @@
+package taskctl
+
+import (
+	"a2sv-backend/task_manager_v3/client"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+func newLoginCommand(opts *options) *cobra.Command {
+	var username, token string
+	var passwordStdin bool
+	cmd := &cobra.Command{
+		Use:   "login",
+		Short: "Log in and store the token in the profile",
+		Long: `Log in with a username and password and store the token in the profile,
+creating the profile if needed. Accounts with two-factor authentication,
+and scripts, can store a personal access token with --token instead.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := opts.profileName()
+			profile, ok := opts.config.Profiles[name]
+			if !ok {
+				profile = &Profile{Server: DefaultServer}
+			}
+			if opts.server != "" {
+				profile.Server = opts.server
+			}
+
+			if token == "" {
+				if username == "" {
+					username = profile.Username
+				}
+				if username == "" {
+					return errors.New("--username is required")
+				}
+				password, err := readPassword(cmd, passwordStdin)
+				if err != nil {
+					return err
+				}
+				api := client.New(profile.Server)
+				result, err := api.Users.Login(cmd.Context(), username, password)
+				if err != nil {
+					return err
+				}
+				if result.MFAPending {
+					return errors.New("this account uses two-factor authentication; create an access token and log in with --token")
+				}
+				token = result.Token
+				profile.Username = username
+			} else {
+				profile.Username = ""
+			}
+
+			profile.Token = token
+			opts.config.Profiles[name] = profile
+			if opts.config.Current == "" {
+				opts.config.Current = name
+			}
+			if err := opts.config.Save(); err != nil {
+				return err
+			}
+			if profile.Username != "" {
+				fmt.Fprintf(cmd.OutOrStdout(), "Logged in to %s as %s (profile %s)\n", profile.Server, profile.Username, name)
+			} else {
+				fmt.Fprintf(cmd.OutOrStdout(), "Stored access token for %s (profile %s)\n", profile.Server, name)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&username, "username", "u", "", "username (default the profile's last one)")
+	cmd.Flags().BoolVar(&passwordStdin, "password-stdin", false, "read the password from standard input")
+	cmd.Flags().StringVar(&token, "token", "", "store this personal access token instead of logging in")
+	return cmd
+}
+
+// readPassword prompts for the password without echo on a terminal, and
+// otherwise reads the first line of standard input
+func readPassword(cmd *cobra.Command, fromStdin bool) (string, error) {
+	in := cmd.InOrStdin()
+	if file, ok := in.(*os.File); ok && !fromStdin && term.IsTerminal(int(file.Fd())) {
+		fmt.Fprint(cmd.ErrOrStderr(), "Password: ")
+		password, err := term.ReadPassword(int(file.Fd()))
+		fmt.Fprintln(cmd.ErrOrStderr())
+		return string(password), err
+	}
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("no password given")
+	}
+	return password, nil
+}
+
+func newLogoutCommand(opts *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "logout",
+		Short: "Forget the profile's token",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profile, err := opts.config.Profile(opts.profileName())
+			if err != nil {
+				return err
+			}
+			profile.Token = ""
+			if err := opts.config.Save(); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Logged out of profile %s\n", opts.profileName())
+			return nil
+		},
+	}
+}
+
+func newProfileCommand(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Manage server profiles",
+	}
+
+	var server string
+	add := &cobra.Command{
+		Use:   "add NAME",
+		Short: "Add a profile, or change its server",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profile, ok := opts.config.Profiles[args[0]]
+			if !ok {
+				profile = &Profile{}
+				opts.config.Profiles[args[0]] = profile
+			}
+			profile.Server = server
+			return opts.config.Save()
+		},
+	}
+	add.Flags().StringVar(&server, "url", DefaultServer, "server URL")
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List profiles",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			current := opts.config.CurrentName()
+			type profileRow struct {
+				Name     string `json:"name"`
+				Server   string `json:"server"`
+				Username string `json:"username,omitempty"`
+				Current  bool   `json:"current"`
+				LoggedIn bool   `json:"logged_in"`
+			}
+			rows := []profileRow{}
+			for _, name := range opts.config.ProfileNames() {
+				profile := opts.config.Profiles[name]
+				rows = append(rows, profileRow{Name: name, Server: profile.Server, Username: profile.Username, Current: name == current, LoggedIn: profile.Token != ""})
+			}
+			return write(cmd.OutOrStdout(), opts.output, rows, func(tw *tabwriter.Writer) {
+				fmt.Fprintln(tw, "CURRENT\tNAME\tSERVER\tUSER")
+				for _, row := range rows {
+					marker := ""
+					if row.Current {
+						marker = "*"
+					}
+					fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", marker, row.Name, row.Server, orDash(row.Username))
+				}
+			})
+		},
+	}
+
+	use := &cobra.Command{
+		Use:               "use NAME",
+		Short:             "Make a profile the current one",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: opts.completeProfileArg,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := opts.config.Profile(args[0]); err != nil {
+				return err
+			}
+			opts.config.Current = args[0]
+			return opts.config.Save()
+		},
+	}
+
+	remove := &cobra.Command{
+		Use:               "remove NAME",
+		Short:             "Delete a profile and its token",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: opts.completeProfileArg,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := opts.config.Profile(args[0]); err != nil {
+				return err
+			}
+			delete(opts.config.Profiles, args[0])
+			if opts.config.Current == args[0] {
+				opts.config.Current = ""
+			}
+			return opts.config.Save()
+		},
+	}
+
+	cmd.AddCommand(add, list, use, remove)
+	return cmd
+}
@@ -0,0 +1,115 @@
+package taskctl
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+)
+
+var formats = []string{formatTable, formatJSON, formatYAML}
+
+func validFormat(format string) bool {
+	for _, f := range formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// write prints value as JSON or YAML, or calls table for the table format.
+// YAML uses the JSON field names, in the same order.
+func write(w io.Writer, format string, value interface{}, table func(w *tabwriter.Writer)) error {
+	switch format {
+	case formatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	case formatYAML:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		// JSON is YAML written in flow style; decode it and print it back
+		// in block style
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			return err
+		}
+		blockStyle(&node)
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(&node); err != nil {
+			return err
+		}
+		return encoder.Close()
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	table(tw)
+	return tw.Flush()
+}
+
+func blockStyle(node *yaml.Node) {
+	// The encoder quotes strings again where they'd read as something else
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+func writeTasks(w io.Writer, format string, tasks []Domain.Task) error {
+	return write(w, format, tasks, func(tw *tabwriter.Writer) {
+		fmt.Fprintln(tw, "ID\tTITLE\tSTATUS\tPRIORITY\tPROJECT\tDUE")
+		for _, task := range tasks {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", task.ID.Hex(), task.Title, orDash(task.Status), orDash(task.Priority), orDash(task.Project), task.DueDate.Format("2006-01-02"))
+		}
+	})
+}
+
+// writeTask prints one task, as a list of fields in table format
+func writeTask(w io.Writer, format string, task Domain.Task) error {
+	return write(w, format, task, func(tw *tabwriter.Writer) {
+		fmt.Fprintf(tw, "ID:\t%s\n", task.ID.Hex())
+		fmt.Fprintf(tw, "Title:\t%s\n", task.Title)
+		fmt.Fprintf(tw, "Description:\t%s\n", orDash(task.Description))
+		fmt.Fprintf(tw, "Status:\t%s\n", orDash(task.Status))
+		fmt.Fprintf(tw, "Priority:\t%s\n", orDash(task.Priority))
+		fmt.Fprintf(tw, "Project:\t%s\n", orDash(task.Project))
+		fmt.Fprintf(tw, "Due:\t%s\n", task.DueDate.Format("2006-01-02 15:04 MST"))
+		labels := make([]string, 0, len(task.Labels))
+		for _, label := range task.Labels {
+			labels = append(labels, label.Hex())
+		}
+		fmt.Fprintf(tw, "Labels:\t%s\n", orDash(strings.Join(labels, ", ")))
+		keys := make([]string, 0, len(task.CustomFields))
+		for key := range task.CustomFields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(tw, "cf.%s:\t%v\n", key, task.CustomFields[key])
+		}
+		fmt.Fprintf(tw, "Created:\t%s\n", task.CreatedAt.Format("2006-01-02 15:04 MST"))
+		fmt.Fprintf(tw, "Updated:\t%s\n", task.UpdatedAt.Format("2006-01-02 15:04 MST"))
+	})
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
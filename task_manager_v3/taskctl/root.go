@@ -0,0 +1,119 @@
+// Package taskctl is the taskctl command line client. It is built on the
+// client package; cmd/taskctl is its entry point.
+package taskctl
+
+import (
+	"a2sv-backend/task_manager_v3/client"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// options are the flags every command shares
+type options struct {
+	configPath string
+	profile    string
+	server     string
+	output     string
+
+	config *Config
+}
+
+// NewRootCommand builds taskctl with all its commands. Shell completion
+// scripts come from its "completion" command.
+func NewRootCommand() *cobra.Command {
+	opts := &options{}
+	root := &cobra.Command{
+		Use:          "taskctl",
+		Short:        "Manage tasks on a task_manager_v3 server",
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// Completion requests parse the flags later, and the
+			// completion functions load the configuration themselves
+			if cmd.Name() == cobra.ShellCompRequestCmd {
+				return nil
+			}
+			return opts.loadConfig()
+		},
+	}
+
+	flags := root.PersistentFlags()
+	flags.StringVar(&opts.configPath, "config", "", "configuration file (default $TASKCTL_CONFIG or the user config dir)")
+	flags.StringVarP(&opts.profile, "profile", "p", "", "server profile to use (default the current profile)")
+	flags.StringVar(&opts.server, "server", "", "server URL, overriding the profile's")
+	flags.StringVarP(&opts.output, "output", "o", formatTable, "output format: table, json or yaml")
+	root.RegisterFlagCompletionFunc("profile", opts.completeProfiles)
+	root.RegisterFlagCompletionFunc("output", cobra.FixedCompletions(formats, cobra.ShellCompDirectiveNoFileComp))
+
+	root.AddCommand(
+		newLoginCommand(opts),
+		newLogoutCommand(opts),
+		newProfileCommand(opts),
+		newTasksCommand(opts),
+		newUsersCommand(opts),
+	)
+	return root
+}
+
+func (o *options) loadConfig() error {
+	if o.config != nil {
+		return nil
+	}
+	if !validFormat(o.output) {
+		return fmt.Errorf("unknown output format %q, expected table, json or yaml", o.output)
+	}
+	path := o.configPath
+	if path == "" {
+		var err error
+		if path, err = DefaultConfigPath(); err != nil {
+			return err
+		}
+	}
+	config, err := LoadConfig(path)
+	if err != nil {
+		return err
+	}
+	o.config = config
+	return nil
+}
+
+// profileName is the profile the command works on
+func (o *options) profileName() string {
+	if o.profile != "" {
+		return o.profile
+	}
+	return o.config.CurrentName()
+}
+
+// client returns an API client for the selected profile, authenticated
+// with its token
+func (o *options) client() (*client.Client, error) {
+	profile, err := o.config.Profile(o.profileName())
+	if err != nil {
+		return nil, err
+	}
+	server := profile.Server
+	if o.server != "" {
+		server = o.server
+	}
+	if profile.Token == "" {
+		return nil, fmt.Errorf("not logged in to profile %q; run taskctl login", o.profileName())
+	}
+	return client.New(server, client.WithToken(profile.Token)), nil
+}
+
+// completeProfiles completes profile names
+func (o *options) completeProfiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if err := o.loadConfig(); err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	return o.config.ProfileNames(), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeProfileArg completes a command's single profile argument
+func (o *options) completeProfileArg(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return o.completeProfiles(cmd, args, toComplete)
+}
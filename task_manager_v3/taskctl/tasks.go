@@ -0,0 +1,319 @@
+package taskctl
+
+import (
+	"a2sv-backend/task_manager_v3/Domain"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	statuses   = []string{Domain.StatusPending, Domain.StatusInProgress, Domain.StatusCompleted}
+	priorities = []string{Domain.PriorityP0, Domain.PriorityP1, Domain.PriorityP2, Domain.PriorityP3}
+)
+
+func newTasksCommand(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "tasks",
+		Aliases: []string{"task"},
+		Short:   "List and manage tasks",
+	}
+	cmd.AddCommand(
+		newTasksListCommand(opts),
+		newTasksGetCommand(opts),
+		newTasksCreateCommand(opts),
+		newTasksUpdateCommand(opts),
+		newTasksDeleteCommand(opts),
+	)
+	return cmd
+}
+
+func newTasksListCommand(opts *options) *cobra.Command {
+	var filter Domain.TaskFilter
+	var labels, fields []string
+	var sort string
+	var page, pageSize int
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List one page of tasks",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			api, err := opts.client()
+			if err != nil {
+				return err
+			}
+			if filter.Labels, err = objectIDs(labels, "label"); err != nil {
+				return err
+			}
+			if filter.CustomFields, err = customFields(fields); err != nil {
+				return err
+			}
+			if sort != "" {
+				for _, field := range strings.Split(sort, ",") {
+					filter.Sort = append(filter.Sort, Domain.SortField{Field: strings.TrimPrefix(field, "-"), Desc: strings.HasPrefix(field, "-")})
+				}
+			}
+
+			tasks, total, err := api.Tasks.ListPage(cmd.Context(), filter, page, pageSize)
+			if err != nil {
+				return err
+			}
+			if err := writeTasks(cmd.OutOrStdout(), opts.output, tasks); err != nil {
+				return err
+			}
+			if opts.output == formatTable {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Page %d, %d of %d tasks\n", page, len(tasks), total)
+			}
+			return nil
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&filter.Status, "status", "", "only tasks with this status")
+	flags.StringVar(&filter.Priority, "priority", "", "only tasks with this priority")
+	flags.StringVar(&filter.Project, "project", "", "only tasks in this project")
+	flags.StringSliceVar(&labels, "label", nil, "only tasks with this label ID (repeatable)")
+	flags.StringArrayVar(&fields, "field", nil, "only tasks with custom field KEY=VALUE (repeatable)")
+	flags.StringVar(&sort, "sort", "", "comma separated fields to sort by, - prefixed for descending")
+	flags.IntVar(&page, "page", 1, "page to show, starting at 1")
+	flags.IntVar(&pageSize, "page-size", Domain.DefaultTaskPageSize, "tasks per page")
+	cmd.RegisterFlagCompletionFunc("status", cobra.FixedCompletions(statuses, cobra.ShellCompDirectiveNoFileComp))
+	cmd.RegisterFlagCompletionFunc("priority", cobra.FixedCompletions(priorities, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+func newTasksGetCommand(opts *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "get ID",
+		Short: "Show a task",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := objectID(args[0], "task")
+			if err != nil {
+				return err
+			}
+			api, err := opts.client()
+			if err != nil {
+				return err
+			}
+			task, err := api.Tasks.GetByID(cmd.Context(), id)
+			if err != nil {
+				return err
+			}
+			return writeTask(cmd.OutOrStdout(), opts.output, task)
+		},
+	}
+}
+
+// taskFlags are the fields create and update set
+type taskFlags struct {
+	title, description, due, status, priority, project, externalID string
+	labels, fields                                                 []string
+}
+
+func (f *taskFlags) register(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	flags.StringVar(&f.title, "title", "", "title")
+	flags.StringVar(&f.description, "description", "", "description")
+	flags.StringVar(&f.due, "due", "", "due date, as 2006-01-02 or RFC 3339")
+	flags.StringVar(&f.status, "status", "", "status: "+strings.Join(statuses, ", "))
+	flags.StringVar(&f.priority, "priority", "", "priority: "+strings.Join(priorities, ", "))
+	flags.StringVar(&f.project, "project", "", "project")
+	flags.StringVar(&f.externalID, "external-id", "", "ID in another system")
+	flags.StringSliceVar(&f.labels, "label", nil, "label ID (repeatable)")
+	flags.StringArrayVar(&f.fields, "field", nil, "custom field KEY=VALUE, VALUE read as JSON when it parses (repeatable)")
+	cmd.RegisterFlagCompletionFunc("status", cobra.FixedCompletions(statuses, cobra.ShellCompDirectiveNoFileComp))
+	cmd.RegisterFlagCompletionFunc("priority", cobra.FixedCompletions(priorities, cobra.ShellCompDirectiveNoFileComp))
+}
+
+// apply sets the fields whose flags were given on task
+func (f *taskFlags) apply(cmd *cobra.Command, task *Domain.Task) error {
+	changed := cmd.Flags().Changed
+	if changed("title") {
+		task.Title = f.title
+	}
+	if changed("description") {
+		task.Description = f.description
+	}
+	if changed("due") {
+		due, err := parseDue(f.due)
+		if err != nil {
+			return err
+		}
+		task.DueDate = due
+	}
+	if changed("status") {
+		task.Status = f.status
+	}
+	if changed("priority") {
+		task.Priority = f.priority
+	}
+	if changed("project") {
+		task.Project = f.project
+	}
+	if changed("external-id") {
+		task.ExternalID = f.externalID
+	}
+	if changed("label") {
+		labels, err := objectIDs(f.labels, "label")
+		if err != nil {
+			return err
+		}
+		task.Labels = labels
+	}
+	if changed("field") {
+		fields, err := customFields(f.fields)
+		if err != nil {
+			return err
+		}
+		if task.CustomFields == nil {
+			task.CustomFields = map[string]interface{}{}
+		}
+		for key, value := range fields {
+			task.CustomFields[key] = value
+		}
+	}
+	return nil
+}
+
+func newTasksCreateCommand(opts *options) *cobra.Command {
+	flags := &taskFlags{}
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a task (admins only)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var task Domain.Task
+			if err := flags.apply(cmd, &task); err != nil {
+				return err
+			}
+			api, err := opts.client()
+			if err != nil {
+				return err
+			}
+			created, err := api.Tasks.Create(cmd.Context(), task)
+			if err != nil {
+				return err
+			}
+			return writeTask(cmd.OutOrStdout(), opts.output, created)
+		},
+	}
+	flags.register(cmd)
+	cmd.MarkFlagRequired("title")
+	cmd.MarkFlagRequired("due")
+	return cmd
+}
+
+func newTasksUpdateCommand(opts *options) *cobra.Command {
+	flags := &taskFlags{}
+	cmd := &cobra.Command{
+		Use:   "update ID",
+		Short: "Change some fields of a task (admins only)",
+		Long:  "Change the fields given as flags, leaving the rest of the task as it is.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := objectID(args[0], "task")
+			if err != nil {
+				return err
+			}
+			api, err := opts.client()
+			if err != nil {
+				return err
+			}
+			// The API replaces whole tasks, so start from the current one
+			task, err := api.Tasks.GetByID(cmd.Context(), id)
+			if err != nil {
+				return err
+			}
+			if err := flags.apply(cmd, &task); err != nil {
+				return err
+			}
+			updated, err := api.Tasks.Update(cmd.Context(), id, task)
+			if err != nil {
+				return err
+			}
+			return writeTask(cmd.OutOrStdout(), opts.output, updated)
+		},
+	}
+	flags.register(cmd)
+	return cmd
+}
+
+func newTasksDeleteCommand(opts *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete ID...",
+		Short: "Delete tasks (admins only)",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ids, err := objectIDs(args, "task")
+			if err != nil {
+				return err
+			}
+			api, err := opts.client()
+			if err != nil {
+				return err
+			}
+			for _, id := range ids {
+				if err := api.Tasks.Delete(cmd.Context(), id); err != nil {
+					return fmt.Errorf("deleting %s: %w", id.Hex(), err)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Deleted task %s\n", id.Hex())
+			}
+			return nil
+		},
+	}
+}
+
+func objectID(hex, what string) (primitive.ObjectID, error) {
+	id, err := primitive.ObjectIDFromHex(hex)
+	if err != nil {
+		return id, fmt.Errorf("invalid %s ID %q", what, hex)
+	}
+	return id, nil
+}
+
+func objectIDs(hexes []string, what string) ([]primitive.ObjectID, error) {
+	ids := make([]primitive.ObjectID, 0, len(hexes))
+	for _, hex := range hexes {
+		id, err := objectID(hex, what)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// customFields parses KEY=VALUE pairs. Values that are valid JSON, like
+// numbers and booleans, keep their type; anything else is a string.
+func customFields(pairs []string) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	for _, pair := range pairs {
+		key, raw, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("custom field %q must be KEY=VALUE", pair)
+		}
+		var value interface{}
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			value = raw
+		}
+		fields[key] = value
+	}
+	return fields, nil
+}
+
+// parseDue reads a due date given as a day, taken as midnight UTC, or as an
+// RFC 3339 time
+func parseDue(raw string) (time.Time, error) {
+	if due, err := time.Parse("2006-01-02", raw); err == nil {
+		return due, nil
+	}
+	due, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid due date %q, expected 2006-01-02 or RFC 3339", raw)
+	}
+	return due, nil
+}
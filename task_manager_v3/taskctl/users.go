@@ -0,0 +1,39 @@
+package taskctl
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func newUsersCommand(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "users",
+		Aliases: []string{"user"},
+		Short:   "Manage users (admins only)",
+	}
+
+	promote := &cobra.Command{
+		Use:   "promote USER_ID",
+		Short: "Make a user an admin",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := objectID(args[0], "user")
+			if err != nil {
+				return err
+			}
+			api, err := opts.client()
+			if err != nil {
+				return err
+			}
+			if err := api.Users.Promote(cmd.Context(), id); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Promoted user %s to admin\n", id.Hex())
+			return nil
+		},
+	}
+
+	cmd.AddCommand(promote)
+	return cmd
+}